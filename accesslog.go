@@ -0,0 +1,80 @@
+package hawk
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// ScopesKey is the context key of the credentials scopes.
+const ScopesKey = "hawk_scopes"
+
+// AccessLogFields is the standard set of authentication fields of an
+// access log entry, named like the tracing attributes.
+// Method is "header", "bewit" or empty if the request is anonymous.
+type AccessLogFields struct {
+	CredentialID string   `json:"hawk.credential_id,omitempty"`
+	Method       string   `json:"hawk.method,omitempty"`
+	Scopes       []string `json:"hawk.scopes,omitempty"`
+}
+
+// String formats the fields as "key=value" pairs.
+func (f AccessLogFields) String() string {
+	return "hawk.credential_id=" + f.CredentialID +
+		" hawk.method=" + f.Method +
+		" hawk.scopes=" + strings.Join(f.Scopes, ",")
+}
+
+// accessLogFields returns the fields from the values set by the Filter.
+func accessLogFields(auth, scopes interface{}) AccessLogFields {
+	res := AccessLogFields{}
+	if a, ok := auth.(*hawk.Auth); ok && a != nil {
+		res.CredentialID = a.Credentials.ID
+		res.Method = "header"
+		if a.IsBewit {
+			res.Method = "bewit"
+		}
+	}
+	if s, ok := scopes.([]string); ok {
+		res.Scopes = s
+	}
+	return res
+}
+
+// AccessLogFieldsFromContext returns the access log fields of an
+// authenticated request, empty fields otherwise.
+func AccessLogFieldsFromContext(c *gin.Context) AccessLogFields {
+	auth, _ := c.Get(AuthKey)
+	scopes, _ := c.Get(ScopesKey)
+	return accessLogFields(auth, scopes)
+}
+
+// ScopesFromContext returns the scopes of the credentials from the context.
+func ScopesFromContext(c *gin.Context) []string {
+	if v, exists := c.Get(ScopesKey); exists {
+		return v.([]string)
+	}
+	return nil
+}
+
+// AccessLog is a companion middleware to use after the Filter:
+// it calls log with the access log fields once the request is processed.
+func AccessLog(log func(c *gin.Context, fields AccessLogFields)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		log(c, AccessLogFieldsFromContext(c))
+	}
+}
+
+// LogFormatter wraps a gin LogFormatter to append the access log fields
+// to each line, for example:
+//
+//	router.Use(gin.LoggerWithFormatter(hawk.LogFormatter(myFormatter)))
+func LogFormatter(f gin.LogFormatter) gin.LogFormatter {
+	return func(params gin.LogFormatterParams) string {
+		line := strings.TrimSuffix(f(params), "\n")
+		fields := accessLogFields(params.Keys[AuthKey], params.Keys[ScopesKey])
+		return line + " " + fields.String() + "\n"
+	}
+}
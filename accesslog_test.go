@@ -0,0 +1,94 @@
+package hawk_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AccessLog", func() {
+
+	var ts *httptest.Server
+	var logged []AccessLogFields
+	var out *bytes.Buffer
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{
+			Key:    "test-cred-key",
+			Scopes: []string{"read", "write"},
+		}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		logged = nil
+		out = &bytes.Buffer{}
+		hm := NewMiddleware(getCredentials, setNonce)
+		router := gin.New()
+		router.Use(gin.LoggerWithConfig(gin.LoggerConfig{
+			Output: out,
+			Formatter: LogFormatter(func(p gin.LogFormatterParams) string {
+				return p.Method + " " + p.Path + "\n"
+			}),
+		}))
+		log := AccessLog(func(c *gin.Context, f AccessLogFields) {
+			logged = append(logged, f)
+		})
+		router.GET("/private", hm.Filter, log, func(c *gin.Context) {
+			Expect(ScopesFromContext(c)).To(Equal([]string{"read", "write"}))
+			c.String(200, "ok")
+		})
+		router.GET("/public", log, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("logs the credential id, method and scopes", func() {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "my-id",
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+
+		Expect(logged).To(Equal([]AccessLogFields{{
+			CredentialID: "my-id",
+			Method:       "header",
+			Scopes:       []string{"read", "write"},
+		}}))
+		Expect(out.String()).To(Equal(
+			"GET /private hawk.credential_id=my-id hawk.method=header hawk.scopes=read,write\n"))
+	})
+
+	It("logs empty fields for anonymous requests", func() {
+		resp, err := http.Get(ts.URL + "/public")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(logged).To(Equal([]AccessLogFields{{}}))
+		Expect(out.String()).To(Equal(
+			"GET /public hawk.credential_id= hawk.method= hawk.scopes=\n"))
+	})
+
+})
@@ -0,0 +1,130 @@
+// Package buffalo provides a Buffalo middleware authenticating with a
+// hawk Middleware. The auth, user, scopes and app of verified requests
+// are set on the buffalo.Context with the hawk keys, and the rejected
+// requests are returned as errors to the Buffalo error handlers:
+//
+//	app.Use(hawkbuffalo.New(hm))
+//	app.GET("/private", func(c buffalo.Context) error {
+//		user, _ := hawkbuffalo.UserFromContext(c)
+//		...
+//	})
+package buffalo
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gobuffalo/buffalo"
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+// call is the state of a request running the Filter. It drops the
+// writes of the Filter once the request is verified or rejected, so
+// Buffalo writes the response.
+type call struct {
+	http.ResponseWriter
+	done     bool
+	err      error
+	verified *http.Request
+}
+
+type callKey struct{}
+
+func (w *call) WriteHeader(code int) {
+	if !w.done {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *call) Write(b []byte) (int, error) {
+	if w.done {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// New returns a Buffalo middleware running the Filter of hm. The
+// rejected requests return c.Error with the status of hm (see
+// hawk.Middleware ErrorStatus), the AbortHandler of hm is not used.
+func New(hm *hawk.Middleware) buffalo.MiddlewareFunc {
+	hm = hm.With(func(m *hawk.Middleware) {
+		m.AbortHandler = func(c *gin.Context, err error) {
+			if w, ok := c.Request.Context().Value(callKey{}).(*call); ok {
+				w.done = true
+				w.err = err
+			}
+		}
+	})
+	filter := hm.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		w := r.Context().Value(callKey{}).(*call)
+		w.done = true
+		w.verified = r
+	}))
+
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			w := &call{ResponseWriter: c.Response()}
+			r := c.Request()
+			filter.ServeHTTP(w, signedRequest(r.WithContext(context.WithValue(r.Context(), callKey{}, w))))
+
+			if w.err != nil {
+				return c.Error(hm.ErrorStatus(w.err), w.err)
+			} else if w.verified == nil {
+				// already rendered by the Filter
+				return nil
+			}
+			// the payload validation reads the body and restores it on the copy
+			r.Body = w.verified.Body
+			if res, ok := hawk.ResultFromRequest(w.verified); ok {
+				c.Set(hawk.AuthKey, res.Auth)
+				c.Set(hawk.UserKey, res.User)
+				if res.Scopes != nil {
+					c.Set(hawk.ScopesKey, res.Scopes)
+				}
+				if res.App != "" {
+					c.Set(hawk.AppKey, res.App)
+					c.Set(hawk.DelegateKey, res.Delegate)
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// signedRequest restores on r the path sent by the client, Buffalo adds
+// a trailing "/" to the URL path before the middlewares run and the MAC
+// would not match.
+func signedRequest(r *http.Request) *http.Request {
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil || u.EscapedPath() == r.URL.EscapedPath() {
+		return r
+	}
+	signed := *r.URL
+	signed.Path = u.Path
+	signed.RawPath = u.RawPath
+	r.URL = &signed
+	return r
+}
+
+// AuthFromContext returns the *hawkgo.Auth from the context and true,
+// or nil and false if it's not set.
+func AuthFromContext(c buffalo.Context) (*hawkgo.Auth, bool) {
+	auth, ok := c.Value(hawk.AuthKey).(*hawkgo.Auth)
+	return auth, ok
+}
+
+// UserFromContext returns the user object from the context and true,
+// or nil and false if it's not set.
+func UserFromContext(c buffalo.Context) (interface{}, bool) {
+	user := c.Value(hawk.UserKey)
+	return user, user != nil
+}
+
+// ScopesFromContext returns the scopes of the credentials from the context.
+func ScopesFromContext(c buffalo.Context) []string {
+	scopes, _ := c.Value(hawk.ScopesKey).([]string)
+	return scopes
+}
@@ -0,0 +1,13 @@
+package buffalo_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestBuffalo(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Buffalo Suite")
+}
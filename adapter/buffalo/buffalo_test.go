@@ -0,0 +1,54 @@
+package buffalo_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gobuffalo/buffalo"
+	hawkbuffalo "github.com/hyperboloide/hawk/adapter/buffalo"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("New", func() {
+
+	var app *buffalo.App
+	var user interface{}
+
+	BeforeEach(func() {
+		gin.SetMode(gin.ReleaseMode)
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+
+		user = nil
+		app = buffalo.New(buffalo.Options{Env: "test"})
+		app.Use(hawkbuffalo.New(store.Middleware()))
+		app.GET("/private", func(c buffalo.Context) error {
+			user, _ = hawkbuffalo.UserFromContext(c)
+			c.Response().WriteHeader(http.StatusAccepted)
+			_, err := c.Response().Write([]byte("ok"))
+			return err
+		})
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	It("sets the user in the context", func() {
+		req := httptest.NewRequest("GET", "http://example.com/private", nil)
+		auth, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := serve(req)
+		Expect(w.Code).To(Equal(http.StatusAccepted))
+		Expect(w.Body.String()).To(Equal("ok"))
+		Expect(user).To(Equal("my-id"))
+		Expect(auth.ValidResponse(w.Header().Get("Server-Authorization"))).To(Succeed())
+	})
+
+})
@@ -0,0 +1,22 @@
+package buffalo_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/hyperboloide/hawk"
+	hawkbuffalo "github.com/hyperboloide/hawk/adapter/buffalo"
+	"github.com/hyperboloide/hawk/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(hm *hawk.Middleware) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			app := buffalo.New(buffalo.Options{Env: "test"})
+			app.Use(hawkbuffalo.New(hm))
+			app.ANY("/{p:.*}", buffalo.WrapHandler(next))
+			return app
+		}
+	})
+}
@@ -0,0 +1,323 @@
+// Package caddy provides the "http.handlers.hawk" Caddy module, to put
+// Hawk authentication in front of any upstream without writing Go:
+//
+//	{
+//		order hawk after basic_auth
+//	}
+//	api.example.com {
+//		hawk {
+//			credentials_file /etc/hawk/credentials.yml
+//			nonce etcd http://etcd-1:2379 http://etcd-2:2379
+//			nonce_ttl 5m
+//			max_skew 1m
+//		}
+//		reverse_proxy localhost:8080 {
+//			header_up X-Hawk-Id {http.vars.hawk.id}
+//		}
+//	}
+//
+// The credentials file is watched and reloaded (see the credstore/file
+// package). The nonces are saved in memory by default, or in etcd for
+// clustered deployments. The rejected requests are returned as errors
+// to the Caddy error handlers (handle_errors).
+package caddy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/gin-gonic/gin"
+	"github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/credstore/file"
+	"github.com/hyperboloide/hawk/noncestore/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+func init() {
+	caddy.RegisterModule(Handler{})
+	httpcaddyfile.RegisterHandlerDirective("hawk", parseCaddyfile)
+}
+
+// Nonce backends.
+const (
+	NonceMemory = "memory"
+	NonceEtcd   = "etcd"
+)
+
+// Nonce configures the nonce backend.
+// Backend is NonceMemory (default) or NonceEtcd
+// Endpoints are the etcd endpoints
+// Prefix is prepended to the etcd keys, "/hawk/nonces/" by default
+// TTL is the minimum time nonces are kept, 2 minutes by default
+type Nonce struct {
+	Backend   string         `json:"backend,omitempty"`
+	Endpoints []string       `json:"endpoints,omitempty"`
+	Prefix    string         `json:"prefix,omitempty"`
+	TTL       caddy.Duration `json:"ttl,omitempty"`
+}
+
+// Handler authenticates the requests with Hawk, the id of the verified
+// credentials is set in the "hawk.id" variable.
+// CredentialsFile is the YAML or JSON credentials file
+// Nonce is the nonce backend
+// MaxSkew if set replaces the timestamp skew of the protocol
+type Handler struct {
+	CredentialsFile string         `json:"credentials_file,omitempty"`
+	Nonce           Nonce          `json:"nonce,omitempty"`
+	MaxSkew         caddy.Duration `json:"max_skew,omitempty"`
+
+	hm     *hawk.Middleware
+	filter http.Handler
+	creds  *file.Store
+	etcd   *clientv3.Client
+}
+
+// CaddyModule returns the Caddy module information.
+func (Handler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.hawk",
+		New: func() caddy.Module { return new(Handler) },
+	}
+}
+
+// Provision loads the credentials and connects the nonce backend.
+func (h *Handler) Provision(ctx caddy.Context) error {
+	creds, err := file.NewStore(h.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	logger := ctx.Logger()
+	creds.OnError = func(err error) {
+		logger.Error("reloading hawk credentials", zap.Error(err))
+	}
+	if err := creds.Watch(); err != nil {
+		return err
+	}
+	h.creds = creds
+
+	ttl := time.Duration(h.Nonce.TTL)
+	if ttl == 0 {
+		ttl = 2 * time.Minute
+	}
+	var setNonce hawk.SetNonceFunc
+	switch h.Nonce.Backend {
+	case "", NonceMemory:
+		setNonce = newMemoryNonces(ttl).SetNonce
+	case NonceEtcd:
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints:   h.Nonce.Endpoints,
+			DialTimeout: 5 * time.Second,
+		})
+		if err != nil {
+			return err
+		}
+		h.etcd = client
+		prefix := h.Nonce.Prefix
+		if prefix == "" {
+			prefix = "/hawk/nonces/"
+		}
+		store := etcd.NewStore(client, prefix)
+		store.TTL = ttl
+		setNonce = store.SetNonce
+	}
+
+	hm := hawk.NewMiddleware(creds.GetCredentials, setNonce)
+	hm.MaxSkew = time.Duration(h.MaxSkew)
+	h.setup(hm)
+	return nil
+}
+
+// setup runs the Filter of hm in ServeHTTP.
+func (h *Handler) setup(hm *hawk.Middleware) {
+	h.hm = hm
+	h.hm.AbortHandler = func(c *gin.Context, err error) {
+		if w, ok := c.Request.Context().Value(callKey{}).(*call); ok {
+			w.done = true
+			w.err = err
+		}
+	}
+	h.filter = h.hm.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		w := r.Context().Value(callKey{}).(*call)
+		w.done = true
+		w.verified = r
+	}))
+}
+
+// Validate checks the configuration.
+func (h *Handler) Validate() error {
+	if h.CredentialsFile == "" {
+		return fmt.Errorf("hawk: credentials_file is required")
+	}
+	switch h.Nonce.Backend {
+	case "", NonceMemory:
+	case NonceEtcd:
+		if len(h.Nonce.Endpoints) == 0 {
+			return fmt.Errorf("hawk: etcd nonce backend without endpoints")
+		}
+	default:
+		return fmt.Errorf("hawk: unknown nonce backend %q", h.Nonce.Backend)
+	}
+	return nil
+}
+
+// Cleanup stops watching the credentials file and closes etcd.
+func (h *Handler) Cleanup() error {
+	if h.creds != nil {
+		h.creds.Close()
+	}
+	if h.etcd != nil {
+		return h.etcd.Close()
+	}
+	return nil
+}
+
+// call is the state of a request running the Filter. It drops the
+// writes of the Filter once the request is verified or rejected, so
+// Caddy writes the response.
+type call struct {
+	http.ResponseWriter
+	done     bool
+	err      error
+	verified *http.Request
+}
+
+type callKey struct{}
+
+func (w *call) WriteHeader(code int) {
+	if !w.done {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *call) Write(b []byte) (int, error) {
+	if w.done {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// ServeHTTP authenticates the request before next.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	c := &call{ResponseWriter: w}
+	h.filter.ServeHTTP(c, r.WithContext(context.WithValue(r.Context(), callKey{}, c)))
+
+	if c.err != nil {
+		return caddyhttp.Error(h.hm.ErrorStatus(c.err), c.err)
+	} else if c.verified == nil {
+		return nil
+	}
+	if res, ok := hawk.ResultFromRequest(c.verified); ok {
+		caddyhttp.SetVar(r.Context(), "hawk.id", res.ID)
+	}
+	// the payload validation reads the body and restores it on the copy
+	r.Body = c.verified.Body
+	return next.ServeHTTP(w, r)
+}
+
+// UnmarshalCaddyfile sets up the handler from Caddyfile tokens:
+//
+//	hawk {
+//		credentials_file <path>
+//		nonce memory|etcd [<endpoints...>]
+//		nonce_prefix <prefix>
+//		nonce_ttl <duration>
+//		max_skew <duration>
+//	}
+func (h *Handler) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	d.Next() // directive name
+	if d.NextArg() {
+		return d.ArgErr()
+	}
+	for d.NextBlock(0) {
+		switch d.Val() {
+		case "credentials_file":
+			if !d.AllArgs(&h.CredentialsFile) {
+				return d.ArgErr()
+			}
+		case "nonce":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			h.Nonce.Backend = d.Val()
+			h.Nonce.Endpoints = d.RemainingArgs()
+		case "nonce_prefix":
+			if !d.AllArgs(&h.Nonce.Prefix) {
+				return d.ArgErr()
+			}
+		case "nonce_ttl", "max_skew":
+			name := d.Val()
+			var v string
+			if !d.AllArgs(&v) {
+				return d.ArgErr()
+			}
+			dur, err := caddy.ParseDuration(v)
+			if err != nil {
+				return d.Errf("parsing %s: %v", name, err)
+			}
+			if name == "nonce_ttl" {
+				h.Nonce.TTL = caddy.Duration(dur)
+			} else {
+				h.MaxSkew = caddy.Duration(dur)
+			}
+		default:
+			return d.Errf("unrecognized hawk option '%s'", d.Val())
+		}
+	}
+	return nil
+}
+
+func parseCaddyfile(h httpcaddyfile.Helper) (caddyhttp.MiddlewareHandler, error) {
+	res := new(Handler)
+	err := res.UnmarshalCaddyfile(h.Dispenser)
+	return res, err
+}
+
+// memoryNonces saves the nonces in memory for ttl.
+type memoryNonces struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	nonces map[string]time.Time
+	prune  time.Time
+}
+
+func newMemoryNonces(ttl time.Duration) *memoryNonces {
+	return &memoryNonces{ttl: ttl, nonces: map[string]time.Time{}}
+}
+
+// SetNonce is a hawk.SetNonceFunc.
+func (m *memoryNonces) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.After(m.prune) {
+		for k, exp := range m.nonces {
+			if now.After(exp) {
+				delete(m.nonces, k)
+			}
+		}
+		m.prune = now.Add(m.ttl)
+	}
+	key := id + ":" + nonce
+	if exp, ok := m.nonces[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	m.nonces[key] = now.Add(m.ttl)
+	return true, nil
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner           = (*Handler)(nil)
+	_ caddy.Validator             = (*Handler)(nil)
+	_ caddy.CleanerUpper          = (*Handler)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Handler)(nil)
+	_ caddyfile.Unmarshaler       = (*Handler)(nil)
+)
@@ -0,0 +1,13 @@
+package caddy_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCaddy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Caddy Suite")
+}
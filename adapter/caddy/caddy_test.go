@@ -0,0 +1,96 @@
+package caddy_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/gin-gonic/gin"
+	hawkcaddy "github.com/hyperboloide/hawk/adapter/caddy"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+
+	var dir string
+	var h *hawkcaddy.Handler
+	var cancel context.CancelFunc
+
+	BeforeEach(func() {
+		gin.SetMode(gin.ReleaseMode)
+		var err error
+		dir, err = ioutil.TempDir("", "hawk-caddy")
+		Expect(err).ToNot(HaveOccurred())
+		path := filepath.Join(dir, "credentials.yml")
+		Expect(ioutil.WriteFile(path, []byte("my-id:\n  key: my-key\n"), 0600)).To(Succeed())
+
+		h = &hawkcaddy.Handler{}
+		d := caddyfile.NewTestDispenser(`hawk {
+			credentials_file ` + path + `
+			nonce memory
+			nonce_ttl 5m
+			max_skew 1m
+		}`)
+		Expect(h.UnmarshalCaddyfile(d)).To(Succeed())
+		Expect(h.CredentialsFile).To(Equal(path))
+		Expect(h.Nonce.Backend).To(Equal(hawkcaddy.NonceMemory))
+		Expect(time.Duration(h.Nonce.TTL)).To(Equal(5 * time.Minute))
+		Expect(time.Duration(h.MaxSkew)).To(Equal(time.Minute))
+
+		var ctx caddy.Context
+		ctx, cancel = caddy.NewContext(caddy.Context{Context: context.Background()})
+		Expect(h.Provision(ctx)).To(Succeed())
+		Expect(h.Validate()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(h.Cleanup()).To(Succeed())
+		cancel()
+		os.RemoveAll(dir)
+	})
+
+	serve := func(req *http.Request) (*httptest.ResponseRecorder, map[string]any, error) {
+		vars := map[string]any{}
+		req = req.WithContext(context.WithValue(req.Context(), caddyhttp.VarsCtxKey, vars))
+		w := httptest.NewRecorder()
+		err := h.ServeHTTP(w, req, caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			w.WriteHeader(http.StatusAccepted)
+			return nil
+		}))
+		return w, vars, err
+	}
+
+	It("authenticates the requests", func() {
+		req := httptest.NewRequest("GET", "http://example.com/private", nil)
+		auth, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w, vars, err := serve(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Code).To(Equal(http.StatusAccepted))
+		Expect(vars["hawk.id"]).To(Equal("my-id"))
+		Expect(auth.ValidResponse(w.Header().Get("Server-Authorization"))).To(Succeed())
+
+		_, _, err = serve(req)
+		var herr caddyhttp.HandlerError
+		Expect(errors.As(err, &herr)).To(BeTrue())
+		Expect(herr.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("validates the configuration", func() {
+		Expect((&hawkcaddy.Handler{}).Validate()).ToNot(Succeed())
+		Expect((&hawkcaddy.Handler{CredentialsFile: "f", Nonce: hawkcaddy.Nonce{Backend: hawkcaddy.NonceEtcd}}).Validate()).ToNot(Succeed())
+		Expect((&hawkcaddy.Handler{CredentialsFile: "f", Nonce: hawkcaddy.Nonce{Backend: "redis"}}).Validate()).ToNot(Succeed())
+	})
+
+})
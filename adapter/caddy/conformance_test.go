@@ -0,0 +1,31 @@
+package caddy_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/hyperboloide/hawk"
+	hawkcaddy "github.com/hyperboloide/hawk/adapter/caddy"
+	"github.com/hyperboloide/hawk/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(hm *hawk.Middleware) func(http.Handler) http.Handler {
+		h := hawkcaddy.NewHandler(hm)
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				err := h.ServeHTTP(w, r, caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+					next.ServeHTTP(w, r)
+					return nil
+				}))
+				// the Caddy error handlers write the rejected requests
+				var herr caddyhttp.HandlerError
+				if errors.As(err, &herr) {
+					w.WriteHeader(herr.StatusCode)
+				}
+			})
+		}
+	})
+}
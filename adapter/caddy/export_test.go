@@ -0,0 +1,11 @@
+package caddy
+
+import "github.com/hyperboloide/hawk"
+
+// NewHandler returns a Handler running the Filter of hm, for the
+// conformance suite.
+func NewHandler(hm *hawk.Middleware) *Handler {
+	h := &Handler{}
+	h.setup(hm)
+	return h
+}
@@ -0,0 +1,16 @@
+package connect_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hyperboloide/hawk"
+	hawkconnect "github.com/hyperboloide/hawk/adapter/connect"
+	"github.com/hyperboloide/hawk/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(hm *hawk.Middleware) func(http.Handler) http.Handler {
+		return hawkconnect.NewInterceptor(hm).Handler
+	})
+}
@@ -0,0 +1,86 @@
+// Package connect provides a connect-go interceptor authenticating the
+// Connect, gRPC and gRPC-Web calls of a handler with a hawk Middleware,
+// so the RPC APIs share the credentials of the REST APIs:
+//
+//	interceptor := hawkconnect.NewInterceptor(hm)
+//	path, handler := greetv1connect.NewGreetServiceHandler(srv, connect.WithInterceptors(interceptor))
+//	mux.Handle(path, interceptor.Handler(handler))
+//
+// The calls are verified by the net/http middleware of the Middleware
+// (see Interceptor.Handler) before connect decodes them, so the payloads
+// are validated like the REST requests. The interceptor rejects the calls
+// of handlers not wrapped by it.
+package connect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/hyperboloide/hawk"
+)
+
+// ErrUnverified is the error of the calls reaching the interceptor
+// without being verified by its Handler.
+var ErrUnverified = errors.New("Call not verified by the interceptor Handler")
+
+// ResultFromContext returns the verified request from the context of
+// the handler and true, or nil and false.
+func ResultFromContext(ctx context.Context) (*hawk.Result, bool) {
+	return hawk.ResultFromRequestContext(ctx)
+}
+
+// Interceptor is the connect.Interceptor of NewInterceptor.
+type Interceptor struct {
+	hm *hawk.Middleware
+}
+
+// NewInterceptor returns an interceptor requiring the handler calls to
+// be verified with hm by its Handler.
+func NewInterceptor(hm *hawk.Middleware) *Interceptor {
+	return &Interceptor{hm: hm}
+}
+
+// Handler verifies the calls of the connect handler next with the
+// Middleware Handler, the payload included.
+func (i *Interceptor) Handler(next http.Handler) http.Handler {
+	return i.hm.Handler(next)
+}
+
+// verified returns an error if the call was not verified by Handler.
+func verified(ctx context.Context) error {
+	if _, ok := ResultFromContext(ctx); !ok {
+		return connect.NewError(connect.CodeUnauthenticated, ErrUnverified)
+	}
+	return nil
+}
+
+// WrapUnary checks that the unary handler calls were verified, the
+// client calls are not changed.
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		} else if err := verified(ctx); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient returns next.
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler checks that the streaming handler calls were
+// verified.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := verified(ctx); err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
@@ -0,0 +1,13 @@
+package connect_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestConnect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Connect Suite")
+}
@@ -0,0 +1,122 @@
+package connect_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"connectrpc.com/connect"
+	"github.com/hyperboloide/hawk"
+	hawkconnect "github.com/hyperboloide/hawk/adapter/connect"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type message struct {
+	Text string `json:"text"`
+}
+
+// jsonCodec encodes the test messages without protobuf.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return "json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// signer signs the client calls with the payload hash of signed, or of
+// the message if nil.
+func signer(id, key string, signed *message, auth **hawkgo.Auth) connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			msg := req.Any()
+			if signed != nil {
+				msg = signed
+			}
+			body, err := json.Marshal(msg)
+			if err != nil {
+				return nil, err
+			}
+			r := httptest.NewRequest("POST", req.Spec().Procedure, nil)
+			r.Host = req.Peer().Addr
+			*auth = hawkgo.NewRequestAuth(r, &hawkgo.Credentials{ID: id, Key: key, Hash: sha256.New}, 0)
+			(*auth).Hash = hawk.PayloadHash(sha256.New, "application/json", body)
+			req.Header().Set("Authorization", (*auth).RequestHeader())
+			return next(ctx, req)
+		}
+	}
+}
+
+var _ = Describe("Interceptor", func() {
+
+	const procedure = "/test.v1.EchoService/Echo"
+	var ts *httptest.Server
+	var mux *http.ServeMux
+	var handler http.Handler
+	var interceptor *hawkconnect.Interceptor
+	var user interface{}
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		user = nil
+
+		hm := store.Middleware()
+		hm.ValidatePayload = true
+		interceptor = hawkconnect.NewInterceptor(hm)
+		handler = connect.NewUnaryHandler(procedure, func(ctx context.Context, req *connect.Request[message]) (*connect.Response[message], error) {
+			if res, ok := hawkconnect.ResultFromContext(ctx); ok {
+				user = res.User
+			}
+			return connect.NewResponse(req.Msg), nil
+		}, connect.WithCodec(jsonCodec{}), connect.WithInterceptors(interceptor))
+		mux = http.NewServeMux()
+		mux.Handle(procedure, interceptor.Handler(handler))
+		ts = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	call := func(id, key string, signed *message) (*connect.Response[message], *hawkgo.Auth, error) {
+		var auth *hawkgo.Auth
+		client := connect.NewClient[message, message](ts.Client(), ts.URL+procedure,
+			connect.WithCodec(jsonCodec{}),
+			connect.WithInterceptors(signer(id, key, signed, &auth)))
+		res, err := client.CallUnary(context.Background(), connect.NewRequest(&message{Text: "hello"}))
+		return res, auth, err
+	}
+
+	It("verifies the calls", func() {
+		res, auth, err := call("my-id", "my-key", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Msg.Text).To(Equal("hello"))
+		Expect(user).To(Equal("my-id"))
+		Expect(auth.ValidResponse(res.Header().Get("Server-Authorization"))).To(Succeed())
+	})
+
+	It("rejects invalid calls", func() {
+		_, _, err := call("my-id", "wrong-key", nil)
+		Expect(connect.CodeOf(err)).To(Equal(connect.CodeUnauthenticated))
+		Expect(user).To(BeNil())
+	})
+
+	It("validates the payloads", func() {
+		_, _, err := call("my-id", "my-key", &message{Text: "tampered"})
+		Expect(connect.CodeOf(err)).To(Equal(connect.CodeUnauthenticated))
+		Expect(user).To(BeNil())
+	})
+
+	It("rejects the calls of handlers not wrapped by the Handler", func() {
+		ts.Config.Handler = handler
+		_, _, err := call("my-id", "my-key", nil)
+		Expect(connect.CodeOf(err)).To(Equal(connect.CodeUnauthenticated))
+		Expect(user).To(BeNil())
+	})
+
+})
@@ -0,0 +1,67 @@
+package hertz_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/hyperboloide/hawk"
+	hawkhertz "github.com/hyperboloide/hawk/adapter/hertz"
+	"github.com/hyperboloide/hawk/conformance"
+)
+
+// hertzHandler serves the net/http requests with a Hertz engine.
+type hertzHandler struct {
+	engine *route.Engine
+}
+
+func (h *hertzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	headers := []ut.Header{}
+	for k, v := range r.Header {
+		for _, vv := range v {
+			headers = append(headers, ut.Header{Key: k, Value: vv})
+		}
+	}
+	url := "http://" + r.Host + r.URL.RequestURI()
+	res := ut.PerformRequest(h.engine, r.Method, url, &ut.Body{Body: bytes.NewReader(body), Len: len(body)}, headers...).Result()
+	res.Header.VisitAll(func(k, v []byte) {
+		if string(k) != "Content-Length" {
+			w.Header().Add(string(k), string(v))
+		}
+	})
+	w.WriteHeader(res.StatusCode())
+	w.Write(res.Body())
+}
+
+// stdHandler serves the Hertz requests with a net/http handler.
+func stdHandler(next http.Handler) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		r, err := http.NewRequestWithContext(c, string(ctx.Method()), ctx.URI().String(), bytes.NewReader(ctx.Request.Body()))
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		w := httptest.NewRecorder()
+		next.ServeHTTP(w, r)
+		ctx.Data(w.Code, w.Header().Get("Content-Type"), w.Body.Bytes())
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(hm *hawk.Middleware) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			engine := route.NewEngine(config.NewOptions(nil))
+			engine.Use(hawkhertz.New(hm))
+			engine.Any("/*p", stdHandler(next))
+			return &hertzHandler{engine: engine}
+		}
+	})
+}
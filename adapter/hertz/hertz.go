@@ -0,0 +1,122 @@
+// Package hertz provides a Hertz (CloudWeGo) middleware authenticating
+// with a hawk Middleware. The Hertz requests are translated to
+// *http.Request for the Filter, and the auth, user, scopes and app of
+// verified requests are set in the RequestContext keys:
+//
+//	h.Use(hawkhertz.New(hm))
+//	h.GET("/private", func(c context.Context, ctx *app.RequestContext) {
+//		user, _ := hawkhertz.UserFromContext(ctx)
+//	})
+package hertz
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+// request returns the *http.Request of ctx.
+func request(c context.Context, ctx *app.RequestContext) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(c, string(ctx.Method()), ctx.URI().String(), bytes.NewReader(ctx.Request.Body()))
+	if err != nil {
+		return nil, err
+	}
+	r.Host = string(ctx.Host())
+	r.RequestURI = string(ctx.Request.RequestURI())
+	if addr := ctx.RemoteAddr(); addr != nil {
+		r.RemoteAddr = addr.String()
+	}
+	ctx.Request.Header.VisitAll(func(k, v []byte) {
+		r.Header.Add(string(k), string(v))
+	})
+	r.ContentLength = int64(len(ctx.Request.Body()))
+	return r, nil
+}
+
+// responseWriter writes the Filter response to the Hertz response.
+type responseWriter struct {
+	ctx         *app.RequestContext
+	header      http.Header
+	wroteHeader bool
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	for k, v := range w.header {
+		for _, vv := range v {
+			w.ctx.Response.Header.Add(k, vv)
+		}
+	}
+	w.ctx.SetStatusCode(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.ctx.Response.AppendBody(b)
+	return len(b), nil
+}
+
+// New returns a Hertz handler running the Filter of hm, the rejected
+// requests are aborted.
+func New(hm *hawk.Middleware) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		r, err := request(c, ctx)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		var verified *http.Request
+		hm.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			verified = r
+		})).ServeHTTP(&responseWriter{ctx: ctx, header: http.Header{}}, r)
+
+		if verified == nil {
+			ctx.Abort()
+			return
+		}
+		if res, ok := hawk.ResultFromRequest(verified); ok {
+			ctx.Set(hawk.AuthKey, res.Auth)
+			ctx.Set(hawk.UserKey, res.User)
+			if res.Scopes != nil {
+				ctx.Set(hawk.ScopesKey, res.Scopes)
+			}
+			if res.App != "" {
+				ctx.Set(hawk.AppKey, res.App)
+				ctx.Set(hawk.DelegateKey, res.Delegate)
+			}
+		}
+		ctx.Next(c)
+	}
+}
+
+// AuthFromContext returns the *hawkgo.Auth from the context and true,
+// or nil and false if it's not set.
+func AuthFromContext(ctx *app.RequestContext) (*hawkgo.Auth, bool) {
+	v, _ := ctx.Get(hawk.AuthKey)
+	auth, ok := v.(*hawkgo.Auth)
+	return auth, ok
+}
+
+// UserFromContext returns the user object from the context and true,
+// or nil and false if it's not set.
+func UserFromContext(ctx *app.RequestContext) (interface{}, bool) {
+	return ctx.Get(hawk.UserKey)
+}
+
+// ScopesFromContext returns the scopes of the credentials from the context.
+func ScopesFromContext(ctx *app.RequestContext) []string {
+	v, _ := ctx.Get(hawk.ScopesKey)
+	scopes, _ := v.([]string)
+	return scopes
+}
@@ -0,0 +1,13 @@
+package hertz_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHertz(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hertz Suite")
+}
@@ -0,0 +1,65 @@
+package hertz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/gin-gonic/gin"
+	hawkhertz "github.com/hyperboloide/hawk/adapter/hertz"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("New", func() {
+
+	var engine *route.Engine
+	var user interface{}
+
+	BeforeEach(func() {
+		gin.SetMode(gin.ReleaseMode)
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+
+		user = nil
+		engine = route.NewEngine(config.NewOptions(nil))
+		engine.Use(hawkhertz.New(store.Middleware()))
+		engine.POST("/private", func(c context.Context, ctx *app.RequestContext) {
+			user, _ = hawkhertz.UserFromContext(ctx)
+			ctx.String(http.StatusAccepted, "ok")
+		})
+	})
+
+	perform := func(body, id, key string) (*ut.ResponseRecorder, *hawkgo.Auth) {
+		headers := []ut.Header{{Key: "Content-Type", Value: "text/plain"}}
+		var auth *hawkgo.Auth
+		if id != "" {
+			req := httptest.NewRequest("POST", "http://example.com/private", strings.NewReader(body))
+			req.Header.Set("Content-Type", "text/plain")
+			var err error
+			auth, err = hawktest.SignRequest(req, id, key)
+			Expect(err).ToNot(HaveOccurred())
+			headers = append(headers, ut.Header{Key: "Authorization", Value: req.Header.Get("Authorization")})
+		}
+		b := &ut.Body{Body: strings.NewReader(body), Len: len(body)}
+		return ut.PerformRequest(engine, "POST", "http://example.com/private", b, headers...), auth
+	}
+
+	It("sets the user in the context", func() {
+		w, auth := perform("payload", "my-id", "my-key")
+		res := w.Result()
+		Expect(res.StatusCode()).To(Equal(http.StatusAccepted))
+		Expect(string(res.Body())).To(Equal("ok"))
+		Expect(user).To(Equal("my-id"))
+		Expect(auth.ValidResponse(string(res.Header.Peek("Server-Authorization")))).To(Succeed())
+	})
+
+})
@@ -0,0 +1,26 @@
+package iris_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hyperboloide/hawk"
+	hawkiris "github.com/hyperboloide/hawk/adapter/iris"
+	"github.com/hyperboloide/hawk/conformance"
+	"github.com/kataras/iris/v12"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(hm *hawk.Middleware) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			app := iris.New()
+			app.Logger().SetLevel("disable")
+			app.Use(hawkiris.New(hm))
+			app.Any("/{p:path}", iris.FromStd(next))
+			if err := app.Build(); err != nil {
+				t.Fatal(err)
+			}
+			return app
+		}
+	})
+}
@@ -0,0 +1,84 @@
+// Package iris provides an Iris handler authenticating with a hawk
+// Middleware. Like the gin Filter, the auth, user, scopes and app of
+// verified requests are set in the context values with the hawk keys:
+//
+//	app.Use(hawkiris.New(hm))
+//	app.Get("/private", func(ctx iris.Context) {
+//		user, _ := hawkiris.UserFromContext(ctx)
+//	})
+package iris
+
+import (
+	"net/http"
+
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+	"github.com/kataras/iris/v12"
+)
+
+// writer holds the status of the Filter once the request is verified,
+// so the next Iris handlers can still set theirs.
+type writer struct {
+	http.ResponseWriter
+	verified bool
+}
+
+func (w *writer) WriteHeader(code int) {
+	if !w.verified {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// New returns an Iris handler running the Filter of hm, the rejected
+// requests are stopped.
+func New(hm *hawk.Middleware) iris.Handler {
+	return func(ctx iris.Context) {
+		w := &writer{ResponseWriter: ctx.ResponseWriter()}
+		var verified *http.Request
+		hm.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			w.verified = true
+			verified = r
+		})).ServeHTTP(w, ctx.Request())
+
+		if verified == nil {
+			ctx.StopExecution()
+			return
+		}
+		ctx.ResetRequest(verified)
+		if res, ok := hawk.ResultFromRequest(verified); ok {
+			values := ctx.Values()
+			values.Set(hawk.AuthKey, res.Auth)
+			values.Set(hawk.UserKey, res.User)
+			if res.Scopes != nil {
+				values.Set(hawk.ScopesKey, res.Scopes)
+			}
+			if res.App != "" {
+				values.Set(hawk.AppKey, res.App)
+				values.Set(hawk.DelegateKey, res.Delegate)
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// AuthFromContext returns the *hawkgo.Auth from the context and true,
+// or nil and false if it's not set.
+func AuthFromContext(ctx iris.Context) (*hawkgo.Auth, bool) {
+	auth, ok := ctx.Values().Get(hawk.AuthKey).(*hawkgo.Auth)
+	return auth, ok
+}
+
+// UserFromContext returns the user object from the context and true,
+// or nil and false if it's not set.
+func UserFromContext(ctx iris.Context) (interface{}, bool) {
+	if !ctx.Values().Exists(hawk.UserKey) {
+		return nil, false
+	}
+	return ctx.Values().Get(hawk.UserKey), true
+}
+
+// ScopesFromContext returns the scopes of the credentials from the context.
+func ScopesFromContext(ctx iris.Context) []string {
+	scopes, _ := ctx.Values().Get(hawk.ScopesKey).([]string)
+	return scopes
+}
@@ -0,0 +1,13 @@
+package iris_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestIris(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Iris Suite")
+}
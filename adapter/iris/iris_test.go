@@ -0,0 +1,66 @@
+package iris_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	hawkiris "github.com/hyperboloide/hawk/adapter/iris"
+	"github.com/hyperboloide/hawk/hawktest"
+	"github.com/kataras/iris/v12"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("New", func() {
+
+	var app *iris.Application
+	var user interface{}
+	var scopes []string
+
+	BeforeEach(func() {
+		gin.SetMode(gin.ReleaseMode)
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key").Scopes = []string{"read"}
+
+		user, scopes = nil, nil
+		app = iris.New()
+		app.Logger().SetLevel("disable")
+		app.Use(hawkiris.New(store.Middleware()))
+		app.Get("/private", func(ctx iris.Context) {
+			user, _ = hawkiris.UserFromContext(ctx)
+			scopes = hawkiris.ScopesFromContext(ctx)
+			ctx.StatusCode(http.StatusAccepted)
+			ctx.WriteString("ok")
+		})
+		app.Post("/empty", func(ctx iris.Context) {
+			ctx.StatusCode(http.StatusNoContent)
+		})
+		Expect(app.Build()).To(Succeed())
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	It("sets the auth and user in the context", func() {
+		req := httptest.NewRequest("GET", "http://example.com/private", nil)
+		auth, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := serve(req)
+		Expect(w.Code).To(Equal(http.StatusAccepted))
+		Expect(w.Body.String()).To(Equal("ok"))
+		Expect(user).To(Equal("my-id"))
+		Expect(scopes).To(Equal([]string{"read"}))
+		Expect(auth.ValidResponse(w.Header().Get("Server-Authorization"))).To(Succeed())
+
+		req = httptest.NewRequest("POST", "http://example.com/empty", nil)
+		_, err = hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serve(req).Code).To(Equal(http.StatusNoContent))
+	})
+
+})
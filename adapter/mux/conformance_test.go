@@ -0,0 +1,22 @@
+package mux_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperboloide/hawk"
+	hawkmux "github.com/hyperboloide/hawk/adapter/mux"
+	"github.com/hyperboloide/hawk/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(hm *hawk.Middleware) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			router := mux.NewRouter()
+			router.Use(hawkmux.New(hm).Middleware)
+			router.PathPrefix("/").Handler(next)
+			return router
+		}
+	})
+}
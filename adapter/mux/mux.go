@@ -0,0 +1,104 @@
+// Package mux provides a gorilla/mux middleware authenticating with a
+// hawk Middleware, with per-route rules to skip the authentication or
+// require scopes:
+//
+//	m := mux.New(hm)
+//	router.Use(m.Middleware)
+//	m.Skip(router.HandleFunc("/health", health))
+//	m.RequireScopes(router.HandleFunc("/admin", admin), "admin")
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperboloide/hawk"
+)
+
+// ErrScope is the error of the requests without the scopes of the route.
+var ErrScope = errors.New("Missing required scope")
+
+// Rule is the authentication rule of a route.
+type Rule struct {
+	Skip   bool
+	Scopes []string
+}
+
+// Middleware is the gorilla/mux middleware, safe for concurrent use.
+type Middleware struct {
+	hm    *hawk.Middleware
+	mu    sync.RWMutex
+	rules map[*mux.Route]Rule
+}
+
+// New creates a Middleware authenticating with hm.
+func New(hm *hawk.Middleware) *Middleware {
+	return &Middleware{
+		hm:    hm,
+		rules: map[*mux.Route]Rule{},
+	}
+}
+
+// SetRule sets the Rule of route.
+func (m *Middleware) SetRule(route *mux.Route, rule Rule) *mux.Route {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules[route] = rule
+	return route
+}
+
+// Skip lets the requests of route through without authentication.
+func (m *Middleware) Skip(route *mux.Route) *mux.Route {
+	return m.SetRule(route, Rule{Skip: true})
+}
+
+// RequireScopes rejects the requests of route without all the scopes
+// with a 403.
+func (m *Middleware) RequireScopes(route *mux.Route, scopes ...string) *mux.Route {
+	return m.SetRule(route, Rule{Scopes: scopes})
+}
+
+// rule returns the Rule of the matched route of r.
+func (m *Middleware) rule(r *http.Request) Rule {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return Rule{}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.rules[route]
+}
+
+// Middleware is a mux.MiddlewareFunc, the verified request is in the
+// request context (see hawk.ResultFromRequest).
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	scoped := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, _ := hawk.ResultFromRequest(r)
+		for _, s := range m.rule(r).Scopes {
+			if res == nil || !hasScope(res.Scopes, s) {
+				http.Error(w, ErrScope.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+	auth := m.hm.Handler(scoped)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.rule(r).Skip {
+			next.ServeHTTP(w, r)
+			return
+		}
+		auth.ServeHTTP(w, r)
+	})
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+	"github.com/hyperboloide/hawk"
+	hawkmux "github.com/hyperboloide/hawk/adapter/mux"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+
+	var router *mux.Router
+	var user interface{}
+
+	BeforeEach(func() {
+		gin.SetMode(gin.ReleaseMode)
+		store := hawktest.NewStore()
+		store.Add("reader", "reader-key").Scopes = []string{"read"}
+		store.Add("admin", "admin-key").Scopes = []string{"read", "admin"}
+
+		m := hawkmux.New(store.Middleware())
+		router = mux.NewRouter()
+		router.Use(m.Middleware)
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			user, _ = hawk.UserFromRequest(r)
+			w.Write([]byte(mux.Vars(r)["name"]))
+		}
+		router.HandleFunc("/files/{name}", handler)
+		m.Skip(router.HandleFunc("/health", handler))
+		m.RequireScopes(router.HandleFunc("/admin/{name}", handler), "admin")
+	})
+
+	request := func(path, id, key string) int {
+		user = nil
+		req := httptest.NewRequest("GET", "http://example.com"+path, nil)
+		if id != "" {
+			_, err := hawktest.SignRequest(req, id, key)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	It("authenticates the routes", func() {
+		Expect(request("/files/a", "reader", "reader-key")).To(Equal(http.StatusOK))
+		Expect(user).To(Equal("reader"))
+		Expect(request("/files/a", "reader", "wrong-key")).To(Equal(http.StatusUnauthorized))
+		Expect(request("/files/a", "", "")).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("skips routes", func() {
+		Expect(request("/health", "", "")).To(Equal(http.StatusOK))
+		Expect(user).To(BeNil())
+	})
+
+	It("requires the scopes of routes", func() {
+		Expect(request("/admin/a", "admin", "admin-key")).To(Equal(http.StatusOK))
+		Expect(request("/admin/a", "reader", "reader-key")).To(Equal(http.StatusForbidden))
+		Expect(request("/admin/a", "", "")).To(Equal(http.StatusUnauthorized))
+	})
+
+})
@@ -0,0 +1,170 @@
+package hawk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminScope is the scope required by the AdminRoutes.
+const AdminScope = "hawk:admin"
+
+// ErrAdminScope is set in context.Err when the credentials calling the
+// AdminRoutes do not have the AdminScope.
+var ErrAdminScope = errors.New("Admin scope required")
+
+// CredentialInfo is the metadata of stored credentials, without the key.
+type CredentialInfo struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Algorithm string    `json:"algorithm,omitempty"`
+	Principal string    `json:"principal,omitempty"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// CredentialStore manages the credentials for the AdminRoutes.
+// Rotate returns the new key, the previous one must only be accepted
+// for a grace period or until RevokePrevious. Rotate, Revoke and
+// RevokePrevious return ErrNotFound for unknown ids. The credstore/sql
+// Store implements it.
+type CredentialStore interface {
+	CreateCredential(ctx context.Context, c *Credential) error
+	Rotate(ctx context.Context, id string) (string, error)
+	RevokePrevious(ctx context.Context, id string) error
+	Revoke(ctx context.Context, id string) error
+	List(ctx context.Context) ([]CredentialInfo, error)
+}
+
+// adminCreate is the body of a credentials creation, TTL is in seconds.
+type adminCreate struct {
+	Scopes    []string `json:"scopes"`
+	Algorithm string   `json:"algorithm"`
+	Principal string   `json:"principal"`
+	TTL       int64    `json:"ttl"`
+}
+
+// adminRotate is the body of a key rotation.
+type adminRotate struct {
+	RevokePrevious bool `json:"revoke_previous"`
+}
+
+// AdminRoutes mounts on rg a credentials management API protected by the
+// Filter and restricted to credentials with the AdminScope:
+//
+//	GET    /credentials            lists the credentials
+//	POST   /credentials            creates credentials and returns the key
+//	POST   /credentials/:id/rotate rotates the key and returns it
+//	DELETE /credentials/:id/previous revokes the previous key
+//	DELETE /credentials/:id        revokes the credentials
+//
+// The creation body is an optional JSON object with "scopes", "algorithm",
+// "principal" and "ttl" (in seconds). The rotation body is an optional
+// JSON object with "revoke_previous" to stop accepting the previous key
+// immediately, otherwise it's accepted for the grace period of the store.
+func (hm *Middleware) AdminRoutes(rg *gin.RouterGroup, store CredentialStore) {
+	g := rg.Group("/credentials", hm.Filter, requireAdminScope)
+
+	g.GET("", func(c *gin.Context) {
+		res, err := store.List(c.Request.Context())
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	})
+
+	g.POST("", func(c *gin.Context) {
+		var req adminCreate
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&req); err != nil {
+				return
+			}
+		}
+		if req.Algorithm != "" {
+			if _, err := HashFunc(req.Algorithm); err != nil {
+				c.AbortWithError(http.StatusBadRequest, err)
+				return
+			}
+		}
+		opts := []CredentialOption{
+			CredentialScopes(req.Scopes...),
+			CredentialAlgorithm(req.Algorithm),
+			CredentialPrincipal(req.Principal),
+		}
+		if req.TTL > 0 {
+			opts = append(opts, CredentialTTL(time.Duration(req.TTL)*time.Second))
+		}
+		cred := NewCredential(opts...)
+		if err := store.CreateCredential(c.Request.Context(), cred); err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"id":         cred.ID,
+			"key":        cred.Key,
+			"created_at": cred.CreatedAt,
+			"expires_at": cred.ExpiresAt,
+		})
+	})
+
+	g.POST("/:id/rotate", func(c *gin.Context) {
+		id := c.Param("id")
+		var req adminRotate
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&req); err != nil {
+				return
+			}
+		}
+		key, err := store.Rotate(c.Request.Context(), id)
+		if err == nil && req.RevokePrevious {
+			err = store.RevokePrevious(c.Request.Context(), id)
+		}
+		if err != nil {
+			c.AbortWithError(adminStatus(err), err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"id":  id,
+			"key": key,
+		})
+	})
+
+	g.DELETE("/:id/previous", func(c *gin.Context) {
+		if err := store.RevokePrevious(c.Request.Context(), c.Param("id")); err != nil {
+			c.AbortWithError(adminStatus(err), err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	g.DELETE("/:id", func(c *gin.Context) {
+		if err := store.Revoke(c.Request.Context(), c.Param("id")); err != nil {
+			c.AbortWithError(adminStatus(err), err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// requireAdminScope rejects credentials without the AdminScope.
+func requireAdminScope(c *gin.Context) {
+	for _, s := range ScopesFromContext(c) {
+		if s == AdminScope {
+			return
+		}
+	}
+	c.AbortWithError(http.StatusForbidden, ErrAdminScope)
+}
+
+// adminStatus returns the status of a CredentialStore error.
+func adminStatus(err error) int {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
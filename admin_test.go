@@ -0,0 +1,194 @@
+package hawk_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// adminStore is an in-memory CredentialStore.
+type adminStore struct {
+	creds    map[string]*Credential
+	keys     map[string]string
+	previous map[string]bool
+	err      error
+}
+
+func (s *adminStore) CreateCredential(ctx context.Context, c *Credential) error {
+	s.creds[c.ID] = c
+	return s.err
+}
+
+func (s *adminStore) Rotate(ctx context.Context, id string) (string, error) {
+	if _, exists := s.creds[id]; !exists {
+		return "", ErrNotFound
+	}
+	s.keys[id] = "rotated-key"
+	s.previous[id] = true
+	return s.keys[id], nil
+}
+
+func (s *adminStore) RevokePrevious(ctx context.Context, id string) error {
+	if _, exists := s.creds[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.previous, id)
+	return nil
+}
+
+func (s *adminStore) Revoke(ctx context.Context, id string) error {
+	if _, exists := s.creds[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.creds, id)
+	return nil
+}
+
+func (s *adminStore) List(ctx context.Context) ([]CredentialInfo, error) {
+	res := []CredentialInfo{}
+	for _, c := range s.creds {
+		res = append(res, CredentialInfo{ID: c.ID, Scopes: c.Scopes})
+	}
+	return res, s.err
+}
+
+var _ = Describe("AdminRoutes", func() {
+
+	var ts *httptest.Server
+	var store *adminStore
+
+	BeforeEach(func() {
+		store = &adminStore{creds: map[string]*Credential{}, keys: map[string]string{}, previous: map[string]bool{}}
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			switch id {
+			case "admin":
+				return &Credentials{Key: "admin-key", Scopes: []string{"read", AdminScope}}, nil
+			case "user":
+				return &Credentials{Key: "user-key", Scopes: []string{"read"}}, nil
+			}
+			return nil, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		router := gin.New()
+		hm.AdminRoutes(router.Group("/admin"), store)
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(id, key, method, path, body string) (int, map[string]interface{}) {
+		var r io.Reader
+		if body != "" {
+			r = strings.NewReader(body)
+		}
+		req, err := http.NewRequest(method, ts.URL+"/admin"+path, r)
+		Expect(err).ToNot(HaveOccurred())
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   id,
+			Key:  key,
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		var res map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&res)
+		return resp.StatusCode, res
+	}
+
+	admin := func(method, path, body string) (int, map[string]interface{}) {
+		return request("admin", "admin-key", method, path, body)
+	}
+
+	It("requires the admin scope", func() {
+		status, _ := request("user", "user-key", "GET", "/credentials", "")
+		Expect(status).To(Equal(403))
+		status, _ = request("admin", "wrong-key", "GET", "/credentials", "")
+		Expect(status).To(Equal(401))
+		status, _ = admin("GET", "/credentials", "")
+		Expect(status).To(Equal(200))
+	})
+
+	It("creates credentials", func() {
+		status, res := admin("POST", "/credentials", `{"scopes":["read"],"algorithm":"sha512","principal":"org","ttl":60}`)
+		Expect(status).To(Equal(201))
+		id := res["id"].(string)
+		Expect(store.creds).To(HaveKey(id))
+		c := store.creds[id]
+		Expect(res["key"]).To(Equal(c.Key))
+		Expect(c.Scopes).To(Equal([]string{"read"}))
+		Expect(c.Algorithm).To(Equal("sha512"))
+		Expect(c.Principal).To(Equal("org"))
+		Expect(c.ExpiresAt).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+
+		status, _ = admin("POST", "/credentials", "")
+		Expect(status).To(Equal(201))
+		Expect(store.creds).To(HaveLen(2))
+
+		status, _ = admin("POST", "/credentials", `{"algorithm":"md5"}`)
+		Expect(status).To(Equal(400))
+		status, _ = admin("POST", "/credentials", `{`)
+		Expect(status).To(Equal(400))
+
+		store.err = errors.New("store error")
+		status, _ = admin("POST", "/credentials", "")
+		Expect(status).To(Equal(500))
+	})
+
+	It("rotates, revokes and lists credentials", func() {
+		c := NewCredential(CredentialScopes("read"))
+		store.creds[c.ID] = c
+
+		status, res := admin("POST", "/credentials/"+c.ID+"/rotate", "")
+		Expect(status).To(Equal(200))
+		Expect(res).To(Equal(map[string]interface{}{"id": c.ID, "key": "rotated-key"}))
+		Expect(store.previous).To(HaveKey(c.ID))
+
+		status, _ = admin("DELETE", "/credentials/"+c.ID+"/previous", "")
+		Expect(status).To(Equal(204))
+		Expect(store.previous).To(BeEmpty())
+
+		status, _ = admin("POST", "/credentials/"+c.ID+"/rotate", `{"revoke_previous":true}`)
+		Expect(status).To(Equal(200))
+		Expect(store.previous).To(BeEmpty())
+
+		req, _ := http.NewRequest("GET", ts.URL+"/admin/credentials", nil)
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{ID: "admin", Key: "admin-key", Hash: sha256.New}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		var list []CredentialInfo
+		Expect(json.NewDecoder(resp.Body).Decode(&list)).To(Succeed())
+		Expect(list).To(Equal([]CredentialInfo{{ID: c.ID, Scopes: []string{"read"}}}))
+
+		status, _ = admin("DELETE", "/credentials/"+c.ID, "")
+		Expect(status).To(Equal(204))
+		Expect(store.creds).To(BeEmpty())
+
+		status, _ = admin("DELETE", "/credentials/"+c.ID, "")
+		Expect(status).To(Equal(404))
+		status, _ = admin("POST", "/credentials/"+c.ID+"/rotate", "")
+		Expect(status).To(Equal(404))
+	})
+
+})
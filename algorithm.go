@@ -0,0 +1,47 @@
+package hawk
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+)
+
+// Names of the supported hash algorithms.
+const (
+	SHA1   = "sha1"
+	SHA256 = "sha256"
+	SHA512 = "sha512"
+)
+
+// ErrUnknownAlgorithm is set in context.Err if the credentials or the
+// Middleware algorithm is not supported. It's a configuration error.
+var ErrUnknownAlgorithm = errors.New("Unknown hash algorithm")
+
+var algorithms = map[string]func() hash.Hash{
+	SHA1:   sha1.New,
+	SHA256: sha256.New,
+	SHA512: sha512.New,
+}
+
+// HashFunc returns the hash function of the algorithm name.
+func HashFunc(name string) (func() hash.Hash, error) {
+	if h, exists := algorithms[name]; exists {
+		return h, nil
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+// hashFunc returns the MAC hash function of creds: its Hash if set, else
+// its Algorithm, else the Middleware Algorithm and sha256 by default.
+func (hm *Middleware) hashFunc(creds *Credentials) (func() hash.Hash, error) {
+	if creds.Hash != nil {
+		return creds.Hash, nil
+	} else if creds.Algorithm != "" {
+		return HashFunc(creds.Algorithm)
+	} else if hm.Algorithm != "" {
+		return HashFunc(hm.Algorithm)
+	}
+	return sha256.New, nil
+}
@@ -0,0 +1,88 @@
+package hawk_test
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Algorithm", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+
+	getCredentials := func(id string) (*Credentials, error) {
+		if id == "default" {
+			return &Credentials{Key: "test-cred-key"}, nil
+		}
+		return &Credentials{
+			Key:       "test-cred-key",
+			Algorithm: id,
+		}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(id string, h func() hash.Hash) int {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   id,
+			Key:  "test-cred-key",
+			Hash: h,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		if resp.StatusCode == 200 {
+			Expect(auth.ValidResponse(resp.Header.Get("Server-Authorization"))).To(Succeed())
+		}
+		return resp.StatusCode
+	}
+
+	It("uses the credentials algorithm", func() {
+		Expect(request(SHA1, sha1.New)).To(Equal(200))
+		Expect(request(SHA512, sha512.New)).To(Equal(200))
+		Expect(request(SHA1, sha256.New)).To(Equal(401))
+	})
+
+	It("uses the middleware algorithm by default", func() {
+		Expect(request("default", sha256.New)).To(Equal(200))
+		hm.Algorithm = SHA512
+		Expect(request("default", sha512.New)).To(Equal(200))
+		Expect(request("default", sha256.New)).To(Equal(401))
+	})
+
+	It("rejects unknown algorithms", func() {
+		Expect(request("md5", sha256.New)).To(Equal(500))
+		_, err := HashFunc("md5")
+		Expect(err).To(Equal(ErrUnknownAlgorithm))
+	})
+
+})
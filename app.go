@@ -0,0 +1,37 @@
+package hawk
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// ErrInvalidApp should be returned by a ValidateAppFunc to reject the
+// "app" and "dlg" fields of a request (Oz delegation).
+var ErrInvalidApp = errors.New("Invalid app or delegation")
+
+// ValidateAppFunc is a function that validates the Oz "app" and "dlg"
+// fields of an authenticated request. It returns ErrInvalidApp if the
+// application or the delegation is not allowed for these credentials.
+// Any other error is an external problem and it will be set as the
+// context error.
+type ValidateAppFunc func(app, dlg string) error
+
+// validateApp calls the ValidateApp callback when the request has an app.
+func (hm *Middleware) validateApp(auth *hawk.Auth) error {
+	if hm.ValidateApp == nil || auth.Credentials.App == "" {
+		return nil
+	}
+	return hm.ValidateApp(auth.Credentials.App, auth.Credentials.Delegate)
+}
+
+// AppFromContext returns the Oz "app" and "dlg" fields of the request
+// and true, or empty strings and false if the request had no app.
+func AppFromContext(c *gin.Context) (string, string, bool) {
+	app, exists := c.Get(AppKey)
+	if !exists {
+		return "", "", false
+	}
+	return app.(string), c.GetString(DelegateKey), true
+}
@@ -0,0 +1,93 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("App and delegation", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var app, dlg string
+	var hasApp bool
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		app, dlg, hasApp = "", "", false
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.ValidateApp = func(app, dlg string) error {
+			switch app {
+			case "allowed-app":
+				return nil
+			case "error-app":
+				return errors.New("store error")
+			}
+			return ErrInvalidApp
+		}
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			app, dlg, hasApp = AppFromContext(c)
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(app, dlg string) int {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:       "id",
+			Key:      "test-cred-key",
+			Hash:     sha256.New,
+			App:      app,
+			Delegate: dlg,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		if resp.StatusCode == 200 {
+			Expect(auth.ValidResponse(resp.Header.Get("Server-Authorization"))).To(Succeed())
+		}
+		return resp.StatusCode
+	}
+
+	It("does not require an app", func() {
+		Expect(request("", "")).To(Equal(200))
+		Expect(hasApp).To(BeFalse())
+	})
+
+	It("propagates a valid app and delegation", func() {
+		Expect(request("allowed-app", "delegating-app")).To(Equal(200))
+		Expect(hasApp).To(BeTrue())
+		Expect(app).To(Equal("allowed-app"))
+		Expect(dlg).To(Equal("delegating-app"))
+	})
+
+	It("rejects invalid apps", func() {
+		Expect(request("other-app", "")).To(Equal(401))
+		Expect(request("error-app", "")).To(Equal(500))
+	})
+
+})
@@ -0,0 +1,73 @@
+package hawk
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// ErrUnknownAttribute is set in context.Err if RejectUnknownAttributes
+// is set and the "Authorization" header has an unrecognized attribute.
+var ErrUnknownAttribute = errors.New("Unknown Authorization attribute")
+
+var knownAttributes = map[string]bool{
+	"id":    true,
+	"ts":    true,
+	"nonce": true,
+	"hash":  true,
+	"ext":   true,
+	"mac":   true,
+	"app":   true,
+	"dlg":   true,
+}
+
+// MaxReportedAttributes is the maximum number of names passed to
+// OnUnknownAttributes for a request.
+const MaxReportedAttributes = 8
+
+// checkAttributes reports the unknown attributes of the "Authorization"
+// header to OnUnknownAttributes and rejects them if
+// RejectUnknownAttributes is set. Malformed headers are left to the
+// protocol parser.
+func (hm *Middleware) checkAttributes(r *http.Request) error {
+	if !hm.RejectUnknownAttributes && hm.OnUnknownAttributes == nil {
+		return nil
+	}
+	header := r.Header.Get("Authorization")
+	if len(header) < 4 || !strings.EqualFold(header[:4], "hawk") {
+		return nil
+	}
+
+	var unknown []string
+	found := false
+	hawk.LexHeader(header[4:], func(key, value string) {
+		if knownAttributes[key] {
+			return
+		}
+		found = true
+		if len(unknown) < MaxReportedAttributes && !contains(unknown, key) {
+			unknown = append(unknown, key)
+		}
+	})
+	if !found {
+		return nil
+	}
+	if hm.OnUnknownAttributes != nil {
+		hm.OnUnknownAttributes(unknown)
+	}
+	if hm.RejectUnknownAttributes {
+		return ErrUnknownAttribute
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unknown attributes", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var seen []string
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		seen = nil
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.OnUnknownAttributes = func(names []string) {
+			seen = append(seen, names...)
+		}
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(extra string) int {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "id",
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, 0)
+		auth.Ext = "some ext"
+		req.Header.Set("Authorization", auth.RequestHeader()+extra)
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode
+	}
+
+	It("counts unknown attributes with a permissive default", func() {
+		Expect(request("")).To(Equal(200))
+		Expect(seen).To(BeEmpty())
+		Expect(request(`, version="2", alg="none"`)).To(Equal(200))
+		Expect(seen).To(Equal([]string{"version", "alg"}))
+	})
+
+	It("rejects unknown attributes in strict mode", func() {
+		hm.RejectUnknownAttributes = true
+		Expect(request("")).To(Equal(200))
+		Expect(request(`, alg="none"`)).To(Equal(401))
+		Expect(seen).To(Equal([]string{"alg"}))
+	})
+
+	It("reports a bounded number of distinct attributes", func() {
+		extra := `, alg="none", alg="none"`
+		for i := 0; i < 20; i++ {
+			extra += fmt.Sprintf(`, x%d="1"`, i)
+		}
+		calls := 0
+		hm.OnUnknownAttributes = func(names []string) {
+			calls++
+			seen = names
+		}
+		Expect(request(extra)).To(Equal(200))
+		Expect(calls).To(Equal(1))
+		Expect(seen).To(HaveLen(MaxReportedAttributes))
+		Expect(seen[:2]).To(Equal([]string{"alg", "x0"}))
+	})
+
+})
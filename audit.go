@@ -0,0 +1,70 @@
+package hawk
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// Outcomes of an AuditEvent.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent is the record of an authentication attempt.
+// ErrorKind and ErrorCode are set for failures, see Classify and ErrorCode.
+type AuditEvent struct {
+	Time         time.Time `json:"time"`
+	CredentialID string    `json:"credential_id,omitempty"`
+	ClientIP     string    `json:"client_ip"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Outcome      string    `json:"outcome"`
+	ErrorKind    string    `json:"error_kind,omitempty"`
+	ErrorCode    string    `json:"error_code,omitempty"`
+
+	// Auth is the verified auth of the successes, without the
+	// credentials key. It's not encoded.
+	Auth *hawk.Auth `json:"-"`
+}
+
+// AuditSink receives the AuditEvents of the Middleware, the audit
+// package provides JSON, rotating file and channel sinks. Audit is
+// called during the request and should not block.
+type AuditSink interface {
+	Audit(e AuditEvent)
+}
+
+// AuditSinkFunc is a function implementing AuditSink.
+type AuditSinkFunc func(e AuditEvent)
+
+// Audit calls f.
+func (f AuditSinkFunc) Audit(e AuditEvent) {
+	f(e)
+}
+
+// audit sends the AuditEvent of an authentication attempt to the AuditSink if set.
+func (hm *Middleware) audit(c *gin.Context, hr *Request, auth *hawk.Auth, err error) {
+	if hm.AuditSink == nil {
+		return
+	}
+	e := AuditEvent{
+		Time:         hm.now(),
+		CredentialID: hr.ID,
+		ClientIP:     hr.ip,
+		Method:       c.Request.Method,
+		Path:         c.Request.URL.Path,
+		Outcome:      AuditSuccess,
+	}
+	if err != nil {
+		e.Outcome = AuditFailure
+		e.ErrorKind = Classify(err).Kind.String()
+		e.ErrorCode = ErrorCode(err)
+	} else if auth != nil {
+		e.Auth = snapshotAuth(auth)
+		e.Auth.Credentials.Key = ""
+	}
+	hm.AuditSink.Audit(e)
+}
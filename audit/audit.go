@@ -0,0 +1,275 @@
+// Package audit provides hawk.AuditSink implementations: JSON lines on a
+// writer (stdout for example), a size rotated file and a channel for
+// custom fan-out.
+//
+//	hm.AuditSink = audit.NewJSON(os.Stdout)
+//
+// The JSON and File sinks write in a goroutine through a queue of
+// QueueSize events, so a slow writer never delays the requests. Events
+// are dropped when the queue is full (see Dropped) and Close writes the
+// queued events.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hyperboloide/hawk"
+)
+
+// DefaultQueueSize is the default JSON and File QueueSize.
+const DefaultQueueSize = 1024
+
+// queue writes the events in a goroutine started on the first push and
+// stopped by close.
+type queue struct {
+	mu      sync.Mutex
+	ch      chan []byte
+	done    chan struct{}
+	dropped uint64
+}
+
+// push queues data for write, it's dropped if size events are queued.
+func (q *queue) push(data []byte, size int, write func([]byte)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ch == nil {
+		if size <= 0 {
+			size = DefaultQueueSize
+		}
+		q.ch = make(chan []byte, size)
+		q.done = make(chan struct{})
+		go q.run(q.ch, q.done, write)
+	}
+	select {
+	case q.ch <- data:
+	default:
+		atomic.AddUint64(&q.dropped, 1)
+	}
+}
+
+func (q *queue) run(ch chan []byte, done chan struct{}, write func([]byte)) {
+	defer close(done)
+	for data := range ch {
+		write(data)
+	}
+}
+
+// close waits for the queued events to be written.
+func (q *queue) close() {
+	q.mu.Lock()
+	ch, done := q.ch, q.done
+	q.ch = nil
+	q.mu.Unlock()
+	if ch != nil {
+		close(ch)
+		<-done
+	}
+}
+
+// JSON writes the events as JSON lines.
+// W is the destination
+// OnError if set is called with the write errors
+// QueueSize is the number of events queued, DefaultQueueSize if 0
+type JSON struct {
+	W         io.Writer
+	OnError   func(error)
+	QueueSize int
+
+	mu sync.Mutex
+	q  queue
+}
+
+// NewJSON creates a new JSON sink writing to w.
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{W: w}
+}
+
+// Audit is a hawk.AuditSink.
+func (s *JSON) Audit(e hawk.AuditEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		s.error(err)
+		return
+	}
+	s.q.push(append(data, '\n'), s.QueueSize, s.write)
+}
+
+func (s *JSON) write(data []byte) {
+	s.mu.Lock()
+	_, err := s.W.Write(data)
+	s.mu.Unlock()
+	s.error(err)
+}
+
+func (s *JSON) error(err error) {
+	if err != nil && s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (s *JSON) Dropped() uint64 {
+	return atomic.LoadUint64(&s.q.dropped)
+}
+
+// Close writes the queued events, the queue is restarted on the next event.
+func (s *JSON) Close() error {
+	s.q.close()
+	return nil
+}
+
+// File writes the events as JSON lines to a file rotated by size:
+// the file is renamed with a ".1" suffix, the previous backups are
+// shifted and the oldest is removed.
+// Path is the file path
+// MaxSize is the size in bytes after which the file is rotated
+// MaxBackups is the number of rotated files kept
+// OnError if set is called with the write and rotation errors
+// QueueSize is the number of events queued, DefaultQueueSize if 0
+type File struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+	OnError    func(error)
+	QueueSize  int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+	q    queue
+}
+
+// NewFile creates a new File sink rotated at 100MB with 5 backups.
+// The file is opened on the first event.
+func NewFile(path string) *File {
+	return &File{
+		Path:       path,
+		MaxSize:    100 << 20,
+		MaxBackups: 5,
+	}
+}
+
+// Audit is a hawk.AuditSink.
+func (s *File) Audit(e hawk.AuditEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		s.error(err)
+		return
+	}
+	s.q.push(append(data, '\n'), s.QueueSize, func(data []byte) {
+		s.error(s.write(data))
+	})
+}
+
+func (s *File) error(err error) {
+	if err != nil && s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (s *File) Dropped() uint64 {
+	return atomic.LoadUint64(&s.q.dropped)
+}
+
+func (s *File) write(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f != nil && s.size+int64(len(data)) > s.MaxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(data)
+	s.size += int64(n)
+	return err
+}
+
+func (s *File) open() error {
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f = f
+	s.size = info.Size()
+	return nil
+}
+
+func (s *File) backup(i int) string {
+	return fmt.Sprintf("%s.%d", s.Path, i)
+}
+
+// rotate closes the file and shifts the backups.
+func (s *File) rotate() error {
+	err := s.f.Close()
+	s.f = nil
+	if err != nil {
+		return err
+	}
+	if s.MaxBackups < 1 {
+		return os.Remove(s.Path)
+	}
+	os.Remove(s.backup(s.MaxBackups))
+	for i := s.MaxBackups - 1; i > 0; i-- {
+		if err := os.Rename(s.backup(i), s.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(s.Path, s.backup(1))
+}
+
+// Close writes the queued events and closes the file, it is reopened on
+// the next event.
+func (s *File) Close() error {
+	s.q.close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// Channel sends the events to C without blocking, events are dropped
+// when C is full.
+type Channel struct {
+	C chan hawk.AuditEvent
+
+	dropped uint64
+}
+
+// NewChannel creates a new Channel sink with a buffer of size events.
+func NewChannel(size int) *Channel {
+	return &Channel{C: make(chan hawk.AuditEvent, size)}
+}
+
+// Audit is a hawk.AuditSink.
+func (s *Channel) Audit(e hawk.AuditEvent) {
+	select {
+	case s.C <- e:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Dropped returns the number of events dropped because C was full.
+func (s *Channel) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
@@ -0,0 +1,136 @@
+package audit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	. "github.com/hyperboloide/hawk/audit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write error")
+}
+
+// blockingWriter blocks the writes until release is closed.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+	writes  int
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+	w.writes++
+	return len(p), nil
+}
+
+var _ = Describe("Sinks", func() {
+
+	event := hawk.AuditEvent{
+		Time:         time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		CredentialID: "my-id",
+		ClientIP:     "10.0.0.1",
+		Method:       "GET",
+		Path:         "/private",
+		Outcome:      hawk.AuditFailure,
+		ErrorKind:    "credentials",
+		ErrorCode:    "invalid_mac",
+	}
+
+	It("writes JSON lines", func() {
+		var buf bytes.Buffer
+		s := NewJSON(&buf)
+		s.Audit(event)
+		s.Audit(event)
+		Expect(s.Close()).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		var res hawk.AuditEvent
+		Expect(json.Unmarshal([]byte(lines[0]), &res)).To(Succeed())
+		Expect(res).To(Equal(event))
+
+		var errs []error
+		s = NewJSON(failingWriter{})
+		s.OnError = func(err error) {
+			errs = append(errs, err)
+		}
+		s.Audit(event)
+		Expect(s.Close()).To(Succeed())
+		Expect(errs).To(HaveLen(1))
+	})
+
+	It("drops the events when the queue is full", func() {
+		w := &blockingWriter{started: make(chan struct{}, 5), release: make(chan struct{})}
+		s := NewJSON(w)
+		s.QueueSize = 2
+		s.Audit(event)
+		Eventually(w.started).Should(Receive())
+		// one event is being written, two are queued
+		for i := 0; i < 4; i++ {
+			s.Audit(event)
+		}
+		Expect(s.Dropped()).To(BeNumerically("==", 2))
+		close(w.release)
+		Expect(s.Close()).To(Succeed())
+		Expect(w.writes).To(Equal(3))
+	})
+
+	It("rotates files", func() {
+		dir, err := ioutil.TempDir("", "audit")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "audit.log")
+
+		data, _ := json.Marshal(event)
+		s := NewFile(path)
+		s.MaxSize = int64(len(data)+1) * 2
+		s.MaxBackups = 2
+		var errs []error
+		s.OnError = func(err error) {
+			errs = append(errs, err)
+		}
+		for i := 0; i < 7; i++ {
+			s.Audit(event)
+		}
+		Expect(s.Close()).To(Succeed())
+		Expect(errs).To(BeEmpty())
+
+		count := func(p string) int {
+			data, err := ioutil.ReadFile(p)
+			Expect(err).ToNot(HaveOccurred())
+			return strings.Count(string(data), "\n")
+		}
+		Expect(count(path)).To(Equal(1))
+		Expect(count(path + ".1")).To(Equal(2))
+		Expect(count(path + ".2")).To(Equal(2))
+		Expect(path + ".3").ToNot(BeAnExistingFile())
+
+		// appends to the existing file after a restart
+		s = NewFile(path)
+		s.Audit(event)
+		Expect(s.Close()).To(Succeed())
+		Expect(count(path)).To(Equal(2))
+	})
+
+	It("sends to a channel", func() {
+		s := NewChannel(1)
+		s.Audit(event)
+		s.Audit(event)
+		Expect(s.C).To(Receive(Equal(event)))
+		Expect(s.Dropped()).To(BeNumerically("==", 1))
+	})
+
+})
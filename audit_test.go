@@ -0,0 +1,86 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuditSink", func() {
+
+	var ts *httptest.Server
+	var events []AuditEvent
+	now := time.Now()
+
+	BeforeEach(func() {
+		events = nil
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{Key: "test-cred-key"}, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		hm.Now = func() time.Time {
+			return now
+		}
+		hm.AuditSink = AuditSinkFunc(func(e AuditEvent) {
+			events = append(events, e)
+		})
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(key string) {
+		req, _ := http.NewRequest("GET", ts.URL+"/private?a=1", nil)
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "my-id",
+			Key:  key,
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+	}
+
+	It("audits authentication attempts", func() {
+		request("test-cred-key")
+		request("wrong-key")
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Auth.Credentials.ID).To(Equal("my-id"))
+		Expect(events[0].Auth.Credentials.Key).To(BeEmpty())
+		events[0].Auth = nil
+		Expect(events).To(Equal([]AuditEvent{{
+			Time:         now,
+			CredentialID: "my-id",
+			ClientIP:     "127.0.0.1",
+			Method:       "GET",
+			Path:         "/private",
+			Outcome:      AuditSuccess,
+		}, {
+			Time:         now,
+			CredentialID: "my-id",
+			ClientIP:     "127.0.0.1",
+			Method:       "GET",
+			Path:         "/private",
+			Outcome:      AuditFailure,
+			ErrorKind:    "credentials",
+			ErrorCode:    "invalid_mac",
+		}}))
+	})
+
+})
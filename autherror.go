@@ -0,0 +1,145 @@
+package hawk
+
+import (
+	"errors"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// ErrorKind is the class of an AuthError.
+type ErrorKind int
+
+const (
+	// KindInternal is an external problem (store, configuration...).
+	KindInternal ErrorKind = iota
+	// KindCredentials is an unknown, expired, revoked or disabled
+	// credentials or an invalid MAC.
+	KindCredentials
+	// KindReplay is a replayed nonce.
+	KindReplay
+	// KindSkew is a timestamp skew too high or an expired bewit.
+	KindSkew
+	// KindRequest is a request rejected for another reason: missing
+	// authentication, invalid payload hash, app, ext or bewit method.
+	KindRequest
+	// KindLimited is a request rate limited, locked out, banned or too slow.
+	KindLimited
+	// KindUnavailable is a verified request rejected during a maintenance.
+	KindUnavailable
+)
+
+var kindNames = map[ErrorKind]string{
+	KindInternal:    "internal",
+	KindCredentials: "credentials",
+	KindReplay:      "replay",
+	KindSkew:        "skew",
+	KindRequest:     "request",
+	KindLimited:     "limited",
+	KindUnavailable: "unavailable",
+}
+
+func (k ErrorKind) String() string {
+	return kindNames[k]
+}
+
+// Sentinels matching any AuthError of their kind with errors.Is:
+//
+//	if errors.Is(err, hawk.ErrKindReplay) { ... }
+var (
+	ErrKindInternal    = &AuthError{Kind: KindInternal}
+	ErrKindCredentials = &AuthError{Kind: KindCredentials}
+	ErrKindReplay      = &AuthError{Kind: KindReplay}
+	ErrKindSkew        = &AuthError{Kind: KindSkew}
+	ErrKindRequest     = &AuthError{Kind: KindRequest}
+	ErrKindLimited     = &AuthError{Kind: KindLimited}
+	ErrKindUnavailable = &AuthError{Kind: KindUnavailable}
+)
+
+var errorKinds = map[error]ErrorKind{
+	ErrNotFound:                KindCredentials,
+	ErrCredentialsExpired:      KindCredentials,
+	ErrCredentialsRevoked:      KindCredentials,
+	ErrPrincipalDisabled:       KindCredentials,
+	ErrFallbackDenied:          KindCredentials,
+	hawk.ErrInvalidMAC:         KindCredentials,
+	hawk.ErrReplay:             KindReplay,
+	hawk.ErrTimestampSkew:      KindSkew,
+	hawk.ErrBewitExpired:       KindSkew,
+	ErrInvalidApp:              KindRequest,
+	ErrInvalidExt:              KindRequest,
+	ErrInvalidPayloadHash:      KindRequest,
+	ErrMixedHash:               KindInternal,
+	ErrMissingPayloadHash:      KindRequest,
+	ErrBodyTooLarge:            KindRequest,
+	ErrTLSRequired:             KindRequest,
+	ErrHeaderTooLarge:          KindRequest,
+	ErrUnknownAttribute:        KindRequest,
+	ErrUnknownTenant:           KindRequest,
+	hawk.ErrInvalidBewitMethod: KindRequest,
+	hawk.ErrMissingServerAuth:  KindRequest,
+	hawk.ErrNoAuth:             KindRequest,
+	ErrBanned:                  KindLimited,
+	ErrRateLimited:             KindLimited,
+	ErrLockedOut:               KindLimited,
+	ErrSlowBody:                KindLimited,
+	ErrMaintenance:             KindUnavailable,
+}
+
+// AuthError is the error set in the context, and passed to the
+// AbortRequestHandler, when the Middleware rejects a request. Cause is
+// the original error passed to the AbortHandler, for example the
+// protocol ErrReplay or a GetCredentialFunc error.
+type AuthError struct {
+	Kind  ErrorKind
+	Cause error
+}
+
+func (e *AuthError) Error() string {
+	if e.Cause == nil {
+		return e.Kind.String()
+	}
+	return e.Cause.Error()
+}
+
+// Unwrap returns the Cause.
+func (e *AuthError) Unwrap() error {
+	return e.Cause
+}
+
+// Is matches the kind sentinels (AuthError without Cause).
+func (e *AuthError) Is(target error) bool {
+	t, ok := target.(*AuthError)
+	return ok && t.Cause == nil && t.Kind == e.Kind
+}
+
+// Classify returns err as an *AuthError, nil if err is nil.
+// Malformed headers and bewits (protocol AuthFormatError) are of
+// KindRequest, errors not known by the Middleware are of KindInternal.
+func Classify(err error) *AuthError {
+	if err == nil {
+		return nil
+	}
+	var res *AuthError
+	if errors.As(err, &res) {
+		return res
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if kind, ok := errorKinds[e]; ok {
+			return &AuthError{Kind: kind, Cause: err}
+		}
+	}
+	var formatErr hawk.AuthFormatError
+	if errors.As(err, &formatErr) {
+		return &AuthError{Kind: KindRequest, Cause: err}
+	}
+	return &AuthError{Kind: KindInternal, Cause: err}
+}
+
+// cause returns the Cause of err if it's an *AuthError.
+func cause(err error) error {
+	var res *AuthError
+	if errors.As(err, &res) && res.Cause != nil {
+		return res.Cause
+	}
+	return err
+}
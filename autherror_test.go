@@ -0,0 +1,195 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthError", func() {
+
+	storeErr := errors.New("store error")
+
+	It("classifies errors", func() {
+		Expect(Classify(nil)).To(BeNil())
+		Expect(Classify(hawk.ErrInvalidMAC).Kind).To(Equal(KindCredentials))
+		Expect(Classify(ErrCredentialsRevoked).Kind).To(Equal(KindCredentials))
+		Expect(Classify(hawk.ErrReplay).Kind).To(Equal(KindReplay))
+		Expect(Classify(hawk.ErrTimestampSkew).Kind).To(Equal(KindSkew))
+		Expect(Classify(hawk.ErrNoAuth).Kind).To(Equal(KindRequest))
+		Expect(Classify(ErrRateLimited).Kind).To(Equal(KindLimited))
+		Expect(Classify(storeErr).Kind).To(Equal(KindInternal))
+		Expect(Classify(fmt.Errorf("wrapped: %w", hawk.ErrReplay)).Kind).To(Equal(KindReplay))
+
+		err := Classify(hawk.ErrReplay)
+		Expect(Classify(err)).To(BeIdenticalTo(err))
+	})
+
+	It("works with errors.Is and errors.As", func() {
+		var err error = Classify(hawk.ErrReplay)
+		Expect(errors.Is(err, ErrKindReplay)).To(BeTrue())
+		Expect(errors.Is(err, ErrKindSkew)).To(BeFalse())
+		Expect(errors.Is(err, hawk.ErrReplay)).To(BeTrue())
+		Expect(err.Error()).To(Equal(hawk.ErrReplay.Error()))
+
+		var authErr *AuthError
+		Expect(errors.As(err, &authErr)).To(BeTrue())
+		Expect(authErr.Cause).To(Equal(hawk.ErrReplay))
+
+		Expect(ISHawkError(err)).To(BeTrue())
+		Expect(ISHawkError(Classify(storeErr))).To(BeFalse())
+		Expect(ISHawkError(nil)).To(BeFalse())
+		Expect(ErrKindReplay.Error()).To(Equal("replay"))
+	})
+
+	It("passes AuthErrors to the AbortHandler", func() {
+		var kinds []ErrorKind
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			if id == "error-id" {
+				return nil, storeErr
+			}
+			return &Credentials{Key: "test-cred-key"}, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return nonce != "replayed", nil
+		})
+		var causes []error
+		hm.AbortHandler = func(c *gin.Context, err error) {
+			var authErr *AuthError
+			Expect(errors.As(err, &authErr)).To(BeFalse())
+			causes = append(causes, err)
+			kinds = append(kinds, Classify(err).Kind)
+			c.String(401, "")
+		}
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts := httptest.NewServer(router)
+		defer ts.Close()
+
+		request := func(id, key, nonce string, offset time.Duration) {
+			req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+			Expect(err).ToNot(HaveOccurred())
+			auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+				ID:   id,
+				Key:  key,
+				Hash: sha256.New,
+			}, offset)
+			if nonce != "" {
+				auth.Nonce = nonce
+			}
+			req.Header.Set("Authorization", auth.RequestHeader())
+			_, err = http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		request("id", "bad-key", "", 0)
+		request("id", "test-cred-key", "replayed", 0)
+		request("id", "test-cred-key", "", time.Hour)
+		request("error-id", "test-cred-key", "", 0)
+		Expect(kinds).To(Equal([]ErrorKind{
+			KindCredentials,
+			KindReplay,
+			KindSkew,
+			KindInternal,
+		}))
+		Expect(causes[:3]).To(Equal([]error{hawk.ErrInvalidMAC, hawk.ErrReplay, hawk.ErrTimestampSkew}))
+		Expect(errors.Is(causes[3], storeErr)).To(BeTrue())
+	})
+
+	It("classifies malformed headers and bewits as request errors", func() {
+		err := hawk.AuthFormatError{Field: "scheme", Err: "must be Hawk"}
+		Expect(Classify(err).Kind).To(Equal(KindRequest))
+		Expect(ErrorCode(err)).To(Equal(ErrorMalformed))
+		Expect(DefaultStatusMapper(err)).To(Equal(http.StatusUnauthorized))
+
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{Key: "test-cred-key"}, nil
+		}, nil)
+		hm.ErrorFormat = ErrorJSON
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		request := func(url, header string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", url, nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+		for _, w := range []*httptest.ResponseRecorder{
+			request("http://example.com/private", "Bearer abc"),
+			request("http://example.com/private", "Hawk garbage"),
+			request("http://example.com/private?bewit=abc", ""),
+		} {
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(w.Body.String()).To(ContainSubstring(`"error":"malformed_auth"`))
+		}
+	})
+
+	It("passes the auth and Request state to the AbortRequestHandler", func() {
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{Key: "test-cred-key", User: "user-" + id}, nil
+		}, nil)
+		hm.AbortHandler = func(c *gin.Context, err error) {
+			Fail("the AbortRequestHandler replaces the AbortHandler")
+		}
+		var got []string
+		var kept *Request
+		hm.AbortRequestHandler = func(c *gin.Context, err *AuthError, auth *hawk.Auth, hr *Request) {
+			Expect(hr).ToNot(BeNil())
+			kept = hr
+			got = append(got, fmt.Sprint(err.Kind, " ", hr.ID, " ", auth != nil))
+			if auth != nil {
+				Expect(c.Writer.Header().Get("Server-Authorization")).ToNot(BeEmpty())
+				Expect(auth.Credentials.Key).To(BeEmpty())
+			}
+			c.String(401, "")
+		}
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+
+		request := func(key string) (*http.Request, int) {
+			req := httptest.NewRequest("GET", "http://example.com/private", nil)
+			auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+				ID:   "my-id",
+				Key:  key,
+				Hash: sha256.New,
+			}, 0)
+			req.Header.Set("Authorization", auth.RequestHeader())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return req, w.Code
+		}
+
+		_, code := request("bad-key")
+		Expect(code).To(Equal(http.StatusUnauthorized))
+		req, code := request("test-cred-key")
+		Expect(code).To(Equal(http.StatusOK))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		Expect(got).To(Equal([]string{
+			"credentials my-id true",
+			"replay my-id false",
+		}))
+		// the Request is a copy not reset by the pool
+		Expect(kept.ID).To(Equal("my-id"))
+	})
+
+})
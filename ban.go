@@ -0,0 +1,176 @@
+package hawk
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBanned is set in context.Err when the BanPolicy rejects the client
+// IP or the credentials id, before the credentials lookup and the MAC
+// computation. The response status is 403.
+var ErrBanned = errors.New("Banned")
+
+// BanPolicy rejects the requests of banned client IPs or credentials ids.
+// Banned returns true if the request must be rejected. An error is an
+// external problem and it will be set as the context error.
+type BanPolicy interface {
+	Banned(ip, id string) (bool, error)
+}
+
+// BanPolicyFunc is a function implementing BanPolicy.
+type BanPolicyFunc func(ip, id string) (bool, error)
+
+// Banned calls f.
+func (f BanPolicyFunc) Banned(ip, id string) (bool, error) {
+	return f(ip, id)
+}
+
+// banned consults the BanPolicy if set.
+func (hr *Request) banned(id string) error {
+	if hr.Hawk.BanPolicy == nil {
+		return nil
+	}
+	banned, err := hr.Hawk.BanPolicy.Banned(hr.ip, id)
+	if err != nil {
+		return err
+	} else if banned {
+		return ErrBanned
+	}
+	return nil
+}
+
+// DefaultFailureMetricsSize is the default FailureMetrics MaxKeys.
+const DefaultFailureMetricsSize = 100000
+
+type failureWindow struct {
+	count int
+	start time.Time
+}
+
+// FailureMetrics counts the authentication failures of the Filter by
+// client IP, by credentials id and by both, over fixed windows of
+// Window. Requests rejected with ErrBanned are not counted, so bans end
+// with the window.
+// MaxKeys if set bounds the IPs, ids and pairs counted in a window, the
+// new ones are not counted once reached. The ended windows are swept
+// every Window.
+type FailureMetrics struct {
+	Window  time.Duration
+	MaxKeys int
+
+	mu        sync.Mutex
+	ips       map[string]*failureWindow
+	ids       map[string]*failureWindow
+	pairs     map[string]*failureWindow
+	nextSweep time.Time
+}
+
+// NewFailureMetrics creates a new FailureMetrics.
+func NewFailureMetrics(window time.Duration) *FailureMetrics {
+	return &FailureMetrics{
+		Window:  window,
+		MaxKeys: DefaultFailureMetricsSize,
+		ips:     map[string]*failureWindow{},
+		ids:     map[string]*failureWindow{},
+		pairs:   map[string]*failureWindow{},
+	}
+}
+
+// pairKey returns the key of ip and id in the pairs.
+func pairKey(ip, id string) string {
+	return ip + " " + id
+}
+
+// Failed records a failure of ip and id, an empty value is ignored.
+func (m *FailureMetrics) Failed(ip, id string) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.After(m.nextSweep) {
+		m.sweep(now)
+	}
+	if ip != "" {
+		m.incr(m.ips, ip, now)
+	}
+	if id != "" {
+		m.incr(m.ids, id, now)
+	}
+	if ip != "" && id != "" {
+		m.incr(m.pairs, pairKey(ip, id), now)
+	}
+}
+
+func (m *FailureMetrics) incr(windows map[string]*failureWindow, key string, now time.Time) {
+	w, exists := windows[key]
+	if !exists && m.MaxKeys > 0 && len(windows) >= m.MaxKeys {
+		return
+	} else if !exists || now.Sub(w.start) >= m.Window {
+		w = &failureWindow{start: now}
+		windows[key] = w
+	}
+	w.count++
+}
+
+// sweep removes the ended windows, m.mu must be held.
+func (m *FailureMetrics) sweep(now time.Time) {
+	for _, windows := range []map[string]*failureWindow{m.ips, m.ids, m.pairs} {
+		for key, w := range windows {
+			if now.Sub(w.start) >= m.Window {
+				delete(windows, key)
+			}
+		}
+	}
+	m.nextSweep = now.Add(m.Window)
+}
+
+func (m *FailureMetrics) get(windows map[string]*failureWindow, key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w, exists := windows[key]
+	if !exists {
+		return 0
+	} else if time.Since(w.start) >= m.Window {
+		delete(windows, key)
+		return 0
+	}
+	return w.count
+}
+
+// IPFailures returns the failures of ip in the current window.
+func (m *FailureMetrics) IPFailures(ip string) int {
+	return m.get(m.ips, ip)
+}
+
+// IDFailures returns the failures of the credentials id in the current
+// window.
+func (m *FailureMetrics) IDFailures(id string) int {
+	return m.get(m.ids, id)
+}
+
+// PairFailures returns the failures of the credentials id from ip in
+// the current window.
+func (m *FailureMetrics) PairFailures(ip, id string) int {
+	return m.get(m.pairs, pairKey(ip, id))
+}
+
+// ThresholdBan returns a BanPolicy banning the IPs with maxIP failures
+// and the credentials ids with maxID failures from the same IP in the
+// current window of m, a 0 max disables the check. The ids are not
+// banned for all the IPs, so failures from anywhere can't lock their
+// legitimate clients out.
+func ThresholdBan(m *FailureMetrics, maxIP, maxID int) BanPolicy {
+	return BanPolicyFunc(func(ip, id string) (bool, error) {
+		if maxIP > 0 && m.IPFailures(ip) >= maxIP {
+			return true, nil
+		}
+		return maxID > 0 && m.PairFailures(ip, id) >= maxID, nil
+	})
+}
+
+// recordFailure records a failure in the FailureMetrics if set.
+func (hm *Middleware) recordFailure(hr *Request, err error) {
+	if hm.FailureMetrics != nil && !errors.Is(err, ErrBanned) {
+		hm.FailureMetrics.Failed(hr.ip, hr.tenantID(hr.ID))
+	}
+}
@@ -0,0 +1,128 @@
+package hawk_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BanPolicy", func() {
+
+	var store *hawktest.Store
+	var hm *Middleware
+	var router *gin.Engine
+	var lookups int
+
+	request := func(id, key, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RemoteAddr = ip + ":1234"
+		_, err := hawktest.SignRequest(req, id, key)
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	BeforeEach(func() {
+		lookups = 0
+		store = hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = NewMiddleware(func(id string) (*Credentials, error) {
+			lookups++
+			return store.GetCredentials(id)
+		}, store.SetNonce)
+		hm.ErrorFormat = ErrorJSON
+		hm.FailureMetrics = NewFailureMetrics(time.Minute)
+		router = gin.New()
+		router.GET("/", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+	})
+
+	It("counts failures by IP and credentials id", func() {
+		Expect(request("my-id", "invalid", "10.0.0.1").Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("my-id", "invalid", "10.0.0.2").Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("unknown", "invalid", "10.0.0.1").Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("my-id", "my-key", "10.0.0.1").Code).To(Equal(http.StatusOK))
+
+		Expect(hm.FailureMetrics.IPFailures("10.0.0.1")).To(Equal(2))
+		Expect(hm.FailureMetrics.IPFailures("10.0.0.2")).To(Equal(1))
+		Expect(hm.FailureMetrics.IDFailures("my-id")).To(Equal(2))
+		Expect(hm.FailureMetrics.IDFailures("unknown")).To(Equal(1))
+		Expect(hm.FailureMetrics.IDFailures("other")).To(Equal(0))
+		Expect(hm.FailureMetrics.PairFailures("10.0.0.1", "my-id")).To(Equal(1))
+		Expect(hm.FailureMetrics.PairFailures("10.0.0.2", "unknown")).To(Equal(0))
+	})
+
+	It("bans before the credentials lookup", func() {
+		hm.BanPolicy = ThresholdBan(hm.FailureMetrics, 2, 0)
+		request("my-id", "invalid", "10.0.0.1")
+		request("my-id", "invalid", "10.0.0.1")
+		Expect(lookups).To(Equal(2))
+
+		w := request("my-id", "my-key", "10.0.0.1")
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(w.Body.String()).To(ContainSubstring(`"banned"`))
+		Expect(lookups).To(Equal(2))
+		Expect(hm.FailureMetrics.IPFailures("10.0.0.1")).To(Equal(2))
+
+		Expect(request("my-id", "my-key", "10.0.0.2").Code).To(Equal(http.StatusOK))
+	})
+
+	It("bans credentials ids by IP", func() {
+		hm.BanPolicy = ThresholdBan(hm.FailureMetrics, 0, 2)
+		request("my-id", "invalid", "10.0.0.1")
+		request("my-id", "invalid", "10.0.0.2")
+		Expect(request("my-id", "my-key", "10.0.0.3").Code).To(Equal(http.StatusOK))
+
+		request("my-id", "invalid", "10.0.0.1")
+		Expect(request("my-id", "my-key", "10.0.0.1").Code).To(Equal(http.StatusForbidden))
+		Expect(request("my-id", "my-key", "10.0.0.3").Code).To(Equal(http.StatusOK))
+	})
+
+	It("bounds and sweeps the failures", func() {
+		m := NewFailureMetrics(20 * time.Millisecond)
+		m.MaxKeys = 2
+		m.Failed("10.0.0.1", "id-1")
+		m.Failed("10.0.0.2", "id-2")
+		m.Failed("10.0.0.3", "id-3")
+		Expect(m.IPFailures("10.0.0.3")).To(BeZero())
+		Expect(m.IDFailures("id-3")).To(BeZero())
+
+		time.Sleep(30 * time.Millisecond)
+		m.Failed("10.0.0.3", "id-3")
+		Expect(m.IPFailures("10.0.0.3")).To(Equal(1))
+		Expect(m.PairFailures("10.0.0.3", "id-3")).To(Equal(1))
+	})
+
+	It("ends the bans with the window", func() {
+		hm.FailureMetrics.Window = 50 * time.Millisecond
+		hm.BanPolicy = ThresholdBan(hm.FailureMetrics, 1, 0)
+		request("my-id", "invalid", "10.0.0.1")
+		Expect(request("my-id", "my-key", "10.0.0.1").Code).To(Equal(http.StatusForbidden))
+		time.Sleep(60 * time.Millisecond)
+		Expect(request("my-id", "my-key", "10.0.0.1").Code).To(Equal(http.StatusOK))
+	})
+
+	It("sets the policy errors", func() {
+		hm.BanPolicy = BanPolicyFunc(func(ip, id string) (bool, error) {
+			return false, errors.New("policy failed")
+		})
+		Expect(request("my-id", "my-key", "10.0.0.1").Code).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("classifies ErrBanned", func() {
+		Expect(ErrorCode(ErrBanned)).To(Equal("banned"))
+		Expect(Classify(ErrBanned).Kind).To(Equal(KindLimited))
+		Expect(DefaultStatusMapper(ErrBanned)).To(Equal(http.StatusForbidden))
+	})
+
+})
@@ -0,0 +1,171 @@
+package hawk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+const benchKey = "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn"
+
+// benchStore is a credentials and nonce store like the ones used in
+// production: a map of many credentials behind a lock, and nonces
+// remembered until they expire.
+type benchStore struct {
+	mu     sync.RWMutex
+	creds  map[string]*Credentials
+	nonces map[string]time.Time
+}
+
+func newBenchStore() *benchStore {
+	s := &benchStore{
+		creds:  make(map[string]*Credentials, 10000),
+		nonces: map[string]time.Time{},
+	}
+	for i := 0; i < 10000; i++ {
+		id := "id-" + strconv.Itoa(i)
+		s.creds[id] = &Credentials{Key: benchKey, User: id, Scopes: []string{"read"}}
+	}
+	return s
+}
+
+func (s *benchStore) GetCredentials(id string) (*Credentials, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.creds[id], nil
+}
+
+func (s *benchStore) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	key := id + ":" + nonce
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.nonces[key]; ok {
+		return false, nil
+	}
+	if len(s.nonces) > 100000 {
+		s.nonces = map[string]time.Time{}
+	}
+	s.nonces[key] = t
+	return true, nil
+}
+
+func benchRouter(opts ...Option) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	s := newBenchStore()
+	hm := NewMiddleware(s.GetCredentials, s.SetNonce)
+	hm.Apply(opts...)
+	router := gin.New()
+	handler := func(c *gin.Context) {}
+	router.GET("/resource", hm.Filter, handler)
+	router.POST("/resource", hm.Filter, handler)
+	return router
+}
+
+func benchCredentials(i int) *hawk.Credentials {
+	return &hawk.Credentials{
+		ID:   "id-" + strconv.Itoa(i%10000),
+		Key:  benchKey,
+		Hash: sha256.New,
+	}
+}
+
+// benchServe serves the requests created by newRequest, which are
+// signed outside of the timer.
+func benchServe(b *testing.B, router *gin.Engine, newRequest func(i int) *http.Request) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		req := newRequest(i)
+		w := httptest.NewRecorder()
+		b.StartTimer()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatal(w.Code, w.Body.String())
+		}
+	}
+}
+
+func BenchmarkFilterHeaderAuth(b *testing.B) {
+	router := benchRouter()
+	benchServe(b, router, func(i int) *http.Request {
+		req := httptest.NewRequest("GET", "http://example.com/resource", nil)
+		auth := hawk.NewRequestAuth(req, benchCredentials(i), 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		return req
+	})
+}
+
+func BenchmarkFilterPayload(b *testing.B) {
+	router := benchRouter(func(hm *Middleware) {
+		hm.ValidatePayload = true
+	})
+	body := bytes.Repeat([]byte("a"), 1024)
+	benchServe(b, router, func(i int) *http.Request {
+		req := httptest.NewRequest("POST", "http://example.com/resource", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "text/plain")
+		creds := benchCredentials(i)
+		auth := hawk.NewRequestAuth(req, creds, 0)
+		auth.Hash = PayloadHash(creds.Hash, "text/plain", body)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		return req
+	})
+}
+
+func BenchmarkFilterBewit(b *testing.B) {
+	router := benchRouter()
+	bewits := make([]string, 100)
+	for i := range bewits {
+		auth, err := hawk.NewURLAuth("http://example.com/resource", benchCredentials(i), time.Hour)
+		if err != nil {
+			b.Fatal(err)
+		}
+		bewits[i] = auth.Bewit()
+	}
+	benchServe(b, router, func(i int) *http.Request {
+		return httptest.NewRequest("GET", "http://example.com/resource?bewit="+bewits[i%len(bewits)], nil)
+	})
+}
+
+func BenchmarkPayloadHash(b *testing.B) {
+	body := bytes.Repeat([]byte("a"), 1024)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for i := 0; i < b.N; i++ {
+		PayloadHash(sha256.New, "application/json", body)
+	}
+}
+
+func BenchmarkResponseHeader(b *testing.B) {
+	req := httptest.NewRequest("GET", "http://example.com/resource", nil)
+	auth := hawk.NewRequestAuth(req, benchCredentials(0), 0)
+	ext := newExtParts("some-app-ext-data")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		responseHeader(auth, ext)
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	s := newBenchStore()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		req := httptest.NewRequest("GET", "http://example.com/resource", nil)
+		auth := hawk.NewRequestAuth(req, benchCredentials(i), 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		b.StartTimer()
+		if _, err := Verify(req, s, s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
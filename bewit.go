@@ -0,0 +1,188 @@
+package hawk
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// ErrBewitURL is set in context.Err by the BewitHandler when the URL to
+// share is invalid or on another host.
+var ErrBewitURL = errors.New("Invalid bewit URL")
+
+// bewitRequest is the body of the BewitHandler.
+type bewitRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// BewitHandler returns a handler minting bewits valid for ttl with the
+// credentials of the request, which must be authenticated by the Filter,
+// to offer share links for example:
+//
+//	router.POST("/share", hm.Filter, hm.BewitHandler(time.Hour))
+//
+// The body is a JSON object with the "url" to share, relative or on the
+// host of the request. The response is a JSON object with the "url"
+// including the bewit, the "bewit" and its "expires_at" time.
+func (hm *Middleware) BewitHandler(ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth, ok := AuthFromContext(c)
+		if !ok || auth.IsBewit {
+			c.AbortWithError(http.StatusUnauthorized, hawk.ErrNoAuth)
+			return
+		}
+		var req bewitRequest
+		if err := c.BindJSON(&req); err != nil {
+			return
+		}
+		u, err := hm.bewitURL(c.Request, req.URL)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		hr := &Request{Hawk: hm, ctx: c.Request.Context()}
+		if err := hm.resolveTenant(c, hr); err != nil {
+			hm.Abortequest(c, err, nil)
+			return
+		}
+		creds := &hawk.Credentials{ID: auth.Credentials.ID}
+		if err := hr.credentialsLookup(creds); err != nil {
+			hm.Abortequest(c, err, nil)
+			return
+		}
+		bewitAuth, err := hawk.NewURLAuth(u.String(), creds, ttl)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		bewit := bewitAuth.Bewit()
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += hm.bewitName() + "=" + bewit
+		c.JSON(http.StatusOK, gin.H{
+			"url":        u.String(),
+			"bewit":      bewit,
+			"expires_at": bewitAuth.Timestamp,
+		})
+	}
+}
+
+// bewitURL resolves rawurl against r and checks it's on the same host,
+// the host signed by the client like in the Filter.
+func (hm *Middleware) bewitURL(r *http.Request, rawurl string) (*url.URL, error) {
+	ref, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, ErrBewitURL
+	}
+	signed := hm.signedRequest(r)
+	base := &url.URL{Scheme: "http", Path: signed.URL.Path}
+	if hm.isTLS(r) {
+		base.Scheme = "https"
+	}
+	base.Host = trimDefaultPort(base.Scheme, signed.Host)
+	res := base.ResolveReference(ref)
+	if trimDefaultPort(res.Scheme, res.Host) != base.Host || res.Scheme != base.Scheme {
+		return nil, ErrBewitURL
+	}
+	res.Fragment = ""
+	return res, nil
+}
+
+// trimDefaultPort removes the default port of scheme from host.
+func trimDefaultPort(scheme, host string) string {
+	if scheme == "https" {
+		return strings.TrimSuffix(host, ":443")
+	}
+	return strings.TrimSuffix(host, ":80")
+}
+
+// bewitName returns the query parameter of the bewits.
+func (hm *Middleware) bewitName() string {
+	if hm.BewitParam != "" {
+		return hm.BewitParam
+	}
+	return "bewit"
+}
+
+// bewit returns the bewit of r from the BewitParam or the BewitHeader,
+// empty if none.
+func (hm *Middleware) bewit(r *http.Request) string {
+	if res := r.URL.Query().Get(hm.bewitName()); res != "" {
+		return res
+	} else if hm.BewitHeader != "" {
+		return r.Header.Get(hm.BewitHeader)
+	}
+	return ""
+}
+
+// bewitTransport returns a shallow copy of r with the bewit in the
+// "bewit" parameter expected by the protocol if it was sent in the
+// BewitParam or the BewitHeader. Other "bewit" parameters are ignored.
+func (hm *Middleware) bewitTransport(r *http.Request) *http.Request {
+	name := hm.bewitName()
+	if name == "bewit" && hm.BewitHeader == "" || r.Header.Get("Authorization") != "" {
+		return r
+	}
+
+	var params []string
+	if r.URL.RawQuery != "" {
+		params = strings.Split(r.URL.RawQuery, "&")
+	}
+	kept := params[:0]
+	found, changed := false, false
+	for _, p := range params {
+		if strings.HasPrefix(p, name+"=") {
+			kept = append(kept, "bewit="+p[len(name)+1:])
+			found, changed = true, name != "bewit"
+		} else if strings.HasPrefix(p, "bewit=") {
+			changed = true
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	if !found && hm.BewitHeader != "" {
+		if bewit := r.Header.Get(hm.BewitHeader); bewit != "" {
+			kept = append(kept, "bewit="+url.QueryEscape(bewit))
+			changed = true
+		}
+	}
+	if !changed {
+		return r
+	}
+	res := *r
+	u := *r.URL
+	u.RawQuery = strings.Join(kept, "&")
+	res.URL = &u
+	return &res
+}
+
+// stripBewit removes the bewit from the URL and the RequestURI of r,
+// keeping the order and encoding of the other parameters.
+func (hm *Middleware) stripBewit(r *http.Request) {
+	r.URL.RawQuery = stripParam(r.URL.RawQuery, hm.bewitName())
+	if i := strings.IndexByte(r.RequestURI, '?'); i >= 0 {
+		r.RequestURI = r.RequestURI[:i]
+		if r.URL.RawQuery != "" {
+			r.RequestURI += "?" + r.URL.RawQuery
+		}
+	}
+}
+
+// stripParam returns the raw query q without the parameter name.
+func stripParam(q, name string) string {
+	params := strings.Split(q, "&")
+	kept := params[:0]
+	for _, p := range params {
+		if !strings.HasPrefix(p, name+"=") {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, "&")
+}
@@ -0,0 +1,217 @@
+package hawk_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BewitHandler", func() {
+
+	var router *gin.Engine
+	var hm *Middleware
+	var user interface{}
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = store.Middleware()
+		router = gin.New()
+		router.POST("/share", hm.Filter, hm.BewitHandler(time.Hour))
+		router.GET("/files/:name", hm.Filter, func(c *gin.Context) {
+			user = GetUser(c)
+			c.String(200, c.Param("name"))
+		})
+	})
+
+	share := func(target string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]string{"url": target})
+		req := httptest.NewRequest("POST", "http://example.com/share", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		res := map[string]interface{}{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	It("mints bewits for the authenticated credentials", func() {
+		code, res := share("/files/report.pdf?b=1&a=2")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(res["url"]).To(HavePrefix("http://example.com/files/report.pdf?b=1&a=2&bewit="))
+		Expect(res["bewit"]).ToNot(BeEmpty())
+		expires, err := time.Parse(time.RFC3339, res["expires_at"].(string))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(expires).To(BeTemporally("~", time.Now().Add(time.Hour), 2*time.Second))
+
+		user = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", res["url"].(string), nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal("report.pdf"))
+		Expect(user).To(Equal("my-id"))
+	})
+
+	It("mints the bewits for the host signed behind a proxy", func() {
+		hm.TrustProxyHeaders = true
+		hm.TrustedProxies = []string{"192.0.2.1"}
+		body, _ := json.Marshal(map[string]string{"url": "https://api.example.com/files/report.pdf"})
+		req := httptest.NewRequest("POST", "https://api.example.com/share", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		req.Host = "internal:8080"
+		req.TLS = nil
+		req.Header.Set("X-Forwarded-Host", "api.example.com")
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		res := map[string]interface{}{}
+		Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+		Expect(res["url"]).To(HavePrefix("https://api.example.com/files/report.pdf?bewit="))
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", res["url"].(string), nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("rejects invalid requests", func() {
+		code, _ := share("http://other.example.com/files/report.pdf")
+		Expect(code).To(Equal(http.StatusBadRequest))
+		code, _ = share("")
+		Expect(code).To(Equal(http.StatusBadRequest))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "http://example.com/share", nil))
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+})
+
+var _ = Describe("StripBewit", func() {
+
+	var hm *Middleware
+	var router *gin.Engine
+	var rawQuery, requestURI, bewit string
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = store.Middleware()
+		router = gin.New()
+		router.GET("/files/:name", hm.Filter, func(c *gin.Context) {
+			rawQuery = c.Request.URL.RawQuery
+			requestURI = c.Request.RequestURI
+			bewit = c.Query("bewit")
+			c.String(200, "ok")
+		})
+	})
+
+	request := func() {
+		u, err := hawktest.NewBewitURL("http://example.com/files/report.pdf?b=1&a=%20", "my-id", "my-key", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+	}
+
+	It("removes the bewit before calling the handlers", func() {
+		hm.StripBewit = true
+		request()
+		Expect(rawQuery).To(Equal("b=1&a=%20"))
+		Expect(requestURI).To(Equal("http://example.com/files/report.pdf?b=1&a=%20"))
+		Expect(bewit).To(BeEmpty())
+	})
+
+	It("keeps the bewit by default", func() {
+		request()
+		Expect(rawQuery).To(ContainSubstring("&bewit="))
+		Expect(requestURI).To(ContainSubstring("&bewit="))
+		Expect(bewit).ToNot(BeEmpty())
+	})
+
+})
+
+var _ = Describe("BewitParam", func() {
+
+	var hm *Middleware
+	var router *gin.Engine
+	var rawQuery string
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = store.Middleware()
+		hm.BewitParam = "token"
+		router = gin.New()
+		router.GET("/files/:name", hm.Filter, func(c *gin.Context) {
+			rawQuery = c.Request.URL.RawQuery
+			c.String(200, "ok")
+		})
+		router.POST("/share", hm.Filter, hm.BewitHandler(time.Minute))
+	})
+
+	bewitURL := func() string {
+		u, err := hawktest.NewBewitURL("http://example.com/files/report.pdf?a=1", "my-id", "my-key", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		return u
+	}
+
+	serve := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	It("reads the bewit from the configured parameter", func() {
+		u := strings.Replace(bewitURL(), "&bewit=", "&token=", 1)
+		Expect(serve(httptest.NewRequest("GET", u, nil))).To(Equal(http.StatusOK))
+
+		hm.StripBewit = true
+		Expect(serve(httptest.NewRequest("GET", u, nil))).To(Equal(http.StatusOK))
+		Expect(rawQuery).To(Equal("a=1"))
+
+		Expect(serve(httptest.NewRequest("GET", bewitURL(), nil))).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("reads the bewit from the BewitHeader", func() {
+		hm.BewitHeader = "X-Bewit"
+		parts := strings.SplitN(bewitURL(), "&bewit=", 2)
+		req := httptest.NewRequest("GET", parts[0], nil)
+		req.Header.Set("X-Bewit", parts[1])
+		Expect(serve(req)).To(Equal(http.StatusOK))
+		Expect(rawQuery).To(Equal("a=1"))
+
+		req = httptest.NewRequest("GET", parts[0], nil)
+		req.Header.Set("X-Bewit", parts[1]+"x")
+		Expect(serve(req)).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("mints bewits with the configured parameter", func() {
+		req := httptest.NewRequest("POST", "http://example.com/share", strings.NewReader(`{"url":"/files/report.pdf"}`))
+		req.Header.Set("Content-Type", "application/json")
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var res struct{ URL string }
+		Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+		Expect(res.URL).To(ContainSubstring("?token="))
+		Expect(serve(httptest.NewRequest("GET", res.URL, nil))).To(Equal(http.StatusOK))
+	})
+
+})
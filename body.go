@@ -0,0 +1,90 @@
+package hawk
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSlowBody is set in context.Err when the request body is not read
+// within the BodyReadTimeout or below the MinBodyRate during payload
+// validation. The response status is 408.
+var ErrSlowBody = errors.New("Request body read too slowly")
+
+// bodyRateGrace is the time given to clients before MinBodyRate applies.
+const bodyRateGrace = time.Second
+
+// SlowBodyAborts returns the number of requests aborted with ErrSlowBody.
+func (hm *Middleware) SlowBodyAborts() uint64 {
+	return atomic.LoadUint64(&hm.slowBodyAborts)
+}
+
+// deadlineReader enforces a read deadline and a minimum transfer rate,
+// using the connection read deadline when available so that blocked
+// reads are interrupted.
+type deadlineReader struct {
+	hm      *Middleware
+	r       io.Reader
+	rc      *http.ResponseController
+	start   time.Time
+	n       int64
+	aborted bool
+}
+
+// bodyReader wraps body with a deadlineReader if needed.
+func (hr *Request) bodyReader(body io.Reader) io.Reader {
+	if hr.Hawk.BodyReadTimeout <= 0 && hr.Hawk.MinBodyRate <= 0 {
+		return body
+	}
+	dr := &deadlineReader{
+		hm:    hr.Hawk,
+		r:     body,
+		start: time.Now(),
+	}
+	if hr.w != nil {
+		dr.rc = http.NewResponseController(hr.w)
+	}
+	return dr
+}
+
+func (dr *deadlineReader) deadline() time.Time {
+	var res time.Time
+	if dr.hm.BodyReadTimeout > 0 {
+		res = dr.start.Add(dr.hm.BodyReadTimeout)
+	}
+	if dr.hm.MinBodyRate > 0 {
+		d := dr.start.Add(bodyRateGrace + time.Duration(dr.n*int64(time.Second)/dr.hm.MinBodyRate))
+		if res.IsZero() || d.Before(res) {
+			res = d
+		}
+	}
+	return res
+}
+
+func (dr *deadlineReader) abort() error {
+	if !dr.aborted {
+		dr.aborted = true
+		atomic.AddUint64(&dr.hm.slowBodyAborts, 1)
+	}
+	return ErrSlowBody
+}
+
+func (dr *deadlineReader) Read(p []byte) (int, error) {
+	deadline := dr.deadline()
+	if time.Now().After(deadline) {
+		return 0, dr.abort()
+	}
+	if dr.rc != nil {
+		dr.rc.SetReadDeadline(deadline)
+		defer dr.rc.SetReadDeadline(time.Time{})
+	}
+	n, err := dr.r.Read(p)
+	dr.n += int64(n)
+	if err != nil && errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, dr.abort()
+	}
+	return n, err
+}
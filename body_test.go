@@ -0,0 +1,85 @@
+package hawk_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Body deadlines", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	body := []byte("slow payload")
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.ValidatePayload = true
+		hm.BodyReadTimeout = 100 * time.Millisecond
+		router := gin.New()
+		router.POST("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	post := func(r io.Reader) (*http.Response, error) {
+		req, err := http.NewRequest("POST", ts.URL+"/private", r)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", "text/plain")
+		req.ContentLength = int64(len(body))
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "id",
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, 0)
+		auth.Hash = PayloadHash(sha256.New, "text/plain", body)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		return http.DefaultClient.Do(req)
+	}
+
+	It("accepts fast bodies", func() {
+		resp, err := post(bytes.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(hm.SlowBodyAborts()).To(BeZero())
+	})
+
+	It("aborts slow bodies", func() {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write(body[:4])
+			time.Sleep(300 * time.Millisecond)
+			pw.Write(body[4:])
+			pw.Close()
+		}()
+		resp, err := post(pr)
+		if err == nil {
+			Expect(resp.StatusCode).To(Equal(408))
+		}
+		Eventually(hm.SlowBodyAborts).Should(BeEquivalentTo(1))
+	})
+
+})
@@ -0,0 +1,24 @@
+package hawk
+
+import (
+	"time"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// now returns the time of the Middleware Now if set, the wall clock otherwise.
+func (hm *Middleware) now() time.Time {
+	if hm.Now != nil {
+		return hm.Now()
+	}
+	return time.Now()
+}
+
+// stamp sets the verification time of auth with the Middleware Now, used
+// by the timestamp skew and bewit expiry checks. The protocol package sets
+// it with its own clock otherwise.
+func (hm *Middleware) stamp(auth *hawk.Auth) {
+	if hm.Now != nil {
+		auth.ActualTimestamp = hm.Now()
+	}
+}
@@ -0,0 +1,104 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Now", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var clock *hawktest.Clock
+	var expires time.Time
+	frozen := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	creds := &hawk.Credentials{
+		ID:   "id",
+		Key:  "test-cred-key",
+		Hash: sha256.New,
+	}
+
+	BeforeEach(func() {
+		clock = hawktest.NewClock(frozen)
+		expires = time.Time{}
+		hm = NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{Key: "test-cred-key", ExpiresAt: expires}, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		hm.Now = clock.Now
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	// send signs at the frozen time
+	send := func() *http.Response {
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		auth := hawk.NewRequestAuth(req, creds, frozen.Sub(time.Now()))
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	request := func() int {
+		return send().StatusCode
+	}
+
+	It("checks the timestamp skew", func() {
+		Expect(request()).To(Equal(200))
+		clock.Advance(2 * time.Minute)
+		Expect(request()).To(Equal(401))
+		hm.Now = nil
+		Expect(request()).To(Equal(401))
+	})
+
+	It("sends the server time on timestamp skews", func() {
+		clock.Advance(2 * time.Minute)
+		resp := send()
+		Expect(resp.StatusCode).To(Equal(401))
+		auth := &hawk.Auth{Credentials: *creds}
+		Expect(resp.Header.Get("WWW-Authenticate")).To(Equal(auth.StaleTimestampHeaderAt(frozen.Add(2 * time.Minute))))
+	})
+
+	It("checks the bewit expiry", func() {
+		auth, err := hawk.NewURLAuth(ts.URL+"/private", creds, frozen.Add(time.Minute).Sub(time.Now()))
+		Expect(err).ToNot(HaveOccurred())
+		url := ts.URL + "/private?bewit=" + auth.Bewit()
+
+		resp, err := http.Get(url)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+
+		clock.Advance(2 * time.Minute)
+		resp, err = http.Get(url)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(401))
+	})
+
+	It("checks the credentials expiry", func() {
+		expires = frozen.Add(time.Second)
+		Expect(request()).To(Equal(200))
+		clock.Advance(time.Second * 2)
+		Expect(request()).To(Equal(401))
+	})
+
+})
@@ -0,0 +1,264 @@
+// Command hawk generates credentials, signs requests, mints bewits and
+// verifies captured headers, to debug interoperability with other clients:
+//
+//	hawk gen [-id-len 12] [-key-len 24] [-charset alphanumeric|urlsafe|hex]
+//	hawk sign -id ID -key KEY [-method GET] [-payload FILE -content-type TYPE] [-ext EXT] URL
+//	hawk bewit -id ID -key KEY [-ttl 1h] [-ext EXT] URL
+//	hawk verify -key KEY -header HEADER [-method GET] [-payload FILE -content-type TYPE] [-skew 0] URL
+//
+// The algorithm is set with -alg (sha256 by default). A payload of "-" is
+// read from stdin. verify checks a bewit URL if -header is empty and
+// prints the normalized string and the expected MAC on failure.
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+var errUsage = errors.New("usage: hawk gen|sign|bewit|verify [flags] [url]")
+
+var charsets = map[string]hawk.Charset{
+	"alphanumeric": hawk.CharsetAlphanumeric,
+	"urlsafe":      hawk.CharsetURLSafe,
+	"hex":          hawk.CharsetHex,
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command of args.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "gen":
+		return gen(args[1:], stdout)
+	case "sign":
+		return sign(args[1:], stdin, stdout)
+	case "bewit":
+		return bewit(args[1:], stdout)
+	case "verify":
+		return verify(args[1:], stdin, stdout)
+	}
+	return errUsage
+}
+
+// request are the flags common to the commands handling requests.
+type request struct {
+	fs          *flag.FlagSet
+	id          *string
+	key         *string
+	alg         *string
+	method      *string
+	payload     *string
+	contentType *string
+	ext         *string
+}
+
+func newRequest(name string) *request {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	return &request{
+		fs:          fs,
+		id:          fs.String("id", "", "credentials id"),
+		key:         fs.String("key", "", "credentials key"),
+		alg:         fs.String("alg", hawk.SHA256, "MAC algorithm (sha1, sha256 or sha512)"),
+		method:      fs.String("method", "GET", "request method"),
+		payload:     fs.String("payload", "", "payload file, - for stdin"),
+		contentType: fs.String("content-type", "", "payload content type"),
+		ext:         fs.String("ext", "", "ext attribute"),
+	}
+}
+
+// parse parses args and returns the URL argument.
+func (r *request) parse(args []string) (string, error) {
+	if err := r.fs.Parse(args); err != nil {
+		return "", err
+	} else if r.fs.NArg() != 1 {
+		return "", errors.New("a single URL argument is required")
+	} else if *r.key == "" {
+		return "", errors.New("-key is required")
+	}
+	return r.fs.Arg(0), nil
+}
+
+func (r *request) credentials() (*hawkgo.Credentials, error) {
+	h, err := hawk.HashFunc(*r.alg)
+	if err != nil {
+		return nil, err
+	}
+	return &hawkgo.Credentials{ID: *r.id, Key: *r.key, Hash: h}, nil
+}
+
+// readPayload returns the payload or nil if not set.
+func (r *request) readPayload(stdin io.Reader) ([]byte, error) {
+	switch *r.payload {
+	case "":
+		return nil, nil
+	case "-":
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(*r.payload)
+}
+
+// payloadHash returns the payload hash of auth.
+func (r *request) payloadHash(auth *hawkgo.Auth, payload []byte) hash.Hash {
+	h := auth.PayloadHash(*r.contentType)
+	h.Write(payload)
+	return h
+}
+
+func gen(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("gen", flag.ContinueOnError)
+	idLen := fs.Int("id-len", 12, "id length")
+	keyLen := fs.Int("key-len", 24, "key length")
+	name := fs.String("charset", "alphanumeric", "charset (alphanumeric, urlsafe or hex)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	charset, exists := charsets[*name]
+	if !exists {
+		return hawk.ErrInvalidCharset
+	}
+	id, key, err := hawk.GenerateCredentials(*idLen, *keyLen, charset)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "id: %s\nkey: %s\n", id, string(key))
+	return nil
+}
+
+func sign(args []string, stdin io.Reader, stdout io.Writer) error {
+	r := newRequest("sign")
+	url, err := r.parse(args)
+	if err != nil {
+		return err
+	}
+	creds, err := r.credentials()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(*r.method, url, nil)
+	if err != nil {
+		return err
+	}
+	payload, err := r.readPayload(stdin)
+	if err != nil {
+		return err
+	}
+
+	auth := hawkgo.NewRequestAuth(req, creds, 0)
+	auth.Ext = *r.ext
+	if payload != nil {
+		auth.SetHash(r.payloadHash(auth, payload))
+	}
+	fmt.Fprintln(stdout, auth.RequestHeader())
+	return nil
+}
+
+func bewit(args []string, stdout io.Writer) error {
+	r := newRequest("bewit")
+	ttl := r.fs.Duration("ttl", time.Hour, "bewit validity")
+	url, err := r.parse(args)
+	if err != nil {
+		return err
+	}
+	creds, err := r.credentials()
+	if err != nil {
+		return err
+	}
+	auth, err := hawkgo.NewURLAuth(url, creds, *ttl)
+	if err != nil {
+		return err
+	}
+	auth.Ext = *r.ext
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	fmt.Fprintln(stdout, url+sep+"bewit="+auth.Bewit())
+	return nil
+}
+
+func verify(args []string, stdin io.Reader, stdout io.Writer) error {
+	r := newRequest("verify")
+	header := r.fs.String("header", "", `"Authorization" header, the URL bewit if empty`)
+	skew := r.fs.Duration("skew", 0, "maximum timestamp skew, 0 ignores the timestamp and bewit expiry")
+	url, err := r.parse(args)
+	if err != nil {
+		return err
+	}
+	creds, err := r.credentials()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(*r.method, url, nil)
+	if err != nil {
+		return err
+	}
+	if *header != "" {
+		req.Header.Set("Authorization", *header)
+	}
+	payload, err := r.readPayload(stdin)
+	if err != nil {
+		return err
+	}
+
+	auth, err := hawkgo.NewAuthFromRequest(req, func(c *hawkgo.Credentials) error {
+		c.Key = creds.Key
+		c.Hash = creds.Hash
+		return nil
+	}, nil)
+	if err != nil {
+		return err
+	}
+	// the skew is checked here, the protocol only checks its own maximum
+	d := auth.ActualTimestamp.Sub(auth.Timestamp)
+	if !auth.IsBewit && *skew != 0 && (d > *skew || d < -*skew) {
+		return fmt.Errorf("timestamp skew of %s", d)
+	} else if !auth.IsBewit || *skew == 0 {
+		auth.ActualTimestamp = auth.Timestamp
+	}
+
+	t := hawkgo.AuthHeader
+	if auth.IsBewit {
+		t = hawkgo.AuthBewit
+	}
+	normalized := auth.NormalizedString(t)
+	if err := auth.Valid(); err != nil {
+		mac := hmac.New(creds.Hash, []byte(creds.Key))
+		mac.Write([]byte(normalized))
+		fmt.Fprintf(stdout, "normalized string:\n%s\nexpected mac: %s\nreceived mac: %s\n",
+			normalized,
+			base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+			base64.StdEncoding.EncodeToString(auth.MAC))
+		return err
+	}
+	if payload != nil {
+		if len(auth.Hash) == 0 {
+			return hawk.ErrMissingPayloadHash
+		} else if !auth.ValidHash(r.payloadHash(auth, payload)) {
+			return hawk.ErrInvalidPayloadHash
+		}
+	}
+	fmt.Fprintf(stdout, "valid: id=%s ts=%d\n", auth.Credentials.ID, auth.Timestamp.Unix())
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/hyperboloide/hawk"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("hawk", func() {
+
+	url := "http://example.com:8080/resource?a=1"
+
+	cmd := func(stdin string, args ...string) (string, error) {
+		var out bytes.Buffer
+		err := run(args, strings.NewReader(stdin), &out)
+		return out.String(), err
+	}
+
+	It("requires a command", func() {
+		_, err := cmd("")
+		Expect(err).To(Equal(errUsage))
+		_, err = cmd("", "unknown")
+		Expect(err).To(Equal(errUsage))
+	})
+
+	It("generates credentials", func() {
+		out, err := cmd("", "gen", "-id-len", "16", "-key-len", "32", "-charset", "hex")
+		Expect(err).ToNot(HaveOccurred())
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(MatchRegexp(`^id: [0-9a-f]{16}$`))
+		Expect(lines[1]).To(MatchRegexp(`^key: [0-9a-f]{32}$`))
+
+		_, err = cmd("", "gen", "-key-len", "8")
+		Expect(err).To(Equal(hawk.ErrWeakCredentials))
+		_, err = cmd("", "gen", "-charset", "emoji")
+		Expect(err).To(Equal(hawk.ErrInvalidCharset))
+	})
+
+	It("signs and verifies requests", func() {
+		header, err := cmd(`{"a":1}`, "sign", "-id", "my-id", "-key", "my-key", "-alg", "sha512",
+			"-method", "POST", "-payload", "-", "-content-type", "application/json", "-ext", "x", url)
+		Expect(err).ToNot(HaveOccurred())
+		header = strings.TrimSpace(header)
+		Expect(header).To(HavePrefix(`Hawk id="my-id"`))
+		Expect(header).To(ContainSubstring(`hash="`))
+
+		verify := func(stdin string, args ...string) (string, error) {
+			return cmd(stdin, append(append([]string{"verify", "-header", header}, args...), url)...)
+		}
+		out, err := verify(`{"a":1}`, "-key", "my-key", "-alg", "sha512",
+			"-method", "POST", "-payload", "-", "-content-type", "application/json", "-skew", "1m")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(HavePrefix("valid: id=my-id"))
+
+		_, err = verify(`{"a":2}`, "-key", "my-key", "-alg", "sha512",
+			"-method", "POST", "-payload", "-", "-content-type", "application/json")
+		Expect(err).To(Equal(hawk.ErrInvalidPayloadHash))
+
+		out, err = verify("", "-key", "wrong-key", "-alg", "sha512", "-method", "POST")
+		Expect(err).To(HaveOccurred())
+		Expect(out).To(ContainSubstring("hawk.1.header\n"))
+		Expect(out).To(ContainSubstring("\nPOST\n/resource?a=1\nexample.com\n8080\n"))
+		Expect(out).To(ContainSubstring("expected mac: "))
+	})
+
+	It("mints and verifies bewits", func() {
+		out, err := cmd("", "bewit", "-id", "my-id", "-key", "my-key", "-ttl", "1m", url)
+		Expect(err).ToNot(HaveOccurred())
+		bewitURL := strings.TrimSpace(out)
+		Expect(bewitURL).To(HavePrefix(url + "&bewit="))
+
+		out, err = cmd("", "verify", "-key", "my-key", "-skew", "1m", bewitURL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(HavePrefix("valid: id=my-id"))
+
+		_, err = cmd("", "verify", "-key", "other-key", bewitURL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("validates arguments", func() {
+		_, err := cmd("", "sign", "-id", "my-id", url)
+		Expect(err).To(HaveOccurred())
+		_, err = cmd("", "sign", "-id", "my-id", "-key", "my-key")
+		Expect(err).To(HaveOccurred())
+		_, err = cmd("", "sign", "-id", "my-id", "-key", "my-key", "-alg", "md5", url)
+		Expect(err).To(Equal(hawk.ErrUnknownAlgorithm))
+	})
+
+})
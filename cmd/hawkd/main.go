@@ -0,0 +1,289 @@
+// Command hawkd is a standalone Hawk authentication server, to use as a
+// sidecar by services in any language:
+//
+//	hawkd -credentials FILE [-addr :8080] [-nonce memory|etcd -etcd URLS] [-nonce-ttl 2m] [-max-skew 0] [-bewit-ttl 1h]
+//	hawkd -db-driver sqlite -db-dsn hawk.db [-admin] ...
+//	hawkd -db-driver sqlite -db-dsn hawk.db -create-admin
+//
+// The credentials are read from a YAML or JSON file (see the
+// credstore/file package) or from a database (see the credstore/sql
+// package), which also enables the credentials admin API. -create-admin
+// creates credentials with the admin scope, prints them and exits.
+//
+// The endpoints are:
+//
+//	POST /verify            verifies a request described in JSON (see hawk.QueueMessage)
+//	ANY  /forward-auth      verifies the requests forwarded by Traefik or NGINX auth_request
+//	POST /bewit             mints a bewit for the authenticated caller (see hawk.BewitHandler)
+//	     /admin/credentials manages the credentials (-admin only, see hawk.AdminRoutes)
+//	GET  /healthz           checks the stores
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/credstore/file"
+	hawksql "github.com/hyperboloide/hawk/credstore/sql"
+	"github.com/hyperboloide/hawk/noncestore/etcd"
+	"github.com/hyperboloide/hawk/noncestore/memory"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	_ "modernc.org/sqlite"
+)
+
+// config is the command line configuration.
+type config struct {
+	addr        string
+	credentials string
+	dbDriver    string
+	dbDSN       string
+	dollar      bool
+	nonce       string
+	etcd        string
+	nonceTTL    time.Duration
+	maxSkew     time.Duration
+	bewitTTL    time.Duration
+	admin       bool
+	createAdmin bool
+}
+
+func parseConfig(args []string) (*config, error) {
+	cfg := &config{}
+	fs := flag.NewFlagSet("hawkd", flag.ContinueOnError)
+	fs.StringVar(&cfg.addr, "addr", ":8080", "listen address")
+	fs.StringVar(&cfg.credentials, "credentials", "", "credentials file")
+	fs.StringVar(&cfg.dbDriver, "db-driver", "", "credentials database driver (sqlite or a driver linked in)")
+	fs.StringVar(&cfg.dbDSN, "db-dsn", "", "credentials database data source name")
+	fs.BoolVar(&cfg.dollar, "db-dollar", false, "use $1 placeholders (PostgreSQL)")
+	fs.StringVar(&cfg.nonce, "nonce", "memory", "nonce backend (memory or etcd)")
+	fs.StringVar(&cfg.etcd, "etcd", "", "comma separated etcd endpoints")
+	fs.DurationVar(&cfg.nonceTTL, "nonce-ttl", 2*time.Minute, "minimum time nonces are kept")
+	fs.DurationVar(&cfg.maxSkew, "max-skew", 0, "timestamp skew, the protocol default if 0")
+	fs.DurationVar(&cfg.bewitTTL, "bewit-ttl", time.Hour, "validity of the minted bewits")
+	fs.BoolVar(&cfg.admin, "admin", false, "enable the credentials admin API (database only)")
+	fs.BoolVar(&cfg.createAdmin, "create-admin", false, "create admin credentials and exit (database only)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case (cfg.credentials == "") == (cfg.dbDriver == ""):
+		return nil, errors.New("either -credentials or -db-driver is required")
+	case (cfg.admin || cfg.createAdmin) && cfg.dbDriver == "":
+		return nil, errors.New("-admin and -create-admin require -db-driver")
+	case cfg.nonce != "memory" && cfg.nonce != "etcd":
+		return nil, fmt.Errorf("unknown nonce backend %q", cfg.nonce)
+	case cfg.nonce == "etcd" && cfg.etcd == "":
+		return nil, errors.New("-nonce etcd requires -etcd")
+	}
+	return cfg, nil
+}
+
+// server is the hawkd handler and its stores.
+type server struct {
+	hm      *hawk.Middleware
+	db      *hawksql.Store
+	handler http.Handler
+	closers []func() error
+}
+
+// verifyResponse is the body of a verified request.
+type verifyResponse struct {
+	ID                  string      `json:"id"`
+	User                interface{} `json:"user,omitempty"`
+	Scopes              []string    `json:"scopes,omitempty"`
+	App                 string      `json:"app,omitempty"`
+	Delegate            string      `json:"dlg,omitempty"`
+	ServerAuthorization string      `json:"server_authorization"`
+}
+
+func newServer(cfg *config) (*server, error) {
+	s := &server{}
+	if err := s.open(cfg); err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.hm.MaxSkew = cfg.maxSkew
+	s.hm.ValidatePayload = true
+	s.hm.ErrorFormat = hawk.ErrorJSON
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST("/verify", s.verify)
+	router.Any("/forward-auth", s.hm.ForwardAuthHandler())
+	router.POST("/bewit", s.hm.Filter, s.hm.BewitHandler(cfg.bewitTTL))
+	if cfg.admin {
+		s.hm.AdminRoutes(router.Group("/admin"), s.db)
+	}
+	router.GET("/healthz", func(c *gin.Context) {
+		if err := s.hm.HealthCheck(c.Request.Context()); err != nil {
+			c.String(http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	s.handler = router
+	return s, nil
+}
+
+// open opens the credentials and nonce stores.
+func (s *server) open(cfg *config) error {
+	var getCredentials hawk.GetCredentialFunc
+	var pingers []hawk.Pinger
+	if cfg.dbDriver != "" {
+		db, err := sql.Open(cfg.dbDriver, cfg.dbDSN)
+		if err != nil {
+			return err
+		}
+		s.closers = append(s.closers, db.Close)
+		dialect := hawksql.Question
+		if cfg.dollar {
+			dialect = hawksql.Dollar
+		}
+		s.db = hawksql.NewStore(db, dialect)
+		if err := s.db.Migrate(context.Background()); err != nil {
+			return err
+		}
+		getCredentials = s.db.GetCredentials
+		pingers = append(pingers, s.db)
+	} else {
+		store, err := file.NewStore(cfg.credentials)
+		if err != nil {
+			return err
+		}
+		store.OnError = func(err error) {
+			fmt.Fprintln(os.Stderr, "reloading credentials:", err)
+		}
+		if err := store.Watch(); err != nil {
+			return err
+		}
+		s.closers = append(s.closers, store.Close)
+		getCredentials = store.GetCredentials
+	}
+
+	var setNonce hawk.SetNonceFunc
+	if cfg.nonce == "etcd" {
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints:   strings.Split(cfg.etcd, ","),
+			DialTimeout: 5 * time.Second,
+		})
+		if err != nil {
+			return err
+		}
+		s.closers = append(s.closers, client.Close)
+		store := etcd.NewStore(client, "/hawkd/nonces/")
+		store.TTL = cfg.nonceTTL
+		store.Timeout = time.Second
+		setNonce = store.SetNonce
+		pingers = append(pingers, store)
+	} else {
+		setNonce = memory.NewStore(cfg.nonceTTL).SetNonce
+	}
+
+	s.hm = hawk.NewMiddleware(getCredentials, setNonce)
+	s.hm.Pingers = pingers
+	return nil
+}
+
+// verify verifies the request of a hawk.QueueMessage JSON body.
+func (s *server) verify(c *gin.Context) {
+	m := &hawk.QueueMessage{}
+	if err := c.ShouldBindJSON(m); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid_body", "message": err.Error()})
+		return
+	}
+	r, err := m.Request()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid_body", "message": err.Error()})
+		return
+	}
+	res, err := s.hm.Verify(r.WithContext(c.Request.Context()))
+	if err != nil {
+		s.hm.Abortequest(c, err, nil)
+		return
+	}
+	c.JSON(http.StatusOK, verifyResponse{
+		ID:                  res.ID,
+		User:                res.User,
+		Scopes:              res.Scopes,
+		App:                 res.App,
+		Delegate:            res.Delegate,
+		ServerAuthorization: res.ServerAuthorization,
+	})
+}
+
+// Close closes the stores.
+func (s *server) Close() error {
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		errs = append(errs, s.closers[i]())
+	}
+	return errors.Join(errs...)
+}
+
+// createAdmin creates admin credentials and prints them.
+func (s *server) createAdmin(ctx context.Context, stdout io.Writer) error {
+	c := hawk.NewCredential(hawk.CredentialScopes(hawk.AdminScope))
+	if err := s.db.CreateCredential(ctx, c); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(stdout, "id:  %s\nkey: %s\n", c.ID, c.Key)
+	return err
+}
+
+func main() {
+	gin.SetMode(gin.ReleaseMode)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run serves until ctx is done.
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	cfg, err := parseConfig(args)
+	if err != nil {
+		return err
+	}
+	s, err := newServer(cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if cfg.createAdmin {
+		return s.createAdmin(ctx, stdout)
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.addr,
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	fmt.Fprintln(stdout, "hawkd listening on", cfg.addr)
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
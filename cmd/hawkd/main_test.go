@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("hawkd", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		gin.SetMode(gin.ReleaseMode)
+		var err error
+		dir, err = ioutil.TempDir("", "hawkd")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	serve := func(s *server, req *http.Request) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		s.handler.ServeHTTP(w, req)
+		res := map[string]interface{}{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	It("parses the configuration", func() {
+		_, err := parseConfig(nil)
+		Expect(err).To(HaveOccurred())
+		_, err = parseConfig([]string{"-credentials", "f", "-admin"})
+		Expect(err).To(HaveOccurred())
+		_, err = parseConfig([]string{"-credentials", "f", "-nonce", "etcd"})
+		Expect(err).To(HaveOccurred())
+		cfg, err := parseConfig([]string{"-credentials", "f", "-addr", ":9000"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.addr).To(Equal(":9000"))
+		Expect(cfg.nonce).To(Equal("memory"))
+	})
+
+	It("verifies requests with a credentials file", func() {
+		path := filepath.Join(dir, "credentials.yml")
+		Expect(ioutil.WriteFile(path, []byte("my-id:\n  key: my-key\n  scopes: [read]\n"), 0600)).To(Succeed())
+		cfg, err := parseConfig([]string{"-credentials", path})
+		Expect(err).ToNot(HaveOccurred())
+		s, err := newServer(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		creds := &hawkgo.Credentials{ID: "my-id", Key: "my-key", Hash: sha256.New}
+		m, err := hawk.NewQueueMessage(creds, "POST", "https://api.example.com/orders", "application/json", []byte(`{"a":1}`))
+		Expect(err).ToNot(HaveOccurred())
+		body, err := m.Marshal()
+		Expect(err).ToNot(HaveOccurred())
+
+		code, res := serve(s, httptest.NewRequest("POST", "/verify", bytes.NewReader(body)))
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(res["id"]).To(Equal("my-id"))
+		Expect(res["scopes"]).To(Equal([]interface{}{"read"}))
+		Expect(res["server_authorization"]).ToNot(BeEmpty())
+
+		code, res = serve(s, httptest.NewRequest("POST", "/verify", bytes.NewReader(body)))
+		Expect(code).To(Equal(http.StatusUnauthorized))
+		Expect(res["error"]).To(Equal("replay"))
+
+		code, _ = serve(s, httptest.NewRequest("POST", "/verify", strings.NewReader("{")))
+		Expect(code).To(Equal(http.StatusBadRequest))
+
+		code, _ = serve(s, httptest.NewRequest("GET", "/healthz", nil))
+		Expect(code).To(Equal(http.StatusOK))
+	})
+
+	It("manages credentials in a database", func() {
+		args := []string{"-db-driver", "sqlite", "-db-dsn", filepath.Join(dir, "hawk.db"), "-admin"}
+		var out bytes.Buffer
+		Expect(run(context.Background(), append(args, "-create-admin"), &out)).To(Succeed())
+		var id, key string
+		for _, line := range strings.Split(out.String(), "\n") {
+			if v := strings.TrimPrefix(line, "id:  "); v != line {
+				id = v
+			} else if v := strings.TrimPrefix(line, "key: "); v != line {
+				key = v
+			}
+		}
+		Expect(id).ToNot(BeEmpty())
+
+		cfg, err := parseConfig(args)
+		Expect(err).ToNot(HaveOccurred())
+		s, err := newServer(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		req := httptest.NewRequest("POST", "http://example.com/admin/credentials", strings.NewReader(`{"scopes":["read"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		_, err = hawktest.SignRequest(req, id, key)
+		Expect(err).ToNot(HaveOccurred())
+		code, res := serve(s, req)
+		Expect(code).To(Equal(http.StatusCreated))
+
+		req = httptest.NewRequest("POST", "http://example.com/bewit", strings.NewReader(`{"url":"/files/a"}`))
+		req.Header.Set("Content-Type", "application/json")
+		_, err = hawktest.SignRequest(req, res["id"].(string), res["key"].(string))
+		Expect(err).ToNot(HaveOccurred())
+		code, res = serve(s, req)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(res["url"]).To(ContainSubstring("bewit="))
+	})
+
+})
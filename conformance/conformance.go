@@ -0,0 +1,204 @@
+// Package conformance is a behavioral test suite for adapters of the hawk
+// Middleware to other frameworks. Run it from an adapter test to check
+// that it authenticates exactly like the gin Filter:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func(hm *hawk.Middleware) func(http.Handler) http.Handler {
+//			return myadapter.New(hm)
+//		})
+//	}
+package conformance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+// Adapter returns a net/http middleware authenticating with hm.
+type Adapter func(hm *hawk.Middleware) func(http.Handler) http.Handler
+
+// Credentials used by the suite.
+const (
+	ID       = "conformance-id"
+	Key      = "conformance-key"
+	ErrorID  = "conformance-error-id"
+	Response = "conformance ok"
+)
+
+var errProvider = errors.New("conformance provider error")
+
+// NewMiddleware returns the Middleware the suite passes to the adapter:
+// credentials ID/Key, an in-memory nonce store and payload validation.
+func NewMiddleware() *hawk.Middleware {
+	nonces := map[string]bool{}
+	var mu sync.Mutex
+
+	hm := hawk.NewMiddleware(func(id string) (*hawk.Credentials, error) {
+		switch id {
+		case ID:
+			return &hawk.Credentials{Key: Key, User: ID}, nil
+		case ErrorID:
+			return nil, errProvider
+		}
+		return nil, nil
+	}, func(id string, nonce string, t time.Time) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if nonces[nonce] {
+			return false, nil
+		}
+		nonces[nonce] = true
+		return true, nil
+	})
+	hm.ValidatePayload = true
+	return hm
+}
+
+type testCase struct {
+	name   string
+	status int
+	req    func(url string) (*http.Request, *hawkgo.Auth)
+}
+
+func credentials(id, key string) *hawkgo.Credentials {
+	return &hawkgo.Credentials{
+		ID:   id,
+		Key:  key,
+		Hash: sha256.New,
+	}
+}
+
+func header(method, url, id, key string, offset time.Duration, body []byte, signed []byte) (*http.Request, *hawkgo.Auth) {
+	req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+	auth := hawkgo.NewRequestAuth(req, credentials(id, key), offset)
+	if signed != nil {
+		req.Header.Set("Content-Type", "text/plain")
+		auth.Hash = hawk.PayloadHash(sha256.New, "text/plain", signed)
+	}
+	req.Header.Set("Authorization", auth.RequestHeader())
+	return req, auth
+}
+
+func bewit(method, url, key string, ttl time.Duration) (*http.Request, *hawkgo.Auth) {
+	req, _ := http.NewRequest("GET", url, nil)
+	auth := hawkgo.NewRequestAuth(req, credentials(ID, key), ttl)
+	req, _ = http.NewRequest(method, url+"?bewit="+auth.Bewit(), nil)
+	return req, auth
+}
+
+var cases = []testCase{
+	{"valid header", 200, func(url string) (*http.Request, *hawkgo.Auth) {
+		return header("GET", url, ID, Key, 0, nil, nil)
+	}},
+	{"invalid header mac", 401, func(url string) (*http.Request, *hawkgo.Auth) {
+		return header("GET", url, ID, "invalid", 0, nil, nil)
+	}},
+	{"unknown credentials", 401, func(url string) (*http.Request, *hawkgo.Auth) {
+		return header("GET", url, "unknown", Key, 0, nil, nil)
+	}},
+	{"no authentication", 401, func(url string) (*http.Request, *hawkgo.Auth) {
+		req, _ := http.NewRequest("GET", url, nil)
+		return req, nil
+	}},
+	{"timestamp skew", 401, func(url string) (*http.Request, *hawkgo.Auth) {
+		return header("GET", url, ID, Key, -2*time.Hour, nil, nil)
+	}},
+	{"valid bewit", 200, func(url string) (*http.Request, *hawkgo.Auth) {
+		return bewit("GET", url, Key, time.Hour)
+	}},
+	{"expired bewit", 401, func(url string) (*http.Request, *hawkgo.Auth) {
+		return bewit("GET", url, Key, -time.Hour)
+	}},
+	{"invalid bewit mac", 401, func(url string) (*http.Request, *hawkgo.Auth) {
+		return bewit("GET", url, "invalid", time.Hour)
+	}},
+	{"bewit on POST", 401, func(url string) (*http.Request, *hawkgo.Auth) {
+		return bewit("POST", url, Key, time.Hour)
+	}},
+	{"valid payload", 200, func(url string) (*http.Request, *hawkgo.Auth) {
+		return header("POST", url, ID, Key, 0, []byte("payload"), []byte("payload"))
+	}},
+	{"tampered payload", 401, func(url string) (*http.Request, *hawkgo.Auth) {
+		return header("POST", url, ID, Key, 0, []byte("tampered"), []byte("payload"))
+	}},
+	{"provider error", 503, func(url string) (*http.Request, *hawkgo.Auth) {
+		return header("GET", url, ErrorID, Key, 0, nil, nil)
+	}},
+}
+
+// Run runs the conformance suite against the adapter.
+func Run(t *testing.T, adapter Adapter) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == "POST" && len(body) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(Response))
+	})
+	ts := httptest.NewServer(adapter(NewMiddleware())(next))
+	defer ts.Close()
+	url := ts.URL + "/conformance"
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req, auth := tc.req(url)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, resp.StatusCode)
+			}
+			if tc.status == 200 {
+				checkSuccess(t, resp, auth)
+			}
+		})
+	}
+
+	t.Run("replayed nonce", func(t *testing.T) {
+		req, _ := header("GET", url, ID, Key, 0, nil, nil)
+		again, _ := http.NewRequest("GET", url, nil)
+		again.Header = req.Header
+		for i, status := range []int{200, 401} {
+			r := req
+			if i > 0 {
+				r = again
+			}
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != status {
+				t.Fatalf("expected status %d, got %d", status, resp.StatusCode)
+			}
+		}
+	})
+}
+
+func checkSuccess(t *testing.T, resp *http.Response, auth *hawkgo.Auth) {
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != Response {
+		t.Fatalf("expected the next handler to be called, got %q", body)
+	}
+	header := resp.Header.Get("Server-Authorization")
+	if header == "" {
+		t.Fatal("missing Server-Authorization header")
+	}
+	if err := auth.ValidResponse(header); err != nil {
+		t.Fatalf("invalid Server-Authorization header: %s", err)
+	}
+}
@@ -0,0 +1,45 @@
+package conformance_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/conformance"
+)
+
+// ginAdapter runs the gin Filter in front of next.
+func ginAdapter(hm *hawk.Middleware) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		router := gin.New()
+		router.Any("/*path", hm.Filter, gin.WrapH(next))
+		return router
+	}
+}
+
+func TestGinFilter(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	conformance.Run(t, ginAdapter)
+}
+
+// negroniAdapter runs the Middleware like negroni.
+func negroniAdapter(hm *hawk.Middleware) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hm.ServeHTTP(w, r, next.ServeHTTP)
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	conformance.Run(t, func(hm *hawk.Middleware) func(http.Handler) http.Handler {
+		return hm.Handler
+	})
+}
+
+func TestNegroni(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	conformance.Run(t, negroniAdapter)
+}
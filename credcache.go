@@ -0,0 +1,183 @@
+package hawk
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultCredentialCacheSize is the default CachedCredentialProvider
+// MaxEntries.
+const DefaultCredentialCacheSize = 10000
+
+type credentialEntry struct {
+	id      string
+	creds   *Credentials
+	expires time.Time
+}
+
+// CredentialLister lists the credentials of a CredentialProvider, the
+// CredentialStore of the AdminRoutes implements it.
+type CredentialLister interface {
+	List(ctx context.Context) ([]CredentialInfo, error)
+}
+
+// CachedCredentialProvider caches the credentials of a
+// CredentialProvider. Revocations and rotations are propagated within
+// TTL, or immediately after Invalidate is called.
+// NotFoundTTL if set caches the unknown ids (nil credentials or
+// ErrNotFound) for this duration, so probing random ids doesn't reach
+// the Provider for every request. Keep it short, new credentials are
+// rejected until it expires or Invalidate is called.
+// MaxEntries if set bounds the cache, the least recently used entries
+// are evicted once the expired ones are swept. The expired entries are
+// also swept every TTL.
+type CachedCredentialProvider struct {
+	Provider    CredentialProvider
+	TTL         time.Duration
+	NotFoundTTL time.Duration
+	MaxEntries  int
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List
+	nextSweep time.Time
+}
+
+// NewCachedCredentialProvider creates a new CachedCredentialProvider.
+func NewCachedCredentialProvider(p CredentialProvider, ttl time.Duration) *CachedCredentialProvider {
+	return &CachedCredentialProvider{
+		Provider:   p,
+		TTL:        ttl,
+		MaxEntries: DefaultCredentialCacheSize,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Get returns the cached credentials if not expired, otherwise it calls
+// the Provider. Errors are not cached, unknown ids only if NotFoundTTL
+// is set and they are returned as nil credentials.
+func (cp *CachedCredentialProvider) Get(ctx context.Context, id string) (*Credentials, error) {
+	now := time.Now()
+	cp.mu.Lock()
+	if el, exists := cp.entries[id]; exists {
+		if e := el.Value.(*credentialEntry); now.Before(e.expires) {
+			cp.lru.MoveToFront(el)
+			cp.mu.Unlock()
+			return e.creds, nil
+		}
+	}
+	cp.mu.Unlock()
+
+	creds, err := cp.Provider.Get(ctx, id)
+	if cp.NotFoundTTL > 0 && (errors.Is(err, ErrNotFound) || (err == nil && creds == nil)) {
+		cp.set(id, nil, now, now.Add(cp.NotFoundTTL))
+		return nil, nil
+	} else if err != nil || creds == nil {
+		return creds, err
+	}
+	cp.set(id, creds, now, now.Add(cp.TTL))
+	return creds, nil
+}
+
+// set caches the creds of id until expires and evicts the expired and
+// least recently used entries.
+func (cp *CachedCredentialProvider) set(id string, creds *Credentials, now, expires time.Time) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if el, exists := cp.entries[id]; exists {
+		el.Value = &credentialEntry{id, creds, expires}
+		cp.lru.MoveToFront(el)
+	} else {
+		cp.entries[id] = cp.lru.PushFront(&credentialEntry{id, creds, expires})
+	}
+
+	full := cp.MaxEntries > 0 && cp.lru.Len() > cp.MaxEntries
+	if full || now.After(cp.nextSweep) {
+		cp.sweep(now)
+	}
+	for cp.MaxEntries > 0 && cp.lru.Len() > cp.MaxEntries {
+		cp.remove(cp.lru.Back())
+	}
+}
+
+// sweep removes the expired entries, cp.mu must be held.
+func (cp *CachedCredentialProvider) sweep(now time.Time) {
+	for el := cp.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if !now.Before(el.Value.(*credentialEntry).expires) {
+			cp.remove(el)
+		}
+		el = prev
+	}
+	cp.nextSweep = now.Add(cp.TTL)
+}
+
+// remove removes the entry of el, cp.mu must be held.
+func (cp *CachedCredentialProvider) remove(el *list.Element) {
+	cp.lru.Remove(el)
+	delete(cp.entries, el.Value.(*credentialEntry).id)
+}
+
+// Preload warms the cache with the credentials of ids, at startup for
+// example, so the first requests don't all reach the Provider. The ids
+// that fail are skipped and the errors returned together.
+func (cp *CachedCredentialProvider) Preload(ids []string) error {
+	ctx := context.Background()
+	var errs []error
+	for _, id := range ids {
+		creds, err := cp.Provider.Get(ctx, id)
+		if err != nil {
+			errs = append(errs, err)
+		} else if creds != nil {
+			now := time.Now()
+			cp.set(id, creds, now, now.Add(cp.TTL))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PreloadAll preloads the credentials listed by the Provider, it must
+// implement CredentialLister. Revoked credentials are skipped.
+func (cp *CachedCredentialProvider) PreloadAll() error {
+	lister, ok := cp.Provider.(CredentialLister)
+	if !ok {
+		return errors.New("Provider is not a CredentialLister")
+	}
+	infos, err := lister.List(context.Background())
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !info.Revoked {
+			ids = append(ids, info.ID)
+		}
+	}
+	return cp.Preload(ids)
+}
+
+// Len returns the number of cached credentials, expired and unknown
+// ids included.
+func (cp *CachedCredentialProvider) Len() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return len(cp.entries)
+}
+
+// Invalidate removes the cached credentials of id.
+func (cp *CachedCredentialProvider) Invalidate(id string) {
+	cp.mu.Lock()
+	if el, exists := cp.entries[id]; exists {
+		cp.remove(el)
+	}
+	cp.mu.Unlock()
+}
+
+// Close closes the Provider.
+func (cp *CachedCredentialProvider) Close() error {
+	return cp.Provider.Close()
+}
@@ -0,0 +1,179 @@
+package hawk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// listingProvider is a provider listing its credentials.
+type listingProvider struct {
+	*provider
+	infos []CredentialInfo
+	err   error
+}
+
+func (p *listingProvider) List(ctx context.Context) ([]CredentialInfo, error) {
+	return p.infos, p.err
+}
+
+var _ = Describe("CachedCredentialProvider", func() {
+
+	var store *hawktest.Store
+	var p *provider
+	var cache *CachedCredentialProvider
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		store = hawktest.NewStore()
+		store.Add("id-1", "key-1")
+		store.Add("id-2", "key-2")
+		p = &provider{store: store}
+		cache = NewCachedCredentialProvider(p, time.Minute)
+	})
+
+	It("caches credentials until the TTL or an invalidation", func() {
+		for i := 0; i < 3; i++ {
+			creds, err := cache.Get(ctx, "id-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(creds.Key).To(Equal("key-1"))
+		}
+		Expect(p.values).To(HaveLen(1))
+
+		cache.Invalidate("id-1")
+		_, err := cache.Get(ctx, "id-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.values).To(HaveLen(2))
+
+		cache.TTL = 0
+		cache.Invalidate("id-1")
+		cache.Get(ctx, "id-1")
+		cache.Get(ctx, "id-1")
+		Expect(p.values).To(HaveLen(4))
+	})
+
+	It("does not cache unknown ids", func() {
+		creds, err := cache.Get(ctx, "unknown")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(BeNil())
+		Expect(cache.Len()).To(Equal(0))
+	})
+
+	It("caches unknown ids for NotFoundTTL", func() {
+		cache.NotFoundTTL = 50 * time.Millisecond
+		for i := 0; i < 3; i++ {
+			creds, err := cache.Get(ctx, "unknown")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(creds).To(BeNil())
+		}
+		Expect(p.values).To(HaveLen(1))
+		Expect(cache.Len()).To(Equal(1))
+
+		store.Add("unknown", "new-key")
+		creds, _ := cache.Get(ctx, "unknown")
+		Expect(creds).To(BeNil())
+		time.Sleep(60 * time.Millisecond)
+		creds, err := cache.Get(ctx, "unknown")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("new-key"))
+		Expect(p.values).To(HaveLen(2))
+	})
+
+	It("caches ErrNotFound as unknown ids", func() {
+		calls := 0
+		cache = NewCachedCredentialProvider(GetCredentialFunc(func(id string) (*Credentials, error) {
+			calls++
+			return nil, ErrNotFound
+		}), time.Minute)
+		cache.NotFoundTTL = time.Minute
+		for i := 0; i < 3; i++ {
+			creds, err := cache.Get(ctx, "unknown")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(creds).To(BeNil())
+		}
+		Expect(calls).To(Equal(1))
+		cache.Invalidate("unknown")
+		cache.Get(ctx, "unknown")
+		Expect(calls).To(Equal(2))
+	})
+
+	It("does not cache errors", func() {
+		calls := 0
+		cache = NewCachedCredentialProvider(GetCredentialFunc(func(id string) (*Credentials, error) {
+			calls++
+			return nil, errors.New("store down")
+		}), time.Minute)
+		cache.NotFoundTTL = time.Minute
+		_, err := cache.Get(ctx, "id")
+		Expect(err).To(HaveOccurred())
+		_, err = cache.Get(ctx, "id")
+		Expect(err).To(HaveOccurred())
+		Expect(calls).To(Equal(2))
+	})
+
+	It("preloads credentials", func() {
+		Expect(cache.Preload([]string{"id-1", "id-2", "unknown"})).To(Succeed())
+		Expect(cache.Len()).To(Equal(2))
+		Expect(p.values).To(HaveLen(3))
+
+		creds, err := cache.Get(ctx, "id-2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("key-2"))
+		Expect(p.values).To(HaveLen(3))
+	})
+
+	It("preloads the credentials listed by the provider", func() {
+		Expect(cache.PreloadAll()).ToNot(Succeed())
+
+		lp := &listingProvider{provider: p, infos: []CredentialInfo{
+			{ID: "id-1"},
+			{ID: "id-2", Revoked: true},
+		}}
+		cache = NewCachedCredentialProvider(lp, time.Minute)
+		Expect(cache.PreloadAll()).To(Succeed())
+		Expect(cache.Len()).To(Equal(1))
+
+		lp.err = errors.New("list failed")
+		Expect(cache.PreloadAll()).To(Equal(lp.err))
+	})
+
+	It("evicts the least recently used credentials", func() {
+		store.Add("id-3", "key-3")
+		cache.MaxEntries = 2
+		cache.Get(ctx, "id-1")
+		cache.Get(ctx, "id-2")
+		cache.Get(ctx, "id-1")
+		cache.Get(ctx, "id-3")
+		Expect(cache.Len()).To(Equal(2))
+		Expect(p.values).To(HaveLen(3))
+
+		cache.Get(ctx, "id-1")
+		Expect(p.values).To(HaveLen(3))
+		cache.Get(ctx, "id-2")
+		Expect(p.values).To(HaveLen(4))
+	})
+
+	It("sweeps the expired entries", func() {
+		cache.NotFoundTTL = time.Millisecond
+		cache.TTL = 20 * time.Millisecond
+		for i := 0; i < 100; i++ {
+			cache.Get(ctx, fmt.Sprint("unknown-", i))
+		}
+		time.Sleep(30 * time.Millisecond)
+		cache.Get(ctx, "id-1")
+		Expect(cache.Len()).To(Equal(1))
+	})
+
+	It("closes the provider", func() {
+		Expect(cache.Close()).To(Succeed())
+		Expect(p.closed).To(BeTrue())
+	})
+
+})
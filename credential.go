@@ -0,0 +1,100 @@
+package hawk
+
+import (
+	"time"
+)
+
+// Credential is a newly generated credential with its metadata, see
+// NewCredential. Credentials returns the Credentials to persist, for
+// example with a SetCredentialsFunc.
+type Credential struct {
+	ID        string
+	Key       string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Scopes    []string
+	Algorithm string
+	User      interface{}
+	Principal string
+}
+
+// CredentialOption configures a Credential, see NewCredential.
+type CredentialOption func(c *Credential)
+
+// CredentialTTL sets the expiration of the credential to ttl after its creation.
+func CredentialTTL(ttl time.Duration) CredentialOption {
+	return func(c *Credential) {
+		c.ExpiresAt = c.CreatedAt.Add(ttl)
+	}
+}
+
+// CredentialExpiresAt sets the expiration of the credential.
+func CredentialExpiresAt(t time.Time) CredentialOption {
+	return func(c *Credential) {
+		c.ExpiresAt = t
+	}
+}
+
+// CredentialScopes sets the scopes of the credential.
+func CredentialScopes(scopes ...string) CredentialOption {
+	return func(c *Credential) {
+		c.Scopes = scopes
+	}
+}
+
+// CredentialAlgorithm sets the MAC algorithm name of the credential.
+func CredentialAlgorithm(name string) CredentialOption {
+	return func(c *Credential) {
+		c.Algorithm = name
+	}
+}
+
+// CredentialUser sets the user of the credential.
+func CredentialUser(user interface{}) CredentialOption {
+	return func(c *Credential) {
+		c.User = user
+	}
+}
+
+// CredentialPrincipal sets the principal owning the credential.
+func CredentialPrincipal(principal string) CredentialOption {
+	return func(c *Credential) {
+		c.Principal = principal
+	}
+}
+
+// NewCredential generates a new credential with a random id of 12 and key
+// of 24 alphanumeric characters (see GenerateCredentials) created now and
+// configured by opts:
+//
+//	c := hawk.NewCredential(hawk.CredentialTTL(90*24*time.Hour), hawk.CredentialScopes("read"))
+//	err := setCredentials(c.ID, c.Credentials())
+func NewCredential(opts ...CredentialOption) *Credential {
+	id, key, err := GenerateCredentials(12, 24, CharsetAlphanumeric)
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	res := &Credential{
+		ID:        string(id),
+		Key:       string(key),
+		CreatedAt: time.Now(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// Credentials returns the Credentials to persist and return from
+// the GetCredentialFunc.
+func (c *Credential) Credentials() *Credentials {
+	return &Credentials{
+		Key:       c.Key,
+		User:      c.User,
+		Algorithm: c.Algorithm,
+		ExpiresAt: c.ExpiresAt,
+		Principal: c.Principal,
+		Scopes:    c.Scopes,
+	}
+}
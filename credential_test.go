@@ -0,0 +1,48 @@
+package hawk_test
+
+import (
+	"time"
+
+	. "github.com/hyperboloide/hawk"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Credential", func() {
+
+	It("generates a random credential", func() {
+		c1, c2 := NewCredential(), NewCredential()
+		Expect(c1.ID).To(HaveLen(12))
+		Expect(c1.Key).To(HaveLen(24))
+		Expect(c1.ID).ToNot(Equal(c2.ID))
+		Expect(c1.Key).ToNot(Equal(c2.Key))
+		Expect(c1.CreatedAt).To(BeTemporally("~", time.Now(), time.Second))
+		Expect(c1.ExpiresAt.IsZero()).To(BeTrue())
+		Expect(DefaultKeyPolicy.Check(c1.Key)).To(Succeed())
+	})
+
+	It("applies the options", func() {
+		c := NewCredential(
+			CredentialTTL(time.Hour),
+			CredentialScopes("read", "write"),
+			CredentialAlgorithm(SHA512),
+			CredentialUser("fred"),
+			CredentialPrincipal("org"),
+		)
+		Expect(c.ExpiresAt).To(Equal(c.CreatedAt.Add(time.Hour)))
+
+		expires := time.Now().Add(time.Minute)
+		Expect(NewCredential(CredentialExpiresAt(expires)).ExpiresAt).To(Equal(expires))
+
+		Expect(c.Credentials()).To(Equal(&Credentials{
+			Key:       c.Key,
+			User:      "fred",
+			Algorithm: SHA512,
+			ExpiresAt: c.ExpiresAt,
+			Principal: "org",
+			Scopes:    []string{"read", "write"},
+		}))
+	})
+
+})
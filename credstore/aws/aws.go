@@ -0,0 +1,182 @@
+// Package aws provides a hawk GetCredentialFunc resolving keys from AWS
+// Secrets Manager or SSM Parameter Store, with client side caching.
+// Access is controlled by IAM: the clients are usually created with the
+// default credentials chain (config.LoadDefaultConfig).
+//
+// The secret or parameter "<Prefix><id>" holds either the key itself or
+// a JSON object:
+//
+//	{"key": "...", "keys": ["..."], "algorithm": "sha256", "user": {...},
+//	 "principal": "...", "scopes": ["..."], "expires_at": "2030-01-01T00:00:00Z",
+//	 "revoked": false}
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/hyperboloide/hawk"
+)
+
+// ErrInvalidSecret is returned when a JSON secret has no key.
+var ErrInvalidSecret = errors.New("Invalid credentials secret")
+
+// SecretsManagerAPI is the Secrets Manager client method used by the Store.
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// SSMAPI is the SSM client method used by the Store.
+type SSMAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// FetchFunc returns the value of the secret name, false if it doesn't exist.
+type FetchFunc func(ctx context.Context, name string) (string, bool, error)
+
+type secret struct {
+	Key       string          `json:"key"`
+	Keys      []string        `json:"keys"`
+	Algorithm string          `json:"algorithm"`
+	User      json.RawMessage `json:"user"`
+	Principal string          `json:"principal"`
+	Scopes    []string        `json:"scopes"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Revoked   bool            `json:"revoked"`
+}
+
+// Store resolves credentials. It's a hawk.CredentialProvider without
+// cache, GetCredentials uses the Cache.
+// Fetch is the FetchFunc
+// Prefix is prepended to the credentials id to get the secret name
+// Timeout if set is the maximum duration of a fetch
+// DecodeUser if set decodes the users into the application type
+// Cache if set caches the credentials of GetCredentials, revocations and
+// rotations are propagated within its TTL or immediately after Invalidate
+type Store struct {
+	Fetch      FetchFunc
+	Prefix     string
+	Timeout    time.Duration
+	DecodeUser hawk.UserDecoder
+	Cache      *hawk.CachedCredentialProvider
+}
+
+// NewStore creates a new Store caching credentials for 5 minutes.
+func NewStore(fetch FetchFunc, prefix string) *Store {
+	s := &Store{
+		Fetch:  fetch,
+		Prefix: prefix,
+	}
+	s.Cache = hawk.NewCachedCredentialProvider(s, 5*time.Minute)
+	return s
+}
+
+// NewSecretsManagerStore creates a new Store reading Secrets Manager
+// secrets (string values, current version).
+func NewSecretsManagerStore(client SecretsManagerAPI, prefix string) *Store {
+	return NewStore(func(ctx context.Context, name string) (string, bool, error) {
+		out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: awssdk.String(name),
+		})
+		var notFound *smtypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return "", false, nil
+		} else if err != nil {
+			return "", false, err
+		}
+		return awssdk.ToString(out.SecretString), true, nil
+	}, prefix)
+}
+
+// NewSSMStore creates a new Store reading SSM parameters, SecureString
+// parameters are decrypted.
+func NewSSMStore(client SSMAPI, prefix string) *Store {
+	return NewStore(func(ctx context.Context, name string) (string, bool, error) {
+		out, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           awssdk.String(name),
+			WithDecryption: awssdk.Bool(true),
+		})
+		var notFound *ssmtypes.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return "", false, nil
+		} else if err != nil {
+			return "", false, err
+		}
+		return awssdk.ToString(out.Parameter.Value), true, nil
+	}, prefix)
+}
+
+// GetCredentials is a hawk.GetCredentialFunc.
+func (s *Store) GetCredentials(id string) (*hawk.Credentials, error) {
+	if s.Cache == nil {
+		return s.Get(context.Background(), id)
+	}
+	return s.Cache.Get(context.Background(), id)
+}
+
+// Invalidate removes the cached credentials of id.
+func (s *Store) Invalidate(id string) {
+	if s.Cache != nil {
+		s.Cache.Invalidate(id)
+	}
+}
+
+// Get fetches the credentials of id, nil if the secret doesn't exist.
+func (s *Store) Get(ctx context.Context, id string) (*hawk.Credentials, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	value, exists, err := s.Fetch(ctx, s.Prefix+id)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return s.parse(value)
+}
+
+// Close does nothing, the clients have no resources to release.
+func (s *Store) Close() error {
+	return nil
+}
+
+// parse returns the credentials of a plain key or JSON secret.
+func (s *Store) parse(value string) (*hawk.Credentials, error) {
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		if value == "" {
+			return nil, ErrInvalidSecret
+		}
+		return &hawk.Credentials{Key: value}, nil
+	}
+
+	sec := &secret{}
+	if err := json.Unmarshal([]byte(value), sec); err != nil {
+		return nil, err
+	} else if sec.Key == "" {
+		return nil, ErrInvalidSecret
+	}
+	res := &hawk.Credentials{
+		Key:       sec.Key,
+		Keys:      sec.Keys,
+		Algorithm: sec.Algorithm,
+		Principal: sec.Principal,
+		Scopes:    sec.Scopes,
+		ExpiresAt: sec.ExpiresAt,
+		Revoked:   sec.Revoked,
+	}
+	if len(sec.User) > 0 && string(sec.User) != "null" {
+		var err error
+		if res.User, err = s.DecodeUser.Decode(sec.User); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,139 @@
+package aws_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/hyperboloide/hawk"
+	. "github.com/hyperboloide/hawk/credstore/aws"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var apiErr = errors.New("api error")
+
+type secretsManager struct {
+	values map[string]string
+	calls  int
+}
+
+func (sm *secretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	sm.calls++
+	name := awssdk.ToString(params.SecretId)
+	if name == "hawk/error-id" {
+		return nil, apiErr
+	}
+	value, exists := sm.values[name]
+	if !exists {
+		return nil, &smtypes.ResourceNotFoundException{}
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: awssdk.String(value)}, nil
+}
+
+type parameterStore struct {
+	values map[string]string
+}
+
+func (ps *parameterStore) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	Expect(awssdk.ToBool(params.WithDecryption)).To(BeTrue())
+	value, exists := ps.values[awssdk.ToString(params.Name)]
+	if !exists {
+		return nil, &ssmtypes.ParameterNotFound{}
+	}
+	return &ssm.GetParameterOutput{
+		Parameter: &ssmtypes.Parameter{Value: awssdk.String(value)},
+	}, nil
+}
+
+var _ = Describe("Store", func() {
+
+	var sm *secretsManager
+	var store *Store
+
+	BeforeEach(func() {
+		sm = &secretsManager{values: map[string]string{
+			"hawk/plain-id": "plain-key",
+			"hawk/json-id": `{"key": "json-key", "keys": ["old-key"], "algorithm": "sha512",
+				"user": "fred", "principal": "org", "scopes": ["read"],
+				"expires_at": "2030-01-01T00:00:00Z"}`,
+			"hawk/invalid-id": `{"algorithm": "sha512"}`,
+		}}
+		store = NewSecretsManagerStore(sm, "hawk/")
+	})
+
+	It("resolves plain and JSON secrets", func() {
+		creds, err := store.GetCredentials("plain-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("plain-key"))
+
+		creds, err = store.GetCredentials("json-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("json-key"))
+		Expect(creds.Keys).To(Equal([]string{"old-key"}))
+		Expect(creds.Algorithm).To(Equal("sha512"))
+		Expect(creds.User).To(Equal("fred"))
+		Expect(creds.Principal).To(Equal("org"))
+		Expect(creds.Scopes).To(Equal([]string{"read"}))
+		Expect(creds.ExpiresAt).To(Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("returns nil for unknown credentials and errors", func() {
+		creds, err := store.GetCredentials("unknown-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(BeNil())
+
+		_, err = store.GetCredentials("error-id")
+		Expect(err).To(Equal(apiErr))
+
+		_, err = store.GetCredentials("invalid-id")
+		Expect(err).To(Equal(ErrInvalidSecret))
+	})
+
+	It("caches credentials", func() {
+		_, err := store.GetCredentials("plain-id")
+		Expect(err).ToNot(HaveOccurred())
+		sm.values["hawk/plain-id"] = "rotated-key"
+		creds, err := store.GetCredentials("plain-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("plain-key"))
+		Expect(sm.calls).To(Equal(1))
+
+		store.Invalidate("plain-id")
+		creds, err = store.GetCredentials("plain-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("rotated-key"))
+		Expect(sm.calls).To(Equal(2))
+	})
+
+	It("is a credentials provider without cache", func() {
+		var _ hawk.CredentialProvider = store
+		store.Cache = nil
+		_, err := store.GetCredentials("plain-id")
+		Expect(err).ToNot(HaveOccurred())
+		creds, err := store.Get(context.Background(), "plain-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("plain-key"))
+		Expect(sm.calls).To(Equal(2))
+	})
+
+	It("resolves SSM parameters", func() {
+		store = NewSSMStore(&parameterStore{values: map[string]string{
+			"/hawk/my-id": "ssm-key",
+		}}, "/hawk/")
+		creds, err := store.GetCredentials("my-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("ssm-key"))
+
+		creds, err = store.GetCredentials("unknown-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(BeNil())
+	})
+
+})
@@ -0,0 +1,89 @@
+// Package env provides a hawk GetCredentialFunc parsing credentials from
+// an environment variable, for 12-factor deployments:
+//
+//	HAWK_CREDS=id1:key1,id2:key2
+//
+// Values prefixed with "base64:" are decoded first, so keys may contain
+// characters that are awkward in shells and env files (quotes, "$"...):
+//
+//	HAWK_CREDS=base64:aWQxOmtleTEsaWQyOmtleTI=
+package env
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hyperboloide/hawk"
+)
+
+// DefaultVariable is the default environment variable name.
+const DefaultVariable = "HAWK_CREDS"
+
+var (
+	// ErrMissingVariable is returned when the variable is not set.
+	ErrMissingVariable = errors.New("Credentials environment variable not set")
+
+	// ErrInvalidFormat is returned when the value is not a list of
+	// "id:key" pairs or an id is repeated.
+	ErrInvalidFormat = errors.New("Invalid credentials format")
+
+	// ErrKeyTooShort is returned when a key is shorter than the
+	// minimum key length.
+	ErrKeyTooShort = errors.New("Credentials key too short")
+)
+
+// Store serves the parsed credentials.
+type Store struct {
+	creds map[string]*hawk.Credentials
+}
+
+// Load parses the credentials from the environment variable name,
+// DefaultVariable if empty. Keys shorter than minKeyLength are rejected.
+func Load(name string, minKeyLength int) (*Store, error) {
+	if name == "" {
+		name = DefaultVariable
+	}
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return nil, ErrMissingVariable
+	}
+	return Parse(value, minKeyLength)
+}
+
+// Parse parses credentials in the "id1:key1,id2:key2" format, optionally
+// base64 encoded with a "base64:" prefix. Keys shorter than minKeyLength
+// are rejected. Errors never contain the keys.
+func Parse(value string, minKeyLength int) (*Store, error) {
+	if strings.HasPrefix(value, "base64:") {
+		decoded, err := base64.StdEncoding.DecodeString(value[len("base64:"):])
+		if err != nil {
+			return nil, ErrInvalidFormat
+		}
+		value = string(decoded)
+	}
+
+	res := &Store{creds: map[string]*hawk.Credentials{}}
+	for i, pair := range strings.Split(strings.TrimSpace(value), ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%w: entry %d", ErrInvalidFormat, i)
+		}
+		id, key := parts[0], parts[1]
+		if _, exists := res.creds[id]; exists {
+			return nil, fmt.Errorf("%w: duplicate id %q", ErrInvalidFormat, id)
+		}
+		if len(key) < minKeyLength {
+			return nil, fmt.Errorf("%w: id %q", ErrKeyTooShort, id)
+		}
+		res.creds[id] = &hawk.Credentials{Key: key}
+	}
+	return res, nil
+}
+
+// GetCredentials is a hawk.GetCredentialFunc.
+func (s *Store) GetCredentials(id string) (*hawk.Credentials, error) {
+	return s.creds[id], nil
+}
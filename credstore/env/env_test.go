@@ -0,0 +1,69 @@
+package env_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+
+	. "github.com/hyperboloide/hawk/credstore/env"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+
+	key := func(s *Store, id string) string {
+		creds, err := s.GetCredentials(id)
+		Expect(err).ToNot(HaveOccurred())
+		if creds == nil {
+			return ""
+		}
+		return creds.Key
+	}
+
+	It("parses credentials", func() {
+		s, err := Parse("id1:key1:with:colons, id2:key2", 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key(s, "id1")).To(Equal("key1:with:colons"))
+		Expect(key(s, "id2")).To(Equal("key2"))
+		Expect(key(s, "id3")).To(BeEmpty())
+	})
+
+	It("parses base64 values", func() {
+		encoded := base64.StdEncoding.EncodeToString([]byte(`id1:k"y$1,id2:k'y$2`))
+		s, err := Parse("base64:"+encoded, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key(s, "id1")).To(Equal(`k"y$1`))
+		Expect(key(s, "id2")).To(Equal(`k'y$2`))
+
+		_, err = Parse("base64:!!", 0)
+		Expect(err).To(Equal(ErrInvalidFormat))
+	})
+
+	It("rejects invalid values", func() {
+		for _, value := range []string{"", "id1", "id1:", ":key", "id1:key1,,id2:key2", "id1:key1,id1:key2"} {
+			_, err := Parse(value, 0)
+			Expect(errors.Is(err, ErrInvalidFormat)).To(BeTrue(), value)
+		}
+	})
+
+	It("rejects short keys without leaking them", func() {
+		_, err := Parse("id1:short-secret", 16)
+		Expect(errors.Is(err, ErrKeyTooShort)).To(BeTrue())
+		Expect(err.Error()).ToNot(ContainSubstring("short-secret"))
+	})
+
+	It("loads the environment variable", func() {
+		os.Unsetenv(DefaultVariable)
+		_, err := Load("", 0)
+		Expect(err).To(Equal(ErrMissingVariable))
+
+		os.Setenv(DefaultVariable, "id1:key1")
+		defer os.Unsetenv(DefaultVariable)
+		s, err := Load("", 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key(s, "id1")).To(Equal("key1"))
+	})
+
+})
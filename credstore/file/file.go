@@ -0,0 +1,242 @@
+// Package file provides a hawk GetCredentialFunc loading credentials
+// from a YAML or JSON file, reloaded when the file changes so keys can
+// be added or rotated without restarting the server:
+//
+//	my-id:
+//	  key: werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn
+//	  keys: [previous-key]
+//	  algorithm: sha256
+//	  user: {name: fred}
+//	  principal: acme
+//	  scopes: [read, write]
+//	  expires_at: 2030-01-01T00:00:00Z
+//	  revoked: false
+package file
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hyperboloide/hawk"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrMissingKey is returned when loading a file with credentials
+// without a key.
+var ErrMissingKey = errors.New("Credentials without a key")
+
+// ErrEmptyFile is returned when loading an empty file, which is usually
+// read while being rewritten in place.
+var ErrEmptyFile = errors.New("Empty credentials file")
+
+// ErrSuspiciousReload is returned when the watcher reads a file removing
+// more than MaxRemoved of the credentials, usually a partial write.
+var ErrSuspiciousReload = errors.New("Credentials file removes too many credentials")
+
+// DefaultMaxRemoved is the default Store MaxRemoved.
+const DefaultMaxRemoved = 0.5
+
+type fileCredentials struct {
+	Key       string      `yaml:"key"`
+	Keys      []string    `yaml:"keys"`
+	Algorithm string      `yaml:"algorithm"`
+	User      interface{} `yaml:"user"`
+	Principal string      `yaml:"principal"`
+	Scopes    []string    `yaml:"scopes"`
+	ExpiresAt time.Time   `yaml:"expires_at"`
+	Revoked   bool        `yaml:"revoked"`
+}
+
+// Store serves the credentials of a file. When watched, an invalid
+// file is reported to OnError and the previous credentials are kept.
+// Path is the file path
+// OnError if set is called with the reload errors
+// MaxRemoved is the maximum fraction of the credentials removed by a
+// reload of the watcher, DefaultMaxRemoved if zero and unlimited if
+// negative. Call Reload to apply larger changes.
+// DecodeUser if set decodes the users into the application type, to
+// use it create the Store directly and call Reload:
+//
+//	s := &file.Store{Path: path, DecodeUser: hawk.JSONUser[User]()}
+//	err := s.Reload()
+type Store struct {
+	Path       string
+	OnError    func(error)
+	DecodeUser hawk.UserDecoder
+	MaxRemoved float64
+
+	mu      sync.RWMutex
+	creds   map[string]*hawk.Credentials
+	stat    os.FileInfo
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewStore creates a new Store and loads the file.
+func NewStore(path string) (*Store, error) {
+	s := &Store{Path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetCredentials is a hawk.GetCredentialFunc.
+func (s *Store) GetCredentials(id string) (*hawk.Credentials, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.creds[id], nil
+}
+
+// Reload loads the file, the current credentials are kept on error.
+func (s *Store) Reload() error {
+	return s.reload(false)
+}
+
+// reload loads the file, checking the credentials removed when watched.
+func (s *Store) reload(watched bool) error {
+	stat, err := os.Stat(s.Path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return err
+	} else if len(bytes.TrimSpace(data)) == 0 {
+		return ErrEmptyFile
+	}
+	parsed := map[string]fileCredentials{}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+
+	creds := make(map[string]*hawk.Credentials, len(parsed))
+	for id, c := range parsed {
+		if c.Key == "" {
+			return ErrMissingKey
+		}
+		user, err := s.DecodeUser.DecodeValue(c.User)
+		if err != nil {
+			return err
+		}
+		creds[id] = &hawk.Credentials{
+			Key:       c.Key,
+			Keys:      c.Keys,
+			Algorithm: c.Algorithm,
+			User:      user,
+			Principal: c.Principal,
+			Scopes:    c.Scopes,
+			ExpiresAt: c.ExpiresAt,
+			Revoked:   c.Revoked,
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stat = stat
+	if watched && s.removed(creds) > s.maxRemoved() {
+		return ErrSuspiciousReload
+	}
+	s.creds = creds
+	return nil
+}
+
+func (s *Store) maxRemoved() float64 {
+	if s.MaxRemoved == 0 {
+		return DefaultMaxRemoved
+	} else if s.MaxRemoved < 0 {
+		return 1
+	}
+	return s.MaxRemoved
+}
+
+// removed returns the fraction of the current credentials missing from
+// creds.
+func (s *Store) removed(creds map[string]*hawk.Credentials) float64 {
+	if len(s.creds) == 0 {
+		return 0
+	}
+	n := 0
+	for id := range s.creds {
+		if _, ok := creds[id]; !ok {
+			n++
+		}
+	}
+	return float64(n) / float64(len(s.creds))
+}
+
+// changed returns true if the file, or the target of the symbolic link,
+// changed since the last reload.
+func (s *Store) changed() bool {
+	stat, err := os.Stat(s.Path)
+	if err != nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stat == nil || !os.SameFile(s.stat, stat) ||
+		!s.stat.ModTime().Equal(stat.ModTime()) || s.stat.Size() != stat.Size()
+}
+
+// Watch reloads the file when it changes until Close is called.
+// The directory is watched and the file checked on every event, so that
+// files replaced by a rename (editors) or behind a swapped symbolic link
+// (Kubernetes config maps and secrets) are reloaded too.
+func (s *Store) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(s.Path)); err != nil {
+		watcher.Close()
+		return err
+	}
+	s.watcher = watcher
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.watch()
+	return nil
+}
+
+func (s *Store) watch() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.watcher.Events:
+			if !s.changed() {
+				continue
+			}
+			if err := s.reload(true); err != nil {
+				s.error(err)
+			}
+		case err := <-s.watcher.Errors:
+			s.error(err)
+		}
+	}
+}
+
+func (s *Store) error(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// Close stops watching the file.
+func (s *Store) Close() error {
+	if s.watcher == nil {
+		return nil
+	}
+	close(s.done)
+	err := s.watcher.Close()
+	s.wg.Wait()
+	s.watcher = nil
+	return err
+}
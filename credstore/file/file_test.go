@@ -0,0 +1,197 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	. "github.com/hyperboloide/hawk/credstore/file"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+
+	var dir, path string
+
+	write := func(p, content string) {
+		Expect(ioutil.WriteFile(p, []byte(content), 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "hawk-file")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(dir, "credentials.yaml")
+		write(path, `
+my-id:
+  key: test-cred-key
+  keys: [old-key]
+  algorithm: sha512
+  user: {name: fred}
+  principal: acme
+  scopes: [read, write]
+  expires_at: 2030-01-01T00:00:00Z
+`)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("loads YAML files", func() {
+		s, err := NewStore(path)
+		Expect(err).ToNot(HaveOccurred())
+		creds, err := s.GetCredentials("my-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(Equal(&hawk.Credentials{
+			Key:       "test-cred-key",
+			Keys:      []string{"old-key"},
+			Algorithm: "sha512",
+			User:      map[string]interface{}{"name": "fred"},
+			Principal: "acme",
+			Scopes:    []string{"read", "write"},
+			ExpiresAt: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		}))
+
+		creds, err = s.GetCredentials("unknown-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(BeNil())
+	})
+
+	It("decodes typed users", func() {
+		type user struct{ Name string }
+		s := &Store{Path: path, DecodeUser: hawk.JSONUser[user]()}
+		Expect(s.Reload()).To(Succeed())
+		creds, err := s.GetCredentials("my-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.User).To(Equal(&user{"fred"}))
+	})
+
+	It("loads JSON files", func() {
+		write(path, `{"my-id": {"key": "json-key", "revoked": true}}`)
+		s, err := NewStore(path)
+		Expect(err).ToNot(HaveOccurred())
+		creds, err := s.GetCredentials("my-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("json-key"))
+		Expect(creds.Revoked).To(BeTrue())
+	})
+
+	It("rejects credentials without a key", func() {
+		write(path, `{"my-id": {"algorithm": "sha256"}}`)
+		_, err := NewStore(path)
+		Expect(err).To(Equal(ErrMissingKey))
+	})
+
+	It("rejects empty files", func() {
+		write(path, "\n")
+		_, err := NewStore(path)
+		Expect(err).To(Equal(ErrEmptyFile))
+	})
+
+	It("reloads the file when it changes", func() {
+		var mu sync.Mutex
+		var errs []error
+		s, err := NewStore(path)
+		Expect(err).ToNot(HaveOccurred())
+		s.OnError = func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+		Expect(s.Watch()).To(Succeed())
+		defer s.Close()
+
+		key := func(id string) string {
+			creds, _ := s.GetCredentials(id)
+			if creds == nil {
+				return ""
+			}
+			return creds.Key
+		}
+
+		write(path, `{"my-id": {"key": "rotated-key"}, "new-id": {"key": "new-key"}}`)
+		Eventually(func() string { return key("my-id") }).Should(Equal("rotated-key"))
+		Eventually(func() string { return key("new-id") }).Should(Equal("new-key"))
+
+		// replaced by a rename
+		tmp := filepath.Join(dir, "tmp.yaml")
+		write(tmp, `{"my-id": {"key": "renamed-key"}}`)
+		Expect(os.Rename(tmp, path)).To(Succeed())
+		Eventually(func() string { return key("my-id") }).Should(Equal("renamed-key"))
+
+		// invalid files are ignored
+		write(path, `{"my-id": {}}`)
+		Eventually(func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(errs)
+		}).ShouldNot(BeZero())
+		Expect(key("my-id")).To(Equal("renamed-key"))
+	})
+
+	It("reloads the files behind a swapped symbolic link", func() {
+		// the layout of the Kubernetes config maps and secrets
+		version := func(name, content string) {
+			Expect(os.Mkdir(filepath.Join(dir, name), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, name, "credentials.yaml"), []byte(content), 0600)).To(Succeed())
+		}
+		swap := func(name string) {
+			tmp := filepath.Join(dir, "..data_tmp")
+			Expect(os.Symlink(name, tmp)).To(Succeed())
+			Expect(os.Rename(tmp, filepath.Join(dir, "..data"))).To(Succeed())
+		}
+		version("..v1", `{"my-id": {"key": "first-key"}}`)
+		swap("..v1")
+		link := filepath.Join(dir, "linked.yaml")
+		Expect(os.Symlink(filepath.Join("..data", "credentials.yaml"), link)).To(Succeed())
+
+		s, err := NewStore(link)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Watch()).To(Succeed())
+		defer s.Close()
+		key := func() string {
+			creds, _ := s.GetCredentials("my-id")
+			return creds.Key
+		}
+		Expect(key()).To(Equal("first-key"))
+
+		version("..v2", `{"my-id": {"key": "second-key"}}`)
+		swap("..v2")
+		Eventually(key).Should(Equal("second-key"))
+	})
+
+	It("rejects the reloads removing too many credentials", func() {
+		write(path, `{"a": {"key": "a-key"}, "b": {"key": "b-key"}, "c": {"key": "c-key"}}`)
+		var mu sync.Mutex
+		var errs []error
+		s, err := NewStore(path)
+		Expect(err).ToNot(HaveOccurred())
+		s.OnError = func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+		Expect(s.Watch()).To(Succeed())
+		defer s.Close()
+
+		write(path, `{"a": {"key": "a-key"}}`)
+		Eventually(func() []error {
+			mu.Lock()
+			defer mu.Unlock()
+			return errs
+		}).Should(ContainElement(ErrSuspiciousReload))
+		creds, _ := s.GetCredentials("c")
+		Expect(creds).ToNot(BeNil())
+
+		Expect(s.Reload()).To(Succeed())
+		creds, _ = s.GetCredentials("c")
+		Expect(creds).To(BeNil())
+	})
+
+})
@@ -0,0 +1,261 @@
+// Package sql provides a hawk GetCredentialFunc backed by a database/sql
+// database, with the schema migrations and helpers to create, rotate
+// and revoke credentials.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+)
+
+// Dialect selects the SQL placeholders of the driver.
+type Dialect int
+
+const (
+	// Question uses "?" placeholders (MySQL, SQLite).
+	Question Dialect = iota
+	// Dollar uses "$1" placeholders (PostgreSQL).
+	Dollar
+)
+
+// Migrations are the schema versions, %[1]s is the table name.
+// They are applied in order by Migrate.
+var Migrations = []string{
+	`CREATE TABLE %[1]s (
+		id VARCHAR(64) PRIMARY KEY,
+		secret VARCHAR(255) NOT NULL,
+		previous_secret VARCHAR(255) NOT NULL DEFAULT '',
+		algorithm VARCHAR(16) NOT NULL DEFAULT '',
+		principal VARCHAR(255) NOT NULL DEFAULT '',
+		scopes VARCHAR(1024) NOT NULL DEFAULT '',
+		user_data TEXT,
+		created_at TIMESTAMP NOT NULL,
+		expires_at TIMESTAMP NULL,
+		revoked BOOLEAN NOT NULL DEFAULT FALSE
+	)`,
+	`ALTER TABLE %[1]s ADD COLUMN previous_expires_at TIMESTAMP NULL`,
+}
+
+// DefaultRotationGrace is the default Store RotationGrace.
+const DefaultRotationGrace = 24 * time.Hour
+
+// Store reads and writes credentials in a table.
+// DB is the database
+// Table is the credentials table name, "hawk_credentials" by default
+// Dialect is the placeholders dialect
+// DecodeUser if set decodes the users into the application type
+// RotationGrace is how long the previous key of a rotated credential is still accepted
+type Store struct {
+	DB            *sql.DB
+	Table         string
+	Dialect       Dialect
+	DecodeUser    hawk.UserDecoder
+	RotationGrace time.Duration
+}
+
+// NewStore creates a new Store.
+func NewStore(db *sql.DB, dialect Dialect) *Store {
+	return &Store{
+		DB:            db,
+		Table:         "hawk_credentials",
+		Dialect:       dialect,
+		RotationGrace: DefaultRotationGrace,
+	}
+}
+
+// Ping is a hawk.Pinger checking the database connection.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
+// query sets the table name and replaces the "?" placeholders for
+// the Dialect.
+func (s *Store) query(q string) string {
+	q = strings.Replace(q, "%[1]s", s.Table, -1)
+	if s.Dialect != Dollar {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// Migrate applies the pending Migrations, the applied versions are
+// saved in the "<Table>_migrations" table.
+func (s *Store) Migrate(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, s.query(
+		`CREATE TABLE IF NOT EXISTS %[1]s_migrations (version INTEGER PRIMARY KEY)`)); err != nil {
+		return err
+	}
+	var version int
+	if err := s.DB.QueryRowContext(ctx, s.query(
+		`SELECT COALESCE(MAX(version), 0) FROM %[1]s_migrations`)).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(Migrations); i++ {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.query(Migrations[i])); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.query(
+			`INSERT INTO %[1]s_migrations (version) VALUES (?)`), i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCredentials is a hawk.GetCredentialFunc. The previous key of a
+// rotated credential is still accepted for the RotationGrace, the ones
+// rotated before the grace deadline was introduced are not.
+func (s *Store) GetCredentials(id string) (*hawk.Credentials, error) {
+	var previous, scopes string
+	var user sql.NullString
+	var expires, previousExpires sql.NullTime
+	res := &hawk.Credentials{}
+	err := s.DB.QueryRow(s.query(
+		`SELECT secret, previous_secret, previous_expires_at, algorithm, principal, scopes, user_data, expires_at, revoked
+		FROM %[1]s WHERE id = ?`), id).Scan(
+		&res.Key, &previous, &previousExpires, &res.Algorithm, &res.Principal, &scopes, &user, &expires, &res.Revoked)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if previous != "" && previousExpires.Valid && time.Now().Before(previousExpires.Time) {
+		res.Keys = []string{previous}
+	}
+	if scopes != "" {
+		res.Scopes = strings.Fields(scopes)
+	}
+	if user.Valid {
+		if res.User, err = s.DecodeUser.Decode([]byte(user.String)); err != nil {
+			return nil, err
+		}
+	}
+	if expires.Valid {
+		res.ExpiresAt = expires.Time
+	}
+	return res, nil
+}
+
+// CreateCredential saves a credential created with hawk.NewCredential,
+// its User is saved in JSON.
+func (s *Store) CreateCredential(ctx context.Context, c *hawk.Credential) error {
+	var user sql.NullString
+	if c.User != nil {
+		data, err := json.Marshal(c.User)
+		if err != nil {
+			return err
+		}
+		user = sql.NullString{String: string(data), Valid: true}
+	}
+	var expires sql.NullTime
+	if !c.ExpiresAt.IsZero() {
+		expires = sql.NullTime{Time: c.ExpiresAt, Valid: true}
+	}
+	_, err := s.DB.ExecContext(ctx, s.query(
+		`INSERT INTO %[1]s (id, secret, algorithm, principal, scopes, user_data, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		c.ID, c.Key, c.Algorithm, c.Principal, strings.Join(c.Scopes, " "), user, c.CreatedAt, expires)
+	return err
+}
+
+// exec executes an update and returns hawk.ErrNotFound if no
+// credentials matched.
+func (s *Store) exec(ctx context.Context, q string, args ...interface{}) error {
+	res, err := s.DB.ExecContext(ctx, s.query(q), args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return hawk.ErrNotFound
+	}
+	return nil
+}
+
+// Rotate replaces the key of the credential with a new random key and
+// returns it. The previous key is still accepted for the RotationGrace,
+// or until RevokePrevious or the next rotation.
+func (s *Store) Rotate(ctx context.Context, id string) (string, error) {
+	key := hawk.NewCredential().Key
+	if err := s.exec(ctx,
+		`UPDATE %[1]s SET previous_secret = secret, previous_expires_at = ?, secret = ? WHERE id = ?`,
+		time.Now().Add(s.RotationGrace), key, id); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// RevokePrevious stops accepting the previous key of a rotated
+// credential before the end of the RotationGrace.
+func (s *Store) RevokePrevious(ctx context.Context, id string) error {
+	return s.exec(ctx,
+		`UPDATE %[1]s SET previous_secret = ?, previous_expires_at = NULL WHERE id = ?`, "", id)
+}
+
+// Revoke revokes the credential.
+func (s *Store) Revoke(ctx context.Context, id string) error {
+	return s.exec(ctx, `UPDATE %[1]s SET revoked = ? WHERE id = ?`, true, id)
+}
+
+// Expire sets the expiration time of the credential.
+func (s *Store) Expire(ctx context.Context, id string, t time.Time) error {
+	return s.exec(ctx, `UPDATE %[1]s SET expires_at = ? WHERE id = ?`, t, id)
+}
+
+// List returns the metadata of all the credentials ordered by id.
+func (s *Store) List(ctx context.Context) ([]hawk.CredentialInfo, error) {
+	rows, err := s.DB.QueryContext(ctx, s.query(
+		`SELECT id, created_at, expires_at, scopes, algorithm, principal, revoked
+		FROM %[1]s ORDER BY id`))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []hawk.CredentialInfo{}
+	for rows.Next() {
+		var info hawk.CredentialInfo
+		var scopes string
+		var expires sql.NullTime
+		if err := rows.Scan(&info.ID, &info.CreatedAt, &expires, &scopes,
+			&info.Algorithm, &info.Principal, &info.Revoked); err != nil {
+			return nil, err
+		}
+		if scopes != "" {
+			info.Scopes = strings.Fields(scopes)
+		}
+		if expires.Valid {
+			info.ExpiresAt = expires.Time
+		}
+		res = append(res, info)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,178 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	. "github.com/hyperboloide/hawk/credstore/sql"
+	_ "modernc.org/sqlite"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+
+	var db *sql.DB
+	var store *Store
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		var err error
+		db, err = sql.Open("sqlite", ":memory:")
+		Expect(err).ToNot(HaveOccurred())
+		db.SetMaxOpenConns(1)
+		store = NewStore(db, Question)
+		Expect(store.Migrate(ctx)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("migrates only once", func() {
+		Expect(store.Migrate(ctx)).To(Succeed())
+		var version int
+		Expect(db.QueryRow("SELECT MAX(version) FROM hawk_credentials_migrations").Scan(&version)).To(Succeed())
+		Expect(version).To(Equal(len(Migrations)))
+	})
+
+	It("creates and reads credentials", func() {
+		c := hawk.NewCredential(
+			hawk.CredentialTTL(time.Hour),
+			hawk.CredentialScopes("read", "write"),
+			hawk.CredentialAlgorithm(hawk.SHA512),
+			hawk.CredentialUser(map[string]interface{}{"name": "fred"}),
+			hawk.CredentialPrincipal("org"),
+		)
+		Expect(store.CreateCredential(ctx, c)).To(Succeed())
+
+		creds, err := store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal(c.Key))
+		Expect(creds.Keys).To(BeNil())
+		Expect(creds.Algorithm).To(Equal(hawk.SHA512))
+		Expect(creds.Principal).To(Equal("org"))
+		Expect(creds.Scopes).To(Equal([]string{"read", "write"}))
+		Expect(creds.User).To(Equal(map[string]interface{}{"name": "fred"}))
+		Expect(creds.ExpiresAt).To(BeTemporally("~", c.ExpiresAt, time.Second))
+		Expect(creds.Revoked).To(BeFalse())
+
+		c = hawk.NewCredential()
+		Expect(store.CreateCredential(ctx, c)).To(Succeed())
+		creds, err = store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.User).To(BeNil())
+		Expect(creds.ExpiresAt.IsZero()).To(BeTrue())
+	})
+
+	It("returns nil for unknown credentials", func() {
+		creds, err := store.GetCredentials("unknown-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(BeNil())
+	})
+
+	It("rotates, expires and revokes credentials", func() {
+		c := hawk.NewCredential()
+		Expect(store.CreateCredential(ctx, c)).To(Succeed())
+
+		key, err := store.Rotate(ctx, c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		creds, err := store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal(key))
+		Expect(creds.Keys).To(Equal([]string{c.Key}))
+
+		expires := time.Now().Add(time.Minute)
+		Expect(store.Expire(ctx, c.ID, expires)).To(Succeed())
+		Expect(store.Revoke(ctx, c.ID)).To(Succeed())
+		creds, err = store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Revoked).To(BeTrue())
+		Expect(creds.ExpiresAt).To(BeTemporally("~", expires, time.Second))
+
+		Expect(store.Revoke(ctx, "unknown-id")).To(Equal(hawk.ErrNotFound))
+		_, err = store.Rotate(ctx, "unknown-id")
+		Expect(err).To(Equal(hawk.ErrNotFound))
+	})
+
+	It("accepts the previous key for the RotationGrace only", func() {
+		c := hawk.NewCredential()
+		Expect(store.CreateCredential(ctx, c)).To(Succeed())
+
+		store.RotationGrace = 50 * time.Millisecond
+		_, err := store.Rotate(ctx, c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		creds, err := store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Keys).To(Equal([]string{c.Key}))
+
+		time.Sleep(60 * time.Millisecond)
+		creds, err = store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Keys).To(BeNil())
+	})
+
+	It("revokes the previous key", func() {
+		c := hawk.NewCredential()
+		Expect(store.CreateCredential(ctx, c)).To(Succeed())
+		_, err := store.Rotate(ctx, c.ID)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(store.RevokePrevious(ctx, c.ID)).To(Succeed())
+		creds, err := store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Keys).To(BeNil())
+		Expect(store.RevokePrevious(ctx, "unknown-id")).To(Equal(hawk.ErrNotFound))
+	})
+
+	It("lists credentials", func() {
+		Expect(store.List(ctx)).To(BeEmpty())
+		c := hawk.NewCredential(hawk.CredentialScopes("read", "write"), hawk.CredentialPrincipal("org"))
+		Expect(store.CreateCredential(ctx, c)).To(Succeed())
+		Expect(store.Revoke(ctx, c.ID)).To(Succeed())
+
+		res, err := store.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res).To(HaveLen(1))
+		Expect(res[0].ID).To(Equal(c.ID))
+		Expect(res[0].Scopes).To(Equal([]string{"read", "write"}))
+		Expect(res[0].Principal).To(Equal("org"))
+		Expect(res[0].Revoked).To(BeTrue())
+		Expect(res[0].ExpiresAt.IsZero()).To(BeTrue())
+		Expect(res[0].CreatedAt).To(BeTemporally("~", c.CreatedAt, time.Second))
+	})
+
+	It("implements the hawk CredentialStore", func() {
+		var _ hawk.CredentialStore = store
+	})
+
+	It("pings the database", func() {
+		var _ hawk.Pinger = store
+		Expect(store.Ping(ctx)).To(Succeed())
+		db.Close()
+		Expect(store.Ping(ctx)).ToNot(Succeed())
+	})
+
+	It("decodes typed users", func() {
+		type user struct{ Name string }
+		store.DecodeUser = hawk.JSONUser[user]()
+		c := hawk.NewCredential(hawk.CredentialUser(user{"fred"}))
+		Expect(store.CreateCredential(ctx, c)).To(Succeed())
+		creds, err := store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.User).To(Equal(&user{"fred"}))
+	})
+
+	It("uses dollar placeholders", func() {
+		store.Dialect = Dollar
+		c := hawk.NewCredential()
+		Expect(store.CreateCredential(ctx, c)).To(Succeed())
+		creds, err := store.GetCredentials(c.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal(c.Key))
+	})
+
+})
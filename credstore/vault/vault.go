@@ -0,0 +1,209 @@
+// Package vault provides a hawk GetCredentialFunc backed by the
+// HashiCorp Vault KV (version 2) secrets engine, so keys never live in
+// the application database.
+//
+// Each credential is a secret at "<Path>/<id>" with the fields:
+//
+//	key        the key (required)
+//	keys       the previous keys still accepted
+//	algorithm  the MAC algorithm name
+//	user       the user set in the context
+//	principal  the principal owning the credential
+//	scopes     the credential scopes
+//	expires_at the RFC 3339 expiration time
+//	revoked    true if the credential is revoked
+//
+// The ids containing "/" or ".." are unknown, so they can't read other
+// secrets of the mount.
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hyperboloide/hawk"
+)
+
+// ErrInvalidSecret is returned when a secret has no key or a field of
+// the wrong type.
+var ErrInvalidSecret = errors.New("Invalid credentials secret")
+
+// Store reads credentials from Vault. It's a hawk.CredentialProvider
+// without cache, GetCredentials uses the Cache.
+// Client is the Vault client
+// Mount is the KV v2 mount path, "secret" by default
+// Path is the path of the credentials secrets in the mount
+// Timeout if set is the maximum duration of a Vault read
+// DecodeUser if set decodes the users into the application type
+// Cache if set caches the credentials of GetCredentials, revocations and
+// rotations are propagated within its TTL or immediately after Invalidate
+type Store struct {
+	Client     *api.Client
+	Mount      string
+	Path       string
+	Timeout    time.Duration
+	DecodeUser hawk.UserDecoder
+	Cache      *hawk.CachedCredentialProvider
+}
+
+// NewStore creates a new Store caching credentials for a minute.
+func NewStore(client *api.Client, path string) *Store {
+	s := &Store{
+		Client: client,
+		Mount:  "secret",
+		Path:   path,
+	}
+	s.Cache = hawk.NewCachedCredentialProvider(s, time.Minute)
+	return s
+}
+
+// GetCredentials is a hawk.GetCredentialFunc.
+func (s *Store) GetCredentials(id string) (*hawk.Credentials, error) {
+	if s.Cache == nil {
+		return s.Get(context.Background(), id)
+	}
+	return s.Cache.Get(context.Background(), id)
+}
+
+// Invalidate removes the cached credentials of id.
+func (s *Store) Invalidate(id string) {
+	if s.Cache != nil {
+		s.Cache.Invalidate(id)
+	}
+}
+
+// Get reads the credentials of id from Vault, nil if the secret doesn't
+// exist or id is not valid.
+func (s *Store) Get(ctx context.Context, id string) (*hawk.Credentials, error) {
+	if id == "" || strings.Contains(id, "/") || strings.Contains(id, "..") {
+		return nil, nil
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	secret, err := s.Client.KVv2(s.Mount).Get(ctx, s.Path+"/"+id)
+	if errors.Is(err, api.ErrSecretNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return s.parse(secret.Data)
+}
+
+// Close does nothing, the Client has no resources to release.
+func (s *Store) Close() error {
+	return nil
+}
+
+// parse returns the credentials stored in the data of a secret.
+func (s *Store) parse(data map[string]interface{}) (*hawk.Credentials, error) {
+	res := &hawk.Credentials{}
+	var ok bool
+	if res.Key, ok = data["key"].(string); !ok || res.Key == "" {
+		return nil, ErrInvalidSecret
+	}
+	var err error
+	if res.User, err = s.DecodeUser.DecodeValue(data["user"]); err != nil {
+		return nil, err
+	}
+	if v, exists := data["algorithm"]; exists {
+		if res.Algorithm, ok = v.(string); !ok {
+			return nil, ErrInvalidSecret
+		}
+	}
+	if v, exists := data["principal"]; exists {
+		if res.Principal, ok = v.(string); !ok {
+			return nil, ErrInvalidSecret
+		}
+	}
+	if v, exists := data["revoked"]; exists {
+		if res.Revoked, ok = v.(bool); !ok {
+			return nil, ErrInvalidSecret
+		}
+	}
+	if v, exists := data["expires_at"]; exists {
+		str, ok := v.(string)
+		if !ok {
+			return nil, ErrInvalidSecret
+		}
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+		}
+		res.ExpiresAt = t
+	}
+	if res.Keys, err = stringSlice(data["keys"]); err != nil {
+		return nil, err
+	}
+	if res.Scopes, err = stringSlice(data["scopes"]); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// stringSlice converts a JSON array of strings.
+func stringSlice(v interface{}) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	values, ok := v.([]interface{})
+	if !ok {
+		return nil, ErrInvalidSecret
+	}
+	res := make([]string, len(values))
+	for i, value := range values {
+		if res[i], ok = value.(string); !ok {
+			return nil, ErrInvalidSecret
+		}
+	}
+	return res, nil
+}
+
+// RenewToken keeps the Client token renewed until ctx is done or the
+// token cannot be renewed anymore, in which case the error is returned.
+// It's meant to run in its own goroutine.
+func (s *Store) RenewToken(ctx context.Context) error {
+	self, err := s.Client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	ttl, err := self.TokenTTL()
+	if err != nil {
+		return err
+	}
+	renewable, err := self.TokenIsRenewable()
+	if err != nil {
+		return err
+	}
+
+	watcher, err := s.Client.NewLifetimeWatcher(&api.LifetimeWatcherInput{
+		Secret: &api.Secret{
+			Auth: &api.SecretAuth{
+				ClientToken:   s.Client.Token(),
+				Renewable:     renewable,
+				LeaseDuration: int(ttl.Seconds()),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.DoneCh():
+			return err
+		case <-watcher.RenewCh():
+		}
+	}
+}
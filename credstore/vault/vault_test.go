@@ -0,0 +1,141 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	. "github.com/hyperboloide/hawk/credstore/vault"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+
+	var ts *httptest.Server
+	var store *Store
+	var secrets map[string]map[string]interface{}
+	var reads, renewals int32
+
+	reply := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	BeforeEach(func() {
+		reads, renewals = 0, 0
+		secrets = map[string]map[string]interface{}{
+			"valid-id": {
+				"key":        "test-cred-key",
+				"keys":       []string{"old-key"},
+				"algorithm":  "sha512",
+				"user":       map[string]string{"name": "fred"},
+				"principal":  "org",
+				"scopes":     []string{"read"},
+				"expires_at": "2100-01-01T00:00:00Z",
+			},
+			"invalid-id": {"key": 42},
+		}
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/v1/auth/token/lookup-self":
+				reply(w, map[string]interface{}{
+					"data": map[string]interface{}{"ttl": 1, "renewable": true},
+				})
+			case r.URL.Path == "/v1/auth/token/renew-self":
+				atomic.AddInt32(&renewals, 1)
+				reply(w, map[string]interface{}{
+					"auth": map[string]interface{}{
+						"client_token":   "token",
+						"lease_duration": 1,
+						"renewable":      true,
+					},
+				})
+			case strings.HasPrefix(r.URL.Path, "/v1/secret/data/hawk/"):
+				atomic.AddInt32(&reads, 1)
+				data, exists := secrets[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/hawk/")]
+				if !exists {
+					w.WriteHeader(404)
+					reply(w, map[string]interface{}{"errors": []string{}})
+					return
+				}
+				reply(w, map[string]interface{}{
+					"data": map[string]interface{}{
+						"data":     data,
+						"metadata": map[string]interface{}{"version": 1},
+					},
+				})
+			default:
+				w.WriteHeader(500)
+			}
+		}))
+
+		config := api.DefaultConfig()
+		config.Address = ts.URL
+		client, err := api.NewClient(config)
+		Expect(err).ToNot(HaveOccurred())
+		client.SetToken("token")
+		store = NewStore(client, "hawk")
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("reads and caches credentials", func() {
+		creds, err := store.GetCredentials("valid-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("test-cred-key"))
+		Expect(creds.Keys).To(Equal([]string{"old-key"}))
+		Expect(creds.Algorithm).To(Equal("sha512"))
+		Expect(creds.User).To(Equal(map[string]interface{}{"name": "fred"}))
+		Expect(creds.Principal).To(Equal("org"))
+		Expect(creds.Scopes).To(Equal([]string{"read"}))
+		Expect(creds.ExpiresAt).To(Equal(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+		_, err = store.GetCredentials("valid-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(atomic.LoadInt32(&reads)).To(Equal(int32(1)))
+
+		secrets["valid-id"]["revoked"] = true
+		store.Invalidate("valid-id")
+		creds, err = store.GetCredentials("valid-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Revoked).To(BeTrue())
+		Expect(atomic.LoadInt32(&reads)).To(Equal(int32(2)))
+	})
+
+	It("returns nil for unknown credentials", func() {
+		creds, err := store.GetCredentials("unknown-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(BeNil())
+	})
+
+	It("rejects the ids reading other secrets", func() {
+		for _, id := range []string{"", "other/valid-id", "../hawk/valid-id", "..", "valid-id/.."} {
+			creds, err := store.GetCredentials(id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(creds).To(BeNil())
+		}
+		Expect(atomic.LoadInt32(&reads)).To(BeZero())
+	})
+
+	It("rejects invalid secrets", func() {
+		_, err := store.GetCredentials("invalid-id")
+		Expect(err).To(Equal(ErrInvalidSecret))
+	})
+
+	It("renews the token", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		Expect(store.RenewToken(ctx)).To(Succeed())
+		Expect(atomic.LoadInt32(&renewals)).To(BeNumerically(">", 0))
+	})
+
+})
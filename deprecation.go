@@ -0,0 +1,26 @@
+package hawk
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deprecationHeaders sends the "Deprecation" (RFC 9745), "Sunset"
+// (RFC 8594) and "Link" headers of deprecated credentials on
+// successful responses if the Middleware DeprecationHeaders is set.
+func (hm *Middleware) deprecationHeaders(c *gin.Context, creds *Credentials) {
+	if !hm.DeprecationHeaders || creds == nil {
+		return
+	}
+	if !creds.Deprecation.IsZero() {
+		c.Header("Deprecation", "@"+strconv.FormatInt(creds.Deprecation.Unix(), 10))
+	}
+	if !creds.Sunset.IsZero() {
+		c.Header("Sunset", creds.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if creds.DeprecationLink != "" && (!creds.Deprecation.IsZero() || !creds.Sunset.IsZero()) {
+		c.Writer.Header().Add("Link", "<"+creds.DeprecationLink+`>; rel="deprecation"`)
+	}
+}
@@ -0,0 +1,88 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeprecationHeaders", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	deprecation := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	getCredentials := func(id string) (*Credentials, error) {
+		if id == "legacy-id" {
+			return &Credentials{
+				Key:             "test-cred-key",
+				Deprecation:     deprecation,
+				Sunset:          sunset,
+				DeprecationLink: "https://example.com/migrate",
+			}, nil
+		}
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.DeprecationHeaders = true
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(id string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   id,
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		return resp
+	}
+
+	It("signals deprecated credentials", func() {
+		resp := request("legacy-id")
+		Expect(resp.Header.Get("Deprecation")).To(Equal("@1767225600"))
+		Expect(resp.Header.Get("Sunset")).To(Equal("Tue, 30 Jun 2026 00:00:00 GMT"))
+		Expect(resp.Header.Get("Link")).To(Equal(`<https://example.com/migrate>; rel="deprecation"`))
+	})
+
+	It("does not signal other credentials", func() {
+		resp := request("valid-id")
+		Expect(resp.Header).ToNot(HaveKey("Deprecation"))
+		Expect(resp.Header).ToNot(HaveKey("Sunset"))
+		Expect(resp.Header).ToNot(HaveKey("Link"))
+	})
+
+	It("is disabled by default", func() {
+		hm.DeprecationHeaders = false
+		Expect(request("legacy-id").Header).ToNot(HaveKey("Deprecation"))
+	})
+
+})
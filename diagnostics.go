@@ -0,0 +1,70 @@
+package hawk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// DiagnosticBundle is a redacted summary of an authentication attempt,
+// safe to return to trusted clients or attach to support tickets: it
+// never contains keys nor MACs.
+// Method is "header", "bewit" or empty if the request had no auth.
+// NormalizedHash is the hex sha256 of the normalized request string,
+// clients can compare it with the hash of their own normalized string.
+// Skew is the server time minus the request timestamp (header only).
+type DiagnosticBundle struct {
+	Method             string        `json:"method"`
+	CredentialID       string        `json:"credential_id,omitempty"`
+	NormalizedHash     string        `json:"normalized_hash,omitempty"`
+	Skew               time.Duration `json:"skew"`
+	CredentialsLatency time.Duration `json:"credentials_latency"`
+	NonceLatency       time.Duration `json:"nonce_latency"`
+	Error              string        `json:"error,omitempty"`
+}
+
+// Diagnostics returns the DiagnosticBundle of the request or nil if
+// the Middleware Diagnostics option is not set. It's available to the
+// next handlers and to the AbortHandler.
+func Diagnostics(c *gin.Context) *DiagnosticBundle {
+	if v, exists := c.Get(DiagnosticsKey); exists {
+		return v.(*DiagnosticBundle)
+	}
+	return nil
+}
+
+// setDiagnostics stores the DiagnosticBundle in the context if enabled.
+func (hm *Middleware) setDiagnostics(c *gin.Context, hr *Request, auth *hawk.Auth, err error) {
+	if !hm.Diagnostics {
+		return
+	}
+
+	res := &DiagnosticBundle{
+		CredentialID:       hr.ID,
+		CredentialsLatency: hr.credentialsLatency,
+		NonceLatency:       hr.nonceLatency,
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	if auth != nil {
+		t := hawk.AuthHeader
+		res.Method = "header"
+		if auth.IsBewit {
+			t = hawk.AuthBewit
+			res.Method = "bewit"
+		} else {
+			res.Skew = auth.ActualTimestamp.Sub(auth.Timestamp)
+		}
+		sum := sha256.Sum256([]byte(auth.NormalizedString(t)))
+		res.NormalizedHash = hex.EncodeToString(sum[:])
+	} else if c.GetHeader("Authorization") != "" {
+		res.Method = "header"
+	} else if hm.bewit(c.Request) != "" {
+		res.Method = "bewit"
+	}
+	c.Set(DiagnosticsKey, res)
+}
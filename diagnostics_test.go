@@ -0,0 +1,101 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diagnostics", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var bundle *DiagnosticBundle
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		bundle = nil
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.Diagnostics = true
+		hm.AbortHandler = func(c *gin.Context, err error) {
+			bundle = Diagnostics(c)
+			c.JSON(401, bundle)
+		}
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			bundle = Diagnostics(c)
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(key string) (*hawk.Auth, int) {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "id",
+			Key:  key,
+			Hash: sha256.New,
+		}, -time.Second)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return auth, resp.StatusCode
+	}
+
+	It("is not set by default", func() {
+		hm.Diagnostics = false
+		_, status := request("test-cred-key")
+		Expect(status).To(Equal(200))
+		Expect(bundle).To(BeNil())
+	})
+
+	It("describes a successful request", func() {
+		auth, status := request("test-cred-key")
+		Expect(status).To(Equal(200))
+		Expect(bundle).ToNot(BeNil())
+		Expect(bundle.Method).To(Equal("header"))
+		Expect(bundle.CredentialID).To(Equal("id"))
+		Expect(bundle.Error).To(BeEmpty())
+		Expect(bundle.Skew).To(BeNumerically(">=", time.Second))
+		sum := sha256.Sum256([]byte(auth.NormalizedString(hawk.AuthHeader)))
+		Expect(bundle.NormalizedHash).To(Equal(hex.EncodeToString(sum[:])))
+	})
+
+	It("describes a failed request", func() {
+		_, status := request("invalid")
+		Expect(status).To(Equal(401))
+		Expect(bundle).ToNot(BeNil())
+		Expect(bundle.Error).To(Equal(hawk.ErrInvalidMAC.Error()))
+		Expect(bundle.NormalizedHash).ToNot(BeEmpty())
+	})
+
+	It("describes a request without auth", func() {
+		resp, err := http.Get(ts.URL + "/private")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(401))
+		Expect(bundle.Method).To(BeEmpty())
+		Expect(bundle.Error).ToNot(BeEmpty())
+	})
+
+})
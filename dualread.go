@@ -0,0 +1,163 @@
+package hawk
+
+import (
+	"crypto/subtle"
+	"reflect"
+	"sync/atomic"
+)
+
+// DefaultDualReadPending is the default DualRead MaxPending.
+const DefaultDualReadPending = 64
+
+// DivergenceKind is the class of a Divergence.
+type DivergenceKind int
+
+const (
+	// DivergenceMissing is credentials found only in the primary store.
+	DivergenceMissing DivergenceKind = iota
+	// DivergenceUnexpected is credentials found only in the secondary store.
+	DivergenceUnexpected
+	// DivergenceMismatch is credentials different in both stores.
+	DivergenceMismatch
+	// DivergenceError is an error returned only by the secondary store.
+	DivergenceError
+)
+
+var divergenceNames = map[DivergenceKind]string{
+	DivergenceMissing:    "missing",
+	DivergenceUnexpected: "unexpected",
+	DivergenceMismatch:   "mismatch",
+	DivergenceError:      "error",
+}
+
+func (k DivergenceKind) String() string {
+	return divergenceNames[k]
+}
+
+// Divergence is a difference between the primary and secondary stores
+// of a DualRead for the credentials ID.
+// Fields are the names of the Credentials fields that differ for a DivergenceMismatch.
+// Err is the secondary error for a DivergenceError.
+type Divergence struct {
+	ID     string
+	Kind   DivergenceKind
+	Fields []string
+	Err    error
+}
+
+// DualRead is a GetCredentialFunc for credential store migrations: it
+// serves the credentials of the Primary store and compares them with the
+// Secondary store, reporting differences to OnDivergence (for metrics).
+// Lookups failing on the Primary are not compared.
+// Primary is the GetCredentialFunc serving the requests
+// Secondary is the GetCredentialFunc of the store compared to Primary
+// OnDivergence is called with each difference found
+// Async if true compares in a goroutine so the Secondary latency and
+// errors never affect the requests
+// MaxPending is the maximum number of asynchronous comparisons running,
+// DefaultDualReadPending if 0. The lookups above are not compared (see
+// Dropped) so a slow Secondary can't pile up goroutines.
+type DualRead struct {
+	Primary      GetCredentialFunc
+	Secondary    GetCredentialFunc
+	OnDivergence func(d Divergence)
+	Async        bool
+	MaxPending   int
+
+	pending int64
+	dropped uint64
+}
+
+// NewDualRead creates a new DualRead comparing stores asynchronously.
+func NewDualRead(primary, secondary GetCredentialFunc, onDivergence func(d Divergence)) *DualRead {
+	return &DualRead{
+		Primary:      primary,
+		Secondary:    secondary,
+		OnDivergence: onDivergence,
+		Async:        true,
+	}
+}
+
+// GetCredentials is a GetCredentialFunc.
+func (d *DualRead) GetCredentials(id string) (*Credentials, error) {
+	res, err := d.Primary(id)
+	if err != nil {
+		return nil, err
+	}
+	if !d.Async {
+		d.compare(id, res)
+	} else if atomic.AddInt64(&d.pending, 1) > int64(d.maxPending()) {
+		atomic.AddInt64(&d.pending, -1)
+		atomic.AddUint64(&d.dropped, 1)
+	} else {
+		go func() {
+			defer atomic.AddInt64(&d.pending, -1)
+			d.compare(id, res)
+		}()
+	}
+	return res, nil
+}
+
+// Dropped returns the number of lookups not compared because MaxPending
+// comparisons were running.
+func (d *DualRead) Dropped() uint64 {
+	return atomic.LoadUint64(&d.dropped)
+}
+
+func (d *DualRead) maxPending() int {
+	if d.MaxPending <= 0 {
+		return DefaultDualReadPending
+	}
+	return d.MaxPending
+}
+
+// compare looks up id in the Secondary store and reports any difference
+// with primary.
+func (d *DualRead) compare(id string, primary *Credentials) {
+	secondary, err := d.Secondary(id)
+	var div *Divergence
+	switch {
+	case err != nil:
+		div = &Divergence{ID: id, Kind: DivergenceError, Err: err}
+	case primary != nil && secondary == nil:
+		div = &Divergence{ID: id, Kind: DivergenceMissing}
+	case primary == nil && secondary != nil:
+		div = &Divergence{ID: id, Kind: DivergenceUnexpected}
+	case primary != nil:
+		if fields := diffCredentials(primary, secondary); len(fields) > 0 {
+			div = &Divergence{ID: id, Kind: DivergenceMismatch, Fields: fields}
+		}
+	}
+	if div != nil && d.OnDivergence != nil {
+		d.OnDivergence(*div)
+	}
+}
+
+// diffCredentials returns the names of the stored fields that differ
+// between a and b. Hash functions are not comparable and User often
+// differs in type between stores, so they are ignored.
+func diffCredentials(a, b *Credentials) []string {
+	var res []string
+	if subtle.ConstantTimeCompare([]byte(a.Key), []byte(b.Key)) != 1 {
+		res = append(res, "Key")
+	}
+	if !reflect.DeepEqual(a.Keys, b.Keys) && (len(a.Keys) > 0 || len(b.Keys) > 0) {
+		res = append(res, "Keys")
+	}
+	if a.Algorithm != b.Algorithm {
+		res = append(res, "Algorithm")
+	}
+	if !a.ExpiresAt.Equal(b.ExpiresAt) {
+		res = append(res, "ExpiresAt")
+	}
+	if a.Revoked != b.Revoked {
+		res = append(res, "Revoked")
+	}
+	if a.Principal != b.Principal {
+		res = append(res, "Principal")
+	}
+	if !reflect.DeepEqual(a.Scopes, b.Scopes) && (len(a.Scopes) > 0 || len(b.Scopes) > 0) {
+		res = append(res, "Scopes")
+	}
+	return res
+}
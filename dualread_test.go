@@ -0,0 +1,108 @@
+package hawk_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/hyperboloide/hawk"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DualRead", func() {
+
+	var primary, secondary map[string]*Credentials
+	var divergences []Divergence
+	var dr *DualRead
+	storeErr := errors.New("store error")
+
+	lookup := func(store *map[string]*Credentials) GetCredentialFunc {
+		return func(id string) (*Credentials, error) {
+			if id == "error" {
+				return nil, storeErr
+			}
+			return (*store)[id], nil
+		}
+	}
+
+	BeforeEach(func() {
+		primary = map[string]*Credentials{}
+		secondary = map[string]*Credentials{}
+		divergences = nil
+		dr = NewDualRead(lookup(&primary), lookup(&secondary), func(d Divergence) {
+			divergences = append(divergences, d)
+		})
+		dr.Async = false
+	})
+
+	It("serves the primary without divergence", func() {
+		expires := time.Now().Add(time.Hour)
+		primary["id"] = &Credentials{Key: "key", ExpiresAt: expires, Scopes: []string{"read"}}
+		secondary["id"] = &Credentials{Key: "key", ExpiresAt: expires.UTC(), Scopes: []string{"read"}, User: "other"}
+		Expect(dr.GetCredentials("id")).To(Equal(primary["id"]))
+		Expect(dr.GetCredentials("unknown")).To(BeNil())
+		Expect(divergences).To(BeEmpty())
+	})
+
+	It("reports divergences", func() {
+		primary["missing"] = &Credentials{Key: "key"}
+		secondary["unexpected"] = &Credentials{Key: "key"}
+		primary["mismatch"] = &Credentials{Key: "key", Principal: "org"}
+		secondary["mismatch"] = &Credentials{Key: "other", Principal: "org", Revoked: true}
+
+		Expect(dr.GetCredentials("missing")).To(Equal(primary["missing"]))
+		Expect(dr.GetCredentials("unexpected")).To(BeNil())
+		Expect(dr.GetCredentials("mismatch")).To(Equal(primary["mismatch"]))
+		Expect(divergences).To(Equal([]Divergence{
+			{ID: "missing", Kind: DivergenceMissing},
+			{ID: "unexpected", Kind: DivergenceUnexpected},
+			{ID: "mismatch", Kind: DivergenceMismatch, Fields: []string{"Key", "Revoked"}},
+		}))
+		Expect(divergences[2].Kind.String()).To(Equal("mismatch"))
+	})
+
+	It("reports secondary errors only", func() {
+		dr.Primary = func(id string) (*Credentials, error) {
+			return &Credentials{Key: "key"}, nil
+		}
+		Expect(dr.GetCredentials("error")).ToNot(BeNil())
+		Expect(divergences).To(Equal([]Divergence{{ID: "error", Kind: DivergenceError, Err: storeErr}}))
+
+		divergences = nil
+		dr.Primary = lookup(&primary)
+		_, err := dr.GetCredentials("error")
+		Expect(err).To(Equal(storeErr))
+		Expect(divergences).To(BeEmpty())
+	})
+
+	It("compares asynchronously", func() {
+		ch := make(chan Divergence, 1)
+		dr = NewDualRead(lookup(&primary), lookup(&secondary), func(d Divergence) {
+			ch <- d
+		})
+		primary["id"] = &Credentials{Key: "key"}
+		Expect(dr.GetCredentials("id")).ToNot(BeNil())
+		Eventually(ch).Should(Receive(Equal(Divergence{ID: "id", Kind: DivergenceMissing})))
+	})
+
+	It("drops the comparisons above MaxPending", func() {
+		release := make(chan struct{})
+		compared := make(chan string, 10)
+		dr = NewDualRead(lookup(&primary), func(id string) (*Credentials, error) {
+			<-release
+			compared <- id
+			return nil, nil
+		}, nil)
+		dr.MaxPending = 2
+		primary["id"] = &Credentials{Key: "key"}
+		for i := 0; i < 5; i++ {
+			Expect(dr.GetCredentials("id")).ToNot(BeNil())
+		}
+		Expect(dr.Dropped()).To(Equal(uint64(3)))
+		close(release)
+		Eventually(compared).Should(HaveLen(2))
+		Consistently(compared).Should(HaveLen(2))
+	})
+
+})
@@ -0,0 +1,70 @@
+package hawk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Base64Normalizer rewrites a base64 value sent by a client into the
+// standard padded encoding expected by Hawk.
+type Base64Normalizer func(string) string
+
+// LenientBase64 is a Base64Normalizer for constrained clients that
+// produce URL-safe base64 or omit padding.
+func LenientBase64(s string) string {
+	s = strings.NewReplacer("-", "+", "_", "/").Replace(strings.TrimRight(s, "="))
+	if n := len(s) % 4; n != 0 {
+		s += strings.Repeat("=", 4-n)
+	}
+	return s
+}
+
+var base64AttrRegexp = regexp.MustCompile(`\b(mac|hash)="([^"]*)"`)
+
+// normalizeEncoding returns a shallow copy of r with the base64 values
+// of the "Authorization" header and of the bewit rewritten by the
+// Base64Normalizer. The bewit is in the "bewit" parameter, see
+// bewitTransport.
+func (hm *Middleware) normalizeEncoding(r *http.Request) *http.Request {
+	res := *r
+	if header := r.Header.Get("Authorization"); header != "" {
+		header = base64AttrRegexp.ReplaceAllStringFunc(header, func(attr string) string {
+			m := base64AttrRegexp.FindStringSubmatch(attr)
+			return m[1] + `="` + hm.Base64Normalizer(m[2]) + `"`
+		})
+		res.Header = r.Header.Clone()
+		res.Header.Set("Authorization", header)
+	} else if bewit := r.URL.Query().Get("bewit"); bewit != "" {
+		if normalized, ok := hm.normalizeBewit(bewit); ok {
+			u := *r.URL
+			for _, v := range []string{bewit, url.QueryEscape(bewit)} {
+				if strings.Contains(u.RawQuery, "bewit="+v) {
+					u.RawQuery = strings.Replace(u.RawQuery, "bewit="+v, "bewit="+normalized, 1)
+					break
+				}
+			}
+			res.URL = &u
+		}
+	}
+	return &res
+}
+
+// normalizeBewit decodes the bewit leniently, rewrites its mac and
+// encodes it back as Hawk expects.
+func (hm *Middleware) normalizeBewit(bewit string) (string, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(LenientBase64(bewit))
+	if err != nil {
+		return "", false
+	}
+	parts := bytes.SplitN(decoded, []byte(`\`), 4)
+	if len(parts) != 4 {
+		return "", false
+	}
+	parts[2] = []byte(hm.Base64Normalizer(string(parts[2])))
+	encoded := base64.URLEncoding.EncodeToString(bytes.Join(parts, []byte(`\`)))
+	return strings.TrimRight(encoded, "="), true
+}
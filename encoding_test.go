@@ -0,0 +1,100 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Base64Normalizer", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+
+	credentials := &hawk.Credentials{
+		ID:   "valid-id",
+		Key:  "test-cred-key",
+		Hash: sha256.New,
+	}
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	// urlSafe rewrites a standard base64 value in the constrained client way.
+	urlSafe := func(s string) string {
+		return strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(s), "=")
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("normalizes URL-safe unpadded values", func() {
+		Expect(LenientBase64("ab-_")).To(Equal("ab+/"))
+		Expect(LenientBase64("YQ")).To(Equal("YQ=="))
+		Expect(LenientBase64("YQ==")).To(Equal("YQ=="))
+	})
+
+	headerRequest := func() *http.Request {
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		header := hawk.NewRequestAuth(req, credentials, 0).RequestHeader()
+		header = regexp.MustCompile(`mac="([^"]*)"`).ReplaceAllStringFunc(header, func(m string) string {
+			return `mac="` + urlSafe(m[5:len(m)-1]) + `"`
+		})
+		req.Header.Set("Authorization", header)
+		return req
+	}
+
+	It("is strict by default", func() {
+		resp, err := http.DefaultClient.Do(headerRequest())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).ToNot(Equal(200))
+	})
+
+	It("accepts constrained header encodings", func() {
+		hm.Base64Normalizer = LenientBase64
+		resp, err := http.DefaultClient.Do(headerRequest())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+	})
+
+	It("accepts constrained bewit encodings", func() {
+		hm.Base64Normalizer = LenientBase64
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		auth := hawk.NewRequestAuth(req, credentials, time.Hour)
+		raw, err := base64.RawURLEncoding.DecodeString(auth.Bewit())
+		Expect(err).ToNot(HaveOccurred())
+		parts := strings.SplitN(string(raw), `\`, 4)
+		parts[2] = urlSafe(parts[2])
+		bewit := base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, `\`)))
+		resp, err := http.Get(ts.URL + "/private?bewit=" + bewit)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+	})
+
+})
@@ -0,0 +1,176 @@
+package hawk
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dchest/uniuri"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidCode is set in context.Err when an enrollment code
+// does not exist, has expired or was already used.
+var ErrInvalidCode = errors.New("Invalid enrollment code")
+
+// SetCodeFunc is a function that saves a newly minted enrollment code
+// with the user the enrolled credentials will belong to and the
+// time after which the code cannot be used anymore.
+type SetCodeFunc func(code string, user interface{}, expires time.Time) error
+
+// GetCodeFunc is a function that returns the user and expiration time
+// of an enrollment code without consuming it. If the code is unknown or
+// already burned it returns false.
+type GetCodeFunc func(code string) (interface{}, time.Time, bool, error)
+
+// BurnCodeFunc is a function that consumes an enrollment code.
+// It returns true the first time it's called with a code, false if the
+// code is unknown or already burned.
+type BurnCodeFunc func(code string) (bool, error)
+
+// SetCredentialsFunc is a function that saves newly enrolled credentials
+// so they can later be returned by the GetCredentialFunc.
+type SetCredentialsFunc func(id string, creds *Credentials) error
+
+// Enrollment provides a credential bootstrap flow: an admin mints a
+// single use code, a device exchanges it for fresh credentials and
+// the code is burned once the credentials are saved.
+// SetCode is the SetCodeFunc
+// GetCode is the GetCodeFunc
+// BurnCode is the BurnCodeFunc
+// SetCredentials is the SetCredentialsFunc
+// TTL is the validity of a code, 24 hours by default
+// KeyPolicy if set is checked against the enrolled credentials keys,
+// the DefaultKeyPolicy by default
+// Now if set is the clock of the codes expiration
+type Enrollment struct {
+	SetCode        SetCodeFunc
+	GetCode        GetCodeFunc
+	BurnCode       BurnCodeFunc
+	SetCredentials SetCredentialsFunc
+	TTL            time.Duration
+	KeyPolicy      *KeyPolicy
+	Now            func() time.Time
+}
+
+// NewEnrollment creates a new Enrollment with the SetCode, GetCode,
+// BurnCode and SetCredentials params set.
+func NewEnrollment(scf SetCodeFunc, gcf GetCodeFunc, bcf BurnCodeFunc, scrf SetCredentialsFunc) *Enrollment {
+	policy := DefaultKeyPolicy
+	return &Enrollment{
+		SetCode:        scf,
+		GetCode:        gcf,
+		BurnCode:       bcf,
+		SetCredentials: scrf,
+		TTL:            24 * time.Hour,
+		KeyPolicy:      &policy,
+	}
+}
+
+// now returns the time of Now if set, the wall clock otherwise.
+func (e *Enrollment) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+// NewCode mints and saves a new enrollment code for user.
+func (e *Enrollment) NewCode(user interface{}) (string, time.Time, error) {
+	code := uniuri.NewLen(24)
+	expires := e.now().Add(e.TTL)
+	if err := e.SetCode(code, user, expires); err != nil {
+		return "", time.Time{}, err
+	}
+	return code, expires, nil
+}
+
+// Enroll saves newly created credentials, burns the code and returns
+// the id and key of the credentials. ErrInvalidCode is returned if the
+// code cannot be used. The code is kept if the credentials can't be
+// saved, and the credentials of a concurrent enrollment failing to burn
+// the code are saved but never returned.
+func (e *Enrollment) Enroll(code string) (string, string, error) {
+	user, expires, ok, err := e.GetCode(code)
+	if err != nil {
+		return "", "", err
+	} else if !ok || e.now().After(expires) {
+		return "", "", ErrInvalidCode
+	}
+
+	c := NewCredential(CredentialUser(user))
+	if e.KeyPolicy != nil {
+		if err := e.KeyPolicy.Check(c.Key); err != nil {
+			return "", "", err
+		}
+	}
+	if err := e.SetCredentials(c.ID, c.Credentials()); err != nil {
+		return "", "", err
+	}
+	if ok, err := e.BurnCode(code); err != nil {
+		return "", "", err
+	} else if !ok {
+		return "", "", ErrInvalidCode
+	}
+	return c.ID, c.Key, nil
+}
+
+// CodeHandler is the admin handler that mints enrollment codes.
+// The optional JSON body is used as the user of the enrolled credentials.
+// It should be protected, for example by the Middleware Filter.
+func (e *Enrollment) CodeHandler(c *gin.Context) {
+	var user interface{}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&user); err != nil {
+			return
+		}
+	}
+
+	code, expires, err := e.NewCode(user)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    code,
+		"expires": expires,
+	})
+}
+
+// enrollmentCode returns the "code" of the POST form or JSON body.
+// The query string is ignored so the codes don't end in the access logs.
+func enrollmentCode(c *gin.Context) string {
+	if c.ContentType() == gin.MIMEJSON {
+		var body struct {
+			Code string `json:"code"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			return ""
+		}
+		return body.Code
+	}
+	return c.PostForm("code")
+}
+
+// EnrollHandler is the public handler where devices exchange an
+// enrollment code (the "code" parameter of a POST form or JSON body)
+// for credentials. Codes in the query string are rejected.
+func (e *Enrollment) EnrollHandler(c *gin.Context) {
+	code := enrollmentCode(c)
+	if code == "" {
+		c.AbortWithError(http.StatusBadRequest, ErrInvalidCode)
+		return
+	}
+
+	id, key, err := e.Enroll(code)
+	if err == ErrInvalidCode {
+		c.AbortWithError(http.StatusUnauthorized, err)
+	} else if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+	} else {
+		c.JSON(http.StatusCreated, gin.H{
+			"id":  id,
+			"key": key,
+		})
+	}
+}
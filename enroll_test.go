@@ -0,0 +1,160 @@
+package hawk_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Enrollment", func() {
+
+	type code struct {
+		user    interface{}
+		expires time.Time
+	}
+
+	var codes map[string]code
+	var creds map[string]*Credentials
+	var enrollment *Enrollment
+	var ts *httptest.Server
+	storeErr := errors.New("store error")
+
+	setCode := func(c string, user interface{}, expires time.Time) error {
+		codes[c] = code{user, expires}
+		return nil
+	}
+
+	getCode := func(c string) (interface{}, time.Time, bool, error) {
+		if c == "error-code" {
+			return nil, time.Time{}, false, storeErr
+		}
+		res, exists := codes[c]
+		return res.user, res.expires, exists, nil
+	}
+
+	burnCode := func(c string) (bool, error) {
+		_, exists := codes[c]
+		delete(codes, c)
+		return exists, nil
+	}
+
+	var credsErr error
+	setCredentials := func(id string, c *Credentials) error {
+		if credsErr != nil {
+			return credsErr
+		}
+		creds[id] = c
+		return nil
+	}
+
+	BeforeEach(func() {
+		codes = map[string]code{}
+		creds = map[string]*Credentials{}
+		credsErr = nil
+		enrollment = NewEnrollment(setCode, getCode, burnCode, setCredentials)
+		router := gin.New()
+		router.POST("/codes", enrollment.CodeHandler)
+		router.POST("/enroll", enrollment.EnrollHandler)
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("enrolls with a valid code only once", func() {
+		c, _, err := enrollment.NewCode("device owner")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(codes).To(HaveKey(c))
+
+		id, key, err := enrollment.Enroll(c)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(HaveKey(id))
+		Expect(creds[id].Key).To(Equal(key))
+		Expect(creds[id].User).To(Equal("device owner"))
+
+		_, _, err = enrollment.Enroll(c)
+		Expect(err).To(Equal(ErrInvalidCode))
+	})
+
+	It("rejects expired codes", func() {
+		now := time.Now()
+		enrollment.Now = func() time.Time { return now }
+		c, expires, err := enrollment.NewCode(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(expires).To(Equal(now.Add(24 * time.Hour)))
+		now = now.Add(25 * time.Hour)
+		_, _, err = enrollment.Enroll(c)
+		Expect(err).To(Equal(ErrInvalidCode))
+	})
+
+	It("returns store errors", func() {
+		_, _, err := enrollment.Enroll("error-code")
+		Expect(err).To(Equal(storeErr))
+	})
+
+	It("keeps the code when the credentials can't be saved", func() {
+		c, _, err := enrollment.NewCode("device owner")
+		Expect(err).ToNot(HaveOccurred())
+		credsErr = storeErr
+		_, _, err = enrollment.Enroll(c)
+		Expect(err).To(Equal(storeErr))
+		Expect(codes).To(HaveKey(c))
+
+		credsErr = nil
+		id, _, err := enrollment.Enroll(c)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds).To(HaveKey(id))
+		Expect(codes).ToNot(HaveKey(c))
+	})
+
+	It("mints and exchanges codes over http", func() {
+		resp, err := http.Post(ts.URL+"/codes", "application/json", strings.NewReader(`{"name":"fred"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(201))
+		minted := struct{ Code string }{}
+		Expect(json.NewDecoder(resp.Body).Decode(&minted)).To(Succeed())
+
+		resp, err = http.PostForm(ts.URL+"/enroll", url.Values{"code": {minted.Code}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(201))
+		enrolled := struct{ ID, Key string }{}
+		Expect(json.NewDecoder(resp.Body).Decode(&enrolled)).To(Succeed())
+		Expect(creds[enrolled.ID].Key).To(Equal(enrolled.Key))
+		Expect(creds[enrolled.ID].User).To(Equal(map[string]interface{}{"name": "fred"}))
+
+		resp, err = http.PostForm(ts.URL+"/enroll", url.Values{"code": {minted.Code}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(401))
+	})
+
+	It("requires a code", func() {
+		resp, err := http.PostForm(ts.URL+"/enroll", url.Values{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(400))
+	})
+
+	It("accepts the code of a JSON body only", func() {
+		code, _, err := enrollment.NewCode(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := http.PostForm(ts.URL+"/enroll?code="+code, url.Values{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(400))
+
+		resp, err = http.Post(ts.URL+"/enroll", "application/json", strings.NewReader(`{"code":"`+code+`"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(201))
+	})
+
+})
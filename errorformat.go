@@ -0,0 +1,116 @@
+package hawk
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// ErrorFormat selects how the Middleware renders errors when no
+// AbortHandler is set.
+type ErrorFormat int
+
+const (
+	// ErrorDefault lets gin render the error (AbortWithError).
+	ErrorDefault ErrorFormat = iota
+	// ErrorJSON renders a JSON body with a stable error code:
+	// {"error":"invalid_mac","message":"invalid MAC"}
+	ErrorJSON
+)
+
+// ErrorInternal is the code of the errors not known by the Middleware.
+const ErrorInternal = "internal_error"
+
+// ErrorMalformed is the code of the malformed "Authorization" headers
+// and bewits (protocol AuthFormatError).
+const ErrorMalformed = "malformed_auth"
+
+// ErrorInvalidCredentials is the public code of the unknown, revoked and
+// disabled credentials, so clients can't probe the credentials ids.
+const ErrorInvalidCredentials = "invalid_credentials"
+
+// publicCodes are the codes collapsed by PublicErrorCode.
+var publicCodes = map[string]string{
+	"credentials_not_found": ErrorInvalidCredentials,
+	"credentials_revoked":   ErrorInvalidCredentials,
+	"principal_disabled":    ErrorInvalidCredentials,
+}
+
+var errorCodes = map[error]string{
+	ErrNotFound:                "credentials_not_found",
+	ErrInvalidApp:              "invalid_app",
+	ErrInvalidExt:              "invalid_ext",
+	ErrCredentialsExpired:      "credentials_expired",
+	ErrCredentialsRevoked:      "credentials_revoked",
+	ErrPrincipalDisabled:       "principal_disabled",
+	ErrInvalidPayloadHash:      "invalid_payload_hash",
+	ErrMissingPayloadHash:      "missing_payload_hash",
+	ErrBodyTooLarge:            "body_too_large",
+	ErrTLSRequired:             "tls_required",
+	ErrHeaderTooLarge:          "header_too_large",
+	ErrUnknownAttribute:        "unknown_attribute",
+	ErrRateLimited:             "rate_limited",
+	ErrLockedOut:               "locked_out",
+	ErrBanned:                  "banned",
+	ErrSlowBody:                "slow_body",
+	ErrMaintenance:             "maintenance",
+	ErrFallbackDenied:          "fallback_denied",
+	ErrUnknownTenant:           "unknown_tenant",
+	hawk.ErrBewitExpired:       "bewit_expired",
+	hawk.ErrInvalidBewitMethod: "invalid_bewit_method",
+	hawk.ErrInvalidMAC:         "invalid_mac",
+	hawk.ErrMissingServerAuth:  "missing_server_auth",
+	hawk.ErrNoAuth:             "no_auth",
+	hawk.ErrReplay:             "replay",
+	hawk.ErrTimestampSkew:      "timestamp_skew",
+}
+
+// ErrorCode returns the stable machine readable code of err,
+// ErrorInternal if err is not an error of the Middleware.
+func ErrorCode(err error) string {
+	if code, ok := errorCodes[cause(err)]; ok {
+		return code
+	}
+	var formatErr hawk.AuthFormatError
+	if errors.As(err, &formatErr) {
+		return ErrorMalformed
+	}
+	return ErrorInternal
+}
+
+// PublicErrorCode returns the ErrorCode of err to send to the clients,
+// ErrorInvalidCredentials for the unknown, revoked and disabled
+// credentials. The ErrorCode is logged and audited.
+func PublicErrorCode(err error) string {
+	code := ErrorCode(err)
+	if public, ok := publicCodes[code]; ok {
+		return public
+	}
+	return code
+}
+
+// renderError aborts the request with the status of err in the
+// Middleware ErrorFormat. Messages of internal errors are not sent
+// to the client but the error is always set in the context.
+func (hm *Middleware) renderError(c *gin.Context, err error) {
+	status := hm.ErrorStatus(err)
+	if hm.ErrorFormat != ErrorJSON {
+		c.AbortWithError(status, err)
+		return
+	}
+
+	c.Error(err)
+	code := PublicErrorCode(err)
+	msg := err.Error()
+	if code == ErrorInternal {
+		msg = http.StatusText(status)
+	} else if code == ErrorInvalidCredentials {
+		msg = "Invalid credentials"
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":   code,
+		"message": msg,
+	})
+}
@@ -0,0 +1,125 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ErrorFormat", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var ctxErr error
+	storeErr := errors.New("store error: db password leaked")
+
+	getCredentials := func(id string) (*Credentials, error) {
+		switch id {
+		case "error-id":
+			return nil, storeErr
+		case "unknown-id":
+			return nil, nil
+		}
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		ctxErr = nil
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.ErrorFormat = ErrorJSON
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			ctxErr = c.Errors.Last()
+		})
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(id, key string) (int, map[string]string) {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		if id != "" {
+			auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+				ID:   id,
+				Key:  key,
+				Hash: sha256.New,
+			}, 0)
+			req.Header.Set("Authorization", auth.RequestHeader())
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		if resp.StatusCode == 200 {
+			return 200, nil
+		}
+		Expect(resp.Header.Get("Content-Type")).To(HavePrefix("application/json"))
+		body := map[string]string{}
+		Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+		return resp.StatusCode, body
+	}
+
+	It("renders authentication errors with a code", func() {
+		status, body := request("id", "bad-key")
+		Expect(status).To(Equal(401))
+		Expect(body).To(Equal(map[string]string{
+			"error":   "invalid_mac",
+			"message": hawk.ErrInvalidMAC.Error(),
+		}))
+
+		status, body = request("unknown-id", "test-cred-key")
+		Expect(status).To(Equal(401))
+		Expect(body).To(Equal(map[string]string{
+			"error":   ErrorInvalidCredentials,
+			"message": "Invalid credentials",
+		}))
+		Expect(ErrorCode(ctxErr)).To(Equal("credentials_not_found"))
+
+		status, body = request("", "")
+		Expect(status).To(Equal(401))
+		Expect(body["error"]).To(Equal("no_auth"))
+
+		status, _ = request("id", "test-cred-key")
+		Expect(status).To(Equal(200))
+	})
+
+	It("does not leak internal error messages", func() {
+		status, body := request("error-id", "test-cred-key")
+		Expect(status).To(Equal(503))
+		Expect(body).To(Equal(map[string]string{
+			"error":   ErrorInternal,
+			"message": "Service Unavailable",
+		}))
+		Expect(errors.Is(ctxErr, storeErr)).To(BeTrue())
+	})
+
+	It("has stable codes", func() {
+		Expect(ErrorCode(hawk.ErrReplay)).To(Equal("replay"))
+		Expect(ErrorCode(ErrRateLimited)).To(Equal("rate_limited"))
+		Expect(ErrorCode(storeErr)).To(Equal(ErrorInternal))
+		Expect(PublicErrorCode(ErrCredentialsRevoked)).To(Equal(ErrorInvalidCredentials))
+		Expect(PublicErrorCode(ErrPrincipalDisabled)).To(Equal(ErrorInvalidCredentials))
+		Expect(PublicErrorCode(hawk.ErrReplay)).To(Equal("replay"))
+	})
+
+})
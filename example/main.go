@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -29,7 +30,7 @@ func getCredentials(id string) (*hawk.Credentials, error) {
 // already exists. If none then returns true and save it so it cannot be
 // replayed.
 func setNonce(id string, nonce string, t time.Time) (bool, error) {
-	key := fmt.Sprintf("%s.%s.%i", id, nonce, t.Unix())
+	key := fmt.Sprintf("%s.%s.%d", id, nonce, t.Unix())
 	if _, exists := nonces[key]; exists {
 		return false, nil
 	}
@@ -59,9 +60,12 @@ func main() {
 	})
 
 	// Create a cred for a user
-	id, key := hawk.GenIDKey()
-	creds[id] = hawk.Credentials{
-		Key: key,
+	id, key, err := hawk.GenerateCredentials(12, 24, hawk.CharsetAlphanumeric)
+	if err != nil {
+		log.Fatal(err)
+	}
+	creds[string(id)] = hawk.Credentials{
+		Key: string(key),
 		User: struct {
 			Name string
 		}{"Fred"},
@@ -0,0 +1,25 @@
+package hawk
+
+import (
+	"errors"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// ErrInvalidExt should be returned by a ValidateExtFunc to reject
+// the "ext" field sent by the client.
+var ErrInvalidExt = errors.New("Invalid ext")
+
+// ValidateExtFunc is a function that inspects the "ext" field sent by
+// the client in the header or the bewit. It returns ErrInvalidExt to
+// reject the request. Any other error is an external problem and it will
+// be set as the context error.
+type ValidateExtFunc func(ext string) error
+
+// validateExt calls the ValidateExt callback if set.
+func (hm *Middleware) validateExt(auth *hawk.Auth) error {
+	if hm.ValidateExt == nil {
+		return nil
+	}
+	return hm.ValidateExt(auth.Ext)
+}
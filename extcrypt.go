@@ -0,0 +1,133 @@
+package hawk
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidEncryptedExt is returned when an encrypted ext cannot be
+// decrypted, because it was not encrypted or was encrypted with another
+// key or for the other direction.
+var ErrInvalidEncryptedExt = errors.New("Invalid encrypted ext")
+
+// encryptedExtPrefix is the version prefix of encrypted ext values.
+const encryptedExtPrefix = "e1."
+
+const (
+	requestExtInfo  = "hawk ext request"
+	responseExtInfo = "hawk ext response"
+)
+
+// extCipher returns the AES-256-GCM cipher derived from the Hawk key
+// for a direction, so a response ext cannot be replayed as a request ext.
+func extCipher(key, info string) (cipher.AEAD, error) {
+	derived, err := hkdf.Key(sha256.New, []byte(key), nil, info, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptExt(key, info string, plaintext []byte) (string, error) {
+	aead, err := extCipher(key, info)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	return encryptedExtPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func decryptExt(key, info, ext string) ([]byte, error) {
+	if !strings.HasPrefix(ext, encryptedExtPrefix) {
+		return nil, ErrInvalidEncryptedExt
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(ext[len(encryptedExtPrefix):])
+	if err != nil {
+		return nil, ErrInvalidEncryptedExt
+	}
+	aead, err := extCipher(key, info)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidEncryptedExt
+	}
+	res, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrInvalidEncryptedExt
+	}
+	return res, nil
+}
+
+// EncryptExt encrypts plaintext with a key derived from the Hawk key,
+// for a client to send in the request ext. The result is header safe.
+func EncryptExt(key string, plaintext []byte) (string, error) {
+	return encryptExt(key, requestExtInfo, plaintext)
+}
+
+// DecryptExt decrypts a request ext encrypted with EncryptExt.
+func DecryptExt(key, ext string) ([]byte, error) {
+	return decryptExt(key, requestExtInfo, ext)
+}
+
+// EncryptResponseExt encrypts plaintext for a response ext.
+func EncryptResponseExt(key string, plaintext []byte) (string, error) {
+	return encryptExt(key, responseExtInfo, plaintext)
+}
+
+// DecryptResponseExt decrypts a response ext, for clients.
+func DecryptResponseExt(key, ext string) ([]byte, error) {
+	return decryptExt(key, responseExtInfo, ext)
+}
+
+// DecryptedExt returns the decrypted ext of the request, it must be
+// called after the Filter.
+func DecryptedExt(c *gin.Context) ([]byte, error) {
+	auth, ok := AuthFromContext(c)
+	if !ok {
+		return nil, ErrInvalidEncryptedExt
+	}
+	key, err := keyFromContext(c, auth)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptExt(key, auth.Ext)
+}
+
+// SetEncryptedExt replaces the "Server-Authorization" header with one
+// sending plaintext encrypted in the ext. It must be called after the
+// Filter and before the response is written.
+func (hm *Middleware) SetEncryptedExt(c *gin.Context, plaintext []byte) error {
+	auth, ok := AuthFromContext(c)
+	if !ok {
+		return ErrInvalidEncryptedExt
+	}
+	key, err := keyFromContext(c, auth)
+	if err != nil {
+		return err
+	}
+	ext, err := EncryptResponseExt(key, plaintext)
+	if err != nil {
+		return err
+	}
+	withKey := *auth
+	withKey.Credentials.Key = key
+	c.Header("Server-Authorization", responseHeader(&withKey, newExtParts(ext)))
+	return nil
+}
@@ -0,0 +1,102 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encrypted ext", func() {
+
+	It("encrypts and decrypts", func() {
+		ext, err := EncryptExt("test-cred-key", []byte("shard=42"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ext).To(HavePrefix("e1."))
+		Expect(ext).ToNot(ContainSubstring("shard"))
+		other, err := EncryptExt("test-cred-key", []byte("shard=42"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(other).ToNot(Equal(ext))
+
+		Expect(DecryptExt("test-cred-key", ext)).To(Equal([]byte("shard=42")))
+
+		_, err = DecryptExt("other-key", ext)
+		Expect(err).To(Equal(ErrInvalidEncryptedExt))
+		_, err = DecryptResponseExt("test-cred-key", ext)
+		Expect(err).To(Equal(ErrInvalidEncryptedExt))
+		_, err = DecryptExt("test-cred-key", "shard=42")
+		Expect(err).To(Equal(ErrInvalidEncryptedExt))
+		_, err = DecryptExt("test-cred-key", "e1.!!")
+		Expect(err).To(Equal(ErrInvalidEncryptedExt))
+		_, err = DecryptExt("test-cred-key", "e1.AAAA")
+		Expect(err).To(Equal(ErrInvalidEncryptedExt))
+	})
+
+	sendEncryptedExt := func(strict bool) {
+		const key = "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn"
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			// the client signs with a rotated key
+			return &Credentials{Key: "new-" + key, Keys: []string{key}}, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		hm.StrictMode = strict
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			Expect(GetAuth(c).Credentials.Key == "").To(Equal(strict))
+			hint, err := DecryptedExt(c)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hm.SetEncryptedExt(c, append(hint, []byte(" ok")...))).To(Succeed())
+			c.String(200, "ok")
+		})
+		router.GET("/public", func(c *gin.Context) {
+			_, err := DecryptedExt(c)
+			Expect(err).To(Equal(ErrInvalidEncryptedExt))
+			Expect(hm.SetEncryptedExt(c, nil)).To(Equal(ErrInvalidEncryptedExt))
+			c.String(200, "ok")
+		})
+		ts := httptest.NewServer(router)
+		defer ts.Close()
+
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "id",
+			Key:  key,
+			Hash: sha256.New,
+		}, 0)
+		auth.Ext, err = EncryptExt(key, []byte("shard=42"))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+
+		header := resp.Header.Get("Server-Authorization")
+		Expect(auth.ValidResponse(header)).To(Succeed())
+		Expect(header).ToNot(ContainSubstring("shard"))
+		Expect(DecryptResponseExt(key, auth.Ext)).To(Equal([]byte("shard=42 ok")))
+
+		resp, err = http.Get(ts.URL + "/public")
+		Expect(err).ToNot(HaveOccurred())
+		b, _ := ioutil.ReadAll(resp.Body)
+		Expect(string(b)).To(Equal("ok"))
+	}
+
+	for _, strict := range []bool{false, true} {
+		strict := strict
+		It(fmt.Sprintf("sends encrypted ext in both directions (StrictMode %v)", strict), func() {
+			sendEncryptedExt(strict)
+		})
+	}
+
+})
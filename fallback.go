@@ -0,0 +1,59 @@
+package hawk
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FallbackKey is the context key set to true when a request was
+// authenticated by the FallbackAuthenticator.
+const FallbackKey = "hawk_fallback"
+
+// ErrFallbackDenied should be returned by a FallbackAuthenticator to
+// reject a request (missing or invalid token for example).
+var ErrFallbackDenied = errors.New("Fallback authentication denied")
+
+// FallbackAuthenticator authenticates the requests sent without a Hawk
+// "Authorization" header nor a bewit, a bearer token or JWT scheme during
+// a migration to Hawk for example. Authenticate returns the user set in
+// the context or ErrFallbackDenied to reject the request. Any other error
+// is an external problem and it will be set as the context error.
+type FallbackAuthenticator interface {
+	Authenticate(c *gin.Context) (interface{}, error)
+}
+
+// FallbackAuthenticatorFunc is a function implementing FallbackAuthenticator.
+type FallbackAuthenticatorFunc func(c *gin.Context) (interface{}, error)
+
+// Authenticate calls f.
+func (f FallbackAuthenticatorFunc) Authenticate(c *gin.Context) (interface{}, error) {
+	return f(c)
+}
+
+// isHawk returns true if c has a Hawk "Authorization" header or a bewit.
+func (hm *Middleware) isHawk(c *gin.Context) bool {
+	header := c.GetHeader("Authorization")
+	return len(header) >= 5 && strings.EqualFold(header[:5], "hawk ") || hm.bewit(c.Request) != ""
+}
+
+// fallback authenticates c with the FallbackAuthenticator. Only the
+// user is set in the context, not the auth (see GetAuth).
+func (hm *Middleware) fallback(c *gin.Context, hr *Request) {
+	user, err := hm.FallbackAuthenticator.Authenticate(c)
+	hm.done(c, hr, nil, err)
+	if err != nil {
+		hm.Abortequest(c, err, nil)
+		return
+	}
+	c.Set(UserKey, user)
+	c.Set(FallbackKey, true)
+	c.Next()
+}
+
+// FallbackFromContext returns true if the request was authenticated by
+// the FallbackAuthenticator.
+func FallbackFromContext(c *gin.Context) bool {
+	return c.GetBool(FallbackKey)
+}
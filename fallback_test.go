@@ -0,0 +1,91 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FallbackAuthenticator", func() {
+
+	var ts *httptest.Server
+	var user interface{}
+	var fallback bool
+
+	BeforeEach(func() {
+		user, fallback = nil, false
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{Key: "test-cred-key", User: "hawk-user"}, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		hm.FallbackAuthenticator = FallbackAuthenticatorFunc(func(c *gin.Context) (interface{}, error) {
+			switch c.GetHeader("Authorization") {
+			case "Bearer valid-token":
+				return "jwt-user", nil
+			case "Bearer error-token":
+				return nil, errors.New("jwks error")
+			}
+			return nil, ErrFallbackDenied
+		})
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			user, _ = UserFromContext(c)
+			fallback = FallbackFromContext(c)
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(header string) int {
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode
+	}
+
+	It("authenticates requests without Hawk", func() {
+		Expect(request("Bearer valid-token")).To(Equal(200))
+		Expect(user).To(Equal("jwt-user"))
+		Expect(fallback).To(BeTrue())
+
+		Expect(request("Bearer invalid-token")).To(Equal(401))
+		Expect(request("")).To(Equal(401))
+		Expect(request("Bearer error-token")).To(Equal(500))
+	})
+
+	It("authenticates Hawk requests with Hawk only", func() {
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "my-id",
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, 0)
+		header := auth.RequestHeader()
+		Expect(request(header)).To(Equal(200))
+		Expect(user).To(Equal("hawk-user"))
+		Expect(fallback).To(BeFalse())
+
+		Expect(request(`hawk id="my-id", mac="AAAA", ts="1", nonce="n"`)).To(Equal(401))
+		Expect(fallback).To(BeFalse())
+
+		Expect(ErrorCode(ErrFallbackDenied)).To(Equal("fallback_denied"))
+	})
+
+})
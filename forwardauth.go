@@ -0,0 +1,87 @@
+package hawk
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forwardedRequest returns the original request forwarded by a Traefik
+// ForwardAuth ("X-Forwarded-Method" and "X-Forwarded-Uri") or an NGINX
+// auth_request ("X-Original-Method" and "X-Original-URI").
+func forwardedRequest(r *http.Request) (*http.Request, error) {
+	method := firstHeader(r, "X-Forwarded-Method")
+	if method == "" {
+		method = firstHeader(r, "X-Original-Method")
+	}
+	if method == "" {
+		method = r.Method
+	}
+	uri := r.Header.Get("X-Forwarded-Uri")
+	if uri == "" {
+		uri = r.Header.Get("X-Original-URI")
+	}
+	if uri == "" {
+		uri = "/"
+	}
+	u, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	res := r.Clone(r.Context())
+	res.Method = method
+	res.URL = u
+	res.RequestURI = uri
+	res.Body = http.NoBody
+	res.ContentLength = 0
+	return res, nil
+}
+
+// ForwardAuthHandler returns a handler validating the original requests
+// forwarded by a Traefik ForwardAuth or an NGINX auth_request. The host,
+// port and scheme are read from the "X-Forwarded-*" headers and the
+// payloads are not validated (the bodies are not forwarded). Verified
+// requests get a 200 with the "X-Hawk-Id", "X-Hawk-Scopes",
+// "X-Hawk-User" (if the user is a string or a fmt.Stringer) and
+// "Server-Authorization" headers to copy to the upstream request.
+// The TrustedProxies must be set to the addresses of the proxy, the
+// "X-Forwarded-*" headers of other peers are ignored. The client IP of
+// the BanPolicy, FailureMetrics and logs is read from its
+// "X-Forwarded-For" header:
+//
+//	hm.TrustedProxies = []string{"10.0.0.0/8"}
+//	router.GET("/auth", hm.ForwardAuthHandler())
+func (hm *Middleware) ForwardAuthHandler() gin.HandlerFunc {
+	fa := hm.With(PayloadValidation(false), func(m *Middleware) {
+		m.TrustProxyHeaders = true
+		m.RequireHashFor = nil
+	})
+	return func(c *gin.Context) {
+		r, err := forwardedRequest(c.Request)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		res, err := fa.Verify(r)
+		if err != nil {
+			fa.Abortequest(c, err, nil)
+			return
+		}
+		c.Header("X-Hawk-Id", res.ID)
+		if len(res.Scopes) > 0 {
+			c.Header("X-Hawk-Scopes", strings.Join(res.Scopes, ","))
+		}
+		switch u := res.User.(type) {
+		case string:
+			c.Header("X-Hawk-User", u)
+		case fmt.Stringer:
+			c.Header("X-Hawk-User", u.String())
+		}
+		c.Header("Server-Authorization", res.ServerAuthorization)
+		c.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,113 @@
+package hawk_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ForwardAuthHandler", func() {
+
+	var router *gin.Engine
+	var hm *Middleware
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key").Scopes = []string{"read", "write"}
+		hm = store.Middleware()
+		hm.ValidatePayload = true
+		// the address of the httptest requests
+		hm.TrustedProxies = []string{"192.0.2.1"}
+		router = gin.New()
+		router.GET("/auth", hm.ForwardAuthHandler())
+	})
+
+	// forward signs the original request and returns the forward auth
+	// request of the proxy.
+	forward := func(method, original string, traefik bool) *http.Request {
+		orig := httptest.NewRequest(method, original, nil)
+		_, err := hawktest.SignRequest(orig, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+
+		req := httptest.NewRequest("GET", "http://auth.internal/auth", nil)
+		req.Header.Set("Authorization", orig.Header.Get("Authorization"))
+		req.Header.Set("X-Forwarded-Proto", orig.URL.Scheme)
+		req.Header.Set("X-Forwarded-Host", orig.URL.Host)
+		if traefik {
+			req.Header.Set("X-Forwarded-Method", method)
+			req.Header.Set("X-Forwarded-Uri", orig.URL.RequestURI())
+		} else {
+			req.Header.Set("X-Original-Method", method)
+			req.Header.Set("X-Original-URI", orig.URL.RequestURI())
+		}
+		return req
+	}
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	It("validates the forwarded requests", func() {
+		w := serve(forward("POST", "https://api.example.com/files?a=1", true))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("X-Hawk-Id")).To(Equal("my-id"))
+		Expect(w.Header().Get("X-Hawk-User")).To(Equal("my-id"))
+		Expect(w.Header().Get("X-Hawk-Scopes")).To(Equal("read,write"))
+		Expect(w.Header().Get("Server-Authorization")).ToNot(BeEmpty())
+
+		w = serve(forward("DELETE", "http://api.example.com:8080/files/a", false))
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("rejects invalid requests", func() {
+		req := forward("POST", "https://api.example.com/files", true)
+		req.Header.Set("X-Forwarded-Uri", "/other")
+		Expect(serve(req).Code).To(Equal(http.StatusUnauthorized))
+
+		req = forward("POST", "https://api.example.com/files", true)
+		req.Header.Set("X-Forwarded-Method", "GET")
+		Expect(serve(req).Code).To(Equal(http.StatusUnauthorized))
+
+		w := serve(httptest.NewRequest("GET", "http://auth.internal/auth", nil))
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		Expect(w.Header().Get("X-Hawk-Id")).To(BeEmpty())
+	})
+
+	It("ignores the forwarded headers of other peers", func() {
+		req := forward("POST", "https://api.example.com/files", true)
+		req.RemoteAddr = "203.0.113.9:1234"
+		Expect(serve(req).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("reads the client IP from the TrustedProxies", func() {
+		hm.FailureMetrics = NewFailureMetrics(time.Minute)
+		hm.TrustedProxies = []string{"10.0.0.0/8"}
+		router = gin.New()
+		router.GET("/auth", hm.ForwardAuthHandler())
+
+		req := forward("POST", "https://api.example.com/files", true)
+		req.Header.Set("X-Forwarded-Uri", "/other")
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		req.RemoteAddr = "10.0.0.1:1234"
+		Expect(serve(req).Code).To(Equal(http.StatusUnauthorized))
+		Expect(hm.FailureMetrics.IPFailures("203.0.113.9")).To(Equal(1))
+
+		req = forward("POST", "https://api.example.com/files", true)
+		req.Header.Set("X-Forwarded-Uri", "/other")
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		req.RemoteAddr = "192.0.2.1:1234"
+		Expect(serve(req).Code).To(Equal(http.StatusUnauthorized))
+		Expect(hm.FailureMetrics.IPFailures("192.0.2.1")).To(Equal(1))
+		Expect(hm.FailureMetrics.IPFailures("203.0.113.9")).To(Equal(1))
+	})
+
+})
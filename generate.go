@@ -0,0 +1,107 @@
+package hawk
+
+import (
+	"crypto/rand"
+	"errors"
+	"math"
+	"math/big"
+)
+
+// ErrInvalidCharset is returned by GenerateCredentials when the charset
+// has less than 2 characters, duplicates or non ASCII characters.
+var ErrInvalidCharset = errors.New("Invalid charset")
+
+// ErrWeakCredentials is returned by GenerateCredentials when the lengths
+// and charset cannot reach MinIDEntropy or MinSecretEntropy.
+var ErrWeakCredentials = errors.New("Generated credentials are too weak")
+
+// Minimum entropy in bits of the values generated by GenerateCredentials.
+// IDs are not secret but must not collide.
+const (
+	MinIDEntropy     = 64
+	MinSecretEntropy = 128
+)
+
+// Charset is the set of characters of generated ids and keys.
+type Charset string
+
+// Predefined charsets.
+const (
+	CharsetAlphanumeric Charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	CharsetURLSafe      Charset = CharsetAlphanumeric + "-_"
+	CharsetHex          Charset = "0123456789abcdef"
+)
+
+// Entropy returns the entropy in bits of n random characters of c.
+func (c Charset) Entropy(n int) float64 {
+	return float64(n) * math.Log2(float64(len(c)))
+}
+
+// valid returns true if c has at least 2 distinct ASCII characters.
+func (c Charset) valid() bool {
+	if len(c) < 2 {
+		return false
+	}
+	seen := map[byte]bool{}
+	for i := 0; i < len(c); i++ {
+		if c[i] > 127 || seen[c[i]] {
+			return false
+		}
+		seen[c[i]] = true
+	}
+	return true
+}
+
+// random returns n uniformly distributed characters of c read from crypto/rand.
+func (c Charset) random(n int) (string, error) {
+	max := big.NewInt(int64(len(c)))
+	res := make([]byte, n)
+	for i := range res {
+		j, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		res[i] = c[j.Int64()]
+	}
+	return string(res), nil
+}
+
+// ID is a generated credentials id.
+type ID string
+
+// Secret is a generated credentials key. It is redacted when formatted
+// to avoid leaking it in logs, use string(secret) to read it.
+type Secret string
+
+// String returns a redacted value.
+func (s Secret) String() string {
+	return "[REDACTED]"
+}
+
+// GoString returns a redacted value.
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// GenerateCredentials generates a random id of idLen and key of keyLen
+// characters of charset with crypto/rand. ErrWeakCredentials is returned
+// if the id entropy is below MinIDEntropy or the key entropy below
+// MinSecretEntropy:
+//
+//	id, key, err := hawk.GenerateCredentials(12, 24, hawk.CharsetAlphanumeric)
+func GenerateCredentials(idLen, keyLen int, charset Charset) (ID, Secret, error) {
+	if !charset.valid() {
+		return "", "", ErrInvalidCharset
+	} else if charset.Entropy(idLen) < MinIDEntropy || charset.Entropy(keyLen) < MinSecretEntropy {
+		return "", "", ErrWeakCredentials
+	}
+	id, err := charset.random(idLen)
+	if err != nil {
+		return "", "", err
+	}
+	key, err := charset.random(keyLen)
+	if err != nil {
+		return "", "", err
+	}
+	return ID(id), Secret(key), nil
+}
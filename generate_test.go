@@ -0,0 +1,57 @@
+package hawk_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/hyperboloide/hawk"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GenerateCredentials", func() {
+
+	It("generates ids and keys from the charset", func() {
+		id, key, err := GenerateCredentials(16, 32, CharsetHex)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(id).To(HaveLen(16))
+		Expect(key).To(HaveLen(32))
+		Expect(strings.Trim(string(id)+string(key), string(CharsetHex))).To(BeEmpty())
+
+		id2, key2, err := GenerateCredentials(16, 32, CharsetHex)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(id2).ToNot(Equal(id))
+		Expect(key2).ToNot(Equal(key))
+	})
+
+	It("rejects weak lengths", func() {
+		_, _, err := GenerateCredentials(12, 16, CharsetAlphanumeric)
+		Expect(err).To(Equal(ErrWeakCredentials))
+		_, _, err = GenerateCredentials(8, 24, CharsetAlphanumeric)
+		Expect(err).To(Equal(ErrWeakCredentials))
+		_, _, err = GenerateCredentials(12, 24, CharsetAlphanumeric)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects invalid charsets", func() {
+		for _, c := range []Charset{"", "a", "aab", "é0123"} {
+			_, _, err := GenerateCredentials(100, 200, c)
+			Expect(err).To(Equal(ErrInvalidCharset))
+		}
+	})
+
+	It("computes the charset entropy", func() {
+		Expect(CharsetHex.Entropy(32)).To(BeNumerically("==", 128))
+		Expect(CharsetURLSafe.Entropy(2)).To(BeNumerically("==", 12))
+	})
+
+	It("redacts secrets", func() {
+		_, key, err := GenerateCredentials(12, 24, CharsetURLSafe)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fmt.Sprint(key)).To(Equal("[REDACTED]"))
+		Expect(fmt.Sprintf("%#v", key)).To(Equal("[REDACTED]"))
+		Expect(string(key)).To(HaveLen(24))
+	})
+
+})
@@ -1,30 +1,73 @@
 package hawk
 
 import (
-	"crypto/sha256"
+	"context"
 	"errors"
+	"hash"
 	"net/http"
 	"time"
 
-	"github.com/dchest/uniuri"
 	"github.com/gin-gonic/gin"
-	hawk "github.com/tent/hawk-go"
+	"github.com/hyperboloide/hawk/noncestore/memory"
+	hawk "github.com/hyperboloide/hawk/protocol"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	AuthKey = "hawk_auth"
-	UserKey = "hawk_user"
+	AuthKey        = "hawk_auth"
+	UserKey        = "hawk_user"
+	DiagnosticsKey = "hawk_diagnostics"
+	AppKey         = "hawk_app"
+	DelegateKey    = "hawk_dlg"
 )
 
 // ErrNotFound is set in context.Err if the GetCredentialFunc
 // returns nil
 var ErrNotFound = errors.New("Credentials not found")
 
+// ErrInvalidKey is set in context.Err if the GetCredentialFunc
+// returns credentials with an empty key or a key shorter than
+// the Middleware MinKeyLength (StrictMinKeyLength in StrictMode).
+// It's a configuration error.
+var ErrInvalidKey = errors.New("Credentials key is empty or too short")
+
+// ErrCredentialsExpired is set in context.Err if the credentials
+// returned by the GetCredentialFunc are expired.
+var ErrCredentialsExpired = errors.New("Credentials expired")
+
+// ErrCredentialsRevoked is set in context.Err if the credentials
+// returned by the GetCredentialFunc are revoked.
+var ErrCredentialsRevoked = errors.New("Credentials revoked")
+
 // Credentials is used to store a key string and a User object.
 // It is returned by a function of type GetCredentialFunc.
+// Keys are previous keys still accepted while the Key is rotated.
+// Algorithm is the name of the MAC algorithm ("sha1", "sha256" or "sha512"),
+// the Middleware Algorithm if empty.
+// Hash is the MAC algorithm function, it takes precedence over Algorithm.
+// PayloadHash is the payload hash algorithm, Hash if nil.
+// ExpiresAt if not zero is the time after which the credentials are rejected.
+// Revoked if true rejects the credentials.
+// Principal is the account (user, organization...) owning the credentials,
+// checked by the Middleware PrincipalChecker.
+// Scopes are set in the context (see ScopesFromContext) and access logs.
+// Deprecation, Sunset and DeprecationLink flag legacy credentials (old key
+// version, deprecated scopes...), see the Middleware DeprecationHeaders.
 type Credentials struct {
-	Key  string
-	User interface{}
+	Key         string
+	Keys        []string
+	User        interface{}
+	Algorithm   string
+	Hash        func() hash.Hash
+	PayloadHash func() hash.Hash
+	ExpiresAt   time.Time
+	Revoked     bool
+	Principal   string
+	Scopes      []string
+
+	Deprecation     time.Time
+	Sunset          time.Time
+	DeprecationLink string
 }
 
 // GetCredentialFunc is a function that returns a *Credentials by id.
@@ -39,40 +82,201 @@ type GetCredentialFunc func(id string) (*Credentials, error)
 // an the nonce should be save to avoid replay problems.
 type SetNonceFunc func(id string, nonce string, t time.Time) (bool, error)
 
+// AbortHandlerFunc handles the rejected requests, the error is the
+// original one (use Classify for its kind).
 type AbortHandlerFunc func(*gin.Context, error)
 
+// AbortRequestHandlerFunc is an AbortHandlerFunc also receiving the auth
+// of the request (nil if not parsed) and the Request state of the Filter
+// (nil outside of it) for the credentials id and user. Both are copies
+// safe to keep after the call, the auth without the credentials key. The
+// "Server-Authorization" header is already set when possible.
+type AbortRequestHandlerFunc func(c *gin.Context, err *AuthError, auth *hawk.Auth, hr *Request)
+
 // Middleware is the middleware object.
 // GetCredentials is the GetCredentialFunc
+// CredentialProvider if set replaces GetCredentials, it's closed by Close
+// GetTenantCredentials if set is used instead of GetCredentials with the tenant of the TenantResolver, the CredentialProvider takes precedence
+// TenantResolver if set resolves the tenant of the requests, set in the context (see TenantFromContext). It requires GetTenantCredentials or a TenantCredentialProvider, otherwise the requests are rejected with ErrNoTenantCredentials
+// Pingers are the other stores checked by HealthCheck, the current CredentialProvider and NonceStore are checked if they implement Pinger
 // SetNonce is the SetNonceFunc
+// NonceFailure sets if requests are rejected (default) or accepted when the SetNonceFunc fails
+// OnNonceError if set is called with the credentials id and the SetNonceFunc errors
+// RequireNonce if true rejects the requests with ErrNoNonceStore if SetNonce is nil, otherwise they are accepted without replay check. NewMiddleware and Verify set it
+// SkipNonceForBewit has no effect, the bewits have no nonce and are accepted without nonce store
+// NonceWindow if set rejects the timestamps further than NonceWindow from now before calling SetNonce, the stores only need to keep the nonces for NonceWindow
+// AbortHandler if set is called with the errors instead of rendering them
+// AbortRequestHandler if set replaces the AbortHandler and also receives the auth and Request state
 // UserParam if set will set the user in the context with a matching key
-// Ext add an "ext" header in the request
+// Algorithm is the default MAC algorithm name, "sha256" if empty
+// Now if set is the clock used to check timestamps skew, bewits and credentials expiry
+// Ext add an "ext" header in the response
+// DeprecationHeaders if true sends "Deprecation", "Sunset" and "Link" headers for deprecated credentials
+// SkipFunc if set and returning true lets the request through unauthenticated
+// FallbackAuthenticator if set authenticates the requests without Hawk authentication
+// BewitParam is the query parameter of the bewits, "bewit" if empty
+// BewitHeader if set is a header accepted to send the bewit instead of the query
+// StripBewit if true removes the bewit parameter from the request URL before calling the handlers (c.Query must not be used before the Filter)
+// ValidatePayload if true checks the body against the payload hash when sent
+// StreamPayload if true checks the payload before the handlers while spooling large bodies to a temporary file instead of buffering them in memory
+// MaxBodySize if set is the maximum body size during payload validation
+// ContentTypeNormalizer if set replaces NormalizeContentType to hash the "Content-Type" header with the payload
+// BodyReadTimeout if set is the maximum time to read the body during payload validation
+// MinBodyRate if set is the minimum body transfer rate in bytes/s during payload validation
+// RequirePayloadHash if true rejects write requests (not GET, HEAD or OPTIONS) without a payload hash
+// RequireHashFor if set lists the methods of the requests that must send a payload hash matching the body, even without ValidatePayload
+// RequireTLS if true rejects requests not received over TLS (or forwarded from https by the TrustedProxies with TrustProxyHeaders)
+// MaxSkew if set is the maximum timestamp skew instead of the protocol one
+// MaxHeaderSize if set is the maximum length of the "Authorization" header
+// RejectUnknownAttributes if true rejects "Authorization" headers with unrecognized attributes
+// OnUnknownAttributes if set is called once per request with the distinct unrecognized attributes, MaxReportedAttributes at most. The names are sent by the clients, count them against an allow list in the metrics
+// ForbidMixedHash if true rejects credentials with a PayloadHash different from Hash
+// KeyPolicy if set is checked against credentials keys at verification time, the weak keys are rejected in StrictMode
+// OnWeakKey if set is called with the credentials id when the key fails the KeyPolicy
+// OnAuthSuccess if set is called with the credentials id when the authentication succeeds
+// OnAuthFailure if set is called with the credentials id (if known) when the authentication fails
+// TrustProxyHeaders if true verifies the MAC with the host and port of the "X-Forwarded-*" headers sent by the TrustedProxies, the headers of other peers are ignored
+// TrustedProxies if set lists the IPs or CIDRs of the proxies whose "X-Forwarded-For" header gives the client IP and whose "X-Forwarded-*" headers are used with TrustProxyHeaders, instead of the gin engine ClientIP (no proxy is trusted by the net/http middlewares)
+// HostOverride if set replaces the host the client used to sign requests
+// PortOverride if set replaces the port the client used to sign requests
+// RequestURLFunc if set returns the host, port and URI used to verify requests, ignoring the other overrides
+// ListenerOverrides maps local listener addresses ("ip:port" or ":port") to host overrides
+// RateLimiter if set is consulted with the credentials id after a successful authentication
+// RateLimitFailures if true also consults the RateLimiter on failures when the id is known
+// PrincipalChecker if set rejects the credentials of disabled principals
+// ValidateApp if set is called with the "app" and "dlg" fields of requests sending an app
+// ValidateExt if set is called with the "ext" field sent by the client
+// Base64Normalizer if set rewrites the base64 values sent by clients (strict if nil)
+// Diagnostics if true stores a DiagnosticBundle in the context (see Diagnostics)
+// Lockout if set temporarily rejects credentials ids after too many invalid MACs from an IP
+// FailureMetrics if set counts the authentication failures by client IP and credentials id
+// BanPolicy if set rejects banned client IPs and credentials ids with a 403 before the credentials lookup (see ThresholdBan)
+// Maintenance if set and enabled rejects verified requests with a 503 (see Maintenance)
+// ErrorFormat is the format of the errors rendered without an AbortHandler (see ErrorJSON)
+// StatusMapper if set returns the status of the errors rendered without an AbortHandler (see DefaultStatusMapper)
+// Logger if set logs the authentication attempts with their latency and failure reason
+// LogIDs sets how credentials ids are logged by the Logger (in clear by default)
+// AuditSink if set receives an AuditEvent for each authentication attempt
+// ProfilerLabels if true sets pprof labels (phase and credentials id hash) during the authentication
+// TracerProvider if set is used instead of the global one to trace the authentication
+// MinKeyLength is the minimum length of a credentials key, empty keys are always invalid
+// StrictMode if true enforces StrictMinKeyLength and the KeyPolicy, checks all the rotated keys of the credentials and keeps the key out of the gin context, GetAuth has no key and the ext helpers look it up again
+// OnInvalidKey if set is called with the credentials id when the key is invalid
 type Middleware struct {
-	GetCredentials GetCredentialFunc
-	SetNonce       SetNonceFunc
-	AbortHandler   AbortHandlerFunc
-	UserParam      string
-	Ext            string
+	GetCredentials          GetCredentialFunc
+	CredentialProvider      CredentialProvider
+	GetTenantCredentials    GetTenantCredentialFunc
+	TenantResolver          TenantResolver
+	Pingers                 []Pinger
+	SetNonce                SetNonceFunc
+	NonceFailure            NonceFailurePolicy
+	OnNonceError            func(id string, err error)
+	RequireNonce            bool
+	SkipNonceForBewit       bool
+	NonceWindow             time.Duration
+	AbortHandler            AbortHandlerFunc
+	AbortRequestHandler     AbortRequestHandlerFunc
+	UserParam               string
+	Algorithm               string
+	Now                     func() time.Time
+	Ext                     string
+	DeprecationHeaders      bool
+	SkipFunc                SkipFunc
+	FallbackAuthenticator   FallbackAuthenticator
+	BewitParam              string
+	BewitHeader             string
+	StripBewit              bool
+	ValidatePayload         bool
+	StreamPayload           bool
+	MaxBodySize             int64
+	ContentTypeNormalizer   ContentTypeNormalizer
+	BodyReadTimeout         time.Duration
+	MinBodyRate             int64
+	RequirePayloadHash      bool
+	RequireHashFor          []string
+	RequireTLS              bool
+	MaxSkew                 time.Duration
+	MaxHeaderSize           int
+	RejectUnknownAttributes bool
+	OnUnknownAttributes     func(names []string)
+	ForbidMixedHash         bool
+	MinKeyLength            int
+	StrictMode              bool
+	OnInvalidKey            func(id string)
+	KeyPolicy               *KeyPolicy
+	OnWeakKey               func(id string)
+	OnAuthSuccess           func(c *gin.Context, id string)
+	OnAuthFailure           func(c *gin.Context, id string, err error)
+	RateLimiter             RateLimiter
+	RateLimitFailures       bool
+	PrincipalChecker        PrincipalChecker
+	ValidateApp             ValidateAppFunc
+	ValidateExt             ValidateExtFunc
+	Base64Normalizer        Base64Normalizer
+	Diagnostics             bool
+	Lockout                 *Lockout
+	FailureMetrics          *FailureMetrics
+	BanPolicy               BanPolicy
+	Maintenance             *Maintenance
+	ErrorFormat             ErrorFormat
+	StatusMapper            StatusMapper
+	Logger                  Logger
+	LogIDs                  LogIDs
+	AuditSink               AuditSink
+	ProfilerLabels          bool
+	TracerProvider          trace.TracerProvider
+	TrustProxyHeaders       bool
+	TrustedProxies          []string
+	HostOverride            string
+	PortOverride            string
+	ListenerOverrides       map[string]HostOverride
+	RequestURLFunc          RequestURLFunc
+
+	ext            *extParts
+	slowBodyAborts uint64
+	nonceFailOpens uint64
+	swap           *swapped
 }
 
 // NewMiddleware creates a new Middleware with the GetCredentials
 // and SetNonce params set. UserParam is set to "user" by default.
+// If snf is nil the nonces are kept in memory (see noncestore/memory)
+// for twice the MaxSkew, or the protocol MaxTimestampSkew if not set,
+// it only works for a single instance.
 func NewMiddleware(gcf GetCredentialFunc, snf SetNonceFunc) *Middleware {
-	return &Middleware{
+	hm := &Middleware{
 		GetCredentials: gcf,
 		SetNonce:       snf,
+		RequireNonce:   true,
+		swap:           &swapped{},
 	}
+	if snf == nil {
+		store := memory.NewStore(2 * hawk.MaxTimestampSkew)
+		hm.SetNonce = func(id string, nonce string, t time.Time) (bool, error) {
+			// MaxSkew may be set after NewMiddleware
+			return store.SetNonceTTL(id, nonce, 2*hm.maxSkew())
+		}
+	}
+	return hm
+}
+
+// maxSkew returns the timestamp skew accepted by the requests, MaxSkew
+// if set or the protocol MaxTimestampSkew.
+func (hm *Middleware) maxSkew() time.Duration {
+	if hm.MaxSkew > 0 {
+		return hm.MaxSkew
+	}
+	return hawk.MaxTimestampSkew
 }
 
+// ISHawkError returns true if err is an authentication error
+// (see Classify), false for rate limits and internal errors.
 func ISHawkError(err error) bool {
-	switch err {
-	case ErrNotFound,
-		hawk.ErrBewitExpired,
-		hawk.ErrInvalidBewitMethod,
-		hawk.ErrInvalidMAC,
-		hawk.ErrMissingServerAuth,
-		hawk.ErrNoAuth,
-		hawk.ErrReplay,
-		hawk.ErrTimestampSkew:
+	if err == nil {
+		return false
+	}
+	switch Classify(err).Kind {
+	case KindCredentials, KindReplay, KindSkew, KindRequest:
 		return true
 	}
 	return false
@@ -80,91 +284,430 @@ func ISHawkError(err error) bool {
 
 // Abortequest aborts the request and set the context error and status.
 // When possible it will attempt to send a "Server-Authorization" header.
+// The error passed to the AbortHandler is the original error, like the
+// protocol ErrReplay, the one set in the context and passed to the
+// AbortRequestHandler is an *AuthError (see Classify).
 func (hm *Middleware) Abortequest(c *gin.Context, err error, auth *hawk.Auth) {
-	isHawk := ISHawkError(err)
+	hm.abort(c, nil, err, auth)
+}
+
+// abort is Abortequest with the Request state of the Filter.
+func (hm *Middleware) abort(c *gin.Context, hr *Request, err error, auth *hawk.Auth) {
+	authErr := Classify(err)
+	isHawk := ISHawkError(authErr)
 	if isHawk && auth != nil {
-		c.Header("Server-Authorization", auth.ResponseHeader(hm.Ext))
+		c.Header("Server-Authorization", hm.responseHeader(auth))
+		if errors.Is(authErr, hawk.ErrTimestampSkew) {
+			c.Header("WWW-Authenticate", auth.StaleTimestampHeaderAt(hm.now()))
+		}
 	}
-	if hm.AbortHandler != nil {
-		hm.AbortHandler(c, err)
-		c.Abort()
-	} else if isHawk {
-		c.AbortWithError(http.StatusUnauthorized, err)
-	} else {
-		c.AbortWithError(http.StatusInternalServerError, err)
+	if !hm.abortHandler(c, hr, authErr, auth) {
+		hm.renderError(c, authErr)
+	}
+}
+
+// abortHandler calls the AbortRequestHandler or the AbortHandler and
+// aborts c, it returns false if none is set.
+func (hm *Middleware) abortHandler(c *gin.Context, hr *Request, err *AuthError, auth *hawk.Auth) bool {
+	switch {
+	case hm.AbortRequestHandler != nil:
+		if auth != nil {
+			auth = snapshotAuth(auth)
+			auth.Credentials.Key = ""
+		}
+		if hr != nil {
+			hr = hr.snapshot()
+		}
+		hm.AbortRequestHandler(c, err, auth, hr)
+	case hm.AbortHandler != nil:
+		hm.AbortHandler(c, cause(err))
+	default:
+		return false
+	}
+	c.Abort()
+	return true
+}
+
+// signedRequest returns r with the host, port and URI signed by the
+// client: the ones of the RequestURLFunc or of the host overrides.
+func (hm *Middleware) signedRequest(r *http.Request) *http.Request {
+	if hm.RequestURLFunc != nil {
+		return hm.requestURL(r)
+	} else if o, ok := hm.hostOverride(r); ok {
+		return overrideHost(r, o)
+	}
+	return r
+}
+
+// verificationRequest returns the request to verify, a shallow copy of r
+// if the host, port or encodings need to be adjusted.
+func (hm *Middleware) verificationRequest(r *http.Request) *http.Request {
+	r = hm.signedRequest(r)
+	r = hm.bewitTransport(r)
+	if hm.Base64Normalizer != nil {
+		r = hm.normalizeEncoding(r)
 	}
+	return r
 }
 
 // Filter is the middleware function that validate the hawk authentication.
+// The "Server-Authorization" header is set before calling the handlers
+// and never covers the response payload, so streamed responses (SSE,
+// chunked) are sent as they are flushed, with the header.
 func (hm *Middleware) Filter(c *gin.Context) {
-	res := &Request{
-		Hawk: hm,
+	if hm.SkipFunc != nil && hm.SkipFunc(c) {
+		c.Next()
+		return
 	}
 
-	auth, err := hawk.NewAuthFromRequest(c.Request, res.CredentialsLookup, res.NonceCheck)
-	if res.Error != nil {
-		hm.Abortequest(c, res.Error, nil)
-	} else if err != nil {
-		hm.Abortequest(c, err, auth)
-	} else if err := auth.Valid(); err != nil {
-		hm.Abortequest(c, err, auth)
+	res := hm.acquireRequest(c)
+	defer releaseRequest(res)
+
+	if err := hm.resolveTenant(c, res); err != nil {
+		hm.fail(c, res, err, nil)
+		return
+	}
+
+	if hm.FallbackAuthenticator != nil && !hm.isHawk(c) {
+		hm.fallback(c, res)
+		return
+	}
+
+	auth, err := hm.authenticate(c, res)
+	if err != nil {
+		hm.fail(c, res, err, auth)
+	} else if err := hm.rateLimit(res.tenantID(res.ID)); err != nil {
+		hm.done(c, res, auth, err)
+		hm.abort(c, res, err, auth)
+	} else if hm.maintenance(c, res, auth) {
+		hm.done(c, res, auth, ErrMaintenance)
 	} else {
-		c.Header("Server-Authorization", auth.ResponseHeader(hm.Ext))
-		c.Set(AuthKey, auth)
+		hm.done(c, res, auth, nil)
+		if hm.OnAuthSuccess != nil {
+			hm.OnAuthSuccess(c, res.ID)
+		}
+		if isWebSocket(c.Request) {
+			hm.webSocket(c, hm.responseHeader(auth), res.User)
+		} else {
+			c.Header("Server-Authorization", hm.responseHeader(auth))
+		}
+		hm.deprecationHeaders(c, res.creds)
+		if auth.IsBewit && hm.StripBewit {
+			hm.stripBewit(c.Request)
+		}
+		c.Set(AuthKey, hm.contextAuth(auth))
+		if hm.StrictMode {
+			c.Set(keyLookupKey, keyLookup{hm: hm, tenant: res.Tenant})
+		}
 		c.Set(UserKey, res.User)
+		if res.Scopes != nil {
+			c.Set(ScopesKey, res.Scopes)
+		}
+		if auth.Credentials.App != "" {
+			c.Set(AppKey, auth.Credentials.App)
+			c.Set(DelegateKey, auth.Credentials.Delegate)
+		}
+		c.Next()
+	}
+}
+
+// authenticate verifies the Hawk authentication of the request of c for
+// the Filter and Verify. On failure the auth is returned when the
+// "Server-Authorization" header can be sent.
+func (hm *Middleware) authenticate(c *gin.Context, res *Request) (*hawk.Auth, error) {
+	if err := hm.checkRequest(c.Request); err != nil {
+		return nil, err
+	}
+
+	auth, err := hawk.NewAuthFromRequest(hm.verificationRequest(c.Request), res.lookup, res.nonceCheck)
+	if err == nil {
+		hm.stamp(auth)
+	}
+	if res.Error != nil {
+		return nil, res.err()
+	} else if err != nil {
+		return auth, err
+	} else if err := res.Validate(c.Request, auth); err != nil {
+		if err == hawk.ErrInvalidMAC && hm.Lockout != nil {
+			if lerr := hm.Lockout.Failed(res.tenantID(res.ID), res.ip); lerr != nil {
+				err = lerr
+			}
+		}
+		return auth, err
+	} else if err := res.lockoutSucceeded(); err != nil {
+		return nil, err
+	} else if err := hm.validateApp(auth); err != nil {
+		return auth, err
+	} else if err := hm.validateExt(auth); err != nil {
+		return auth, err
+	}
+	return auth, nil
+}
+
+// fail calls the OnAuthFailure callback and aborts the request.
+func (hm *Middleware) fail(c *gin.Context, hr *Request, err error, auth *hawk.Auth) {
+	hm.done(c, hr, auth, err)
+	hm.recordFailure(hr, err)
+	if hm.OnAuthFailure != nil {
+		hm.OnAuthFailure(c, hr.ID, err)
+	}
+	if hm.RateLimitFailures && hr.ID != "" {
+		if rlErr := hm.rateLimit(hr.tenantID(hr.ID)); rlErr != nil {
+			err = rlErr
+		}
+	}
+	hm.abort(c, hr, err, auth)
+}
+
+// OptionalFilter is like Filter but lets anonymous requests (without an
+// "Authorization" header or a bewit) through, in which case
+// no auth and user are set in the context.
+func (hm *Middleware) OptionalFilter(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" && hm.bewit(c.Request) == "" {
 		c.Next()
+		return
+	}
+	hm.Filter(c)
+}
+
+// snapshotAuth returns a deep copy of auth that is never modified by the
+// middleware, so it's safe to read from contexts copied with c.Copy()
+// after the request finished.
+func snapshotAuth(auth *hawk.Auth) *hawk.Auth {
+	res := *auth
+	res.MAC = append([]byte(nil), auth.MAC...)
+	res.Hash = append([]byte(nil), auth.Hash...)
+	return &res
+}
+
+// snapshot returns a copy of the exported fields of hr, the pooled hr is
+// reset once the Filter returns.
+func (hr *Request) snapshot() *Request {
+	return &Request{
+		Hawk:        hr.Hawk,
+		ID:          hr.ID,
+		Tenant:      hr.Tenant,
+		User:        hr.User,
+		Ok:          hr.Ok,
+		Error:       hr.Error,
+		PayloadHash: hr.PayloadHash,
+		Scopes:      hr.Scopes,
 	}
 }
 
 // Request represent the state of a request.
+// It only lives during the Filter call and is never referenced from
+// the gin context, so it must not be retained by handlers: the Requests
+// of Filter are pooled and reset when it returns.
 type Request struct {
-	Hawk  *Middleware
-	ID    string
-	User  interface{}
-	Ok    bool
-	Error error
+	Hawk        *Middleware
+	ID          string
+	Tenant      string
+	User        interface{}
+	Ok          bool
+	Error       error
+	PayloadHash func() hash.Hash
+	Scopes      []string
+
+	ctx         context.Context
+	ip          string
+	creds       *Credentials
+	keys        []string
+	w           http.ResponseWriter
+	start       time.Time
+	storeFailed bool
+	spool       *spool
+
+	lookup     hawk.CredentialsLookupFunc
+	nonceCheck hawk.NonceCheckFunc
+
+	credentialsLatency time.Duration
+	nonceLatency       time.Duration
+}
+
+// lockoutSucceeded resets the Lockout counter after a valid MAC.
+func (hr *Request) lockoutSucceeded() error {
+	if hr.Hawk.Lockout == nil {
+		return nil
+	}
+	return hr.Hawk.Lockout.Succeeded(hr.tenantID(hr.ID), hr.ip)
+}
+
+// Validate checks the MAC and the payload of the request.
+func (hr *Request) Validate(r *http.Request, auth *hawk.Auth) error {
+	_, span := hr.startSpan("hawk.Validate", auth.Credentials.ID)
+	var err error
+	hr.profile("validate", auth.Credentials.ID, func() {
+		err = hr.validate(r, auth)
+	})
+	endSpan(span, err)
+	return err
+}
+
+func (hr *Request) validate(r *http.Request, auth *hawk.Auth) error {
+	if hr.Hawk.skewed(auth) {
+		return hawk.ErrTimestampSkew
+	} else if hr.Hawk.MaxSkew > 0 && !auth.IsBewit {
+		// the skew was checked against MaxSkew, not the protocol one
+		actual := auth.ActualTimestamp
+		auth.ActualTimestamp = auth.Timestamp
+		defer func() {
+			auth.ActualTimestamp = actual
+		}()
+	}
+	err := auth.Valid()
+	if err == hawk.ErrInvalidMAC && len(hr.keys) > 0 {
+		// in StrictMode all the previous keys are checked so the time
+		// taken does not tell which one matched
+		primary, found := auth.Credentials.Key, ""
+		for _, key := range hr.keys {
+			auth.Credentials.Key = key
+			if kerr := auth.Valid(); kerr != hawk.ErrInvalidMAC && err == hawk.ErrInvalidMAC {
+				err, found = kerr, key
+				if !hr.Hawk.StrictMode {
+					break
+				}
+			}
+		}
+		auth.Credentials.Key = primary
+		if err == nil {
+			auth.Credentials.Key = found
+		}
+	}
+	if err == nil {
+		err = hr.ValidatePayload(r, auth)
+	}
+	return err
 }
 
-// CredentialsLookup lookup the credantial for hawk-go from the user
+// CredentialsLookup lookup the credantial for the protocol from the user
 // provided GetCredentialFunc.
 func (hr *Request) CredentialsLookup(creds *hawk.Credentials) error {
+	_, span := hr.startSpan("hawk.CredentialsLookup", creds.ID)
+	start := time.Now()
+	var err error
+	hr.profile("credentials", creds.ID, func() {
+		err = hr.credentialsLookup(creds)
+	})
+	hr.credentialsLatency = time.Since(start)
+	endSpan(span, err)
+	return err
+}
 
+func (hr *Request) credentialsLookup(creds *hawk.Credentials) error {
 	id := creds.ID
-	if res, err := hr.Hawk.GetCredentials(id); err != nil {
+	hr.ID = id
+	if err := hr.Hawk.checkTenant(); err != nil {
+		hr.Error = err
+		return err
+	}
+	if hr.Hawk.Lockout != nil {
+		if locked, err := hr.Hawk.Lockout.Locked(hr.tenantID(id), hr.ip); err != nil {
+			hr.Error = err
+			return err
+		} else if locked {
+			return ErrLockedOut
+		}
+	}
+	if err := hr.banned(hr.tenantID(id)); err != nil {
+		if err != ErrBanned {
+			hr.Error = err
+		}
+		return err
+	}
+	if res, err := hr.getCredentials(id); err != nil {
 		hr.Error = err
+		hr.storeFailed = true
 		return err
 	} else if res == nil {
 		return ErrNotFound
+	} else if res.Revoked {
+		return ErrCredentialsRevoked
+	} else if !res.ExpiresAt.IsZero() && hr.Hawk.now().After(res.ExpiresAt) {
+		return ErrCredentialsExpired
+	} else if disabled, err := hr.Hawk.principalDisabled(res); err != nil {
+		hr.Error = err
+		return err
+	} else if disabled {
+		return ErrPrincipalDisabled
+	} else if err := hr.checkKeys(id, res); err != nil {
+		hr.Error = err
+		return err
+	} else if h, err := hr.Hawk.hashFunc(res); err != nil {
+		hr.Error = err
+		return err
 	} else {
 		creds.Key = res.Key
+		hr.keys = res.Keys
+		creds.Hash = h
+		hr.PayloadHash = res.PayloadHash
+		if hr.PayloadHash == nil {
+			hr.PayloadHash = creds.Hash
+		} else if hr.Hawk.ForbidMixedHash && !sameHash(creds.Hash, hr.PayloadHash) {
+			return ErrMixedHash
+		}
 		hr.User = res.User
-		creds.Hash = sha256.New
+		hr.Scopes = res.Scopes
+		hr.creds = res
 		hr.Ok = true
 		return nil
 	}
 }
 
-// NonceCheck call the SetNonceFunc on behalf of hawk-go.
+// NonceCheck call the SetNonceFunc on behalf of the protocol.
 func (hr *Request) NonceCheck(nonce string, t time.Time, creds *hawk.Credentials) bool {
-	if hr.Error != nil || !hr.Ok || hr.Hawk.SetNonce == nil {
+	if hr.Error != nil || !hr.Ok {
+		return false
+	}
+	nonces := hr.Hawk.nonceStore()
+	if nonces == nil {
+		if hr.Hawk.RequireNonce {
+			hr.Error = ErrNoNonceStore
+			return false
+		}
+		return true
+	} else if !hr.Hawk.inNonceWindow(t) {
+		hr.Error = hawk.ErrTimestampSkew
 		return false
 	}
 
-	ok, err := hr.Hawk.SetNonce(creds.ID, nonce, t)
+	_, span := hr.startSpan("hawk.NonceCheck", creds.ID)
+	start := time.Now()
+	var ok bool
+	var err error
+	hr.profile("nonce", creds.ID, func() {
+		ok, err = nonces.SetNonce(hr.tenantID(creds.ID), nonce, t)
+	})
+	hr.nonceLatency = time.Since(start)
+	if err == nil && !ok {
+		endSpan(span, hawk.ErrReplay)
+	} else {
+		endSpan(span, err)
+	}
 	if err != nil {
+		if hr.nonceFailure(creds.ID, err) {
+			return true
+		}
 		hr.Error = err
+		hr.storeFailed = true
 		return false
 	}
 	return ok
 }
 
 // GenIDKey generates a random id and key.
+//
+// Deprecated: use GenerateCredentials or NewCredential.
 func GenIDKey() (string, string) {
-	return uniuri.NewLen(12), uniuri.NewLen(24)
+	c := NewCredential()
+	return c.ID, c.Key
 }
 
-// GetAuth returns the *hawk.Auth from the context.
+// GetAuth returns the *hawk.Auth from the context, without the
+// credentials key in StrictMode.
 // Will panic if not set (i.e. when the filter fail or has not happend yet)
+// The returned value is a read only snapshot that can safely be used
+// from a context copied with c.Copy() in another goroutine.
 func GetAuth(c *gin.Context) *hawk.Auth {
 	return c.MustGet(AuthKey).(*hawk.Auth)
 }
@@ -175,3 +718,20 @@ func GetAuth(c *gin.Context) *hawk.Auth {
 func GetUser(c *gin.Context) interface{} {
 	return c.MustGet(UserKey)
 }
+
+// AuthFromContext returns the *hawk.Auth from the context and true,
+// or nil and false if it's not set. Like GetAuth the value is a read only
+// snapshot.
+func AuthFromContext(c *gin.Context) (*hawk.Auth, bool) {
+	if v, exists := c.Get(AuthKey); exists {
+		auth, ok := v.(*hawk.Auth)
+		return auth, ok
+	}
+	return nil, false
+}
+
+// UserFromContext returns the user object from the context and true,
+// or nil and false if it's not set.
+func UserFromContext(c *gin.Context) (interface{}, bool) {
+	return c.Get(UserKey)
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/dchest/uniuri"
 	"github.com/gin-gonic/gin"
 	. "github.com/hyperboloide/hawk"
-	hawk "github.com/tent/hawk-go"
+	hawk "github.com/hyperboloide/hawk/protocol"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -25,13 +25,33 @@ var _ = Describe("Hawk", func() {
 	}{1, "test user"}
 
 	creds := map[string]string{
-		"valid-id": "test-cred-key",
+		"valid-id":     "test-cred-key",
+		"empty-key-id": "",
 	}
 	credsError := errors.New("test error")
 	getCredentials := func(id string) (*Credentials, error) {
 		if id == "error-creds-id" {
 			return nil, credsError
 		}
+		if id == "expired-id" {
+			return &Credentials{
+				Key:       "test-cred-key",
+				ExpiresAt: time.Now().Add(-time.Minute),
+			}, nil
+		}
+		if id == "revoked-id" {
+			return &Credentials{
+				Key:     "test-cred-key",
+				Revoked: true,
+			}, nil
+		}
+		if id == "rotated-id" {
+			return &Credentials{
+				Key:  "new-cred-key",
+				Keys: []string{"test-cred-key"},
+				User: user,
+			}, nil
+		}
 		if key, exists := creds[id]; !exists {
 			return nil, nil
 		} else {
@@ -86,6 +106,54 @@ var _ = Describe("Hawk", func() {
 				Expect(hr.User).To(BeNil())
 			})
 
+			It("returns error if credentials are expired", func() {
+				hc := &hawk.Credentials{
+					ID: "expired-id",
+				}
+				err := hr.CredentialsLookup(hc)
+				Expect(err).To(Equal(ErrCredentialsExpired))
+				Expect(hr.Error).To(BeNil())
+				Expect(hr.Ok).To(BeFalse())
+				Expect(ISHawkError(err)).To(BeTrue())
+			})
+
+			It("returns error if credentials are revoked", func() {
+				hc := &hawk.Credentials{
+					ID: "revoked-id",
+				}
+				err := hr.CredentialsLookup(hc)
+				Expect(err).To(Equal(ErrCredentialsRevoked))
+				Expect(hr.Error).To(BeNil())
+				Expect(hr.Ok).To(BeFalse())
+				Expect(ISHawkError(err)).To(BeTrue())
+			})
+
+			It("returns error if the key is empty", func() {
+				invalid := ""
+				hm.OnInvalidKey = func(id string) {
+					invalid = id
+				}
+				hc := &hawk.Credentials{
+					ID: "empty-key-id",
+				}
+				err := hr.CredentialsLookup(hc)
+				Expect(err).To(Equal(ErrInvalidKey))
+				Expect(hr.Error).To(Equal(ErrInvalidKey))
+				Expect(hr.Ok).To(BeFalse())
+				Expect(invalid).To(Equal("empty-key-id"))
+			})
+
+			It("returns error if the key is too short", func() {
+				hm.MinKeyLength = 24
+				hc := &hawk.Credentials{
+					ID: "valid-id",
+				}
+				err := hr.CredentialsLookup(hc)
+				Expect(err).To(Equal(ErrInvalidKey))
+				Expect(hr.Error).To(Equal(ErrInvalidKey))
+				Expect(hr.Ok).To(BeFalse())
+			})
+
 			It("returns nil and set Request if ok", func() {
 				hc := &hawk.Credentials{
 					ID: "valid-id",
@@ -138,8 +206,10 @@ var _ = Describe("Hawk", func() {
 		var ts *httptest.Server
 		var hm *Middleware
 		var credentials *hawk.Credentials
+		var asyncResults chan string
 
 		BeforeEach(func() {
+			asyncResults = make(chan string, 1)
 			credentials = &hawk.Credentials{
 				ID:   "valid-id",
 				Key:  "test-cred-key",
@@ -150,6 +220,29 @@ var _ = Describe("Hawk", func() {
 			router.Any("/private", hm.Filter, func(c *gin.Context) {
 				c.String(200, "ok")
 			})
+			router.GET("/async", hm.Filter, func(c *gin.Context) {
+				cp := c.Copy()
+				go func() {
+					defer GinkgoRecover()
+					time.Sleep(10 * time.Millisecond)
+					auth, ok := AuthFromContext(cp)
+					Expect(ok).To(BeTrue())
+					u, ok := UserFromContext(cp)
+					Expect(ok).To(BeTrue())
+					asyncResults <- auth.Credentials.ID + ":" + u.(struct {
+						ID   int
+						Name string
+					}).Name
+				}()
+				c.String(200, "ok")
+			})
+			router.Any("/optional", hm.OptionalFilter, func(c *gin.Context) {
+				if _, ok := UserFromContext(c); ok {
+					c.String(200, "user")
+				} else {
+					c.String(200, "anonymous")
+				}
+			})
 			ts = httptest.NewServer(router)
 		})
 
@@ -182,7 +275,7 @@ var _ = Describe("Hawk", func() {
 		It("invalid bwit string", func() {
 			resp, err := http.Get(ts.URL + "/private?bewit=" + uniuri.NewLen(90))
 			Expect(err).ToNot(HaveOccurred())
-			Expect(resp.StatusCode).To(Equal(500))
+			Expect(resp.StatusCode).To(Equal(401))
 		})
 
 		It("invalid bwit auth key", func() {
@@ -208,6 +301,61 @@ var _ = Describe("Hawk", func() {
 
 		})
 
+		It("valid header with precomputed ext", func() {
+			hm.Ext = `my\app`
+			hm.PrecomputeExt()
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+				auth := hawk.NewRequestAuth(req, credentials, 0)
+				req.Header.Set("Authorization", auth.RequestHeader())
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				header := resp.Header["Server-Authorization"][0]
+				Expect(header).To(ContainSubstring(`ext="` + hm.Ext + `"`))
+				Expect(auth.ValidResponse(header)).ToNot(HaveOccurred())
+				hm.Ext = "changed"
+			}
+		})
+
+		It("auth and user survive c.Copy()", func() {
+			req, err := http.NewRequest("GET", ts.URL+"/async", nil)
+			auth := hawk.NewRequestAuth(req, credentials, 0)
+			req.Header.Set("Authorization", auth.RequestHeader())
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+			Eventually(asyncResults).Should(Receive(Equal("valid-id:test user")))
+		})
+
+		It("valid header with rotated keys", func() {
+			for _, key := range []string{"test-cred-key", "new-cred-key"} {
+				req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+				auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+					ID:   "rotated-id",
+					Key:  key,
+					Hash: sha256.New,
+				}, 0)
+				req.Header.Set("Authorization", auth.RequestHeader())
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				header := resp.Header["Server-Authorization"][0]
+				Expect(auth.ValidResponse(header)).ToNot(HaveOccurred())
+			}
+
+			req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+			auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+				ID:   "rotated-id",
+				Key:  "invalid key!",
+				Hash: sha256.New,
+			}, 0)
+			req.Header.Set("Authorization", auth.RequestHeader())
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(401))
+		})
+
 		It("invalid header auth key", func() {
 			req, err := http.NewRequest("GET", ts.URL+"/private", nil)
 			auth := hawk.NewRequestAuth(req, credentials, 0)
@@ -227,6 +375,109 @@ var _ = Describe("Hawk", func() {
 			Expect(resp.StatusCode).To(Equal(401))
 		})
 
+		It("optional without auth", func() {
+			resp, err := http.Get(ts.URL + "/optional")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+			b, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b)).To(Equal("anonymous"))
+		})
+
+		It("optional with valid header", func() {
+			req, err := http.NewRequest("GET", ts.URL+"/optional", nil)
+			auth := hawk.NewRequestAuth(req, credentials, 0)
+			req.Header.Set("Authorization", auth.RequestHeader())
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+			b, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b)).To(Equal("user"))
+		})
+
+		It("optional with invalid header", func() {
+			req, err := http.NewRequest("GET", ts.URL+"/optional", nil)
+			auth := hawk.NewRequestAuth(req, credentials, 0)
+			auth.Credentials.Key = "invalid key!"
+			req.Header.Set("Authorization", auth.RequestHeader())
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(401))
+		})
+
+		It("skip paths", func() {
+			hm.SkipFunc = SkipPaths("/health", "/private")
+			resp, err := http.Get(ts.URL + "/private")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(resp.Header.Get("Server-Authorization")).To(BeEmpty())
+		})
+
+		It("skip methods", func() {
+			hm.SkipFunc = SkipAny(SkipPaths("/health"), SkipMethods("options"))
+			req, err := http.NewRequest("OPTIONS", ts.URL+"/private", nil)
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+
+			resp, err = http.Get(ts.URL + "/private")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(401))
+		})
+
+		It("validates the client ext", func() {
+			hm.ValidateExt = func(ext string) error {
+				if ext != "tenant-1" {
+					return ErrInvalidExt
+				}
+				return nil
+			}
+			for ext, status := range map[string]int{"tenant-1": 200, "tenant-2": 401, "": 401} {
+				req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+				auth := hawk.NewRequestAuth(req, credentials, 0)
+				auth.Ext = ext
+				req.Header.Set("Authorization", auth.RequestHeader())
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(status))
+			}
+		})
+
+		It("calls the success and failure callbacks", func() {
+			successes := []string{}
+			failures := []string{}
+			hm.OnAuthSuccess = func(c *gin.Context, id string) {
+				successes = append(successes, id)
+			}
+			hm.OnAuthFailure = func(c *gin.Context, id string, err error) {
+				defer GinkgoRecover()
+				Expect(err).To(HaveOccurred())
+				failures = append(failures, id)
+			}
+
+			req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+			auth := hawk.NewRequestAuth(req, credentials, 0)
+			req.Header.Set("Authorization", auth.RequestHeader())
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+
+			auth = hawk.NewRequestAuth(req, credentials, 0)
+			auth.Credentials.Key = "invalid key!"
+			req.Header.Set("Authorization", auth.RequestHeader())
+			resp, err = http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(401))
+
+			resp, err = http.Get(ts.URL + "/private")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(401))
+
+			Expect(successes).To(Equal([]string{"valid-id"}))
+			Expect(failures).To(Equal([]string{"valid-id", ""}))
+		})
+
 		It("use custom AbortHandler", func() {
 			hm.AbortHandler = func(c *gin.Context, err error) {
 				defer GinkgoRecover()
@@ -249,6 +500,30 @@ var _ = Describe("Hawk", func() {
 
 	})
 
+	Context("Context getters", func() {
+		It("returns false when not set", func() {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			auth, ok := AuthFromContext(c)
+			Expect(ok).To(BeFalse())
+			Expect(auth).To(BeNil())
+			u, ok := UserFromContext(c)
+			Expect(ok).To(BeFalse())
+			Expect(u).To(BeNil())
+		})
+
+		It("returns auth and user when set", func() {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Set(AuthKey, &hawk.Auth{})
+			c.Set(UserKey, user)
+			auth, ok := AuthFromContext(c)
+			Expect(ok).To(BeTrue())
+			Expect(auth).ToNot(BeNil())
+			u, ok := UserFromContext(c)
+			Expect(ok).To(BeTrue())
+			Expect(u).To(Equal(user))
+		})
+	})
+
 	It("GenIDKey", func() {
 		id, key := GenIDKey()
 		Expect(len(id)).To(Equal(12))
@@ -0,0 +1,79 @@
+package hawkclient
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+// BewitURL returns raw with a "bewit" query parameter for the credentials
+// id and key, valid for ttl. The URL is canonicalized the way browsers
+// send it so the bewit stays valid once shared: the host is lowercased,
+// the default port removed (the MAC covers it anyway), an empty path set
+// to "/", the fragment and a previous bewit removed. The query is kept
+// as is, in its order and encoding, with the bewit last. The Algorithm
+// and Ext options are used.
+func BewitURL(raw string, id, key string, ttl time.Duration, opts ...Option) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.New("hawkclient: bewit URLs must be http or https")
+	} else if u.Host == "" {
+		return "", errors.New("hawkclient: bewit URLs must have a host")
+	}
+	canonicalize(u)
+
+	o := newOptions(opts)
+	h, err := hawk.HashFunc(o.algorithm)
+	if err != nil {
+		return "", err
+	}
+	auth, err := hawkgo.NewURLAuth(u.String(), &hawkgo.Credentials{ID: id, Key: key, Hash: h}, ttl)
+	if err != nil {
+		return "", err
+	}
+	auth.Ext = o.ext
+
+	bewit := "bewit=" + auth.Bewit()
+	if u.RawQuery == "" {
+		u.RawQuery = bewit
+	} else {
+		u.RawQuery += "&" + bewit
+	}
+	return u.String(), nil
+}
+
+// canonicalize lowercases the host of u, removes its default port,
+// fragment and bewit parameters and sets an empty path to "/".
+func canonicalize(u *url.URL) {
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	if u.RawQuery != "" {
+		var kept []string
+		for _, p := range strings.Split(u.RawQuery, "&") {
+			if p != "" && !strings.HasPrefix(p, "bewit=") {
+				kept = append(kept, p)
+			}
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	u.ForceQuery = false
+}
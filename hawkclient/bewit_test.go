@@ -0,0 +1,110 @@
+package hawkclient_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyperboloide/hawk"
+	. "github.com/hyperboloide/hawk/hawkclient"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BewitURL", func() {
+
+	Context("with the hapi/hawk vectors", func() {
+
+		BeforeEach(func() {
+			hawkgo.Now = func() time.Time {
+				return time.Unix(1356420407, 232000000)
+			}
+		})
+
+		AfterEach(func() {
+			hawkgo.Now = time.Now
+		})
+
+		bewit := func(raw string, opts ...Option) string {
+			res, err := BewitURL(raw, "123456", "2983d45yun89q", 300*time.Second, opts...)
+			Expect(err).ToNot(HaveOccurred())
+			u, err := url.Parse(res)
+			Expect(err).ToNot(HaveOccurred())
+			return u.Query().Get("bewit")
+		}
+
+		It("returns a valid bewit value", func() {
+			Expect(bewit("https://example.com/somewhere/over/the/rainbow", Ext("xandyandz"))).
+				To(Equal("MTIzNDU2XDEzNTY0MjA3MDdca3NjeHdOUjJ0SnBQMVQxekRMTlBiQjVVaUtJVTl0T1NKWFRVZEc3WDloOD1ceGFuZHlhbmR6"))
+		})
+
+		It("returns a valid bewit value (explicit port)", func() {
+			Expect(bewit("https://example.com:8080/somewhere/over/the/rainbow", Ext("xandyandz"))).
+				To(Equal("MTIzNDU2XDEzNTY0MjA3MDdcaFpiSjNQMmNLRW80a3kwQzhqa1pBa1J5Q1p1ZWc0V1NOYnhWN3ZxM3hIVT1ceGFuZHlhbmR6"))
+		})
+
+		It("returns a valid bewit value (null ext)", func() {
+			Expect(bewit("https://example.com/somewhere/over/the/rainbow")).
+				To(Equal("MTIzNDU2XDEzNTY0MjA3MDdcSUdZbUxnSXFMckNlOEN4dktQczRKbFdJQStValdKSm91d2dBUmlWaENBZz1c"))
+		})
+
+		It("ignores the default port and the host case", func() {
+			expected := bewit("https://example.com/somewhere/over/the/rainbow", Ext("xandyandz"))
+			Expect(bewit("https://EXAMPLE.com:443/somewhere/over/the/rainbow", Ext("xandyandz"))).To(Equal(expected))
+		})
+
+	})
+
+	It("canonicalizes the URL", func() {
+		for raw, expected := range map[string]string{
+			"http://Example.COM:80":                 "http://example.com/?bewit=",
+			"https://example.com:443/a?z=1&a=2#top": "https://example.com/a?z=1&a=2&bewit=",
+			"http://example.com:8080/a?bewit=old&b": "http://example.com:8080/a?b&bewit=",
+			"http://[::1]:80/a?x=%2F&&y=":           "http://[::1]/a?x=%2F&y=&bewit=",
+		} {
+			res, err := BewitURL(raw, "id", "key", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(HavePrefix(expected), raw)
+		}
+
+		for _, raw := range []string{"ftp://example.com/", "/relative", "http://%zz"} {
+			_, err := BewitURL(raw, "id", "key", time.Minute)
+			Expect(err).To(HaveOccurred(), raw)
+		}
+		_, err := BewitURL("http://example.com/", "id", "key", time.Minute, Algorithm("md5"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("is accepted by the Middleware", func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm := store.Middleware()
+		router := gin.New()
+		router.GET("/files/:name", hm.Filter, func(c *gin.Context) {
+			c.String(200, c.Query("v"))
+		})
+
+		for _, raw := range []string{
+			"http://Example.com:80/files/report.pdf?v=2&a=b",
+			"http://example.com/files/report.pdf?bewit=old&v=2",
+		} {
+			signed, err := BewitURL(raw, "my-id", "my-key", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", signed, nil))
+			Expect(w.Code).To(Equal(http.StatusOK), raw)
+			Expect(w.Body.String()).To(Equal("2"))
+		}
+
+		signed, _ := BewitURL("http://example.com/files/report.pdf", "my-id", "my-key", time.Minute, Algorithm(hawk.SHA512))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", signed, nil))
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+})
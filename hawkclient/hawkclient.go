@@ -0,0 +1,249 @@
+// Package hawkclient signs the requests of the services calling each
+// other with Hawk, the counterpart of the hawk Middleware:
+//
+//	req, _ := http.NewRequest("GET", "https://orders.internal/orders", nil)
+//	auth, err := hawkclient.SignRequest(req, "id", "key")
+//	resp, err := http.DefaultClient.Do(req)
+//	err = auth.ValidResponse(resp.Header.Get("Server-Authorization"))
+//
+// The Transport signs all the requests of an http.Client:
+//
+//	client := &http.Client{Transport: hawkclient.NewTransport("id", "key")}
+package hawkclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+// options are the signing options.
+type options struct {
+	algorithm   string
+	ext         string
+	app         string
+	delegate    string
+	offset      time.Duration
+	payloadHash bool
+}
+
+// Option is a signing option.
+type Option func(o *options)
+
+// Algorithm sets the MAC algorithm of the credentials, "sha256" by default.
+func Algorithm(name string) Option {
+	return func(o *options) {
+		o.algorithm = name
+	}
+}
+
+// Ext sets the "ext" attribute.
+func Ext(ext string) Option {
+	return func(o *options) {
+		o.ext = ext
+	}
+}
+
+// App sets the "app" and "dlg" attributes.
+func App(app, delegate string) Option {
+	return func(o *options) {
+		o.app = app
+		o.delegate = delegate
+	}
+}
+
+// Offset is added to the timestamp to compensate the clock skew with
+// the server.
+func Offset(d time.Duration) Option {
+	return func(o *options) {
+		o.offset = d
+	}
+}
+
+// PayloadHash sets if the payload hash of the body is sent, true by
+// default.
+func PayloadHash(enabled bool) Option {
+	return func(o *options) {
+		o.payloadHash = enabled
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{algorithm: hawk.SHA256, payloadHash: true}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// SignRequest sets the "Authorization" header of req for the credentials
+// id and key. The payload hash is included if req has a body, which is
+// buffered and can still be sent. The returned auth validates the
+// "Server-Authorization" response header.
+func SignRequest(req *http.Request, id, key string, opts ...Option) (*hawkgo.Auth, error) {
+	return sign(req, id, key, newOptions(opts))
+}
+
+func sign(req *http.Request, id, key string, o *options) (*hawkgo.Auth, error) {
+	h, err := hawk.HashFunc(o.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	auth := hawkgo.NewRequestAuth(req, &hawkgo.Credentials{
+		ID:       id,
+		Key:      key,
+		Hash:     h,
+		App:      o.app,
+		Delegate: o.delegate,
+	}, o.offset)
+	auth.Ext = o.ext
+
+	if o.payloadHash && req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		ph := auth.PayloadHash(hawk.NormalizeContentType(req.Header.Get("Content-Type")))
+		ph.Write(body)
+		auth.SetHash(ph)
+	}
+	req.Header.Set("Authorization", auth.RequestHeader())
+	return auth, nil
+}
+
+// Transport is an http.RoundTripper signing the requests with the
+// credentials ID and Key.
+// Base is the RoundTripper sending the requests, http.DefaultTransport if nil
+// Options are the signing options
+// VerifyResponse if true checks the "Server-Authorization" header of the responses
+// Retry if set retries the failed requests (see DefaultRetryPolicy), the bodies are buffered to be sent again
+type Transport struct {
+	Base           http.RoundTripper
+	ID             string
+	Key            string
+	Options        []Option
+	VerifyResponse bool
+	Retry          *RetryPolicy
+
+	offset atomic.Int64
+}
+
+// NewTransport creates a new Transport.
+func NewTransport(id, key string, opts ...Option) *Transport {
+	return &Transport{
+		ID:      id,
+		Key:     key,
+		Options: opts,
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// ClockOffset returns the clock offset with the server learned from the
+// stale timestamp responses, added to the Offset option.
+func (t *Transport) ClockOffset() time.Duration {
+	return time.Duration(t.offset.Load())
+}
+
+// RoundTrip signs a copy of req and sends it, retried with the Retry
+// policy. A response without a valid "Server-Authorization" header is
+// closed and an error returned if VerifyResponse is set.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if t.Retry != nil && req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	skewed := false
+	for attempt := 0; ; attempt++ {
+		r := req.Clone(req.Context())
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		o := newOptions(t.Options)
+		o.offset += t.ClockOffset()
+		auth, err := sign(r, t.ID, t.Key, o)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.base().RoundTrip(r)
+
+		wait, retry := t.retry(r, attempt, resp, err, o, &skewed)
+		if !retry {
+			if err != nil || !t.VerifyResponse {
+				return resp, err
+			}
+			if err := auth.ValidResponse(resp.Header.Get("Server-Authorization")); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retry returns the wait before the next attempt and true if the
+// request must be retried.
+func (t *Transport) retry(req *http.Request, attempt int, resp *http.Response, err error, o *options, skewed *bool) (time.Duration, bool) {
+	p := t.Retry
+	if p == nil {
+		return 0, false
+	}
+	switch {
+	case err != nil:
+		retry := p.ConnectionErrors && (p.NonIdempotent || idempotent(req))
+		return p.backoff(attempt), retry && attempt < p.MaxRetries
+	case resp.StatusCode == http.StatusUnauthorized:
+		if !p.Skew || *skewed {
+			return 0, false
+		}
+		now, ok := serverTime(resp.Header.Get("WWW-Authenticate"), t.Key, o.algorithm)
+		if !ok {
+			return 0, false
+		}
+		// o.offset is the Offset option plus the previous clock offset
+		t.offset.Store(int64(time.Until(now) - o.offset + t.ClockOffset()))
+		*skewed = true
+		return 0, true
+	case resp.StatusCode == http.StatusTooManyRequests:
+		if !p.TooManyRequests || attempt >= p.MaxRetries {
+			return 0, false
+		}
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return p.limit(d), true
+		}
+		return p.backoff(attempt), true
+	}
+	return 0, false
+}
@@ -0,0 +1,13 @@
+package hawkclient_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHawkclient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hawkclient Suite")
+}
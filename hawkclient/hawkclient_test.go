@@ -0,0 +1,140 @@
+package hawkclient_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyperboloide/hawk"
+	. "github.com/hyperboloide/hawk/hawkclient"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("hawkclient", func() {
+
+	var ts *httptest.Server
+	var store *hawktest.Store
+	var hm *hawk.Middleware
+
+	BeforeEach(func() {
+		store = hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		creds := store.Add("sha512-id", "sha512-key")
+		creds.Hash, _ = hawk.HashFunc(hawk.SHA512)
+		hm = store.Middleware()
+		hm.ValidatePayload = true
+		hm.ValidateExt = func(ext string) error {
+			if ext != "" && ext != "good" {
+				return hawk.ErrInvalidExt
+			}
+			return nil
+		}
+		router := gin.New()
+		router.Any("/echo", hm.Filter, func(c *gin.Context) {
+			app, dlg, _ := hawk.AppFromContext(c)
+			body, _ := io.ReadAll(c.Request.Body)
+			c.String(200, app+"|"+dlg+"|"+string(body))
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	do := func(req *http.Request) (int, string) {
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	It("signs requests with a payload hash", func() {
+		req, _ := http.NewRequest("POST", ts.URL+"/echo", strings.NewReader("hello"))
+		req.Header.Set("Content-Type", "text/plain")
+		auth, err := SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(req.Header.Get("Authorization")).To(ContainSubstring(`hash="`))
+		Expect(req.GetBody).ToNot(BeNil())
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(auth.ValidResponse(resp.Header.Get("Server-Authorization"))).To(Succeed())
+	})
+
+	It("applies the options", func() {
+		req, _ := http.NewRequest("POST", ts.URL+"/echo", strings.NewReader("hello"))
+		_, err := SignRequest(req, "sha512-id", "sha512-key",
+			Algorithm(hawk.SHA512), Ext("good"), App("my-app", "my-dlg"), PayloadHash(false))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(req.Header.Get("Authorization")).ToNot(ContainSubstring(`hash="`))
+		status, body := do(req)
+		Expect(status).To(Equal(200))
+		Expect(body).To(Equal("my-app|my-dlg|hello"))
+
+		req, _ = http.NewRequest("GET", ts.URL+"/echo", nil)
+		SignRequest(req, "my-id", "my-key", Ext("bad"))
+		status, _ = do(req)
+		Expect(status).To(Equal(401))
+
+		req, _ = http.NewRequest("GET", ts.URL+"/echo", nil)
+		SignRequest(req, "my-id", "my-key", Offset(-time.Hour))
+		status, _ = do(req)
+		Expect(status).To(Equal(401))
+
+		_, err = SignRequest(req, "my-id", "my-key", Algorithm("md5"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("signs the requests of a client", func() {
+		t := NewTransport("my-id", "my-key")
+		t.VerifyResponse = true
+		client := &http.Client{Transport: t}
+
+		req, _ := http.NewRequest("PUT", ts.URL+"/echo", strings.NewReader("hello"))
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(string(body)).To(Equal("||hello"))
+		Expect(req.Header.Get("Authorization")).To(BeEmpty())
+
+		t.Key = "invalid"
+		_, err = client.Get(ts.URL + "/echo")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("signs escaped paths", func() {
+		router := gin.New()
+		router.GET("/files/*name", hm.Filter, func(c *gin.Context) {
+			c.String(200, c.Param("name"))
+		})
+		files := httptest.NewServer(router)
+		defer files.Close()
+
+		for _, path := range []string{"/files/a%20b", "/files/a%2Fb"} {
+			req, _ := http.NewRequest("GET", files.URL+path, nil)
+			_, err := SignRequest(req, "my-id", "my-key")
+			Expect(err).ToNot(HaveOccurred())
+			code, _ := do(req)
+			Expect(code).To(Equal(200), path)
+
+			signed, err := BewitURL(files.URL+path, "my-id", "my-key", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			req, _ = http.NewRequest("GET", signed, nil)
+			code, _ = do(req)
+			Expect(code).To(Equal(200), path)
+		}
+	})
+
+})
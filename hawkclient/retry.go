@@ -0,0 +1,116 @@
+package hawkclient
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+// RetryPolicy sets which requests the Transport retries.
+// MaxRetries is the maximum number of retries after the first attempt
+// MinBackoff is the wait before the first retry, doubled for each retry
+// MaxBackoff if set is the maximum wait, "Retry-After" headers included
+// Skew if true retries once the requests rejected with the server time (stale timestamp), the clock offset is kept for the next requests
+// ConnectionErrors if true retries the idempotent requests failing without a response
+// NonIdempotent if true also retries the connection errors of the non idempotent requests, they may have been processed by the server
+// TooManyRequests if true retries the 429 responses after their "Retry-After" header or the backoff
+type RetryPolicy struct {
+	MaxRetries       int
+	MinBackoff       time.Duration
+	MaxBackoff       time.Duration
+	Skew             bool
+	ConnectionErrors bool
+	NonIdempotent    bool
+	TooManyRequests  bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy retrying all the cases but
+// the connection errors of non idempotent requests up to 3 times,
+// waiting from 100ms to 5s.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries:       3,
+		MinBackoff:       100 * time.Millisecond,
+		MaxBackoff:       5 * time.Second,
+		Skew:             true,
+		ConnectionErrors: true,
+		TooManyRequests:  true,
+	}
+}
+
+// idempotent returns true if req can be sent again after a connection
+// error: its method is idempotent or it has an "Idempotency-Key" header.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// backoff returns the wait before the retry following attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff << attempt
+	if d < p.MinBackoff {
+		d = p.MaxBackoff
+	}
+	return p.limit(d)
+}
+
+func (p *RetryPolicy) limit(d time.Duration) time.Duration {
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// retryAfter returns the wait of a "Retry-After" header in seconds or
+// as a date, false if unset or invalid.
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	} else if s, err := strconv.Atoi(header); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	} else if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+var staleTimestamp = regexp.MustCompile(`ts="(\d+)", tsm="([^"]+)"`)
+
+// serverTime returns the server time of a stale timestamp
+// "WWW-Authenticate" header, false if absent or not signed by key.
+func serverTime(header, key string, algorithm string) (time.Time, bool) {
+	m := staleTimestamp.FindStringSubmatch(header)
+	if m == nil {
+		return time.Time{}, false
+	}
+	tsm, err := base64.StdEncoding.DecodeString(m[2])
+	if err != nil {
+		return time.Time{}, false
+	}
+	h, err := hawk.HashFunc(algorithm)
+	if err != nil {
+		return time.Time{}, false
+	}
+	mac := (&hawkgo.Credentials{Key: key, Hash: h}).MAC()
+	mac.Write([]byte("hawk.1.ts\n" + m[1] + "\n"))
+	if !hmac.Equal(mac.Sum(nil), tsm) {
+		return time.Time{}, false
+	}
+	ts, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
@@ -0,0 +1,183 @@
+package hawkclient_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk/hawkclient"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var _ = Describe("RetryPolicy", func() {
+
+	var ts *httptest.Server
+	var clock *hawktest.Clock
+	var limited, attempts int
+	var t *Transport
+	var client *http.Client
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		clock = hawktest.NewClock(time.Now())
+		hm := store.Middleware()
+		hm.Now = clock.Now
+		hm.ValidatePayload = true
+		limited, attempts = 0, 0
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			attempts++
+		})
+		router.Any("/echo", hm.Filter, func(c *gin.Context) {
+			if limited > 0 {
+				limited--
+				c.Header("Retry-After", "0")
+				c.AbortWithStatus(http.StatusTooManyRequests)
+				return
+			}
+			body, _ := io.ReadAll(c.Request.Body)
+			c.String(200, string(body))
+		})
+		ts = httptest.NewServer(router)
+
+		t = NewTransport("my-id", "my-key")
+		t.Retry = DefaultRetryPolicy()
+		t.Retry.MinBackoff = time.Millisecond
+		t.VerifyResponse = true
+		client = &http.Client{Transport: t}
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("retries with the server time after a stale timestamp", func() {
+		clock.Advance(10 * time.Minute)
+		resp, err := client.Get(ts.URL + "/echo")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(attempts).To(Equal(2))
+		Expect(t.ClockOffset()).To(BeNumerically("~", 10*time.Minute, 2*time.Second))
+
+		resp, err = client.Get(ts.URL + "/echo")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("does not retry the skews when disabled or the key is invalid", func() {
+		clock.Advance(10 * time.Minute)
+		t.Retry.Skew = false
+		resp, err := client.Get(ts.URL + "/echo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(401))
+		Expect(attempts).To(Equal(1))
+
+		t.Retry.Skew = true
+		t.VerifyResponse = false
+		t.Key = "invalid"
+		resp, err = client.Get(ts.URL + "/echo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(401))
+		Expect(attempts).To(Equal(2))
+	})
+
+	It("retries the rate limited requests with their body", func() {
+		limited = 2
+		resp, err := client.Post(ts.URL+"/echo", "text/plain", strings.NewReader("hello"))
+		Expect(err).ToNot(HaveOccurred())
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(string(body)).To(Equal("hello"))
+		Expect(attempts).To(Equal(3))
+
+		limited = 5
+		t.Retry.MaxRetries = 1
+		resp, err = client.Get(ts.URL + "/echo")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(attempts).To(Equal(5))
+	})
+
+	It("retries the connection errors", func() {
+		failures := 2
+		t.Base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if failures > 0 {
+				failures--
+				return nil, errors.New("connection refused")
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		})
+		resp, err := client.Get(ts.URL + "/echo")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+
+		failures = 2
+		t.Retry.ConnectionErrors = false
+		_, err = client.Get(ts.URL + "/echo")
+		Expect(err).To(HaveOccurred())
+		Expect(failures).To(Equal(1))
+	})
+
+	It("retries the connection errors of non idempotent requests on demand", func() {
+		failures := 1
+		t.Base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if failures > 0 {
+				failures--
+				return nil, errors.New("connection reset")
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		})
+		_, err := client.Post(ts.URL+"/echo", "text/plain", strings.NewReader("hello"))
+		Expect(err).To(HaveOccurred())
+
+		failures = 1
+		req, _ := http.NewRequest("POST", ts.URL+"/echo", strings.NewReader("hello"))
+		req.Header.Set("Idempotency-Key", "key-1")
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+
+		failures = 1
+		t.Retry.NonIdempotent = true
+		resp, err = client.Post(ts.URL+"/echo", "text/plain", strings.NewReader("hello"))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+	})
+
+	It("stops waiting when the request is canceled", func() {
+		limited = 5
+		t.Retry.MinBackoff = time.Hour
+		ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/echo", nil)
+		_, err := client.Do(req)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+})
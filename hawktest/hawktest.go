@@ -0,0 +1,146 @@
+// Package hawktest provides utilities to write integration tests for Hawk
+// protected handlers: an in-memory credentials and nonce Store, request
+// and bewit signing helpers, and a fake Clock.
+//
+//	store := hawktest.NewStore()
+//	store.Add("id", "key")
+//	router.GET("/private", store.Middleware().Filter, handler)
+//
+//	req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+//	auth, err := hawktest.SignRequest(req, "id", "key")
+package hawktest
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawkclient"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+// Store is an in-memory credentials and nonce store, safe for concurrent use.
+type Store struct {
+	mu     sync.Mutex
+	creds  map[string]*hawk.Credentials
+	nonces map[string]bool
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{
+		creds:  map[string]*hawk.Credentials{},
+		nonces: map[string]bool{},
+	}
+}
+
+// Add saves credentials with key for id, id is also the User.
+func (s *Store) Add(id, key string) *hawk.Credentials {
+	creds := &hawk.Credentials{Key: key, User: id}
+	s.SetCredentials(id, creds)
+	return creds
+}
+
+// SetCredentials is a hawk.SetCredentialsFunc.
+func (s *Store) SetCredentials(id string, creds *hawk.Credentials) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.creds[id] = creds
+	return nil
+}
+
+// Delete removes the credentials of id.
+func (s *Store) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.creds, id)
+}
+
+// GetCredentials is a hawk.GetCredentialFunc.
+func (s *Store) GetCredentials(id string) (*hawk.Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.creds[id], nil
+}
+
+// SetNonce is a hawk.SetNonceFunc, nonces are kept until ResetNonces.
+func (s *Store) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := id + ":" + nonce
+	if s.nonces[key] {
+		return false, nil
+	}
+	s.nonces[key] = true
+	return true, nil
+}
+
+// ResetNonces forgets the saved nonces.
+func (s *Store) ResetNonces() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nonces = map[string]bool{}
+}
+
+// Middleware creates a hawk.Middleware using the Store.
+func (s *Store) Middleware() *hawk.Middleware {
+	return hawk.NewMiddleware(s.GetCredentials, s.SetNonce)
+}
+
+// SignRequest sets the "Authorization" header of req for the sha256
+// credentials id and key. The payload hash is included if req has a body.
+// The returned auth validates the "Server-Authorization" response header.
+func SignRequest(req *http.Request, id, key string) (*hawkgo.Auth, error) {
+	return hawkclient.SignRequest(req, id, key)
+}
+
+// NewBewitURL returns rawurl with a "bewit" query parameter for the sha256
+// credentials id and key, valid for ttl.
+func NewBewitURL(rawurl, id, key string, ttl time.Duration) (string, error) {
+	return hawkclient.BewitURL(rawurl, id, key, ttl)
+}
+
+// Clock is a fake clock, safe for concurrent use. Set the Middleware Now
+// to the Clock Now to verify requests at the Clock time.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock creates a Clock frozen at t.
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+// Now returns the current time of the Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set sets the current time of the Clock.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the Clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Install makes the protocol package use the Clock to sign and validate
+// timestamps until restore is called. It changes a global, so tests using
+// it must not run in parallel.
+func (c *Clock) Install() (restore func()) {
+	prev := hawkgo.Now
+	hawkgo.Now = c.Now
+	return func() {
+		hawkgo.Now = prev
+	}
+}
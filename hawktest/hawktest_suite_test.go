@@ -0,0 +1,13 @@
+package hawktest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHawktest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hawktest Suite")
+}
@@ -0,0 +1,114 @@
+package hawktest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hawktest", func() {
+
+	var ts *httptest.Server
+	var store *Store
+
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		store = NewStore()
+		store.Add("id", "test-key")
+		hm := store.Middleware()
+		hm.ValidatePayload = true
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		router.POST("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	do := func(req *http.Request) *http.Response {
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	It("signs requests", func() {
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		auth, err := SignRequest(req, "id", "test-key")
+		Expect(err).ToNot(HaveOccurred())
+		resp := do(req)
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(auth.ValidResponse(resp.Header.Get("Server-Authorization"))).To(Succeed())
+
+		req, _ = http.NewRequest("GET", ts.URL+"/private", nil)
+		_, err = SignRequest(req, "id", "wrong-key")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(do(req).StatusCode).To(Equal(401))
+	})
+
+	It("signs payloads", func() {
+		req, _ := http.NewRequest("POST", ts.URL+"/private", strings.NewReader(`{"a":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		_, err := SignRequest(req, "id", "test-key")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(req.Header.Get("Authorization")).To(ContainSubstring(`hash="`))
+		Expect(do(req).StatusCode).To(Equal(200))
+	})
+
+	It("rejects replays until nonces are reset", func() {
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		_, err := SignRequest(req, "id", "test-key")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(do(req).StatusCode).To(Equal(200))
+		Expect(do(req).StatusCode).To(Equal(401))
+		store.ResetNonces()
+		Expect(do(req).StatusCode).To(Equal(200))
+	})
+
+	It("deletes credentials", func() {
+		store.Delete("id")
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		_, err := SignRequest(req, "id", "test-key")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(do(req).StatusCode).To(Equal(401))
+	})
+
+	It("creates bewit urls that expire with the clock", func() {
+		clock := NewClock(time.Now())
+		restore := clock.Install()
+		defer restore()
+
+		u, err := NewBewitURL(ts.URL+"/private?a=1", "id", "test-key", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(u).To(ContainSubstring("?a=1&bewit="))
+		req, _ := http.NewRequest("GET", u, nil)
+		Expect(do(req).StatusCode).To(Equal(200))
+
+		clock.Advance(2 * time.Minute)
+		req, _ = http.NewRequest("GET", u, nil)
+		Expect(do(req).StatusCode).To(Equal(401))
+	})
+
+	It("freezes time", func() {
+		t := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := NewClock(t)
+		Expect(clock.Now()).To(Equal(t))
+		clock.Advance(time.Hour)
+		Expect(clock.Now()).To(Equal(t.Add(time.Hour)))
+		clock.Set(t)
+		Expect(clock.Now()).To(Equal(t))
+	})
+
+})
@@ -0,0 +1,69 @@
+package hawk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"sync"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// headerPool holds the buffers of the "Server-Authorization" headers.
+var headerPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// noExt are the extParts of an empty Ext.
+var noExt = newExtParts("")
+
+// extParts are the portions of the response header that only depend
+// on the Middleware Ext.
+type extParts struct {
+	ext    string
+	header string
+}
+
+func newExtParts(ext string) *extParts {
+	res := &extParts{ext: ext}
+	if ext != "" {
+		res.header = `, ext="` + ext + `"`
+	}
+	return res
+}
+
+// PrecomputeExt precomputes the static Ext portions of the
+// "Server-Authorization" header. It must be called again if Ext is changed.
+func (hm *Middleware) PrecomputeExt() {
+	hm.ext = newExtParts(hm.Ext)
+}
+
+// responseHeader returns the "Server-Authorization" header value without
+// altering auth.
+func (hm *Middleware) responseHeader(auth *hawk.Auth) string {
+	ext := hm.ext
+	if ext == nil && hm.Ext == "" {
+		ext = noExt
+	} else if ext == nil || ext.ext != hm.Ext {
+		ext = newExtParts(hm.Ext)
+	}
+	return responseHeader(auth, ext)
+}
+
+// responseHeader returns the "Server-Authorization" header value with ext.
+func responseHeader(auth *hawk.Auth, ext *extParts) string {
+	var encoded [128]byte
+	sum := auth.ResponseMAC(ext.ext)
+	n := base64.StdEncoding.EncodedLen(len(sum))
+	base64.StdEncoding.Encode(encoded[:n], sum)
+
+	buf := headerPool.Get().(*bytes.Buffer)
+	defer headerPool.Put(buf)
+	buf.Reset()
+	buf.WriteString(`Hawk mac="`)
+	buf.Write(encoded[:n])
+	buf.WriteByte('"')
+	buf.WriteString(ext.header)
+	return buf.String()
+}
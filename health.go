@@ -0,0 +1,62 @@
+package hawk
+
+import (
+	"context"
+	"errors"
+)
+
+// Pinger is implemented by the stores that can check their backend
+// connection, see HealthCheck.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingerFunc is a function implementing Pinger.
+type PingerFunc func(ctx context.Context) error
+
+// Ping calls f.
+func (f PingerFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+// pingers returns the Pingers, with the current CredentialProvider and
+// NonceStore if they implement Pinger, including the ones set by
+// SetCredentialProvider and SetNonceStore.
+func (hm *Middleware) pingers() []Pinger {
+	res := make([]Pinger, 0, len(hm.Pingers)+2)
+	if p, ok := hm.credentialProvider().(Pinger); ok {
+		res = append(res, p)
+	}
+	if p, ok := hm.nonceStore().(Pinger); ok {
+		res = append(res, p)
+	}
+	return append(res, hm.Pingers...)
+}
+
+// HealthCheck pings the current CredentialProvider and NonceStore if
+// they implement Pinger, and the Pingers, concurrently. It returns
+// their errors joined, nil if all are healthy. Wire it into a readiness
+// probe so instances fail fast when a store is down:
+//
+//	router.GET("/ready", func(c *gin.Context) {
+//		if err := hm.HealthCheck(c.Request.Context()); err != nil {
+//			c.String(http.StatusServiceUnavailable, err.Error())
+//			return
+//		}
+//		c.String(http.StatusOK, "ok")
+//	})
+func (hm *Middleware) HealthCheck(ctx context.Context) error {
+	pingers := hm.pingers()
+	errs := make([]error, len(pingers))
+	done := make(chan struct{})
+	for i, p := range pingers {
+		go func(i int, p Pinger) {
+			errs[i] = p.Ping(ctx)
+			done <- struct{}{}
+		}(i, p)
+	}
+	for range pingers {
+		<-done
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package hawk_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/hyperboloide/hawk"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// pingStore is a credentials provider and nonce store failing its
+// pings with err.
+type pingStore struct {
+	err error
+}
+
+func (s *pingStore) Get(ctx context.Context, id string) (*Credentials, error) {
+	return nil, ErrNotFound
+}
+
+func (s *pingStore) Close() error {
+	return nil
+}
+
+func (s *pingStore) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	return true, nil
+}
+
+func (s *pingStore) Ping(ctx context.Context) error {
+	return s.err
+}
+
+var _ = Describe("HealthCheck", func() {
+
+	It("pings the stores", func() {
+		hm := NewMiddleware(nil, nil)
+		Expect(hm.HealthCheck(context.Background())).To(Succeed())
+
+		redisErr := errors.New("redis is down")
+		healthy := PingerFunc(func(ctx context.Context) error {
+			return nil
+		})
+		hm.Pingers = []Pinger{healthy, healthy}
+		Expect(hm.HealthCheck(context.Background())).To(Succeed())
+
+		hm.Pingers = append(hm.Pingers, PingerFunc(func(ctx context.Context) error {
+			return redisErr
+		}))
+		err := hm.HealthCheck(context.Background())
+		Expect(errors.Is(err, redisErr)).To(BeTrue())
+	})
+
+	It("pings the current credentials provider and nonce store", func() {
+		hm := NewMiddleware(nil, nil)
+		dbErr := errors.New("database is down")
+		nonceErr := errors.New("nonce store is down")
+		hm.CredentialProvider = &pingStore{err: dbErr}
+		err := hm.HealthCheck(context.Background())
+		Expect(errors.Is(err, dbErr)).To(BeTrue())
+
+		hm.SetCredentialProvider(&pingStore{})
+		Expect(hm.HealthCheck(context.Background())).To(Succeed())
+
+		hm.SetNonceStore(&pingStore{err: nonceErr})
+		err = hm.HealthCheck(context.Background())
+		Expect(errors.Is(err, nonceErr)).To(BeTrue())
+	})
+
+})
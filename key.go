@@ -0,0 +1,80 @@
+package hawk
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrWeakKey is returned when a key does not satisfy a KeyPolicy.
+var ErrWeakKey = errors.New("Credentials key is too weak")
+
+// KeyPolicy is the minimum strength required for credentials keys.
+// MinLength is the minimum number of characters.
+// MinEntropy is the minimum estimated entropy in bits (see KeyEntropy).
+type KeyPolicy struct {
+	MinLength  int
+	MinEntropy float64
+}
+
+// DefaultKeyPolicy is satisfied by the keys generated with NewCredential.
+var DefaultKeyPolicy = KeyPolicy{
+	MinLength:  16,
+	MinEntropy: 48,
+}
+
+// Check returns ErrWeakKey if key does not satisfy the policy.
+func (kp KeyPolicy) Check(key string) error {
+	if len(key) < kp.MinLength || KeyEntropy(key) < kp.MinEntropy {
+		return ErrWeakKey
+	}
+	return nil
+}
+
+// KeyEntropy estimates the entropy in bits of key from the frequency
+// of its characters. Repetitive or low variety keys get a low score.
+func KeyEntropy(key string) float64 {
+	if len(key) == 0 {
+		return 0
+	}
+	freq := map[rune]float64{}
+	n := 0.0
+	for _, r := range key {
+		freq[r]++
+		n++
+	}
+	perChar := 0.0
+	for _, f := range freq {
+		p := f / n
+		perChar -= p * math.Log2(p)
+	}
+	return perChar * n
+}
+
+// checkKeys validates the keys of the credentials found for id.
+// Empty or too short keys are invalid, weak keys are reported to
+// the OnWeakKey callback and rejected with ErrWeakKey in StrictMode.
+func (hr *Request) checkKeys(id string, creds *Credentials) error {
+	weak := false
+	min := hr.Hawk.minKeyLength()
+	for _, key := range append([]string{creds.Key}, creds.Keys...) {
+		if key == "" || len(key) < min {
+			if hr.Hawk.OnInvalidKey != nil {
+				hr.Hawk.OnInvalidKey(id)
+			}
+			return ErrInvalidKey
+		}
+		if hr.Hawk.KeyPolicy != nil && hr.Hawk.KeyPolicy.Check(key) != nil {
+			weak = true
+		}
+	}
+	if !weak {
+		return nil
+	}
+	if hr.Hawk.OnWeakKey != nil {
+		hr.Hawk.OnWeakKey(id)
+	}
+	if hr.Hawk.StrictMode {
+		return ErrWeakKey
+	}
+	return nil
+}
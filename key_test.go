@@ -0,0 +1,56 @@
+package hawk_test
+
+import (
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyPolicy", func() {
+
+	It("estimates entropy", func() {
+		Expect(KeyEntropy("")).To(BeZero())
+		Expect(KeyEntropy("aaaaaaaaaaaaaaaaaaaaaaaa")).To(BeZero())
+		Expect(KeyEntropy("abab")).To(BeNumerically("==", 4))
+	})
+
+	It("accepts generated keys", func() {
+		for i := 0; i < 100; i++ {
+			_, key := GenIDKey()
+			Expect(DefaultKeyPolicy.Check(key)).To(Succeed())
+		}
+	})
+
+	It("rejects weak keys", func() {
+		Expect(DefaultKeyPolicy.Check("short")).To(Equal(ErrWeakKey))
+		Expect(DefaultKeyPolicy.Check("aaaaaaaaaaaaaaaaaaaaaaaa")).To(Equal(ErrWeakKey))
+		Expect(DefaultKeyPolicy.Check("passwordpassword")).To(Equal(ErrWeakKey))
+	})
+
+	It("is optionally checked at verification time", func() {
+		weak := ""
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{Key: "aaaaaaaaaaaaaaaaaaaaaaaa"}, nil
+		}, nil)
+		hm.KeyPolicy = &DefaultKeyPolicy
+		hm.OnWeakKey = func(id string) {
+			weak = id
+		}
+		hr := &Request{Hawk: hm}
+		Expect(hr.CredentialsLookup(&hawk.Credentials{ID: "weak-id"})).To(Succeed())
+		Expect(weak).To(Equal("weak-id"))
+	})
+
+	It("rejects weak keys in StrictMode", func() {
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{Key: "aaaaaaaaaaaaaaaaaaaaaaaa"}, nil
+		}, nil)
+		hm.KeyPolicy = &DefaultKeyPolicy
+		hm.StrictMode = true
+		hr := &Request{Hawk: hm}
+		Expect(hr.CredentialsLookup(&hawk.Credentials{ID: "weak-id"})).To(Equal(ErrWeakKey))
+	})
+
+})
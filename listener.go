@@ -0,0 +1,65 @@
+package hawk
+
+import (
+	"net"
+	"net/http"
+)
+
+// HostOverride replaces the host and/or port the client used to sign
+// the request when verifying the MAC. Empty values are left unchanged.
+type HostOverride struct {
+	Host string
+	Port string
+}
+
+// localAddr returns the local address of the connection that accepted r.
+func localAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		return addr.String()
+	}
+	return ""
+}
+
+// listenerOverride returns the HostOverride of the listener that accepted r.
+// ListenerOverrides keys are matched against the local "ip:port" first,
+// then against ":port".
+func (hm *Middleware) listenerOverride(r *http.Request) (HostOverride, bool) {
+	if len(hm.ListenerOverrides) == 0 {
+		return HostOverride{}, false
+	}
+	addr := localAddr(r)
+	if o, exists := hm.ListenerOverrides[addr]; exists {
+		return o, true
+	}
+	if _, port, err := net.SplitHostPort(addr); err == nil {
+		o, exists := hm.ListenerOverrides[":"+port]
+		return o, exists
+	}
+	return HostOverride{}, false
+}
+
+// overrideHost returns a shallow copy of r with the host and port
+// overridden by o.
+func overrideHost(r *http.Request, o HostOverride) *http.Request {
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host, port = r.Host, ""
+	}
+	if o.Host != "" {
+		host = o.Host
+	}
+	if o.Port != "" {
+		port = o.Port
+	}
+
+	res := *r
+	u := *r.URL
+	u.Host = ""
+	res.URL = &u
+	if port != "" {
+		res.Host = net.JoinHostPort(host, port)
+	} else {
+		res.Host = host
+	}
+	return &res
+}
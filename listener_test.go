@@ -0,0 +1,89 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListenerOverrides", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+
+	credentials := &hawk.Credentials{
+		ID:   "valid-id",
+		Key:  "test-cred-key",
+		Hash: sha256.New,
+	}
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	publicRequest := func() *http.Response {
+		signed, err := http.NewRequest("GET", "https://api.example.com/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(signed, credentials, 0)
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	It("fails without override", func() {
+		Expect(publicRequest().StatusCode).To(Equal(401))
+	})
+
+	It("overrides by listener address", func() {
+		hm.ListenerOverrides = map[string]HostOverride{
+			ts.Listener.Addr().String(): {Host: "api.example.com", Port: "443"},
+		}
+		Expect(publicRequest().StatusCode).To(Equal(200))
+	})
+
+	It("overrides by listener port", func() {
+		_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+		Expect(err).ToNot(HaveOccurred())
+		hm.ListenerOverrides = map[string]HostOverride{
+			":" + port: {Host: "api.example.com", Port: "443"},
+		}
+		Expect(publicRequest().StatusCode).To(Equal(200))
+	})
+
+	It("ignores other listeners", func() {
+		hm.ListenerOverrides = map[string]HostOverride{
+			"10.0.0.1:8443": {Host: "api.example.com", Port: "443"},
+		}
+		Expect(publicRequest().StatusCode).To(Equal(401))
+	})
+
+})
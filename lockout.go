@@ -0,0 +1,177 @@
+package hawk
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLockedOut is set in context.Err when a credentials id is temporarily
+// locked for a client IP after too many invalid MACs. The response
+// status is 429.
+var ErrLockedOut = errors.New("Too many failed attempts")
+
+// FailureStore stores the consecutive failure counters of the Lockout.
+// Incr increments the counter of key and returns the new value, the
+// counter expires after ttl. Get returns the current value (0 if unknown)
+// and Reset deletes the counter.
+type FailureStore interface {
+	Incr(key string, ttl time.Duration) (int, error)
+	Get(key string) (int, error)
+	Reset(key string) error
+}
+
+// Lockout rejects requests for a credentials id and client IP after
+// Threshold consecutive invalid MACs, until Duration elapsed since the
+// last failure.
+type Lockout struct {
+	Store     FailureStore
+	Threshold int
+	Duration  time.Duration
+}
+
+// NewLockout creates a new Lockout with an in-memory FailureStore.
+func NewLockout(threshold int, d time.Duration) *Lockout {
+	return &Lockout{
+		Store:     NewMemoryFailureStore(),
+		Threshold: threshold,
+		Duration:  d,
+	}
+}
+
+func lockoutKey(id, ip string) string {
+	return id + "|" + ip
+}
+
+// Locked returns true if id is locked out for ip.
+func (l *Lockout) Locked(id, ip string) (bool, error) {
+	n, err := l.Store.Get(lockoutKey(id, ip))
+	if err != nil {
+		return false, err
+	}
+	return n >= l.Threshold, nil
+}
+
+// Failed records an invalid MAC for id and ip.
+func (l *Lockout) Failed(id, ip string) error {
+	_, err := l.Store.Incr(lockoutKey(id, ip), l.Duration)
+	return err
+}
+
+// Succeeded resets the counter of id and ip.
+func (l *Lockout) Succeeded(id, ip string) error {
+	return l.Store.Reset(lockoutKey(id, ip))
+}
+
+// DefaultFailureStoreSize is the default MemoryFailureStore MaxKeys.
+const DefaultFailureStoreSize = 100000
+
+type failureCounter struct {
+	key     string
+	count   int
+	expires time.Time
+}
+
+// MemoryFailureStore is an in-memory FailureStore.
+// MaxKeys if set bounds the counters, the least recently incremented
+// ones are evicted once the expired ones are swept, so the new keys are
+// always counted. The expired counters are also swept every ttl of Incr.
+type MemoryFailureStore struct {
+	MaxKeys int
+
+	mu        sync.Mutex
+	counters  map[string]*list.Element
+	lru       *list.List
+	nextSweep time.Time
+}
+
+// NewMemoryFailureStore creates a new MemoryFailureStore.
+func NewMemoryFailureStore() *MemoryFailureStore {
+	return &MemoryFailureStore{
+		MaxKeys:  DefaultFailureStoreSize,
+		counters: map[string]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// Incr increments the counter of key.
+func (s *MemoryFailureStore) Incr(key string, ttl time.Duration) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, exists := s.counters[key]
+	if !exists {
+		el = s.lru.PushFront(&failureCounter{key: key})
+		s.counters[key] = el
+	} else {
+		s.lru.MoveToFront(el)
+	}
+	c := el.Value.(*failureCounter)
+	if now.After(c.expires) {
+		c.count = 0
+	}
+	c.count++
+	c.expires = now.Add(ttl)
+
+	full := s.MaxKeys > 0 && s.lru.Len() > s.MaxKeys
+	if full || now.After(s.nextSweep) {
+		s.sweep(now)
+		s.nextSweep = now.Add(ttl)
+	}
+	for s.MaxKeys > 0 && s.lru.Len() > s.MaxKeys {
+		s.remove(s.lru.Back())
+	}
+	return c.count, nil
+}
+
+// Get returns the counter of key.
+func (s *MemoryFailureStore) Get(key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, exists := s.counters[key]
+	if !exists {
+		return 0, nil
+	} else if c := el.Value.(*failureCounter); time.Now().After(c.expires) {
+		s.remove(el)
+		return 0, nil
+	} else {
+		return c.count, nil
+	}
+}
+
+// sweep removes the expired counters, s.mu must be held.
+func (s *MemoryFailureStore) sweep(now time.Time) {
+	for el := s.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if now.After(el.Value.(*failureCounter).expires) {
+			s.remove(el)
+		}
+		el = prev
+	}
+}
+
+// remove removes the counter of el, s.mu must be held.
+func (s *MemoryFailureStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.counters, el.Value.(*failureCounter).key)
+}
+
+// Len returns the number of counters, expired ones included.
+func (s *MemoryFailureStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// Reset deletes the counter of key.
+func (s *MemoryFailureStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, exists := s.counters[key]; exists {
+		s.remove(el)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lockout", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.Lockout = NewLockout(2, time.Minute)
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(id, key string) int {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   id,
+			Key:  key,
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode
+	}
+
+	It("locks out after consecutive invalid MACs", func() {
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "invalid")).To(Equal(429))
+		Expect(request("a", "test-cred-key")).To(Equal(429))
+		Expect(request("b", "test-cred-key")).To(Equal(200))
+	})
+
+	It("resets on success", func() {
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "test-cred-key")).To(Equal(200))
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "test-cred-key")).To(Equal(200))
+	})
+
+	It("expires counters", func() {
+		store := NewMemoryFailureStore()
+		Expect(store.Incr("k", time.Millisecond)).To(Equal(1))
+		time.Sleep(2 * time.Millisecond)
+		Expect(store.Get("k")).To(Equal(0))
+		Expect(store.Incr("k", time.Minute)).To(Equal(1))
+		Expect(store.Incr("k", time.Minute)).To(Equal(2))
+		Expect(store.Reset("k")).To(Succeed())
+		Expect(store.Get("k")).To(Equal(0))
+	})
+
+	It("bounds and sweeps the counters", func() {
+		store := NewMemoryFailureStore()
+		store.MaxKeys = 2
+		Expect(store.Incr("a", time.Millisecond)).To(Equal(1))
+		Expect(store.Incr("b", time.Minute)).To(Equal(1))
+		time.Sleep(2 * time.Millisecond)
+		Expect(store.Incr("c", time.Minute)).To(Equal(1))
+		Expect(store.Len()).To(Equal(2))
+		Expect(store.Get("a")).To(Equal(0))
+
+		// the least recently incremented counter is evicted when full
+		Expect(store.Incr("b", time.Minute)).To(Equal(2))
+		Expect(store.Incr("d", time.Minute)).To(Equal(1))
+		Expect(store.Incr("d", time.Minute)).To(Equal(2))
+		Expect(store.Len()).To(Equal(2))
+		Expect(store.Get("c")).To(Equal(0))
+		Expect(store.Get("b")).To(Equal(2))
+	})
+
+})
@@ -0,0 +1,77 @@
+package hawk
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// Logger is the minimal logger of the Middleware, implemented by *slog.Logger.
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+// LogIDs sets how credentials ids are logged by the Middleware Logger.
+type LogIDs int
+
+const (
+	// LogIDsClear logs the credentials ids.
+	LogIDsClear LogIDs = iota
+	// LogIDsHashed logs a short hash of the credentials ids, so
+	// attempts can be correlated without exposing ids.
+	LogIDsHashed
+	// LogIDsOmitted does not log the credentials ids.
+	LogIDsOmitted
+)
+
+// done reports the outcome of the authentication of hr to the
+// diagnostics, the Logger and the AuditSink.
+func (hm *Middleware) done(c *gin.Context, hr *Request, auth *hawk.Auth, err error) {
+	hm.setDiagnostics(c, hr, auth, err)
+	hm.logAttempt(c, hr, auth, err)
+	hm.audit(c, hr, auth, err)
+}
+
+// logAttempt logs an authentication attempt if the Logger is set:
+// successes at the debug level, failures at the warn level and internal
+// errors at the error level.
+func (hm *Middleware) logAttempt(c *gin.Context, hr *Request, auth *hawk.Auth, err error) {
+	if hm.Logger == nil {
+		return
+	}
+
+	args := make([]any, 0, 10)
+	if hr.ID != "" {
+		switch hm.LogIDs {
+		case LogIDsClear:
+			args = append(args, "hawk.credential_id", hr.ID)
+		case LogIDsHashed:
+			args = append(args, "hawk.credential_id", credentialLabel(hr.ID))
+		}
+	}
+	if auth != nil {
+		method := "header"
+		if auth.IsBewit {
+			method = "bewit"
+		}
+		args = append(args, "hawk.method", method)
+	}
+	if !hr.start.IsZero() {
+		args = append(args, "hawk.latency", time.Since(hr.start))
+	}
+
+	ctx := c.Request.Context()
+	if err == nil {
+		hm.Logger.Log(ctx, slog.LevelDebug, "hawk authentication succeeded", args...)
+		return
+	}
+	level := slog.LevelWarn
+	if Classify(err).Kind == KindInternal {
+		level = slog.LevelError
+	}
+	args = append(args, "hawk.error", ErrorCode(err), "error", err.Error())
+	hm.Logger.Log(ctx, level, "hawk authentication failed", args...)
+}
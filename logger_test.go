@@ -0,0 +1,102 @@
+package hawk_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logger", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var buf *bytes.Buffer
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		hm = NewMiddleware(func(id string) (*Credentials, error) {
+			if id == "error-id" {
+				return nil, errors.New("store error")
+			}
+			return &Credentials{Key: "test-cred-key"}, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		hm.Logger = slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(id, key string) map[string]interface{} {
+		buf.Reset()
+		req, _ := http.NewRequest("GET", ts.URL+"/private", nil)
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   id,
+			Key:  key,
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(1))
+		var res map[string]interface{}
+		Expect(json.Unmarshal([]byte(lines[0]), &res)).To(Succeed())
+		return res
+	}
+
+	It("logs successes", func() {
+		entry := request("my-id", "test-cred-key")
+		Expect(entry["level"]).To(Equal("DEBUG"))
+		Expect(entry["msg"]).To(Equal("hawk authentication succeeded"))
+		Expect(entry["hawk.credential_id"]).To(Equal("my-id"))
+		Expect(entry["hawk.method"]).To(Equal("header"))
+		Expect(entry).To(HaveKey("hawk.latency"))
+		Expect(entry).ToNot(HaveKey("error"))
+	})
+
+	It("logs failures with their reason", func() {
+		entry := request("my-id", "wrong-key")
+		Expect(entry["level"]).To(Equal("WARN"))
+		Expect(entry["msg"]).To(Equal("hawk authentication failed"))
+		Expect(entry["hawk.error"]).To(Equal("invalid_mac"))
+
+		entry = request("error-id", "test-cred-key")
+		Expect(entry["level"]).To(Equal("ERROR"))
+		Expect(entry["hawk.error"]).To(Equal(ErrorInternal))
+		Expect(entry["error"]).To(Equal("store error"))
+	})
+
+	It("redacts credentials ids", func() {
+		hm.LogIDs = LogIDsHashed
+		entry := request("my-id", "test-cred-key")
+		Expect(entry["hawk.credential_id"]).To(HaveLen(12))
+		Expect(entry["hawk.credential_id"]).ToNot(Equal("my-id"))
+
+		hm.LogIDs = LogIDsOmitted
+		Expect(request("my-id", "test-cred-key")).ToNot(HaveKey("hawk.credential_id"))
+	})
+
+})
@@ -0,0 +1,80 @@
+package hawk
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// ErrMaintenance is set in context.Err when an authenticated request is
+// rejected during a maintenance. The response status is 503.
+var ErrMaintenance = errors.New("Service under maintenance")
+
+type maintenanceState struct {
+	until   time.Time
+	message string
+}
+
+// Maintenance is a time-boxed maintenance mode, safe to toggle at
+// runtime. While enabled verified requests receive a 503 with a
+// "Retry-After" header, unauthenticated requests still get a 401.
+type Maintenance struct {
+	state atomic.Value
+}
+
+// Enable enables the maintenance for d with a message for the clients.
+// It's disabled automatically after d.
+func (m *Maintenance) Enable(d time.Duration, message string) {
+	m.state.Store(&maintenanceState{time.Now().Add(d), message})
+}
+
+// Disable disables the maintenance.
+func (m *Maintenance) Disable() {
+	m.state.Store(&maintenanceState{})
+}
+
+// Active returns true, the remaining duration and the message if the
+// maintenance is enabled.
+func (m *Maintenance) Active() (bool, time.Duration, string) {
+	s, _ := m.state.Load().(*maintenanceState)
+	if s == nil {
+		return false, 0, ""
+	}
+	remaining := time.Until(s.until)
+	if remaining <= 0 {
+		return false, 0, ""
+	}
+	return true, remaining, s.message
+}
+
+// maintenance aborts verified requests with a structured 503 if the
+// Middleware Maintenance is active. It returns true if aborted.
+func (hm *Middleware) maintenance(c *gin.Context, hr *Request, auth *hawk.Auth) bool {
+	if hm.Maintenance == nil {
+		return false
+	}
+	active, remaining, message := hm.Maintenance.Active()
+	if !active {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(remaining.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.Header("Server-Authorization", hm.responseHeader(auth))
+	if hm.abortHandler(c, hr, Classify(ErrMaintenance), auth) {
+		return true
+	}
+	c.Error(Classify(ErrMaintenance))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":       PublicErrorCode(ErrMaintenance),
+		"message":     message,
+		"retry_after": retryAfter,
+	})
+	return true
+}
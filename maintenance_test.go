@@ -0,0 +1,106 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Maintenance", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.Maintenance = &Maintenance{}
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(key string) (*http.Response, *hawk.Auth) {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "id",
+			Key:  key,
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp, auth
+	}
+
+	It("is disabled by default", func() {
+		active, _, _ := hm.Maintenance.Active()
+		Expect(active).To(BeFalse())
+		resp, _ := request("test-cred-key")
+		Expect(resp.StatusCode).To(Equal(200))
+	})
+
+	It("rejects verified requests with a 503", func() {
+		hm.Maintenance.Enable(90*time.Second, "database upgrade")
+
+		resp, auth := request("test-cred-key")
+		Expect(resp.StatusCode).To(Equal(503))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("90"))
+		Expect(auth.ValidResponse(resp.Header.Get("Server-Authorization"))).To(Succeed())
+		body := map[string]interface{}{}
+		Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+		Expect(body).To(Equal(map[string]interface{}{
+			"error":       "maintenance",
+			"message":     "database upgrade",
+			"retry_after": float64(90),
+		}))
+
+		resp, _ = request("bad-key")
+		Expect(resp.StatusCode).To(Equal(401))
+
+		hm.Maintenance.Disable()
+		resp, _ = request("test-cred-key")
+		Expect(resp.StatusCode).To(Equal(200))
+	})
+
+	It("ends automatically", func() {
+		hm.Maintenance.Enable(-time.Second, "")
+		resp, _ := request("test-cred-key")
+		Expect(resp.StatusCode).To(Equal(200))
+	})
+
+	It("calls the AbortHandler", func() {
+		hm.Maintenance.Enable(time.Minute, "")
+		hm.AbortHandler = func(c *gin.Context, err error) {
+			Expect(Classify(err).Kind).To(Equal(KindUnavailable))
+			c.String(503, "later")
+		}
+		resp, _ := request("test-cred-key")
+		Expect(resp.StatusCode).To(Equal(503))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("60"))
+	})
+
+})
@@ -0,0 +1,77 @@
+package hawk
+
+import (
+	"time"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// SignMessage signs msg with the credentials of credID, so that messages
+// sent outside of HTTP (WebSocket frames, queues...) are authenticated with
+// the same credentials as the requests. host and port identify the
+// destination and must be the same when verifying.
+func (hm *Middleware) SignMessage(credID, host, port string, msg []byte) (*hawk.MessageAuth, error) {
+	return hm.SignTenantMessage("", credID, host, port, msg)
+}
+
+// SignTenantMessage is SignMessage with the credentials of credID in
+// tenant, see GetTenantCredentialFunc.
+func (hm *Middleware) SignTenantMessage(tenant, credID, host, port string, msg []byte) (*hawk.MessageAuth, error) {
+	hr, err := hm.messageRequest(tenant)
+	if err != nil {
+		return nil, err
+	}
+	creds := &hawk.Credentials{ID: credID}
+	if err := hr.credentialsLookup(creds); err != nil {
+		return nil, err
+	}
+	return hawk.SignMessage(creds, host, port, msg), nil
+}
+
+// VerifyMessage verifies a message signed with SignMessage using the
+// Middleware credentials and nonces. The nonce is saved only when the MAC
+// is valid. The returned Request holds the credentials id, user and scopes.
+func (hm *Middleware) VerifyMessage(host, port string, msg []byte, m *hawk.MessageAuth) (*Request, error) {
+	return hm.VerifyTenantMessage("", host, port, msg, m)
+}
+
+// VerifyTenantMessage is VerifyMessage with the credentials and nonces
+// of tenant, see GetTenantCredentialFunc.
+func (hm *Middleware) VerifyTenantMessage(tenant, host, port string, msg []byte, m *hawk.MessageAuth) (*Request, error) {
+	hr, err := hm.messageRequest(tenant)
+	if err != nil {
+		return nil, err
+	}
+	creds := &hawk.Credentials{ID: m.ID}
+	if err := hr.credentialsLookup(creds); err != nil {
+		return nil, err
+	}
+
+	err = m.Valid(creds, host, port, msg, hm.now())
+	if err == hawk.ErrInvalidMAC {
+		for _, key := range hr.keys {
+			creds.Key = key
+			if err = m.Valid(creds, host, port, msg, hm.now()); err != hawk.ErrInvalidMAC {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	} else if !hr.NonceCheck(m.Nonce, time.Unix(m.Timestamp, 0), creds) {
+		if hr.Error != nil {
+			return nil, hr.Error
+		}
+		return nil, hawk.ErrReplay
+	}
+	return hr, nil
+}
+
+// messageRequest returns the Request of a message of tenant, which is
+// required with a TenantResolver.
+func (hm *Middleware) messageRequest(tenant string) (*Request, error) {
+	if tenant == "" && hm.TenantResolver != nil {
+		return nil, ErrUnknownTenant
+	}
+	return &Request{Hawk: hm, Tenant: tenant}, nil
+}
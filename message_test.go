@@ -0,0 +1,60 @@
+package hawk_test
+
+import (
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Message", func() {
+
+	var store *hawktest.Store
+	var hm *Middleware
+	msg := []byte(`{"event":"created"}`)
+
+	BeforeEach(func() {
+		store = hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = store.Middleware()
+	})
+
+	It("signs and verifies messages", func() {
+		m, err := hm.SignMessage("my-id", "ws.example.com", "443", msg)
+		Expect(err).ToNot(HaveOccurred())
+		hr, err := hm.VerifyMessage("ws.example.com", "443", msg, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hr.ID).To(Equal("my-id"))
+		Expect(hr.User).To(Equal("my-id"))
+
+		_, err = hm.VerifyMessage("ws.example.com", "443", msg, m)
+		Expect(err).To(Equal(hawk.ErrReplay))
+
+		_, err = hm.SignMessage("unknown-id", "ws.example.com", "443", msg)
+		Expect(err).To(Equal(ErrNotFound))
+	})
+
+	It("rejects tampered messages without saving the nonce", func() {
+		m, err := hm.SignMessage("my-id", "ws.example.com", "443", msg)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = hm.VerifyMessage("ws.example.com", "443", []byte(`{"event":"deleted"}`), m)
+		Expect(err).To(Equal(hawk.ErrInvalidMAC))
+		_, err = hm.VerifyMessage("ws.example.com", "443", msg, m)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("verifies messages signed with a previous key", func() {
+		m, err := hm.SignMessage("my-id", "ws.example.com", "443", msg)
+		Expect(err).ToNot(HaveOccurred())
+		store.SetCredentials("my-id", &Credentials{Key: "new-key", Keys: []string{"my-key"}})
+		_, err = hm.VerifyMessage("ws.example.com", "443", msg, m)
+		Expect(err).ToNot(HaveOccurred())
+
+		store.Delete("my-id")
+		_, err = hm.VerifyMessage("ws.example.com", "443", msg, m)
+		Expect(err).To(Equal(ErrNotFound))
+	})
+
+})
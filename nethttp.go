@@ -0,0 +1,134 @@
+package hawk
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+type resultContextKey struct{}
+
+// httpChain is the Middleware and next handler of a net/http request,
+// passed to the shared engine in the request context. If set handle
+// replaces the Filter.
+type httpChain struct {
+	hm     *Middleware
+	next   http.Handler
+	handle gin.HandlerFunc
+}
+
+type httpChainKey struct{}
+
+var (
+	httpEngineOnce sync.Once
+	httpEngine     *gin.Engine
+)
+
+// engine returns the gin engine running the Filter for the net/http
+// middlewares. The handlers are registered with NoRoute so every method
+// and path is served. No proxy is trusted, the client IP is the remote
+// address unless the Middleware TrustedProxies is set.
+func engine() *gin.Engine {
+	httpEngineOnce.Do(func() {
+		httpEngine = gin.New()
+		httpEngine.SetTrustedProxies(nil)
+		httpEngine.NoRoute(func(c *gin.Context) {
+			chain := c.Request.Context().Value(httpChainKey{}).(*httpChain)
+			if chain.handle != nil {
+				chain.handle(c)
+			} else {
+				chain.hm.Filter(c)
+			}
+		}, serveNext)
+	})
+	return httpEngine
+}
+
+// serveNext calls the next handler with the Result in the request context.
+func serveNext(c *gin.Context) {
+	chain := c.Request.Context().Value(httpChainKey{}).(*httpChain)
+	r := c.Request
+	if auth, ok := AuthFromContext(c); ok {
+		res := &Result{
+			ID:                  auth.Credentials.ID,
+			User:                c.MustGet(UserKey),
+			Scopes:              ScopesFromContext(c),
+			Auth:                auth,
+			ServerAuthorization: c.Writer.Header().Get("Server-Authorization"),
+		}
+		res.App, res.Delegate, _ = AppFromContext(c)
+		if v := c.GetString(ServerAuthorizationKey); v != "" {
+			res.ServerAuthorization = v
+		}
+		ctx := context.WithValue(r.Context(), resultContextKey{}, res)
+		r = r.WithContext(context.WithValue(ctx, userContextKey{}, res.User))
+	}
+	// NoRoute handlers start with a 404 status
+	c.Status(http.StatusOK)
+	chain.next.ServeHTTP(c.Writer, r)
+}
+
+// Handler returns a net/http middleware running the Filter before next,
+// for alice chains and the routers accepting func(http.Handler) http.Handler:
+//
+//	chain := alice.New(hm.Handler).Then(mux)
+//
+// The verified request is in the request context, see ResultFromRequest.
+func (hm *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hm.serveHTTP(w, r, next)
+	})
+}
+
+// ServeHTTP runs the Filter before next, it's a negroni.Handler:
+//
+//	n := negroni.New(hm)
+func (hm *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	hm.serveHTTP(w, r, next)
+}
+
+func (hm *Middleware) serveHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	ctx := context.WithValue(r.Context(), httpChainKey{}, &httpChain{hm: hm, next: next})
+	engine().ServeHTTP(w, r.WithContext(ctx))
+}
+
+// serveGin calls handle with a gin context of r from the shared engine,
+// nothing is written to the client.
+func serveGin(r *http.Request, handle gin.HandlerFunc) {
+	ctx := context.WithValue(r.Context(), httpChainKey{}, &httpChain{handle: handle})
+	engine().ServeHTTP(&discardWriter{}, r.WithContext(ctx))
+}
+
+// discardWriter is a ResponseWriter ignoring the response.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *discardWriter) WriteHeader(status int) {}
+
+// ResultFromRequest returns the verified request of the net/http
+// middlewares (see Handler) from the request context and true, or nil
+// and false if the request was not authenticated (SkipFunc, FallbackAuthenticator).
+func ResultFromRequest(r *http.Request) (*Result, bool) {
+	return ResultFromRequestContext(r.Context())
+}
+
+// ResultFromRequestContext is ResultFromRequest for the handlers only
+// receiving the context of the request (RPC frameworks).
+func ResultFromRequestContext(ctx context.Context) (*Result, bool) {
+	res, ok := ctx.Value(resultContextKey{}).(*Result)
+	return res, ok
+}
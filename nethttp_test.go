@@ -0,0 +1,76 @@
+package hawk_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+
+	var handler http.Handler
+	var res *Result
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key").Scopes = []string{"read"}
+		res = nil
+		handler = store.Middleware().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, _ = ResultFromRequest(r)
+			w.Write([]byte("ok"))
+		}))
+	})
+
+	It("passes the verified request to the next handler", func() {
+		req := httptest.NewRequest("PROPFIND", "http://example.com/files/a", strings.NewReader("body"))
+		auth, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal("ok"))
+		Expect(res.ID).To(Equal("my-id"))
+		Expect(res.User).To(Equal("my-id"))
+		Expect(res.Scopes).To(Equal([]string{"read"}))
+		Expect(res.ServerAuthorization).To(Equal(w.Header().Get("Server-Authorization")))
+		Expect(auth.ValidResponse(res.ServerAuthorization)).To(Succeed())
+	})
+
+	It("rejects invalid requests", func() {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		Expect(res).To(BeNil())
+	})
+
+	It("only trusts the X-Forwarded-For of the TrustedProxies", func() {
+		hm := hawktest.NewStore().Middleware()
+		hm.FailureMetrics = NewFailureMetrics(time.Minute)
+		handler := hm.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		request := func() {
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.2")
+			req.Header.Set("Authorization", `Hawk id="unknown", ts="1", nonce="n", mac="bWFj"`)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		request()
+		Expect(hm.FailureMetrics.IPFailures("10.0.0.1")).To(Equal(1))
+		Expect(hm.FailureMetrics.IPFailures("203.0.113.9")).To(BeZero())
+
+		hm.TrustedProxies = []string{"10.0.0.0/8"}
+		request()
+		Expect(hm.FailureMetrics.IPFailures("203.0.113.9")).To(Equal(1))
+		Expect(hm.FailureMetrics.IPFailures("10.0.0.2")).To(BeZero())
+	})
+
+})
@@ -0,0 +1,66 @@
+package hawk
+
+import (
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoNonceStore is set in context.Err if RequireNonce is set without a
+// nonce store (SetNonce is nil). It's a configuration error.
+var ErrNoNonceStore = errors.New("No nonce store configured")
+
+// NonceFailurePolicy sets how the Middleware handles the SetNonceFunc
+// errors (store unavailable for example).
+type NonceFailurePolicy int
+
+const (
+	// NonceFailClosed rejects the request, the error is set in the
+	// context. It's the default.
+	NonceFailClosed NonceFailurePolicy = iota
+	// NonceFailOpen accepts the nonce without replay protection, the
+	// error is reported to OnNonceError, the Logger and NonceFailOpens.
+	NonceFailOpen
+)
+
+// NonceFailOpens returns the number of nonces accepted on SetNonceFunc
+// errors with NonceFailOpen.
+func (hm *Middleware) NonceFailOpens() uint64 {
+	return atomic.LoadUint64(&hm.nonceFailOpens)
+}
+
+// nonceFailure returns true if the request goes on after the
+// SetNonceFunc err.
+func (hr *Request) nonceFailure(id string, err error) bool {
+	hm := hr.Hawk
+	if hm.OnNonceError != nil {
+		hm.OnNonceError(id, err)
+	}
+	if hm.NonceFailure != NonceFailOpen {
+		return false
+	}
+	atomic.AddUint64(&hm.nonceFailOpens, 1)
+	if hm.Logger != nil {
+		args := make([]any, 0, 4)
+		switch hm.LogIDs {
+		case LogIDsClear:
+			args = append(args, "hawk.credential_id", id)
+		case LogIDsHashed:
+			args = append(args, "hawk.credential_id", credentialLabel(id))
+		}
+		args = append(args, "error", err.Error())
+		hm.Logger.Log(hr.context(), slog.LevelWarn, "hawk nonce accepted without replay protection", args...)
+	}
+	return true
+}
+
+// inNonceWindow returns false if t is further than the NonceWindow
+// from now.
+func (hm *Middleware) inNonceWindow(t time.Time) bool {
+	if hm.NonceWindow <= 0 {
+		return true
+	}
+	d := hm.now().Sub(t)
+	return d <= hm.NonceWindow && d >= -hm.NonceWindow
+}
@@ -0,0 +1,187 @@
+package hawk_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NonceFailure", func() {
+
+	var hm *Middleware
+	var router *gin.Engine
+	var failed []string
+	redisErr := errors.New("redis is down")
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = NewMiddleware(store.GetCredentials, func(id, nonce string, t time.Time) (bool, error) {
+			return false, redisErr
+		})
+		failed = nil
+		hm.OnNonceError = func(id string, err error) {
+			Expect(err).To(Equal(redisErr))
+			failed = append(failed, id)
+		}
+		router = gin.New()
+		router.GET("/", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+	})
+
+	serve := func() int {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	It("rejects requests by default", func() {
+		Expect(serve()).ToNot(Equal(http.StatusOK))
+		Expect(failed).To(Equal([]string{"my-id"}))
+		Expect(hm.NonceFailOpens()).To(BeZero())
+	})
+
+	It("accepts requests with NonceFailOpen", func() {
+		hm.NonceFailure = NonceFailOpen
+		Expect(serve()).To(Equal(http.StatusOK))
+		Expect(serve()).To(Equal(http.StatusOK))
+		Expect(failed).To(Equal([]string{"my-id", "my-id"}))
+		Expect(hm.NonceFailOpens()).To(Equal(uint64(2)))
+	})
+
+})
+
+var _ = Describe("NoNonceStore", func() {
+
+	var hm *Middleware
+	var router *gin.Engine
+	var store *hawktest.Store
+
+	BeforeEach(func() {
+		store = hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = NewMiddleware(store.GetCredentials, nil)
+		hm.SetNonce = nil
+		router = gin.New()
+		router.GET("/", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+	})
+
+	serve := func() int {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	serveBewit := func() int {
+		url, err := hawktest.NewBewitURL("http://example.com/", "my-id", "my-key", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w.Code
+	}
+
+	It("rejects requests without a nonce store by default", func() {
+		Expect(serve()).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("accepts bewits without a nonce store", func() {
+		Expect(serveBewit()).To(Equal(http.StatusOK))
+	})
+
+	It("skips the nonce check without RequireNonce", func() {
+		Expect(hm.RequireNonce).To(BeTrue())
+		hm.Apply(NonceValidation(false))
+		Expect(hm.RequireNonce).To(BeFalse())
+		Expect(serve()).To(Equal(http.StatusOK))
+		Expect(serveBewit()).To(Equal(http.StatusOK))
+	})
+
+	It("accepts requests with a nonce store", func() {
+		hm.SetNonceStore(SetNonceFunc(store.SetNonce))
+		Expect(serve()).To(Equal(http.StatusOK))
+	})
+
+	It("rejects timestamps out of the NonceWindow", func() {
+		var calls int
+		hm.SetNonce = func(id, nonce string, t time.Time) (bool, error) {
+			calls++
+			return store.SetNonce(id, nonce, t)
+		}
+		hm.NonceWindow = 10 * time.Second
+		Expect(serve()).To(Equal(http.StatusOK))
+		hm.Now = func() time.Time { return time.Now().Add(30 * time.Second) }
+		Expect(serve()).To(Equal(http.StatusUnauthorized))
+		Expect(calls).To(Equal(1))
+	})
+
+})
+
+var _ = Describe("NewMiddleware without SetNonce", func() {
+
+	It("keeps the nonces in memory", func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm := NewMiddleware(store.GetCredentials, nil)
+		Expect(hm.SetNonce).ToNot(BeNil())
+		router := gin.New()
+		router.GET("/", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("keeps the nonces for twice the MaxSkew", func() {
+		defer func(d time.Duration) { hawk.MaxTimestampSkew = d }(hawk.MaxTimestampSkew)
+		hawk.MaxTimestampSkew = 10 * time.Millisecond
+
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm := NewMiddleware(store.GetCredentials, nil)
+		hm.MaxSkew = time.Minute
+		router := gin.New()
+		router.GET("/", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		time.Sleep(30 * time.Millisecond)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+})
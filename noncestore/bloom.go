@@ -0,0 +1,212 @@
+package noncestore
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+)
+
+// bloom is a bloom filter of m bits and k hashes.
+type bloom struct {
+	bits []uint64
+	k    uint64
+}
+
+func newBloom(m, k uint64) *bloom {
+	return &bloom{bits: make([]uint64, (m+63)/64), k: k}
+}
+
+// positions calls f with the k bits of h (double hashing).
+func (b *bloom) positions(h uint64, f func(i uint64) bool) bool {
+	m := uint64(len(b.bits)) * 64
+	h1, h2 := h&0xffffffff, h>>32|1
+	for i := uint64(0); i < b.k; i++ {
+		if !f((h1 + i*h2) % m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloom) test(h uint64) bool {
+	return b.positions(h, func(i uint64) bool {
+		return b.bits[i/64]&(1<<(i%64)) != 0
+	})
+}
+
+func (b *bloom) add(h uint64) {
+	b.positions(h, func(i uint64) bool {
+		b.bits[i/64] |= 1 << (i % 64)
+		return true
+	})
+}
+
+// BloomFront is a bloom filter in front of a remote nonce store (Redis,
+// etcd...) to save most of its round trips. The nonces definitely never
+// seen by the filter are accepted locally and saved in the Remote store
+// in the background (see Start), only the possible duplicates are
+// checked synchronously with the Remote store. Without Start, or when
+// the background queue is full, every nonce is saved synchronously and
+// SetNonce returns the answer of the Remote store.
+//
+// The filter only knows the nonces of its instance: a nonce replayed on
+// another instance is accepted until the background save is done, and
+// then reported to OnReplay like with MultiRegion. Use it when the
+// replay window of the background saves is acceptable, or with the
+// requests of a credentials id routed to the same instance.
+//
+// The filters are rotated every Window and the previous one is kept, so
+// nonces are remembered between Window and 2*Window.
+// Remote is the remote SetNonceFunc
+// Window is the rotation period, it must be longer than the timestamp skew
+// OnReplay if set is called when a nonce saved in the background was already used
+// OnError if set is called with the background saves errors
+type BloomFront struct {
+	Remote   hawk.SetNonceFunc
+	Window   time.Duration
+	OnReplay func(id, nonce string, t time.Time)
+	OnError  func(error)
+
+	seed              maphash.Seed
+	m, k              uint64
+	mu                sync.Mutex
+	current, previous *bloom
+	rotate            time.Time
+	pending           map[string]int
+
+	qmu   sync.RWMutex
+	queue chan replicated
+	wg    sync.WaitGroup
+}
+
+// NewBloomFront creates a new BloomFront with filters sized for capacity
+// nonces per Window with a false positive rate fpRate.
+func NewBloomFront(remote hawk.SetNonceFunc, window time.Duration, capacity int, fpRate float64) *BloomFront {
+	n := math.Max(float64(capacity), 1)
+	m := math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(math.Round(m/n*math.Ln2), 1)
+	return &BloomFront{
+		Remote:  remote,
+		Window:  window,
+		seed:    maphash.MakeSeed(),
+		m:       uint64(math.Max(m, 64)),
+		k:       uint64(k),
+		pending: map[string]int{},
+	}
+}
+
+// filters rotates the filters if needed, b.mu must be held.
+func (b *BloomFront) filters(now time.Time) {
+	if b.current == nil || now.After(b.rotate) {
+		if b.current != nil && now.Sub(b.rotate) < b.Window {
+			b.previous = b.current
+		} else {
+			b.previous = nil
+		}
+		b.current = newBloom(b.m, b.k)
+		b.rotate = now.Add(b.Window)
+	}
+}
+
+// SetNonce is a hawk.SetNonceFunc.
+func (b *BloomFront) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	key := id + ":" + nonce
+	h := maphash.String(b.seed, key)
+
+	b.mu.Lock()
+	b.filters(time.Now())
+	seen := b.current.test(h) || (b.previous != nil && b.previous.test(h))
+	if !seen {
+		b.current.add(h)
+		b.pending[key]++
+		b.mu.Unlock()
+		if b.enqueue(replicated{id, nonce, t}) {
+			return true, nil
+		}
+		ok, err := b.Remote(id, nonce, t)
+		b.saved(key)
+		return ok, err
+	}
+	_, saving := b.pending[key]
+	b.mu.Unlock()
+	if saving {
+		return false, nil
+	}
+
+	ok, err := b.Remote(id, nonce, t)
+	if ok && err == nil {
+		b.mu.Lock()
+		b.current.add(h)
+		b.mu.Unlock()
+	}
+	return ok, err
+}
+
+// enqueue queues a nonce to be saved in the background, it returns false
+// if not started or the queue is full.
+func (b *BloomFront) enqueue(n replicated) bool {
+	b.qmu.RLock()
+	defer b.qmu.RUnlock()
+	if b.queue == nil {
+		return false
+	}
+	select {
+	case b.queue <- n:
+		return true
+	default:
+		return false
+	}
+}
+
+// saved removes a saved nonce from the pending ones.
+func (b *BloomFront) saved(key string) {
+	b.mu.Lock()
+	if b.pending[key]--; b.pending[key] <= 0 {
+		delete(b.pending, key)
+	}
+	b.mu.Unlock()
+}
+
+func (b *BloomFront) write(n replicated) {
+	ok, err := b.Remote(n.id, n.nonce, n.t)
+	b.saved(n.id + ":" + n.nonce)
+	if err != nil {
+		if b.OnError != nil {
+			b.OnError(err)
+		}
+	} else if !ok && b.OnReplay != nil {
+		b.OnReplay(n.id, n.nonce, n.t)
+	}
+}
+
+// Start saves the nonces in the background, buffering up to size
+// nonces. Without Start, or when the buffer is full, they are saved
+// before SetNonce returns.
+func (b *BloomFront) Start(size int) {
+	queue := make(chan replicated, size)
+	b.qmu.Lock()
+	b.queue = queue
+	b.qmu.Unlock()
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for n := range queue {
+			b.write(n)
+		}
+	}()
+}
+
+// Stop stops the background saves after saving the queued nonces, the
+// nonces are then saved synchronously.
+func (b *BloomFront) Stop() {
+	b.qmu.Lock()
+	if b.queue != nil {
+		close(b.queue)
+		b.queue = nil
+	}
+	b.qmu.Unlock()
+	b.wg.Wait()
+}
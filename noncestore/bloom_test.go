@@ -0,0 +1,169 @@
+package noncestore_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/hyperboloide/hawk/noncestore"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// remote is a remote nonce store counting its calls.
+type remote struct {
+	region
+	calls int
+	fail  bool
+}
+
+func newRemote() *remote {
+	return &remote{region: region{nonces: map[string]bool{}}}
+}
+
+func (r *remote) setNonce(id string, nonce string, t time.Time) (bool, error) {
+	r.mu.Lock()
+	r.calls++
+	fail := r.fail
+	r.mu.Unlock()
+	if fail {
+		return false, errors.New("remote failed")
+	}
+	return r.region.setNonce(id, nonce, t)
+}
+
+func (r *remote) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+var _ = Describe("BloomFront", func() {
+
+	var r *remote
+	var b *BloomFront
+
+	BeforeEach(func() {
+		r = newRemote()
+		b = NewBloomFront(r.setNonce, time.Minute, 1000, 0.001)
+	})
+
+	It("accepts new nonces locally and saves them in the background", func() {
+		release := make(chan struct{})
+		r.mu.Lock()
+		go func() {
+			<-release
+			r.mu.Unlock()
+		}()
+		b.Start(100)
+		for i := 0; i < 50; i++ {
+			ok, err := b.SetNonce("my-id", fmt.Sprint(i), time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		}
+
+		// the replays are rejected while the saves are pending
+		ok, err := b.SetNonce("my-id", "1", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		close(release)
+		b.Stop()
+		Expect(r.count()).To(Equal(50))
+		Expect(r.nonces).To(HaveLen(50))
+	})
+
+	It("checks the possible duplicates with the remote store", func() {
+		ok, _ := b.SetNonce("my-id", "nonce", time.Now())
+		Expect(ok).To(BeTrue())
+		Expect(r.count()).To(Equal(1))
+
+		ok, err := b.SetNonce("my-id", "nonce", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(r.count()).To(Equal(2))
+
+		r.fail = true
+		_, err = b.SetNonce("my-id", "nonce", time.Now())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("accepts the false positives confirmed by the remote store", func() {
+		b = NewBloomFront(r.setNonce, time.Minute, 1, 0.5)
+		accepted := 0
+		for i := 0; i < 200; i++ {
+			if ok, _ := b.SetNonce("my-id", fmt.Sprint(i), time.Now()); ok {
+				accepted++
+			}
+		}
+		Expect(accepted).To(Equal(200))
+		Expect(r.nonces).To(HaveLen(200))
+	})
+
+	It("reports the replays found by the background saves", func() {
+		var mu sync.Mutex
+		var replays []string
+		b.OnReplay = func(id, nonce string, t time.Time) {
+			mu.Lock()
+			replays = append(replays, nonce)
+			mu.Unlock()
+		}
+		errs := 0
+		b.OnError = func(err error) {
+			errs++
+		}
+		r.setNonce("my-id", "used-elsewhere", time.Now())
+
+		b.Start(10)
+		ok, _ := b.SetNonce("my-id", "used-elsewhere", time.Now())
+		Expect(ok).To(BeTrue())
+		b.Stop()
+		Expect(replays).To(Equal([]string{"used-elsewhere"}))
+
+		b.Start(10)
+		r.mu.Lock()
+		r.fail = true
+		r.mu.Unlock()
+		b.SetNonce("my-id", "other", time.Now())
+		b.Stop()
+		Expect(errs).To(Equal(1))
+	})
+
+	It("returns the answer of the remote store when saving synchronously", func() {
+		r.setNonce("my-id", "used-elsewhere", time.Now())
+		ok, err := b.SetNonce("my-id", "used-elsewhere", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		r.fail = true
+		ok, err = b.SetNonce("my-id", "other", time.Now())
+		Expect(err).To(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		// after Stop the nonces are saved synchronously
+		r.fail = false
+		b.Start(10)
+		b.Stop()
+		ok, err = b.SetNonce("my-id", "new", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(r.nonces).To(HaveKey("my-idnew"))
+	})
+
+	It("forgets the nonces after two windows", func() {
+		b.Window = 20 * time.Millisecond
+		b.SetNonce("my-id", "nonce", time.Now())
+		time.Sleep(25 * time.Millisecond)
+		b.SetNonce("my-id", "other", time.Now())
+		ok, _ := b.SetNonce("my-id", "nonce", time.Now())
+		Expect(ok).To(BeFalse())
+
+		time.Sleep(50 * time.Millisecond)
+		calls := r.count()
+		b.SetNonce("my-id", "nonce2", time.Now())
+		Expect(r.count()).To(Equal(calls + 1))
+	})
+
+})
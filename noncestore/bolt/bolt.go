@@ -0,0 +1,172 @@
+// Package bolt provides a hawk SetNonceFunc backed by an embedded bbolt
+// database, for single binary deployments: unlike the memory store the
+// nonces survive restarts, so requests can't be replayed after a quick
+// restart.
+//
+// The nonces are saved in a bucket per Window of time, expired nonces
+// are pruned by deleting whole buckets.
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var rootBucket = []byte("hawk_nonces")
+
+// Store saves nonces in a bbolt database. The buckets older than TTL
+// are pruned at most every Window by SetNonce, or by Prune (see
+// noncestore.Janitor). It's safe for concurrent use.
+// DB is the bbolt database
+// TTL is the minimum time nonces are kept, it must be longer than the timestamp skew
+// Window is the time span of a bucket
+type Store struct {
+	DB     *bolt.DB
+	TTL    time.Duration
+	Window time.Duration
+
+	mu    sync.Mutex
+	prune time.Time
+}
+
+// NewStore creates a new Store keeping nonces ttl, in buckets of a
+// quarter of ttl.
+func NewStore(db *bolt.DB, ttl time.Duration) *Store {
+	return &Store{
+		DB:     db,
+		TTL:    ttl,
+		Window: ttl / 4,
+	}
+}
+
+// Open opens or creates the database at path and returns a Store
+// keeping nonces ttl, see Close.
+func Open(path string, ttl time.Duration) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(db, ttl), nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.DB.Close()
+}
+
+func (s *Store) window() int64 {
+	if s.Window <= 0 {
+		return int64(time.Second)
+	}
+	return int64(s.Window)
+}
+
+// bucketName returns the name of the bucket of the window starting at
+// start, sorted by time.
+func bucketName(start int64) []byte {
+	name := make([]byte, 8)
+	binary.BigEndian.PutUint64(name, uint64(start))
+	return name
+}
+
+// SetNonce is a hawk.SetNonceFunc.
+func (s *Store) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	now := time.Now()
+	key := []byte(id + ":" + nonce)
+	w := s.window()
+	current := now.UnixNano() / w * w
+	oldest := now.Add(-s.TTL).UnixNano() / w * w
+
+	ok := false
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(rootBucket)
+		if err != nil {
+			return err
+		}
+		if s.shouldPrune(now) {
+			if err := prune(root, now.Add(-s.TTL).UnixNano(), w); err != nil {
+				return err
+			}
+		}
+		for start := current; start >= oldest; start -= w {
+			if b := root.Bucket(bucketName(start)); b != nil && b.Get(key) != nil {
+				return nil
+			}
+		}
+		b, err := root.CreateBucketIfNotExists(bucketName(current))
+		if err != nil {
+			return err
+		}
+		ok = true
+		return b.Put(key, []byte{})
+	})
+	return ok && err == nil, err
+}
+
+// shouldPrune returns true at most every Window.
+func (s *Store) shouldPrune(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Before(s.prune) {
+		return false
+	}
+	s.prune = now.Add(time.Duration(s.window()))
+	return true
+}
+
+// prune deletes the buckets of the windows ended before olderThan.
+func prune(root *bolt.Bucket, olderThan, w int64) error {
+	var names [][]byte
+	c := root.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		if int64(binary.BigEndian.Uint64(k))+w > olderThan {
+			break
+		}
+		names = append(names, k)
+	}
+	for _, name := range names {
+		if err := root.DeleteBucket(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune deletes the buckets older than olderThan, it's a
+// noncestore.Pruner.
+func (s *Store) Prune(olderThan time.Time) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(rootBucket)
+		if root == nil {
+			return nil
+		}
+		return prune(root, olderThan.UnixNano(), s.window())
+	})
+}
+
+// Len returns the number of nonces kept, expired included until pruned.
+func (s *Store) Len() (int, error) {
+	n := 0
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket(rootBucket)
+		if root == nil {
+			return nil
+		}
+		return root.ForEachBucket(func(name []byte) error {
+			n += root.Bucket(name).Stats().KeyN
+			return nil
+		})
+	})
+	return n, err
+}
+
+// Ping is a hawk.Pinger checking the database is open.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.DB.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
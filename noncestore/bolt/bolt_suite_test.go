@@ -0,0 +1,13 @@
+package bolt_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestBolt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bolt Suite")
+}
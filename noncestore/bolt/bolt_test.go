@@ -0,0 +1,93 @@
+package bolt_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/noncestore"
+	. "github.com/hyperboloide/hawk/noncestore/bolt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+
+	var dir, path string
+	var s *Store
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "hawk-bolt")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(dir, "nonces.db")
+		s, err = Open(path, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		s.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("rejects replayed nonces", func() {
+		ok, err := s.SetNonce("my-id", "nonce", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		ok, err = s.SetNonce("my-id", "nonce", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		ok, _ = s.SetNonce("other-id", "nonce", time.Now())
+		Expect(ok).To(BeTrue())
+		Expect(s.Len()).To(Equal(2))
+	})
+
+	It("keeps the nonces across restarts", func() {
+		s.SetNonce("my-id", "nonce", time.Now())
+		Expect(s.Close()).To(Succeed())
+
+		var err error
+		s, err = Open(path, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		ok, err := s.SetNonce("my-id", "nonce", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("accepts the nonces again after TTL and prunes them", func() {
+		s.TTL = 40 * time.Millisecond
+		s.Window = 10 * time.Millisecond
+		s.SetNonce("my-id", "nonce", time.Now())
+		time.Sleep(60 * time.Millisecond)
+
+		ok, err := s.SetNonce("my-id", "nonce", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(s.Len()).To(Equal(1))
+	})
+
+	It("prunes the buckets older than a time", func() {
+		s.Window = 10 * time.Millisecond
+		s.SetNonce("my-id", "old", time.Now())
+		time.Sleep(25 * time.Millisecond)
+		s.SetNonce("my-id", "new", time.Now())
+
+		var _ noncestore.Pruner = s
+		Expect(s.Prune(time.Now().Add(-5 * time.Millisecond))).To(Succeed())
+		Expect(s.Len()).To(Equal(1))
+		ok, _ := s.SetNonce("my-id", "new", time.Now())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("pings the database", func() {
+		var _ hawk.Pinger = s
+		Expect(s.Ping(context.Background())).To(Succeed())
+		s.Close()
+		Expect(s.Ping(context.Background())).ToNot(Succeed())
+	})
+
+})
@@ -0,0 +1,87 @@
+// Package dynamodb provides a hawk SetNonceFunc backed by DynamoDB, for
+// replay protection in serverless deployments.
+//
+// The table must have a string partition key (Key attribute, "nonce" by
+// default) and DynamoDB TTL enabled on the Expiry attribute ("expires_at"
+// by default).
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// PutItemAPI is the DynamoDB client method used by the Store.
+type PutItemAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// Store saves nonces with a conditional PutItem: a nonce is accepted
+// only if it doesn't exist or is expired (DynamoDB deletes expired items
+// lazily).
+// Client is the DynamoDB client
+// Table is the table name
+// Key is the partition key attribute name
+// Expiry is the TTL attribute name
+// TTL is how long nonces are kept, it must be longer than the timestamp skew
+// Timeout if set is the maximum duration of a PutItem
+type Store struct {
+	Client  PutItemAPI
+	Table   string
+	Key     string
+	Expiry  string
+	TTL     time.Duration
+	Timeout time.Duration
+}
+
+// NewStore creates a new Store keeping nonces for 2 minutes.
+func NewStore(client PutItemAPI, table string) *Store {
+	return &Store{
+		Client: client,
+		Table:  table,
+		Key:    "nonce",
+		Expiry: "expires_at",
+		TTL:    2 * time.Minute,
+	}
+}
+
+// SetNonce is a hawk.SetNonceFunc.
+func (s *Store) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	expires := strconv.FormatInt(time.Now().Add(s.TTL).Unix(), 10)
+	_, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: awssdk.String(s.Table),
+		Item: map[string]types.AttributeValue{
+			s.Key:    &types.AttributeValueMemberS{Value: id + ":" + nonce},
+			s.Expiry: &types.AttributeValueMemberN{Value: expires},
+		},
+		ConditionExpression: awssdk.String("attribute_not_exists(#key) OR #expiry < :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":    s.Key,
+			"#expiry": s.Expiry,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: now},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,13 @@
+package dynamodb_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDynamodb(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dynamodb Suite")
+}
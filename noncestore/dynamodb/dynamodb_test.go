@@ -0,0 +1,74 @@
+package dynamodb_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/hyperboloide/hawk/noncestore/dynamodb"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var apiErr = errors.New("api error")
+
+// table emulates the conditional expression used by the Store.
+type table struct {
+	items map[string]int64
+	fail  bool
+}
+
+func (t *table) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if t.fail {
+		return nil, apiErr
+	}
+	Expect(awssdk.ToString(params.TableName)).To(Equal("nonces"))
+	Expect(awssdk.ToString(params.ConditionExpression)).To(Equal("attribute_not_exists(#key) OR #expiry < :now"))
+	key := params.Item[params.ExpressionAttributeNames["#key"]].(*types.AttributeValueMemberS).Value
+	expires, _ := strconv.ParseInt(params.Item[params.ExpressionAttributeNames["#expiry"]].(*types.AttributeValueMemberN).Value, 10, 64)
+	now, _ := strconv.ParseInt(params.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value, 10, 64)
+
+	if current, exists := t.items[key]; exists && current >= now {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+	t.items[key] = expires
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+var _ = Describe("Store", func() {
+
+	var tbl *table
+	var store *Store
+
+	BeforeEach(func() {
+		tbl = &table{items: map[string]int64{}}
+		store = NewStore(tbl, "nonces")
+	})
+
+	It("rejects replayed nonces", func() {
+		now := time.Now()
+		Expect(store.SetNonce("id", "nonce", now)).To(BeTrue())
+		Expect(store.SetNonce("id", "nonce", now)).To(BeFalse())
+		Expect(store.SetNonce("other-id", "nonce", now)).To(BeTrue())
+		Expect(tbl.items["id:nonce"]).To(BeNumerically("~", now.Add(2*time.Minute).Unix(), 1))
+	})
+
+	It("accepts expired nonces not yet deleted", func() {
+		store.TTL = -time.Hour
+		Expect(store.SetNonce("id", "nonce", time.Now())).To(BeTrue())
+		Expect(store.SetNonce("id", "nonce", time.Now())).To(BeTrue())
+	})
+
+	It("returns errors", func() {
+		tbl.fail = true
+		ok, err := store.SetNonce("id", "nonce", time.Now())
+		Expect(err).To(Equal(apiErr))
+		Expect(ok).To(BeFalse())
+	})
+
+})
@@ -0,0 +1,127 @@
+package etcd_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	. "github.com/hyperboloide/hawk/noncestore/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var etcdErr = errors.New("etcd error")
+
+// fakeEtcd emulates the create if not exists transactions of the Store.
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Lease
+
+	keys   map[string]clientv3.LeaseID
+	grants []int64
+	fail   bool
+}
+
+type fakeTxn struct {
+	f    *fakeEtcd
+	cmps []clientv3.Cmp
+	ops  []clientv3.Op
+}
+
+func (f *fakeEtcd) Txn(ctx context.Context) clientv3.Txn {
+	return &fakeTxn{f: f}
+}
+
+func (f *fakeEtcd) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	if f.fail {
+		return nil, etcdErr
+	}
+	f.grants = append(f.grants, ttl)
+	return &clientv3.LeaseGrantResponse{ID: clientv3.LeaseID(len(f.grants))}, nil
+}
+
+func (f *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	if f.fail {
+		return nil, etcdErr
+	}
+	return &clientv3.GetResponse{}, nil
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmps = cs
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.ops = ops
+	return t
+}
+
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	Expect(t.cmps).To(HaveLen(1))
+	Expect(t.ops).To(HaveLen(1))
+	key := string(t.cmps[0].KeyBytes())
+	if _, exists := t.f.keys[key]; exists {
+		return &clientv3.TxnResponse{Succeeded: false}, nil
+	}
+	Expect(t.ops[0].IsPut()).To(BeTrue())
+	Expect(string(t.ops[0].KeyBytes())).To(Equal(key))
+	t.f.keys[key] = 0
+	return &clientv3.TxnResponse{Succeeded: true}, nil
+}
+
+var _ = Describe("Store", func() {
+
+	var f *fakeEtcd
+	var store *Store
+
+	BeforeEach(func() {
+		f = &fakeEtcd{keys: map[string]clientv3.LeaseID{}}
+		store = &Store{KV: f, Lease: f, Prefix: "hawk/nonces/", TTL: time.Minute}
+	})
+
+	It("rejects replayed nonces", func() {
+		now := time.Now()
+		Expect(store.SetNonce("id", "nonce", now)).To(BeTrue())
+		Expect(store.SetNonce("id", "nonce", now)).To(BeFalse())
+		Expect(store.SetNonce("other-id", "nonce", now)).To(BeTrue())
+		Expect(f.keys).To(HaveKey("hawk/nonces/id/nonce"))
+	})
+
+	It("shares leases for TTL", func() {
+		Expect(store.SetNonce("id", "nonce1", time.Now())).To(BeTrue())
+		Expect(store.SetNonce("id", "nonce2", time.Now())).To(BeTrue())
+		Expect(f.grants).To(Equal([]int64{120}))
+
+	})
+
+	It("grants a new lease after TTL", func() {
+		store.TTL = 10 * time.Millisecond
+		Expect(store.SetNonce("id", "nonce1", time.Now())).To(BeTrue())
+		time.Sleep(20 * time.Millisecond)
+		Expect(store.SetNonce("id", "nonce2", time.Now())).To(BeTrue())
+		Expect(f.grants).To(HaveLen(2))
+	})
+
+	It("returns errors", func() {
+		f.fail = true
+		ok, err := store.SetNonce("id", "nonce", time.Now())
+		Expect(err).To(Equal(etcdErr))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("pings etcd", func() {
+		var _ hawk.Pinger = store
+		Expect(store.Ping(context.Background())).To(Succeed())
+		f.fail = true
+		Expect(store.Ping(context.Background())).To(Equal(etcdErr))
+	})
+
+})
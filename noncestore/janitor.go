@@ -0,0 +1,89 @@
+package noncestore
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Pruner is a nonce store deleting its nonces saved before olderThan,
+// for the stores without expiration (SQL, Bolt...).
+type Pruner interface {
+	Prune(olderThan time.Time) error
+}
+
+// Janitor prunes a nonce store periodically so it doesn't grow unbounded.
+// Every Interval plus a random duration up to Jitter (so the instances
+// sharing a store don't prune at the same time), the nonces older than
+// MaxAge are pruned.
+// Store is the Pruner
+// Interval is the time between two prunes
+// Jitter if set is the maximum random delay added to Interval
+// MaxAge is the age of the nonces pruned, it must be longer than the timestamp skew
+// OnError if set is called with the Prune errors
+type Janitor struct {
+	Store    Pruner
+	Interval time.Duration
+	Jitter   time.Duration
+	MaxAge   time.Duration
+	OnError  func(error)
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewJanitor creates a new Janitor pruning the nonces older than maxAge
+// every interval, with a jitter of a tenth of interval.
+func NewJanitor(store Pruner, interval, maxAge time.Duration) *Janitor {
+	return &Janitor{
+		Store:    store,
+		Interval: interval,
+		Jitter:   interval / 10,
+		MaxAge:   maxAge,
+	}
+}
+
+// Prune prunes the nonces older than MaxAge now.
+func (j *Janitor) Prune() error {
+	err := j.Store.Prune(time.Now().Add(-j.MaxAge))
+	if err != nil && j.OnError != nil {
+		j.OnError(err)
+	}
+	return err
+}
+
+func (j *Janitor) wait() time.Duration {
+	if j.Jitter <= 0 {
+		return j.Interval
+	}
+	return j.Interval + time.Duration(rand.Int63n(int64(j.Jitter)))
+}
+
+// Start prunes the Store in the background until Stop is called.
+func (j *Janitor) Start() {
+	j.stop = make(chan struct{})
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		timer := time.NewTimer(j.wait())
+		defer timer.Stop()
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-timer.C:
+				j.Prune()
+				timer.Reset(j.wait())
+			}
+		}
+	}()
+}
+
+// Stop stops the Janitor and waits for a running prune.
+func (j *Janitor) Stop() {
+	if j.stop != nil {
+		close(j.stop)
+		j.wg.Wait()
+		j.stop = nil
+	}
+}
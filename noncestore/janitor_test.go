@@ -0,0 +1,81 @@
+package noncestore_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/hyperboloide/hawk/noncestore"
+	"github.com/hyperboloide/hawk/noncestore/memory"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type pruner struct {
+	mu    sync.Mutex
+	calls []time.Time
+	err   error
+}
+
+func (p *pruner) Prune(olderThan time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, olderThan)
+	return p.err
+}
+
+func (p *pruner) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.calls)
+}
+
+var _ = Describe("Janitor", func() {
+
+	It("prunes the nonces older than MaxAge", func() {
+		p := &pruner{}
+		j := NewJanitor(p, time.Hour, 2*time.Minute)
+		Expect(j.Jitter).To(Equal(6 * time.Minute))
+		Expect(j.Prune()).To(Succeed())
+		Expect(p.calls).To(HaveLen(1))
+		Expect(p.calls[0]).To(BeTemporally("~", time.Now().Add(-2*time.Minute), time.Second))
+	})
+
+	It("prunes periodically until stopped", func() {
+		p := &pruner{}
+		j := NewJanitor(p, 5*time.Millisecond, time.Minute)
+		j.Start()
+		Eventually(p.count).Should(BeNumerically(">=", 3))
+		j.Stop()
+		n := p.count()
+		time.Sleep(20 * time.Millisecond)
+		Expect(p.count()).To(Equal(n))
+		j.Stop()
+	})
+
+	It("reports the errors", func() {
+		p := &pruner{err: errors.New("prune failed")}
+		errs := make(chan error, 10)
+		j := NewJanitor(p, time.Millisecond, time.Minute)
+		j.Jitter = 0
+		j.OnError = func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}
+		j.Start()
+		defer j.Stop()
+		Eventually(errs).Should(Receive(Equal(p.err)))
+	})
+
+	It("prunes a memory store", func() {
+		s := memory.NewStore(time.Hour)
+		s.SetNonce("my-id", "nonce", time.Now())
+		j := NewJanitor(s, time.Hour, 0)
+		Expect(j.Prune()).To(Succeed())
+		Expect(s.Len()).To(Equal(0))
+	})
+
+})
@@ -0,0 +1,28 @@
+package memory_test
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/noncestore/memory"
+)
+
+func benchmarkSetNonce(b *testing.B, setNonce hawk.SetNonceFunc) {
+	var n int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			setNonce("id", strconv.FormatInt(atomic.AddInt64(&n, 1), 10), time.Now())
+		}
+	})
+}
+
+func BenchmarkStore(b *testing.B) {
+	benchmarkSetNonce(b, memory.NewStore(time.Minute).SetNonce)
+}
+
+func BenchmarkShardedStore(b *testing.B) {
+	benchmarkSetNonce(b, memory.NewShardedStore(time.Minute, 0).SetNonce)
+}
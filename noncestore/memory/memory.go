@@ -0,0 +1,140 @@
+// Package memory provides an in-memory hawk SetNonceFunc, for single
+// instance deployments.
+package memory
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// Store saves nonces in memory for TTL, expired nonces are pruned at
+// most every TTL. It's safe for concurrent use.
+// TTL is the minimum time nonces are kept, it must be longer than the timestamp skew
+type Store struct {
+	TTL time.Duration
+
+	mu     sync.Mutex
+	nonces map[string]entry
+	prune  time.Time
+}
+
+// entry is a saved nonce.
+type entry struct {
+	saved   time.Time
+	expires time.Time
+}
+
+// NewStore creates a new Store keeping nonces ttl.
+func NewStore(ttl time.Duration) *Store {
+	return &Store{TTL: ttl}
+}
+
+// SetNonce is a hawk.SetNonceFunc.
+func (s *Store) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	return s.SetNonceTTL(id, nonce, s.TTL)
+}
+
+// SetNonceTTL is like SetNonce but keeps the nonce ttl instead of TTL,
+// when the TTL depends on a skew configured at runtime.
+func (s *Store) SetNonceTTL(id string, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.nonces == nil {
+		s.nonces = map[string]entry{}
+	}
+	if now.After(s.prune) {
+		for k, e := range s.nonces {
+			if now.After(e.expires) {
+				delete(s.nonces, k)
+			}
+		}
+		s.prune = now.Add(ttl)
+	}
+	key := id + ":" + nonce
+	if e, ok := s.nonces[key]; ok && !now.After(e.expires) {
+		return false, nil
+	}
+	s.nonces[key] = entry{saved: now, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Prune deletes the nonces saved before olderThan, it's a
+// noncestore.Pruner.
+func (s *Store) Prune(olderThan time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.nonces {
+		if e.saved.Before(olderThan) {
+			delete(s.nonces, k)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of nonces kept.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.nonces)
+}
+
+// DefaultShards is the number of shards of NewShardedStore.
+const DefaultShards = 256
+
+// ShardedStore is a Store striped in shards with their own lock, so
+// the nonce checks of concurrent requests don't contend on a single
+// mutex. The nonces are distributed by a hash of the id and nonce.
+type ShardedStore struct {
+	seed   maphash.Seed
+	shards []Store
+}
+
+// NewShardedStore creates a new ShardedStore keeping nonces ttl in
+// shards stores, DefaultShards if shards <= 0.
+func NewShardedStore(ttl time.Duration, shards int) *ShardedStore {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	s := &ShardedStore{
+		seed:   maphash.MakeSeed(),
+		shards: make([]Store, shards),
+	}
+	for i := range s.shards {
+		s.shards[i].TTL = ttl
+	}
+	return s
+}
+
+func (s *ShardedStore) shard(id, nonce string) *Store {
+	var h maphash.Hash
+	h.SetSeed(s.seed)
+	h.WriteString(id)
+	h.WriteByte(':')
+	h.WriteString(nonce)
+	return &s.shards[h.Sum64()%uint64(len(s.shards))]
+}
+
+// SetNonce is a hawk.SetNonceFunc.
+func (s *ShardedStore) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	return s.shard(id, nonce).SetNonce(id, nonce, t)
+}
+
+// Prune deletes the nonces saved before olderThan in all the shards,
+// it's a noncestore.Pruner.
+func (s *ShardedStore) Prune(olderThan time.Time) error {
+	for i := range s.shards {
+		s.shards[i].Prune(olderThan)
+	}
+	return nil
+}
+
+// Len returns the number of nonces kept.
+func (s *ShardedStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		n += s.shards[i].Len()
+	}
+	return n
+}
@@ -0,0 +1,101 @@
+package memory_test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/hyperboloide/hawk/noncestore/memory"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+
+	It("rejects replayed nonces until they expire", func() {
+		s := NewStore(20 * time.Millisecond)
+		ok, err := s.SetNonce("my-id", "nonce", time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		ok, _ = s.SetNonce("my-id", "nonce", time.Now())
+		Expect(ok).To(BeFalse())
+		ok, _ = s.SetNonce("other-id", "nonce", time.Now())
+		Expect(ok).To(BeTrue())
+
+		time.Sleep(30 * time.Millisecond)
+		ok, _ = s.SetNonce("my-id", "nonce", time.Now())
+		Expect(ok).To(BeTrue())
+		Expect(s.Len()).To(Equal(1))
+	})
+
+	It("prunes the nonces saved before a time", func() {
+		s := NewStore(time.Minute)
+		s.SetNonce("my-id", "old", time.Now())
+		time.Sleep(5 * time.Millisecond)
+		limit := time.Now()
+		s.SetNonce("my-id", "new", time.Now())
+
+		Expect(s.Prune(limit)).To(Succeed())
+		Expect(s.Len()).To(Equal(1))
+		ok, _ := s.SetNonce("my-id", "old", time.Now())
+		Expect(ok).To(BeTrue())
+		ok, _ = s.SetNonce("my-id", "new", time.Now())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps a nonce for the ttl of the call", func() {
+		s := NewStore(20 * time.Millisecond)
+		ok, _ := s.SetNonceTTL("my-id", "nonce", time.Minute)
+		Expect(ok).To(BeTrue())
+
+		time.Sleep(30 * time.Millisecond)
+		ok, _ = s.SetNonce("my-id", "nonce", time.Now())
+		Expect(ok).To(BeFalse())
+	})
+
+	Context("ShardedStore", func() {
+
+		It("rejects replayed nonces until they expire", func() {
+			s := NewShardedStore(20*time.Millisecond, 0)
+			ok, err := s.SetNonce("my-id", "nonce", time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			ok, _ = s.SetNonce("my-id", "nonce", time.Now())
+			Expect(ok).To(BeFalse())
+
+			time.Sleep(30 * time.Millisecond)
+			ok, _ = s.SetNonce("my-id", "nonce", time.Now())
+			Expect(ok).To(BeTrue())
+		})
+
+		It("accepts each nonce once under concurrency", func() {
+			s := NewShardedStore(time.Minute, 16)
+			var mu sync.Mutex
+			accepted := 0
+			var wg sync.WaitGroup
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 500; i++ {
+						if ok, _ := s.SetNonce("my-id", fmt.Sprint(i), time.Now()); ok {
+							mu.Lock()
+							accepted++
+							mu.Unlock()
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			Expect(accepted).To(Equal(500))
+			Expect(s.Len()).To(Equal(500))
+
+			Expect(s.Prune(time.Now())).To(Succeed())
+			Expect(s.Len()).To(Equal(0))
+		})
+
+	})
+
+})
@@ -0,0 +1,142 @@
+// Package noncestore provides nonce storage strategies for the hawk
+// Middleware SetNonce.
+package noncestore
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperboloide/hawk"
+)
+
+// ErrReplicationQueueFull is passed to MultiRegion OnError when an
+// accepted nonce could not be queued for replication.
+var ErrReplicationQueueFull = errors.New("Nonce replication queue is full")
+
+// ErrReplicationStopped is returned by MultiRegion SetNonce after Stop.
+var ErrReplicationStopped = errors.New("Nonce replication is stopped")
+
+// Replicator publishes the nonces accepted in a region to the other
+// regions, which should pass them to their MultiRegion Receive method.
+type Replicator interface {
+	Publish(region, id, nonce string, t time.Time) error
+}
+
+type replicated struct {
+	id    string
+	nonce string
+	t     time.Time
+}
+
+// MultiRegion is a nonce strategy for multi-region deployments.
+// Nonces are checked against a region-local store, partitioned by
+// timestamp window so that stores only keep the current windows.
+// Accepted nonces are optionally replicated asynchronously: nonces
+// received from other regions are saved locally so later replays are
+// rejected, and a nonce already used in both regions is reported to
+// OnReplay (a replay can only be detected after the fact across regions).
+// Region is the local region name
+// Local is the region-local SetNonceFunc
+// Window is the timestamp partition size
+// OnReplay if set is called when a nonce received from region was already used
+// OnError if set is called with the replication errors
+type MultiRegion struct {
+	Region   string
+	Local    hawk.SetNonceFunc
+	Window   time.Duration
+	OnReplay func(region, id, nonce string, t time.Time)
+	OnError  func(error)
+
+	mu      sync.RWMutex
+	queue   chan replicated
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewMultiRegion creates a new MultiRegion with a local store.
+func NewMultiRegion(region string, local hawk.SetNonceFunc, window time.Duration) *MultiRegion {
+	return &MultiRegion{
+		Region: region,
+		Local:  local,
+		Window: window,
+	}
+}
+
+// partition returns the nonce prefixed by its timestamp window.
+func (m *MultiRegion) partition(nonce string, t time.Time) string {
+	if m.Window <= 0 {
+		return nonce
+	}
+	return strconv.FormatInt(t.UnixNano()/int64(m.Window), 10) + ":" + nonce
+}
+
+// SetNonce is a hawk.SetNonceFunc checking the nonce in the local store
+// and queuing it for replication. It returns ErrReplicationStopped after
+// Stop.
+func (m *MultiRegion) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.stopped {
+		return false, ErrReplicationStopped
+	}
+	ok, err := m.Local(id, m.partition(nonce, t), t)
+	if ok && err == nil && m.queue != nil {
+		select {
+		case m.queue <- replicated{id, nonce, t}:
+		default:
+			m.error(ErrReplicationQueueFull)
+		}
+	}
+	return ok, err
+}
+
+// Receive saves a nonce accepted in another region.
+func (m *MultiRegion) Receive(region, id, nonce string, t time.Time) error {
+	ok, err := m.Local(id, m.partition(nonce, t), t)
+	if err != nil {
+		return err
+	}
+	if !ok && m.OnReplay != nil {
+		m.OnReplay(region, id, nonce, t)
+	}
+	return nil
+}
+
+// Start replicates the accepted nonces with r in the background,
+// buffering up to size nonces.
+func (m *MultiRegion) Start(r Replicator, size int) {
+	queue := make(chan replicated, size)
+	m.mu.Lock()
+	m.queue, m.stopped = queue, false
+	m.mu.Unlock()
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for n := range queue {
+			if err := r.Publish(m.Region, n.id, n.nonce, n.t); err != nil {
+				m.error(err)
+			}
+		}
+	}()
+}
+
+// Stop stops the replication after publishing the queued nonces, the
+// nonces are then rejected with ErrReplicationStopped.
+func (m *MultiRegion) Stop() {
+	m.mu.Lock()
+	if m.queue != nil {
+		close(m.queue)
+		m.queue = nil
+	}
+	m.stopped = true
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+func (m *MultiRegion) error(err error) {
+	if m.OnError != nil {
+		m.OnError(err)
+	}
+}
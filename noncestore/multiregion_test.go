@@ -0,0 +1,124 @@
+package noncestore_test
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/hyperboloide/hawk/noncestore"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type region struct {
+	mu     sync.Mutex
+	nonces map[string]bool
+}
+
+func (r *region) setNonce(id string, nonce string, t time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nonces[id+nonce] {
+		return false, nil
+	}
+	r.nonces[id+nonce] = true
+	return true, nil
+}
+
+type replicator map[string]*MultiRegion
+
+func (r replicator) Publish(from, id, nonce string, t time.Time) error {
+	for name, m := range r {
+		if name != from {
+			m.Receive(from, id, nonce, t)
+		}
+	}
+	return nil
+}
+
+var _ = Describe("MultiRegion", func() {
+
+	var eu, us *MultiRegion
+	var euStore, usStore *region
+	var replays chan string
+
+	BeforeEach(func() {
+		euStore = &region{nonces: map[string]bool{}}
+		usStore = &region{nonces: map[string]bool{}}
+		eu = NewMultiRegion("eu", euStore.setNonce, time.Minute)
+		us = NewMultiRegion("us", usStore.setNonce, time.Minute)
+		replays = make(chan string, 10)
+		for _, m := range []*MultiRegion{eu, us} {
+			m.OnReplay = func(region, id, nonce string, t time.Time) {
+				replays <- region + ":" + nonce
+			}
+		}
+	})
+
+	It("rejects local replays", func() {
+		t := time.Now()
+		Expect(eu.SetNonce("id", "n1", t)).To(BeTrue())
+		Expect(eu.SetNonce("id", "n1", t)).To(BeFalse())
+	})
+
+	It("partitions by timestamp window", func() {
+		t := time.Unix(600, 0)
+		Expect(eu.SetNonce("id", "n1", t)).To(BeTrue())
+		Expect(eu.SetNonce("id", "n1", t.Add(time.Minute))).To(BeTrue())
+		Expect(euStore.nonces).To(HaveLen(2))
+		for k := range euStore.nonces {
+			Expect(k).To(MatchRegexp(`^id\d+:n1$`))
+		}
+	})
+
+	It("replicates accepted nonces", func() {
+		r := replicator{"eu": eu, "us": us}
+		eu.Start(r, 10)
+		us.Start(r, 10)
+		t := time.Now()
+		Expect(eu.SetNonce("id", "n1", t)).To(BeTrue())
+		eu.Stop()
+		Expect(us.SetNonce("id", "n1", t)).To(BeFalse())
+		us.Stop()
+		Expect(replays).To(BeEmpty())
+	})
+
+	It("rejects the nonces after Stop", func() {
+		eu.Start(replicator{"eu": eu}, 10)
+		eu.Stop()
+		ok, err := eu.SetNonce("id", "n1", time.Now())
+		Expect(err).To(Equal(ErrReplicationStopped))
+		Expect(ok).To(BeFalse())
+		Expect(euStore.nonces).To(BeEmpty())
+	})
+
+	It("reports replays detected after the fact", func() {
+		t := time.Now()
+		Expect(eu.SetNonce("id", "n1", t)).To(BeTrue())
+		Expect(us.SetNonce("id", "n1", t)).To(BeTrue())
+		Expect(us.Receive("eu", "id", "n1", t)).To(Succeed())
+		Expect(replays).To(Receive(Equal("eu:n1")))
+	})
+
+	It("reports a full replication queue", func() {
+		errs := []error{}
+		eu.OnError = func(err error) {
+			errs = append(errs, err)
+		}
+		block := make(chan bool)
+		eu.Start(blockingReplicator(block), 0)
+		t := time.Now()
+		Expect(eu.SetNonce("id", "n1", t)).To(BeTrue())
+		Expect(errs).To(ContainElement(ErrReplicationQueueFull))
+		close(block)
+		eu.Stop()
+	})
+
+})
+
+type blockingReplicator chan bool
+
+func (b blockingReplicator) Publish(from, id, nonce string, t time.Time) error {
+	<-b
+	return nil
+}
@@ -0,0 +1,13 @@
+package noncestore_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestNoncestore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Noncestore Suite")
+}
@@ -0,0 +1,244 @@
+package hawk
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+var (
+	// ErrInvalidPayloadHash is set in context.Err if the request body
+	// does not match the payload hash sent by the client.
+	ErrInvalidPayloadHash = errors.New("Invalid payload hash")
+
+	// ErrMixedHash is set in context.Err if ForbidMixedHash is set and the
+	// credentials payload hash algorithm differs from the MAC one. It's a
+	// configuration error of the credentials (KindInternal), the response
+	// status is 500.
+	ErrMixedHash = errors.New("Mixed payload and MAC hash algorithms")
+
+	// ErrMissingPayloadHash is set in context.Err if RequirePayloadHash
+	// is set and a write request has no payload hash.
+	ErrMissingPayloadHash = errors.New("Missing payload hash")
+
+	// ErrBodyTooLarge is set in context.Err if the body is larger than
+	// MaxBodySize. The response status is 413.
+	ErrBodyTooLarge = errors.New("Request body too large")
+)
+
+// sameHash returns true if both functions create the same hash algorithm.
+func sameHash(a, b func() hash.Hash) bool {
+	ha, hb := a(), b()
+	return reflect.TypeOf(ha) == reflect.TypeOf(hb) && ha.Size() == hb.Size()
+}
+
+// PayloadHash computes the Hawk payload hash of body using h, the
+// contentType should be normalized with NormalizeContentType.
+func PayloadHash(h func() hash.Hash, contentType string, body []byte) []byte {
+	ph := h()
+	ph.Write([]byte("hawk.1.payload\n"))
+	ph.Write([]byte(contentType))
+	ph.Write([]byte("\n"))
+	ph.Write(body)
+	ph.Write([]byte("\n"))
+	return ph.Sum(nil)
+}
+
+// isWrite returns true if requests with method may have a body.
+func isWrite(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	return true
+}
+
+// ValidatePayload checks the request body against the payload hash
+// sent by the client, if any, and when the Middleware ValidatePayload
+// option is set. The body is restored for the next handlers.
+// With RequirePayloadHash write requests must send a payload hash, and
+// the requests with a method of RequireHashFor must send a valid one.
+func (hr *Request) ValidatePayload(r *http.Request, auth *hawk.Auth) error {
+	requiredFor := hr.Hawk.requireHashFor(r.Method)
+	if len(auth.Hash) == 0 && (requiredFor || hr.Hawk.RequirePayloadHash && isWrite(r.Method)) {
+		return ErrMissingPayloadHash
+	}
+	if !hr.Hawk.ValidatePayload && !requiredFor || len(auth.Hash) == 0 {
+		return nil
+	} else if hr.Hawk.StreamPayload {
+		return hr.streamPayload(r, auth)
+	}
+	return hr.checkPayload(r, auth)
+}
+
+// requireHashFor returns true if method is in the RequireHashFor methods.
+func (hm *Middleware) requireHashFor(method string) bool {
+	for _, m := range hm.RequireHashFor {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPayload checks the request body against the payload hash and
+// restores the body.
+func (hr *Request) checkPayload(r *http.Request, auth *hawk.Auth) error {
+	max := hr.Hawk.MaxBodySize
+	if max > 0 && r.ContentLength > max {
+		return ErrBodyTooLarge
+	}
+	var body []byte
+	if r.Body != nil {
+		reader := hr.bodyReader(r.Body)
+		if max > 0 {
+			reader = io.LimitReader(reader, max+1)
+		}
+		var err error
+		if body, err = ioutil.ReadAll(reader); err != nil {
+			return err
+		} else if max > 0 && int64(len(body)) > max {
+			return ErrBodyTooLarge
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if subtle.ConstantTimeCompare(PayloadHash(hr.payloadHash(auth), hr.Hawk.contentType(r), body), auth.Hash) != 1 {
+		return ErrInvalidPayloadHash
+	}
+	return nil
+}
+
+// payloadHash returns the payload hash algorithm of the credentials.
+func (hr *Request) payloadHash(auth *hawk.Auth) func() hash.Hash {
+	if hr.PayloadHash != nil {
+		return hr.PayloadHash
+	}
+	return auth.Credentials.Hash
+}
+
+// NormalizeContentType returns the content type covered by the payload
+// hash, as in the Hawk reference implementation: the lower case media
+// type, without parameters.
+func NormalizeContentType(ct string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+}
+
+// ContentTypeNormalizer normalizes the "Content-Type" header of requests
+// before it's hashed with the payload, see NormalizeContentType.
+type ContentTypeNormalizer func(ct string) string
+
+// contentType returns the normalized content type of r.
+func (hm *Middleware) contentType(r *http.Request) string {
+	if hm.ContentTypeNormalizer != nil {
+		return hm.ContentTypeNormalizer(r.Header.Get("Content-Type"))
+	}
+	return NormalizeContentType(r.Header.Get("Content-Type"))
+}
+
+// spoolMemory is the size of the streamed bodies kept in memory, larger
+// ones are spooled to a temporary file.
+const spoolMemory = 64 << 10
+
+// streamPayload hashes the body of r while spooling it to a temporary
+// file, so large bodies are verified before the handlers without
+// buffering them in memory. The body of r is replaced by the spool,
+// removed when the Request is released.
+func (hr *Request) streamPayload(r *http.Request, auth *hawk.Auth) error {
+	max := hr.Hawk.MaxBodySize
+	if max > 0 && r.ContentLength > max {
+		return ErrBodyTooLarge
+	}
+	h := hr.payloadHash(auth)()
+	h.Write([]byte("hawk.1.payload\n"))
+	h.Write([]byte(hr.Hawk.contentType(r)))
+	h.Write([]byte("\n"))
+	sp := &spool{}
+	hr.spool = sp
+	if r.Body != nil {
+		reader := hr.bodyReader(r.Body)
+		if max > 0 {
+			reader = io.LimitReader(reader, max+1)
+		}
+		n, err := io.Copy(io.MultiWriter(h, sp), reader)
+		if err != nil {
+			return err
+		} else if max > 0 && n > max {
+			return ErrBodyTooLarge
+		}
+		r.Body.Close()
+	}
+	h.Write([]byte("\n"))
+	if subtle.ConstantTimeCompare(h.Sum(nil), auth.Hash) != 1 {
+		return ErrInvalidPayloadHash
+	}
+	if err := sp.rewind(); err != nil {
+		return err
+	}
+	r.Body = sp
+	return nil
+}
+
+// spool is a body kept in memory up to spoolMemory, then in a temporary
+// file. It's read after rewind and the file is removed by Close.
+type spool struct {
+	buf  bytes.Buffer
+	file *os.File
+	r    io.Reader
+}
+
+func (sp *spool) Write(p []byte) (int, error) {
+	if sp.file == nil && sp.buf.Len()+len(p) <= spoolMemory {
+		return sp.buf.Write(p)
+	}
+	if sp.file == nil {
+		f, err := os.CreateTemp("", "hawk-body-")
+		if err != nil {
+			return 0, err
+		}
+		sp.file = f
+		if _, err := sp.buf.WriteTo(f); err != nil {
+			return 0, err
+		}
+	}
+	return sp.file.Write(p)
+}
+
+// rewind makes the spool readable from its start.
+func (sp *spool) rewind() error {
+	if sp.file == nil {
+		sp.r = &sp.buf
+		return nil
+	}
+	sp.r = sp.file
+	_, err := sp.file.Seek(0, io.SeekStart)
+	return err
+}
+
+func (sp *spool) Read(p []byte) (int, error) {
+	if sp.r == nil {
+		return 0, io.EOF
+	}
+	return sp.r.Read(p)
+}
+
+// Close removes the temporary file, if any.
+func (sp *spool) Close() error {
+	if sp.file == nil {
+		return nil
+	}
+	f := sp.file
+	sp.file, sp.r = nil, nil
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,207 @@
+package hawk_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Payload", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var payloadHash func() hash.Hash
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{
+			Key:         "test-cred-key",
+			Hash:        sha1.New,
+			PayloadHash: payloadHash,
+		}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		payloadHash = sha256.New
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.ValidatePayload = true
+		router := gin.New()
+		router.POST("/private", hm.Filter, func(c *gin.Context) {
+			b, err := ioutil.ReadAll(c.Request.Body)
+			if err != nil {
+				c.String(400, ErrorCode(err))
+				return
+			}
+			c.String(200, string(b))
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	post := func(body, signed []byte, h func() hash.Hash) *http.Response {
+		req, err := http.NewRequest("POST", ts.URL+"/private", bytes.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "id",
+			Key:  "test-cred-key",
+			Hash: sha1.New,
+		}, 0)
+		if h != nil {
+			auth.Hash = PayloadHash(h, "application/json", signed)
+		}
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	It("validates a payload hashed with a different algorithm", func() {
+		body := []byte(`{"a":1}`)
+		resp := post(body, body, sha256.New)
+		Expect(resp.StatusCode).To(Equal(200))
+		b, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal(body))
+	})
+
+	It("rejects a tampered payload", func() {
+		resp := post([]byte(`{"a":2}`), []byte(`{"a":1}`), sha256.New)
+		Expect(resp.StatusCode).To(Equal(401))
+	})
+
+	It("rejects a payload hashed with the MAC algorithm", func() {
+		body := []byte(`{"a":1}`)
+		resp := post(body, body, sha1.New)
+		Expect(resp.StatusCode).To(Equal(401))
+	})
+
+	It("forbids mixing when strict", func() {
+		hm.ForbidMixedHash = true
+		body := []byte(`{"a":1}`)
+		resp := post(body, body, sha256.New)
+		Expect(resp.StatusCode).To(Equal(500))
+		Expect(Classify(ErrMixedHash).Kind).To(Equal(KindInternal))
+
+		payloadHash = nil
+		resp = post(body, body, sha1.New)
+		Expect(resp.StatusCode).To(Equal(200))
+	})
+
+	It("requires a valid payload hash for the RequireHashFor methods", func() {
+		hm.ValidatePayload = false
+		body := []byte(`{"a":1}`)
+		Expect(post(body, nil, nil).StatusCode).To(Equal(200))
+		Expect(post([]byte(`{"a":2}`), body, sha256.New).StatusCode).To(Equal(200))
+
+		hm.RequireHashFor = []string{"post", "PUT"}
+		Expect(post(body, nil, nil).StatusCode).To(Equal(401))
+		Expect(post([]byte(`{"a":2}`), body, sha256.New).StatusCode).To(Equal(401))
+		Expect(post(body, body, sha256.New).StatusCode).To(Equal(200))
+	})
+
+	It("limits the body size", func() {
+		hm.MaxBodySize = 8
+		body := []byte(`{"a":1}`)
+		Expect(post(body, body, sha256.New).StatusCode).To(Equal(200))
+		body = []byte(`{"a":"large"}`)
+		Expect(post(body, body, sha256.New).StatusCode).To(Equal(413))
+	})
+
+	Context("when streamed", func() {
+
+		BeforeEach(func() {
+			hm.StreamPayload = true
+		})
+
+		It("validates the payload before the handlers", func() {
+			body := bytes.Repeat([]byte("a"), 1<<20)
+			resp := post(body, body, sha256.New)
+			Expect(resp.StatusCode).To(Equal(200))
+			b, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(body))
+
+			resp = post([]byte(`{"a":2}`), []byte(`{"a":1}`), sha256.New)
+			Expect(resp.StatusCode).To(Equal(401))
+		})
+
+		It("rejects a tampered body bound by the handlers", func() {
+			var called bool
+			router := gin.New()
+			router.POST("/private", hm.Filter, func(c *gin.Context) {
+				called = true
+				var v struct{ A int }
+				if err := c.ShouldBindJSON(&v); err != nil {
+					c.String(400, err.Error())
+					return
+				}
+				c.JSON(200, v)
+			})
+			ts.Config.Handler = router
+
+			resp := post([]byte(`{"a":2}   `), []byte(`{"a":1}   `), sha256.New)
+			Expect(resp.StatusCode).To(Equal(401))
+			Expect(called).To(BeFalse())
+			Expect(post([]byte(`{"a":1}`), []byte(`{"a":1}`), sha256.New).StatusCode).To(Equal(200))
+		})
+
+		It("limits the body size", func() {
+			hm.MaxBodySize = 8
+			body := []byte(`{"a":"large"}`)
+			Expect(post(body, body, sha256.New).StatusCode).To(Equal(413))
+
+			req := httptest.NewRequest("POST", "http://example.com/private", ioutil.NopCloser(bytes.NewReader(body)))
+			req.ContentLength = -1
+			auth := hawk.NewRequestAuth(req, &hawk.Credentials{ID: "id", Key: "test-cred-key", Hash: sha1.New}, 0)
+			auth.Hash = PayloadHash(sha256.New, "", body)
+			req.Header.Set("Authorization", auth.RequestHeader())
+			hr := &Request{Hawk: hm, PayloadHash: sha256.New}
+			Expect(hr.ValidatePayload(req, auth)).To(Equal(ErrBodyTooLarge))
+		})
+
+	})
+
+	It("normalizes the content type", func() {
+		body := []byte(`{"a":1}`)
+		post := func(header, hashed string) int {
+			req, err := http.NewRequest("POST", ts.URL+"/private", bytes.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", header)
+			auth := hawk.NewRequestAuth(req, &hawk.Credentials{ID: "id", Key: "test-cred-key", Hash: sha1.New}, 0)
+			auth.Hash = PayloadHash(sha256.New, hashed, body)
+			req.Header.Set("Authorization", auth.RequestHeader())
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			return resp.StatusCode
+		}
+
+		Expect(NormalizeContentType(" Application/JSON ; charset=UTF-8")).To(Equal("application/json"))
+		Expect(post("Application/JSON ; charset=utf-8", "application/json")).To(Equal(200))
+		Expect(post("application/json; charset=utf-8", "application/json; charset=utf-8")).To(Equal(401))
+
+		hm.ContentTypeNormalizer = func(ct string) string {
+			return ct
+		}
+		Expect(post("application/json; charset=utf-8", "application/json; charset=utf-8")).To(Equal(200))
+	})
+
+})
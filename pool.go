@@ -0,0 +1,39 @@
+package hawk
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestPool holds the Requests of Filter. The protocol callbacks are
+// bound once per pooled Request rather than on every call.
+var requestPool = sync.Pool{
+	New: func() interface{} {
+		hr := &Request{}
+		hr.lookup = hr.CredentialsLookup
+		hr.nonceCheck = hr.NonceCheck
+		return hr
+	},
+}
+
+// acquireRequest returns a pooled Request for c, see releaseRequest.
+func (hm *Middleware) acquireRequest(c *gin.Context) *Request {
+	hr := requestPool.Get().(*Request)
+	hr.Hawk = hm
+	hr.ctx = c.Request.Context()
+	hr.ip = hm.clientIP(c)
+	hr.w = c.Writer
+	hr.start = time.Now()
+	return hr
+}
+
+// releaseRequest resets hr and puts it back in the pool.
+func releaseRequest(hr *Request) {
+	if hr.spool != nil {
+		hr.spool.Close()
+	}
+	*hr = Request{lookup: hr.lookup, nonceCheck: hr.nonceCheck}
+	requestPool.Put(hr)
+}
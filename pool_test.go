@@ -0,0 +1,72 @@
+package hawk_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request pool", func() {
+
+	It("does not leak state between requests", func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key").Scopes = []string{"read"}
+		hm := store.Middleware()
+		var failedID string
+		hm.OnAuthFailure = func(c *gin.Context, id string, err error) {
+			failedID = id
+		}
+		var scopes []string
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			scopes = ScopesFromContext(c)
+			c.String(200, "ok")
+		})
+
+		for i := 0; i < 10; i++ {
+			req := httptest.NewRequest("GET", "http://example.com/private", nil)
+			_, err := hawktest.SignRequest(req, "my-id", "my-key")
+			Expect(err).ToNot(HaveOccurred())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(scopes).To(Equal([]string{"read"}))
+
+			failedID = "unset"
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/private", nil))
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(failedID).To(BeEmpty())
+		}
+	})
+
+	// Performance regression gate, see the benchmarks in bench_test.go.
+	// The budget leaves room for the allocations of gin and the race
+	// detector.
+	It("stays within the allocation budget", func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm := NewMiddleware(store.GetCredentials, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {})
+		req := httptest.NewRequest("GET", "http://example.com/private", nil)
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+
+		allocs := testing.AllocsPerRun(100, func() {
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		})
+		Expect(allocs).To(BeNumerically("<=", 60))
+	})
+
+})
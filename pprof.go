@@ -0,0 +1,32 @@
+package hawk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime/pprof"
+)
+
+// credentialLabel returns a short hash of the credentials id, so hot
+// credentials can be told apart in profiles without exposing ids.
+func credentialLabel(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:6])
+}
+
+// profile calls f with the pprof labels "hawk.phase" and
+// "hawk.credential" set if the Middleware ProfilerLabels is set.
+func (hr *Request) profile(phase, id string, f func()) {
+	if !hr.Hawk.ProfilerLabels {
+		f()
+		return
+	}
+	ctx := hr.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	labels := pprof.Labels("hawk.phase", phase, "hawk.credential", credentialLabel(id))
+	pprof.Do(ctx, labels, func(context.Context) {
+		f()
+	})
+}
@@ -0,0 +1,83 @@
+package hawk_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProfilerLabels", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var credentialsProfile, nonceProfile string
+
+	goroutines := func() string {
+		buf := &bytes.Buffer{}
+		Expect(pprof.Lookup("goroutine").WriteTo(buf, 1)).To(Succeed())
+		return buf.String()
+	}
+
+	getCredentials := func(id string) (*Credentials, error) {
+		credentialsProfile = goroutines()
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		nonceProfile = goroutines()
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		credentialsProfile, nonceProfile = "", ""
+		hm = NewMiddleware(getCredentials, setNonce)
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func() {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "valid-id",
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+	}
+
+	It("labels the authentication phases", func() {
+		hm.ProfilerLabels = true
+		request()
+		Expect(credentialsProfile).To(ContainSubstring(`"hawk.phase":"credentials"`))
+		Expect(credentialsProfile).To(MatchRegexp(`"hawk.credential":"[0-9a-f]{12}"`))
+		Expect(credentialsProfile).ToNot(ContainSubstring("valid-id"))
+		Expect(nonceProfile).To(ContainSubstring(`"hawk.phase":"nonce"`))
+	})
+
+	It("does not label by default", func() {
+		request()
+		Expect(credentialsProfile).ToNot(ContainSubstring("hawk.phase"))
+	})
+
+})
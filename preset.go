@@ -0,0 +1,104 @@
+package hawk
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+var (
+	// ErrTLSRequired is set in context.Err if RequireTLS is set and the
+	// request was not received over TLS.
+	ErrTLSRequired = errors.New("TLS required")
+
+	// ErrHeaderTooLarge is set in context.Err if the "Authorization"
+	// header is longer than MaxHeaderSize.
+	ErrHeaderTooLarge = errors.New("Authorization header too large")
+)
+
+// Option configures a Middleware, see Apply.
+type Option func(hm *Middleware)
+
+// Apply applies the options in order and returns hm, so a preset can
+// be adopted and deliberately diverged from:
+//
+//	hm := hawk.NewMiddleware(gcf, snf).Apply(hawk.PresetStrict())
+//	hm.MaxSkew = time.Minute
+func (hm *Middleware) Apply(opts ...Option) *Middleware {
+	for _, opt := range opts {
+		opt(hm)
+	}
+	return hm
+}
+
+// PresetStrict is the hardening profile: payloads are validated and
+// required on writes, TLS is required, the timestamp skew is limited to
+// 30 seconds, the "Authorization" header to 4KB, credentials keys
+// failing the DefaultKeyPolicy and mixed hash algorithms are rejected and
+// the StrictMode is enabled.
+func PresetStrict() Option {
+	return func(hm *Middleware) {
+		hm.ValidatePayload = true
+		hm.RequirePayloadHash = true
+		hm.RequireTLS = true
+		hm.MaxSkew = 30 * time.Second
+		hm.MaxHeaderSize = 4096
+		hm.ForbidMixedHash = true
+		policy := DefaultKeyPolicy
+		hm.KeyPolicy = &policy
+		hm.Base64Normalizer = nil
+		hm.StrictMode = true
+	}
+}
+
+// PresetCompat is the legacy friendly profile: payloads are not
+// validated, plain http and lenient base64 values are accepted and
+// the protocol defaults are used for the timestamp skew.
+func PresetCompat() Option {
+	return func(hm *Middleware) {
+		hm.ValidatePayload = false
+		hm.RequirePayloadHash = false
+		hm.RequireTLS = false
+		hm.MaxSkew = 0
+		hm.MaxHeaderSize = 0
+		hm.ForbidMixedHash = false
+		hm.KeyPolicy = nil
+		hm.Base64Normalizer = LenientBase64
+		hm.StrictMode = false
+	}
+}
+
+// isTLS returns true if r was received over TLS, or forwarded from
+// https by a trusted proxy.
+func (hm *Middleware) isTLS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return hm.fromTrustedProxy(r) &&
+		strings.EqualFold(firstHeader(r, "X-Forwarded-Proto"), "https")
+}
+
+// checkRequest enforces RequireTLS, MaxHeaderSize and the unknown
+// attributes policy before the request is parsed.
+func (hm *Middleware) checkRequest(r *http.Request) error {
+	if hm.RequireTLS && !hm.isTLS(r) {
+		return ErrTLSRequired
+	}
+	if hm.MaxHeaderSize > 0 && len(r.Header.Get("Authorization")) > hm.MaxHeaderSize {
+		return ErrHeaderTooLarge
+	}
+	return hm.checkAttributes(r)
+}
+
+// skewed returns true if the timestamp of a header authenticated
+// request is skewed by more than MaxSkew.
+func (hm *Middleware) skewed(auth *hawk.Auth) bool {
+	if hm.MaxSkew <= 0 || auth.IsBewit {
+		return false
+	}
+	skew := auth.ActualTimestamp.Sub(auth.Timestamp)
+	return skew > hm.MaxSkew || skew < -hm.MaxSkew
+}
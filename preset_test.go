@@ -0,0 +1,130 @@
+package hawk_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Presets", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+	var lastErr error
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		lastErr = nil
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.OnAuthFailure = func(c *gin.Context, id string, err error) {
+			lastErr = err
+		}
+		router := gin.New()
+		router.Any("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(method string, body []byte, offset time.Duration, headers map[string]string) int {
+		req, err := http.NewRequest(method, ts.URL+"/private", bytes.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "id",
+			Key:  "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+			Hash: sha256.New,
+		}, offset)
+		if body != nil {
+			req.Header.Set("Content-Type", "text/plain")
+			auth.Hash = PayloadHash(sha256.New, "text/plain", body)
+		}
+		req.Header.Set("Authorization", auth.RequestHeader())
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode
+	}
+
+	It("returns the Middleware from Apply", func() {
+		Expect(hm.Apply(PresetStrict())).To(BeIdenticalTo(hm))
+		Expect(hm.ValidatePayload).To(BeTrue())
+		hm.Apply(PresetCompat())
+		Expect(hm.ValidatePayload).To(BeFalse())
+		Expect(hm.Base64Normalizer).ToNot(BeNil())
+	})
+
+	It("requires TLS in strict mode", func() {
+		hm.Apply(PresetStrict())
+		Expect(request("GET", nil, 0, nil)).To(Equal(401))
+		Expect(lastErr).To(Equal(ErrTLSRequired))
+
+		hm.TrustProxyHeaders = true
+		hm.HostOverride = "127.0.0.1"
+		hm.PortOverride = strings.Split(ts.Listener.Addr().String(), ":")[1]
+		Expect(request("GET", nil, 0, map[string]string{
+			"X-Forwarded-Proto": "https",
+		})).To(Equal(401))
+		Expect(lastErr).To(Equal(ErrTLSRequired))
+
+		hm.TrustedProxies = []string{"127.0.0.1"}
+		Expect(request("GET", nil, 0, map[string]string{
+			"X-Forwarded-Proto": "https",
+		})).To(Equal(200))
+	})
+
+	Context("with TLS", func() {
+
+		BeforeEach(func() {
+			hm.Apply(PresetStrict())
+			hm.RequireTLS = false
+		})
+
+		It("requires a payload hash on writes", func() {
+			Expect(request("GET", nil, 0, nil)).To(Equal(200))
+			Expect(request("DELETE", nil, 0, nil)).To(Equal(401))
+			Expect(lastErr).To(Equal(ErrMissingPayloadHash))
+			Expect(request("POST", []byte("body"), 0, nil)).To(Equal(200))
+		})
+
+		It("limits the timestamp skew", func() {
+			Expect(request("GET", nil, 20*time.Second, nil)).To(Equal(200))
+			Expect(request("GET", nil, 45*time.Second, nil)).To(Equal(401))
+			Expect(lastErr).To(Equal(hawk.ErrTimestampSkew))
+
+			hm.Apply(PresetCompat())
+			Expect(request("GET", nil, 45*time.Second, nil)).To(Equal(200))
+		})
+
+		It("limits the header size", func() {
+			Expect(request("GET", nil, 0, map[string]string{
+				"Authorization": "Hawk id=\"" + strings.Repeat("a", 5000) + "\"",
+			})).To(Equal(401))
+			Expect(lastErr).To(Equal(ErrHeaderTooLarge))
+		})
+
+	})
+
+})
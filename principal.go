@@ -0,0 +1,123 @@
+package hawk
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPrincipalDisabled is set in context.Err if the credentials belong
+// to a principal disabled by the PrincipalChecker.
+var ErrPrincipalDisabled = errors.New("Principal disabled")
+
+// PrincipalChecker is an account level kill switch: Disabled returns
+// true if all the credentials of principal must be rejected. An error is
+// an external problem and it will be set as the context error.
+type PrincipalChecker interface {
+	Disabled(principal string) (bool, error)
+}
+
+// PrincipalCheckerFunc is a function implementing PrincipalChecker.
+type PrincipalCheckerFunc func(principal string) (bool, error)
+
+// Disabled calls f.
+func (f PrincipalCheckerFunc) Disabled(principal string) (bool, error) {
+	return f(principal)
+}
+
+// principalDisabled checks the credentials principal if any.
+func (hm *Middleware) principalDisabled(creds *Credentials) (bool, error) {
+	if hm.PrincipalChecker == nil || creds.Principal == "" {
+		return false, nil
+	}
+	return hm.PrincipalChecker.Disabled(creds.Principal)
+}
+
+// DefaultPrincipalCacheSize is the default CachedPrincipalChecker
+// MaxEntries.
+const DefaultPrincipalCacheSize = 10000
+
+type principalEntry struct {
+	disabled bool
+	expires  time.Time
+}
+
+// CachedPrincipalChecker caches the results of a PrincipalChecker.
+// Disabling a principal is guaranteed to propagate to the instance
+// within TTL, or immediately after Invalidate is called (from a pub/sub
+// notification for example).
+// MaxEntries if set bounds the cache, the new principals are not cached
+// once reached. The expired entries are swept every TTL.
+type CachedPrincipalChecker struct {
+	Checker    PrincipalChecker
+	TTL        time.Duration
+	MaxEntries int
+
+	mu        sync.RWMutex
+	entries   map[string]principalEntry
+	nextSweep time.Time
+}
+
+// NewCachedPrincipalChecker creates a new CachedPrincipalChecker.
+func NewCachedPrincipalChecker(checker PrincipalChecker, ttl time.Duration) *CachedPrincipalChecker {
+	return &CachedPrincipalChecker{
+		Checker:    checker,
+		TTL:        ttl,
+		MaxEntries: DefaultPrincipalCacheSize,
+		entries:    map[string]principalEntry{},
+	}
+}
+
+// Disabled returns the cached result if not expired, otherwise
+// it calls the Checker. Errors are not cached.
+func (cc *CachedPrincipalChecker) Disabled(principal string) (bool, error) {
+	now := time.Now()
+	cc.mu.RLock()
+	e, exists := cc.entries[principal]
+	cc.mu.RUnlock()
+	if exists && now.Before(e.expires) {
+		return e.disabled, nil
+	}
+
+	disabled, err := cc.Checker.Disabled(principal)
+	if err != nil {
+		return false, err
+	}
+	cc.set(principal, principalEntry{disabled, now.Add(cc.TTL)}, now)
+	return disabled, nil
+}
+
+// set caches e for principal unless MaxEntries is reached once the
+// expired entries are swept.
+func (cc *CachedPrincipalChecker) set(principal string, e principalEntry, now time.Time) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	_, exists := cc.entries[principal]
+	full := cc.MaxEntries > 0 && len(cc.entries) >= cc.MaxEntries
+	if !exists && (full || now.After(cc.nextSweep)) {
+		for p, entry := range cc.entries {
+			if !now.Before(entry.expires) {
+				delete(cc.entries, p)
+			}
+		}
+		cc.nextSweep = now.Add(cc.TTL)
+		if cc.MaxEntries > 0 && len(cc.entries) >= cc.MaxEntries {
+			return
+		}
+	}
+	cc.entries[principal] = e
+}
+
+// Len returns the number of cached results, expired ones included.
+func (cc *CachedPrincipalChecker) Len() int {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return len(cc.entries)
+}
+
+// Invalidate removes the cached result of principal.
+func (cc *CachedPrincipalChecker) Invalidate(principal string) {
+	cc.mu.Lock()
+	delete(cc.entries, principal)
+	cc.mu.Unlock()
+}
@@ -0,0 +1,99 @@
+package hawk_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrincipalChecker", func() {
+
+	var disabled map[string]bool
+	var calls int
+	checkErr := errors.New("checker error")
+
+	checker := PrincipalCheckerFunc(func(principal string) (bool, error) {
+		calls++
+		if principal == "error-org" {
+			return false, checkErr
+		}
+		return disabled[principal], nil
+	})
+
+	lookup := func(hm *Middleware, principal string) (*Request, error) {
+		hm.GetCredentials = func(id string) (*Credentials, error) {
+			return &Credentials{Key: "test-cred-key", Principal: principal}, nil
+		}
+		hr := &Request{Hawk: hm}
+		return hr, hr.CredentialsLookup(&hawk.Credentials{ID: "id"})
+	}
+
+	BeforeEach(func() {
+		disabled = map[string]bool{"disabled-org": true}
+		calls = 0
+	})
+
+	It("rejects credentials of disabled principals", func() {
+		hm := NewMiddleware(nil, nil)
+		hm.PrincipalChecker = checker
+
+		_, err := lookup(hm, "active-org")
+		Expect(err).ToNot(HaveOccurred())
+
+		hr, err := lookup(hm, "disabled-org")
+		Expect(err).To(Equal(ErrPrincipalDisabled))
+		Expect(hr.Error).To(BeNil())
+		Expect(ISHawkError(err)).To(BeTrue())
+
+		hr, err = lookup(hm, "error-org")
+		Expect(err).To(Equal(checkErr))
+		Expect(hr.Error).To(Equal(checkErr))
+
+		_, err = lookup(hm, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(calls).To(Equal(3))
+	})
+
+	It("caches results until the TTL or an invalidation", func() {
+		cc := NewCachedPrincipalChecker(checker, time.Hour)
+		Expect(cc.Disabled("org")).To(BeFalse())
+		disabled["org"] = true
+		Expect(cc.Disabled("org")).To(BeFalse())
+		Expect(calls).To(Equal(1))
+
+		cc.Invalidate("org")
+		Expect(cc.Disabled("org")).To(BeTrue())
+		Expect(calls).To(Equal(2))
+
+		cc.TTL = 0
+		cc.Invalidate("org")
+		Expect(cc.Disabled("org")).To(BeTrue())
+		Expect(cc.Disabled("org")).To(BeTrue())
+		Expect(calls).To(Equal(4))
+	})
+
+	It("bounds and sweeps the cache", func() {
+		cc := NewCachedPrincipalChecker(checker, time.Hour)
+		cc.MaxEntries = 2
+		for _, p := range []string{"a", "b", "c"} {
+			Expect(cc.Disabled(p)).To(BeFalse())
+		}
+		Expect(cc.Len()).To(Equal(2))
+		Expect(cc.Disabled("c")).To(BeFalse())
+		Expect(calls).To(Equal(4))
+
+		cc = NewCachedPrincipalChecker(checker, time.Millisecond)
+		cc.MaxEntries = 2
+		Expect(cc.Disabled("a")).To(BeFalse())
+		Expect(cc.Disabled("b")).To(BeFalse())
+		time.Sleep(2 * time.Millisecond)
+		Expect(cc.Disabled("c")).To(BeFalse())
+		Expect(cc.Len()).To(Equal(1))
+	})
+
+})
@@ -0,0 +1,71 @@
+package protocol
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"time"
+)
+
+// MessageAuth is the authorization of a message sent outside of HTTP
+// (WebSocket frames, queues...), JSON encoded like the reference
+// implementation. Hash and MAC are base64 encoded.
+type MessageAuth struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"ts"`
+	Nonce     string `json:"nonce"`
+	Hash      string `json:"hash"`
+	MAC       string `json:"mac"`
+}
+
+// messageAuth returns the Auth of a message, the MAC is not set.
+func messageAuth(creds *Credentials, host, port string, ts time.Time, nonce string, msg []byte) *Auth {
+	auth := &Auth{
+		Credentials: *creds,
+		Host:        host,
+		Port:        port,
+		Nonce:       nonce,
+		Timestamp:   ts,
+	}
+	h := auth.PayloadHash("")
+	h.Write(msg)
+	auth.SetHash(h)
+	return auth
+}
+
+// SignMessage returns the authorization of msg sent to host and port with creds.
+func SignMessage(creds *Credentials, host, port string, msg []byte) *MessageAuth {
+	auth := messageAuth(creds, host, port, Now(), nonce(), msg)
+	return &MessageAuth{
+		ID:        creds.ID,
+		Timestamp: auth.Timestamp.Unix(),
+		Nonce:     auth.Nonce,
+		Hash:      base64.StdEncoding.EncodeToString(auth.Hash),
+		MAC:       base64.StdEncoding.EncodeToString(auth.mac(AuthMessage)),
+	}
+}
+
+// Valid checks the timestamp skew against now, the payload hash of msg
+// and the MAC with creds.
+func (m *MessageAuth) Valid(creds *Credentials, host, port string, msg []byte, now time.Time) error {
+	if m.ID == "" || m.Nonce == "" || m.Timestamp == 0 {
+		return AuthFormatError{"message", "missing id, ts or nonce"}
+	}
+	mac, err := base64.StdEncoding.DecodeString(m.MAC)
+	if err != nil || len(mac) == 0 {
+		return AuthFormatError{"mac", "malformed base64 encoding"}
+	}
+	hash, err := base64.StdEncoding.DecodeString(m.Hash)
+	if err != nil || len(hash) == 0 {
+		return AuthFormatError{"hash", "malformed base64 encoding"}
+	}
+
+	auth := messageAuth(creds, host, port, time.Unix(m.Timestamp, 0), m.Nonce, msg)
+	if skew := now.Sub(auth.Timestamp); skew > MaxTimestampSkew || skew < -MaxTimestampSkew {
+		return ErrTimestampSkew
+	} else if !hmac.Equal(auth.Hash, hash) {
+		return ErrInvalidMAC
+	} else if !hmac.Equal(auth.mac(AuthMessage), mac) {
+		return ErrInvalidMAC
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package protocol_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	. "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MessageAuth", func() {
+
+	creds := &Credentials{
+		ID:   "dh37fgj492je",
+		Key:  "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Hash: sha256.New,
+	}
+	msg := []byte("I am the boodyman")
+
+	It("signs and validates messages", func() {
+		m := SignMessage(creds, "example.com", "8080", msg)
+		Expect(m.ID).To(Equal("dh37fgj492je"))
+		Expect(m.Nonce).ToNot(BeEmpty())
+		Expect(m.Valid(creds, "example.com", "8080", msg, time.Now())).To(Succeed())
+
+		// same normalized string as the reference implementation
+		hash := sha256.New()
+		hash.Write([]byte("hawk.1.payload\n\nI am the boodyman\n"))
+		Expect(m.Hash).To(Equal(base64.StdEncoding.EncodeToString(hash.Sum(nil))))
+		mac := hmac.New(sha256.New, []byte(creds.Key))
+		mac.Write([]byte("hawk.1.message\n" + strconv.FormatInt(m.Timestamp, 10) + "\n" + m.Nonce +
+			"\n\n\nexample.com\n8080\n" + m.Hash + "\n\n"))
+		Expect(m.MAC).To(Equal(base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+	})
+
+	It("rejects invalid messages", func() {
+		m := SignMessage(creds, "example.com", "8080", msg)
+		now := time.Now()
+		Expect(m.Valid(creds, "example.com", "8080", []byte("other"), now)).To(Equal(ErrInvalidMAC))
+		Expect(m.Valid(creds, "example.org", "8080", msg, now)).To(Equal(ErrInvalidMAC))
+		Expect(m.Valid(&Credentials{ID: creds.ID, Key: "other", Hash: sha256.New}, "example.com", "8080", msg, now)).To(Equal(ErrInvalidMAC))
+		Expect(m.Valid(creds, "example.com", "8080", msg, now.Add(2*time.Minute))).To(Equal(ErrTimestampSkew))
+
+		bad := *m
+		bad.MAC = "%"
+		_, ok := bad.Valid(creds, "example.com", "8080", msg, now).(AuthFormatError)
+		Expect(ok).To(BeTrue())
+		bad = *m
+		bad.Nonce = ""
+		_, ok = bad.Valid(creds, "example.com", "8080", msg, now).(AuthFormatError)
+		Expect(ok).To(BeTrue())
+	})
+
+})
@@ -0,0 +1,606 @@
+// Package protocol implements the Hawk protocol: the normalized strings,
+// MACs, "Authorization" and "Server-Authorization" headers, payload
+// hashes and bewits. It is wire compatible with the Hawk reference
+// implementation and the API of github.com/tent/hawk-go, which it
+// replaces.
+//
+// A client signs a request:
+//
+//	auth := protocol.NewRequestAuth(req, &protocol.Credentials{ID: id, Key: key, Hash: sha256.New}, 0)
+//	req.Header.Set("Authorization", auth.RequestHeader())
+//
+// and a server authenticates it with NewAuthFromRequest and Valid.
+package protocol
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"hash"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authentication errors.
+var (
+	ErrNoAuth             = AuthError("no Authorization header or bewit parameter found")
+	ErrReplay             = AuthError("request nonce is being replayed")
+	ErrInvalidMAC         = AuthError("invalid MAC")
+	ErrBewitExpired       = AuthError("bewit expired")
+	ErrTimestampSkew      = AuthError("timestamp skew too high")
+	ErrMissingServerAuth  = AuthError("missing Server-Authentication header")
+	ErrInvalidBewitMethod = AuthError("bewit only allows HEAD and GET requests")
+)
+
+// AuthError is an authentication error.
+type AuthError string
+
+func (e AuthError) Error() string {
+	return "hawk: " + string(e)
+}
+
+// AuthFormatError is a malformed "Authorization" header or bewit.
+// Field is the invalid attribute and Err the problem.
+type AuthFormatError struct {
+	Field string
+	Err   string
+}
+
+func (e AuthFormatError) Error() string {
+	return "hawk: invalid " + e.Field + ", " + e.Err
+}
+
+// Credentials are the credentials used to sign or verify a request.
+// Hash is the MAC algorithm, sha256.New for example.
+// App and Delegate are the optional "app" and "dlg" attributes.
+type Credentials struct {
+	ID   string
+	Key  string
+	Hash func() hash.Hash
+
+	App      string
+	Delegate string
+}
+
+// MAC returns a new HMAC of the credentials key.
+func (c *Credentials) MAC() hash.Hash {
+	return hmac.New(c.Hash, []byte(c.Key))
+}
+
+// AuthType is the type of a normalized string.
+type AuthType int
+
+// Types of normalized strings.
+const (
+	AuthHeader AuthType = iota
+	AuthResponse
+	AuthBewit
+	AuthMessage
+)
+
+func (a AuthType) String() string {
+	switch a {
+	case AuthResponse:
+		return "response"
+	case AuthBewit:
+		return "bewit"
+	case AuthMessage:
+		return "message"
+	}
+	return "header"
+}
+
+// MaxTimestampSkew is the maximum difference between the timestamp of a
+// request and the server time checked by Valid.
+var MaxTimestampSkew = time.Minute
+
+// Now is the clock used to sign requests and to set ActualTimestamp.
+var Now = time.Now
+
+// bufferPool holds the buffers of the normalized strings.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Auth is the authentication of a request.
+// MAC is the MAC sent by the client.
+// Hash is the payload hash, ReqHash is true if it was sent by the client.
+// Timestamp is the request timestamp or the bewit expiration and
+// ActualTimestamp the time the request was received.
+type Auth struct {
+	Credentials Credentials
+
+	Method     string
+	RequestURI string
+	Host       string
+	Port       string
+
+	MAC   []byte
+	Nonce string
+	Ext   string
+	Hash  []byte
+
+	ReqHash bool
+	IsBewit bool
+
+	Timestamp       time.Time
+	ActualTimestamp time.Time
+}
+
+// CredentialsLookupFunc completes the credentials found in a request
+// (ID, App and Delegate are set) with the Key and Hash.
+type CredentialsLookupFunc func(*Credentials) error
+
+// NonceCheckFunc returns false if the nonce was already used.
+type NonceCheckFunc func(string, time.Time, *Credentials) bool
+
+func nonce() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// NewRequestAuth creates the Auth to sign req with creds, tsOffset is
+// added to the timestamp to compensate the client clock skew.
+func NewRequestAuth(req *http.Request, creds *Credentials, tsOffset time.Duration) *Auth {
+	auth := &Auth{
+		Method:      req.Method,
+		Credentials: *creds,
+		Timestamp:   Now().Add(tsOffset),
+		Nonce:       nonce(),
+		RequestURI:  req.URL.RequestURI(),
+	}
+	auth.Host, auth.Port = extractReqHostPort(req)
+	return auth
+}
+
+// NewURLAuth creates the Auth to sign a bewit for uri with creds, the
+// bewit expires after ttl.
+func NewURLAuth(uri string, creds *Credentials, ttl time.Duration) (*Auth, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	auth := &Auth{
+		Method:      "GET",
+		Credentials: *creds,
+		Timestamp:   Now().Add(ttl),
+	}
+	if u.Path != "" {
+		auth.RequestURI = u.RequestURI()
+	} else {
+		auth.RequestURI = "/" + u.RequestURI()
+	}
+	auth.Host, auth.Port = extractURLHostPort(u)
+	return auth, nil
+}
+
+// defaultPort returns the port of scheme, 443 unless "http".
+func defaultPort(scheme string) string {
+	if scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+func splitHostPort(hostport string) (string, string) {
+	if strings.Contains(hostport, ":") {
+		host, port, _ := net.SplitHostPort(hostport)
+		return host, port
+	}
+	return hostport, ""
+}
+
+// extractReqHostPort returns the host and port of req, the URL host of
+// client requests takes precedence over the Host header.
+func extractReqHostPort(req *http.Request) (host string, port string) {
+	host, port = splitHostPort(req.Host)
+	if req.URL.Host != "" {
+		host, port = splitHostPort(req.URL.Host)
+	}
+	if port == "" {
+		port = defaultPort(req.URL.Scheme)
+	}
+	return
+}
+
+func extractURLHostPort(u *url.URL) (host string, port string) {
+	host, port = splitHostPort(u.Host)
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+	return
+}
+
+// NewAuthFromRequest parses the "Authorization" header or the "bewit"
+// query parameter of req, completes the credentials with creds and
+// checks the nonce of headers with nonce. The MAC is not checked, see Valid.
+func NewAuthFromRequest(req *http.Request, creds CredentialsLookupFunc, nonce NonceCheckFunc) (*Auth, error) {
+	header := req.Header.Get("Authorization")
+	bewit := req.URL.Query().Get("bewit")
+
+	auth := &Auth{Method: req.Method, RequestURI: req.URL.EscapedPath()}
+	if req.URL.RawQuery != "" {
+		auth.RequestURI += "?" + req.URL.RawQuery
+	}
+	if bewit != "" && header == "" {
+		if req.Method != "GET" && req.Method != "HEAD" {
+			return nil, ErrInvalidBewitMethod
+		}
+		auth.IsBewit = true
+		auth.RequestURI = stripBewit(req.URL)
+		if err := auth.ParseBewit(bewit); err != nil {
+			return nil, err
+		}
+	} else {
+		if header == "" {
+			return nil, ErrNoAuth
+		}
+		if err := auth.ParseHeader(header, AuthHeader); err != nil {
+			return nil, err
+		}
+	}
+	auth.Host, auth.Port = extractReqHostPort(req)
+	if creds != nil {
+		if err := creds(&auth.Credentials); err != nil {
+			return nil, err
+		}
+	}
+	if !auth.IsBewit && nonce != nil && !nonce(auth.Nonce, auth.Timestamp, &auth.Credentials) {
+		return nil, ErrReplay
+	}
+	auth.ActualTimestamp = Now()
+	return auth, nil
+}
+
+// stripBewit returns the request URI of u without the "bewit" parameter,
+// keeping the order and encoding of the path and other parameters.
+func stripBewit(u *url.URL) string {
+	uri := u.EscapedPath()
+	if u.RawQuery == "" {
+		return uri
+	}
+	var kept []string
+	for _, p := range strings.Split(u.RawQuery, "&") {
+		if !strings.HasPrefix(p, "bewit=") {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) > 0 {
+		uri += "?" + strings.Join(kept, "&")
+	}
+	return uri
+}
+
+// ParseHeader parses an "Authorization" (t is AuthHeader) or
+// "Server-Authorization" (t is AuthResponse) header.
+func (auth *Auth) ParseHeader(header string, t AuthType) error {
+	if len(header) < 4 || !strings.EqualFold(header[:4], "hawk") {
+		return AuthFormatError{"scheme", "must be Hawk"}
+	}
+	var err error
+	ok := LexHeader(header[4:], func(k, v string) {
+		if err != nil {
+			return
+		}
+		switch k {
+		case "id":
+			auth.Credentials.ID = v
+		case "ts":
+			ts, perr := strconv.ParseInt(v, 10, 64)
+			if perr != nil {
+				err = AuthFormatError{"ts", "not an integer"}
+				return
+			}
+			auth.Timestamp = time.Unix(ts, 0)
+		case "nonce":
+			auth.Nonce = v
+		case "ext":
+			auth.Ext = v
+		case "mac":
+			mac, derr := base64.StdEncoding.DecodeString(v)
+			if derr != nil {
+				err = AuthFormatError{"mac", "malformed base64 encoding"}
+				return
+			}
+			auth.MAC = mac
+		case "hash":
+			h, derr := base64.StdEncoding.DecodeString(v)
+			if derr != nil {
+				err = AuthFormatError{"hash", "malformed base64 encoding"}
+				return
+			}
+			auth.Hash = h
+		case "app":
+			auth.Credentials.App = v
+		case "dlg":
+			auth.Credentials.Delegate = v
+		}
+	})
+	if !ok {
+		return AuthFormatError{"header", "malformed"}
+	} else if err != nil {
+		return err
+	}
+	if len(auth.MAC) == 0 {
+		return AuthFormatError{"mac", "missing or empty"}
+	}
+	if t == AuthHeader {
+		if auth.Credentials.ID == "" {
+			return AuthFormatError{"id", "missing or empty"}
+		}
+		if auth.Timestamp.IsZero() {
+			return AuthFormatError{"ts", "missing, empty, or zero"}
+		}
+		if auth.Nonce == "" {
+			return AuthFormatError{"nonce", "missing or empty"}
+		}
+	}
+	auth.ReqHash = true
+	return nil
+}
+
+// LexHeader calls f with the `key="value"` attributes of s, a header
+// value without its scheme. It returns false if s is malformed.
+func LexHeader(s string, f func(key, value string)) bool {
+	s = strings.TrimSpace(s)
+	for len(s) > 0 {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			return false
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = s[eq+1:]
+		if len(s) == 0 || s[0] != '"' {
+			return false
+		}
+		end := strings.Index(s[1:], `"`)
+		if end < 0 {
+			return false
+		}
+		f(key, s[1:end+1])
+		s = strings.TrimSpace(s[end+2:])
+		s = strings.TrimSpace(strings.TrimPrefix(s, ","))
+	}
+	return true
+}
+
+// ParseBewit parses a bewit: the base64url encoding of
+// `id\expiration\mac\ext`, with or without padding.
+func (auth *Auth) ParseBewit(bewit string) error {
+	if len(bewit)%4 != 0 {
+		bewit += strings.Repeat("=", 4-len(bewit)%4)
+	}
+	decoded, err := base64.URLEncoding.DecodeString(bewit)
+	if err != nil {
+		return AuthFormatError{"bewit", "malformed base64 encoding"}
+	}
+	components := bytes.SplitN(decoded, []byte(`\`), 4)
+	if len(components) != 4 {
+		return AuthFormatError{"bewit", "missing components"}
+	}
+	auth.Credentials.ID = string(components[0])
+	ts, err := strconv.ParseInt(string(components[1]), 10, 64)
+	if err != nil {
+		return AuthFormatError{"ts", "not an integer"}
+	}
+	auth.Timestamp = time.Unix(ts, 0)
+	auth.MAC = make([]byte, base64.StdEncoding.DecodedLen(len(components[2])))
+	n, err := base64.StdEncoding.Decode(auth.MAC, components[2])
+	if err != nil {
+		return AuthFormatError{"mac", "malformed base64 encoding"}
+	}
+	auth.MAC = auth.MAC[:n]
+	auth.Ext = string(components[3])
+	return nil
+}
+
+// Valid checks the timestamp skew or the bewit expiration against
+// ActualTimestamp and the MAC.
+func (auth *Auth) Valid() error {
+	t := AuthHeader
+	if auth.IsBewit {
+		t = AuthBewit
+		if auth.Method != "GET" && auth.Method != "HEAD" {
+			return ErrInvalidBewitMethod
+		}
+		if auth.ActualTimestamp.After(auth.Timestamp) {
+			return ErrBewitExpired
+		}
+	} else {
+		skew := auth.ActualTimestamp.Sub(auth.Timestamp)
+		if skew > MaxTimestampSkew || skew < -MaxTimestampSkew {
+			return ErrTimestampSkew
+		}
+	}
+	if !hmac.Equal(auth.mac(t), auth.MAC) {
+		return ErrInvalidMAC
+	}
+	return nil
+}
+
+// ValidResponse checks the "Server-Authorization" header of the response.
+func (auth *Auth) ValidResponse(header string) error {
+	if header == "" {
+		return ErrMissingServerAuth
+	}
+	res := &Auth{}
+	if err := res.ParseHeader(header, AuthResponse); err != nil {
+		return err
+	}
+	auth.Ext = res.Ext
+	auth.Hash = res.Hash
+	if !hmac.Equal(auth.mac(AuthResponse), res.MAC) {
+		return ErrInvalidMAC
+	}
+	return nil
+}
+
+// PayloadHash returns the hash to write the payload of contentType to,
+// see SetHash and ValidHash.
+func (auth *Auth) PayloadHash(contentType string) hash.Hash {
+	h := auth.Credentials.Hash()
+	h.Write([]byte("hawk.1.payload\n"))
+	h.Write([]byte(contentType))
+	h.Write([]byte("\n"))
+	return h
+}
+
+// ValidHash returns true if the payload hash h matches the Hash.
+func (auth *Auth) ValidHash(h hash.Hash) bool {
+	h.Write([]byte("\n"))
+	return hmac.Equal(h.Sum(nil), auth.Hash)
+}
+
+// SetHash sets the payload hash h as the Hash.
+func (auth *Auth) SetHash(h hash.Hash) {
+	h.Write([]byte("\n"))
+	auth.Hash = h.Sum(nil)
+	auth.ReqHash = false
+}
+
+// NormalizedString returns the string signed by the MAC.
+func (auth *Auth) NormalizedString(t AuthType) string {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+	buf.Reset()
+	auth.writeNormalized(buf, t)
+	return buf.String()
+}
+
+// writeNormalized writes the normalized string to buf without
+// intermediate strings.
+func (auth *Auth) writeNormalized(buf *bytes.Buffer, t AuthType) {
+	auth.writeNormalizedWith(buf, t, auth.Hash, auth.Ext)
+}
+
+// writeNormalizedWith is writeNormalized with the payload hash and ext
+// replaced.
+func (auth *Auth) writeNormalizedWith(buf *bytes.Buffer, t AuthType, hash []byte, ext string) {
+	var scratch [128]byte
+	buf.WriteString("hawk.1.")
+	buf.WriteString(t.String())
+	buf.WriteByte('\n')
+	buf.Write(strconv.AppendInt(scratch[:0], auth.Timestamp.Unix(), 10))
+	buf.WriteByte('\n')
+	buf.WriteString(auth.Nonce)
+	buf.WriteByte('\n')
+	buf.WriteString(auth.Method)
+	buf.WriteByte('\n')
+	buf.WriteString(auth.RequestURI)
+	buf.WriteByte('\n')
+	buf.WriteString(auth.Host)
+	buf.WriteByte('\n')
+	buf.WriteString(auth.Port)
+	buf.WriteByte('\n')
+	buf.Write(base64.StdEncoding.AppendEncode(scratch[:0], hash))
+	buf.WriteByte('\n')
+	if strings.ContainsAny(ext, "\\\n") {
+		buf.WriteString(strings.Replace(strings.Replace(ext, `\`, `\\`, -1), "\n", `\n`, -1))
+	} else {
+		buf.WriteString(ext)
+	}
+	buf.WriteByte('\n')
+	if auth.Credentials.App != "" {
+		buf.WriteString(auth.Credentials.App)
+		buf.WriteByte('\n')
+		buf.WriteString(auth.Credentials.Delegate)
+		buf.WriteByte('\n')
+	}
+}
+
+func (auth *Auth) mac(t AuthType) []byte {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+	buf.Reset()
+	auth.writeNormalized(buf, t)
+	mac := auth.Credentials.MAC()
+	mac.Write(buf.Bytes())
+	return mac.Sum(nil)
+}
+
+// ResponseMAC returns the MAC of a response with ext and without payload
+// hash, like ResponseHeader but without modifying auth.
+func (auth *Auth) ResponseMAC(ext string) []byte {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+	buf.Reset()
+	auth.writeNormalizedWith(buf, AuthResponse, nil, ext)
+	mac := auth.Credentials.MAC()
+	mac.Write(buf.Bytes())
+	return mac.Sum(nil)
+}
+
+// StaleTimestampHeader returns the "WWW-Authenticate" header with the
+// server time, for clients to correct their clock skew.
+func (auth *Auth) StaleTimestampHeader() string {
+	return auth.StaleTimestampHeaderAt(Now())
+}
+
+// StaleTimestampHeaderAt is like StaleTimestampHeader with the server
+// time now.
+func (auth *Auth) StaleTimestampHeaderAt(now time.Time) string {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	mac := auth.Credentials.MAC()
+	mac.Write([]byte("hawk.1.ts\n" + ts + "\n"))
+	return `Hawk ts="` + ts +
+		`", tsm="` + base64.StdEncoding.EncodeToString(mac.Sum(nil)) +
+		`", error="Stale timestamp"`
+}
+
+// Bewit returns the bewit of a GET request.
+func (auth *Auth) Bewit() string {
+	auth.Method = "GET"
+	auth.Nonce = ""
+	return strings.TrimRight(base64.URLEncoding.EncodeToString([]byte(auth.Credentials.ID+`\`+
+		strconv.FormatInt(auth.Timestamp.Unix(), 10)+`\`+
+		base64.StdEncoding.EncodeToString(auth.mac(AuthBewit))+`\`+
+		auth.Ext)), "=")
+}
+
+// RequestHeader returns the "Authorization" header and sets the MAC.
+func (auth *Auth) RequestHeader() string {
+	auth.MAC = auth.mac(AuthHeader)
+	h := `Hawk id="` + auth.Credentials.ID +
+		`", mac="` + base64.StdEncoding.EncodeToString(auth.MAC) +
+		`", ts="` + strconv.FormatInt(auth.Timestamp.Unix(), 10) +
+		`", nonce="` + auth.Nonce + `"`
+	if len(auth.Hash) > 0 {
+		h += `, hash="` + base64.StdEncoding.EncodeToString(auth.Hash) + `"`
+	}
+	if auth.Ext != "" {
+		h += `, ext="` + auth.Ext + `"`
+	}
+	if auth.Credentials.App != "" {
+		h += `, app="` + auth.Credentials.App + `", dlg="` + auth.Credentials.Delegate + `"`
+	}
+	return h
+}
+
+// ResponseHeader returns the "Server-Authorization" header with ext. The
+// request payload hash is not included, set the response payload hash
+// with SetHash before.
+func (auth *Auth) ResponseHeader(ext string) string {
+	auth.Ext = ext
+	if auth.ReqHash {
+		auth.Hash = nil
+	}
+	h := `Hawk mac="` + base64.StdEncoding.EncodeToString(auth.mac(AuthResponse)) + `"`
+	if auth.Ext != "" {
+		h += `, ext="` + auth.Ext + `"`
+	}
+	if len(auth.Hash) > 0 {
+		h += `, hash="` + base64.StdEncoding.EncodeToString(auth.Hash) + `"`
+	}
+	return h
+}
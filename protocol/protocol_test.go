@@ -0,0 +1,160 @@
+package protocol_test
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	. "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Golden vectors of the Hawk reference implementation.
+var _ = Describe("Protocol", func() {
+
+	creds := &Credentials{
+		ID:   "dh37fgj492je",
+		Key:  "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Hash: sha256.New,
+	}
+
+	newAuth := func() *Auth {
+		return &Auth{
+			Credentials: *creds,
+			Method:      "GET",
+			RequestURI:  "/resource/1?b=1&a=2",
+			Host:        "example.com",
+			Port:        "8000",
+			Nonce:       "j4h3g2",
+			Ext:         "some-app-ext-data",
+			Timestamp:   time.Unix(1353832234, 0),
+		}
+	}
+
+	It("signs headers", func() {
+		auth := newAuth()
+		Expect(auth.NormalizedString(AuthHeader)).To(Equal(
+			"hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1?b=1&a=2\nexample.com\n8000\n\nsome-app-ext-data\n"))
+		Expect(auth.RequestHeader()).To(Equal(
+			`Hawk id="dh37fgj492je", mac="6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE=", ts="1353832234", nonce="j4h3g2", ext="some-app-ext-data"`))
+	})
+
+	It("signs payloads", func() {
+		auth := newAuth()
+		auth.Method = "POST"
+		h := auth.PayloadHash("text/plain")
+		h.Write([]byte("Thank you for flying Hawk"))
+		auth.SetHash(h)
+		Expect(base64.StdEncoding.EncodeToString(auth.Hash)).To(Equal("Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY="))
+		Expect(auth.RequestHeader()).To(ContainSubstring(`mac="aSe1DERmZuRl3pI36/9BdZmnErTw3sNzOOAUlfeKjVw="`))
+	})
+
+	It("signs with sha1", func() {
+		auth := newAuth()
+		auth.Credentials.Hash = sha1.New
+		auth.RequestHeader()
+		Expect(auth.MAC).To(HaveLen(sha1.Size))
+	})
+
+	It("signs bewits", func() {
+		auth := &Auth{
+			Credentials: Credentials{ID: "123456", Key: "2983d45yun89q", Hash: sha256.New},
+			RequestURI:  "/somewhere/over/the/rainbow",
+			Host:        "example.com",
+			Port:        "443",
+			Ext:         "xandyandz",
+			Timestamp:   time.Unix(1356420707, 0),
+		}
+		Expect(auth.Bewit()).To(Equal(
+			"MTIzNDU2XDEzNTY0MjA3MDdca3NjeHdOUjJ0SnBQMVQxekRMTlBiQjVVaUtJVTl0T1NKWFRVZEc3WDloOD1ceGFuZHlhbmR6"))
+	})
+
+	It("authenticates requests", func() {
+		req, _ := http.NewRequest("GET", "http://example.com:8000/resource/1?b=1&a=2", nil)
+		client := NewRequestAuth(req, creds, 0)
+		client.Ext = "some-app-ext-data"
+		req.Header.Set("Authorization", client.RequestHeader())
+
+		nonces := map[string]bool{}
+		lookup := func(c *Credentials) error {
+			c.Key = creds.Key
+			c.Hash = creds.Hash
+			return nil
+		}
+		check := func(nonce string, t time.Time, c *Credentials) bool {
+			res := !nonces[nonce]
+			nonces[nonce] = true
+			return res
+		}
+
+		auth, err := NewAuthFromRequest(req, lookup, check)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(auth.Valid()).To(Succeed())
+		Expect(auth.Ext).To(Equal("some-app-ext-data"))
+		mac := base64.StdEncoding.EncodeToString(auth.ResponseMAC("response-ext"))
+		Expect(auth.Ext).To(Equal("some-app-ext-data"))
+		Expect(client.ValidResponse(auth.ResponseHeader("response-ext"))).To(Succeed())
+		Expect(client.ValidResponse(`Hawk mac="` + mac + `", ext="response-ext"`)).To(Succeed())
+		Expect(client.Ext).To(Equal("response-ext"))
+		Expect(client.ValidResponse("")).To(Equal(ErrMissingServerAuth))
+
+		_, err = NewAuthFromRequest(req, lookup, check)
+		Expect(err).To(Equal(ErrReplay))
+
+		auth.ActualTimestamp = auth.Timestamp.Add(2 * MaxTimestampSkew)
+		Expect(auth.Valid()).To(Equal(ErrTimestampSkew))
+		auth.ActualTimestamp = auth.Timestamp
+		auth.Credentials.Key = "wrong-key"
+		Expect(auth.Valid()).To(Equal(ErrInvalidMAC))
+	})
+
+	It("authenticates bewits", func() {
+		client, err := NewURLAuth("https://example.com/resource?a=1", creds, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		req, _ := http.NewRequest("GET", "https://example.com/resource?a=1&bewit="+client.Bewit(), nil)
+
+		auth, err := NewAuthFromRequest(req, func(c *Credentials) error {
+			c.Key = creds.Key
+			c.Hash = creds.Hash
+			return nil
+		}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(auth.IsBewit).To(BeTrue())
+		Expect(auth.RequestURI).To(Equal("/resource?a=1"))
+		Expect(auth.Valid()).To(Succeed())
+
+		auth.ActualTimestamp = auth.Timestamp.Add(time.Second)
+		Expect(auth.Valid()).To(Equal(ErrBewitExpired))
+
+		req, _ = http.NewRequest("POST", req.URL.String(), nil)
+		_, err = NewAuthFromRequest(req, nil, nil)
+		Expect(err).To(Equal(ErrInvalidBewitMethod))
+	})
+
+	It("rejects malformed headers", func() {
+		for header, field := range map[string]string{
+			`Basic abc`:                      "scheme",
+			`Hawk id=`:                       "header",
+			`Hawk id="a", ts="1", nonce="n"`: "mac",
+			`Hawk id="a", ts="x", nonce="n", mac="AAAA"`:     "ts",
+			`Hawk id="a", ts="1", nonce="n", mac="invalid!"`: "mac",
+			`Hawk ts="1", nonce="n", mac="AAAA"`:             "id",
+			`Hawk id="a", ts="1", mac="AAAA"`:                "nonce",
+		} {
+			req, _ := http.NewRequest("GET", "http://example.com/", nil)
+			req.Header.Set("Authorization", header)
+			_, err := NewAuthFromRequest(req, nil, nil)
+			Expect(err).To(BeAssignableToTypeOf(AuthFormatError{}), header)
+			Expect(err.(AuthFormatError).Field).To(Equal(field), header)
+		}
+
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		_, err := NewAuthFromRequest(req, nil, nil)
+		Expect(err).To(Equal(ErrNoAuth))
+	})
+
+})
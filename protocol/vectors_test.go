@@ -0,0 +1,257 @@
+package protocol_test
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Interop vectors of the hapi/hawk reference implementation tests, the
+// headers are in its attributes order.
+var _ = Describe("Reference vectors", func() {
+
+	const refKey = "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn"
+
+	lookup := func(key string, h func() hash.Hash) CredentialsLookupFunc {
+		return func(c *Credentials) error {
+			c.Key = key
+			c.Hash = h
+			return nil
+		}
+	}
+
+	attribute := func(header, name string) string {
+		i := strings.Index(header, name+`="`)
+		Expect(i).To(BeNumerically(">=", 0), header)
+		v := header[i+len(name)+2:]
+		return v[:strings.Index(v, `"`)]
+	}
+
+	Context("client headers", func() {
+
+		for _, v := range []struct {
+			name, url, algorithm, contentType, ext, hash, mac string
+		}{
+			{"sha1", "http://example.net/somewhere/over/the/rainbow", "sha1", "", "Bazinga!",
+				"bsvY3IfUllw6V5rvk4tStEvpBhE=", "qbf1ZPG/r/e06F4ht+T77LXi5vw="},
+			{"sha256", "https://example.net/somewhere/over/the/rainbow", "sha256", "text/plain", "Bazinga!",
+				"2QfCt3GuY9HQnHWyWD3wX68ZOKbynqlfYmuO2ZBRqtY=", "q1CwFoSHzPZSkbIvl0oYlD+91rBUEvFk763nMjMndj8="},
+			{"no ext", "https://example.net/somewhere/over/the/rainbow", "sha256", "text/plain", "",
+				"2QfCt3GuY9HQnHWyWD3wX68ZOKbynqlfYmuO2ZBRqtY=", "HTgtd0jPI6E4izx8e4OHdO36q00xFCU0FolNq3RiCYs="},
+		} {
+			v := v
+			It("signs "+v.name, func() {
+				h := sha256.New
+				if v.algorithm == "sha1" {
+					h = sha1.New
+				}
+				req, _ := http.NewRequest("POST", v.url, nil)
+				auth := NewRequestAuth(req, &Credentials{ID: "123456", Key: "2983d45yun89q", Hash: h}, 0)
+				auth.Timestamp = time.Unix(1353809207, 0)
+				auth.Nonce = "Ygvqdz"
+				auth.Ext = v.ext
+				ph := auth.PayloadHash(v.contentType)
+				ph.Write([]byte("something to write about"))
+				auth.SetHash(ph)
+
+				header := auth.RequestHeader()
+				Expect(attribute(header, "hash")).To(Equal(v.hash))
+				Expect(attribute(header, "mac")).To(Equal(v.mac))
+			})
+		}
+
+		It("parses and validates the reference header", func() {
+			req, _ := http.NewRequest("POST", "http://example.net/somewhere/over/the/rainbow", nil)
+			req.Header.Set("Authorization", `Hawk id="123456", ts="1353809207", nonce="Ygvqdz", `+
+				`hash="bsvY3IfUllw6V5rvk4tStEvpBhE=", ext="Bazinga!", mac="qbf1ZPG/r/e06F4ht+T77LXi5vw="`)
+			auth, err := NewAuthFromRequest(req, lookup("2983d45yun89q", sha1.New), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(auth.Port).To(Equal("80"))
+			Expect(auth.Ext).To(Equal("Bazinga!"))
+			auth.ActualTimestamp = auth.Timestamp
+			Expect(auth.Valid()).To(Succeed())
+
+			ph := auth.PayloadHash("")
+			ph.Write([]byte("something to write about"))
+			Expect(auth.ValidHash(ph)).To(BeTrue())
+		})
+
+	})
+
+	Context("server headers", func() {
+
+		for _, v := range []struct {
+			name, url, header string
+			h                 func() hash.Hash
+		}{
+			{"sha1", "http://example.com:8080/resource/4?filter=a",
+				`Hawk id="1", ts="1353788437", nonce="k3j4h2", mac="zy79QQ5/EYFmQqutVnYb73gAc/U=", ext="hello"`, sha1.New},
+			{"sha256", "http://example.com:8000/resource/1?b=1&a=2",
+				`Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="m8r1rHbXN6NgO+KIIhjO7sFRyd78RNGVUwehe8Cp2dU=", ext="some-app-data"`, sha256.New},
+		} {
+			v := v
+			It("authenticates "+v.name, func() {
+				req, _ := http.NewRequest("GET", v.url, nil)
+				req.Header.Set("Authorization", v.header)
+				auth, err := NewAuthFromRequest(req, lookup(refKey, v.h), nil)
+				Expect(err).ToNot(HaveOccurred())
+				auth.ActualTimestamp = auth.Timestamp
+				Expect(auth.Valid()).To(Succeed())
+
+				auth.Port = "80"
+				Expect(auth.Valid()).To(Equal(ErrInvalidMAC))
+			})
+		}
+
+		It("validates the reference response", func() {
+			auth := &Auth{
+				Credentials: Credentials{ID: "123456", Key: refKey, Hash: sha256.New},
+				Method:      "POST",
+				RequestURI:  "/resource/4?filter=a",
+				Host:        "example.com",
+				Port:        "8080",
+				Nonce:       "eb5S_L",
+				Timestamp:   time.Unix(1362336900, 0),
+			}
+			Expect(auth.ValidResponse(`Hawk mac="XIJRsMl/4oL+nn+vKoeVZPdCHXB4yJkNnBbTbHFZUYE=", ` +
+				`hash="f9cDF/TDm7TkYRLnGwRMfeDzT6LixQVLvrIKhh0vgmM=", ext="response-specific"`)).To(Succeed())
+			Expect(auth.Ext).To(Equal("response-specific"))
+			ph := auth.PayloadHash("text/plain")
+			ph.Write([]byte("some reply"))
+			Expect(auth.ValidHash(ph)).To(BeTrue())
+		})
+
+	})
+
+	Context("bewits", func() {
+
+		BeforeEach(func() {
+			Now = func() time.Time {
+				return time.Unix(1356420407, 232000000)
+			}
+		})
+
+		AfterEach(func() {
+			Now = time.Now
+		})
+
+		for _, v := range []struct {
+			name, url, ext, bewit string
+		}{
+			{"default port", "https://example.com/somewhere/over/the/rainbow", "xandyandz",
+				"MTIzNDU2XDEzNTY0MjA3MDdca3NjeHdOUjJ0SnBQMVQxekRMTlBiQjVVaUtJVTl0T1NKWFRVZEc3WDloOD1ceGFuZHlhbmR6"},
+			{"explicit port", "https://example.com:8080/somewhere/over/the/rainbow", "xandyandz",
+				"MTIzNDU2XDEzNTY0MjA3MDdcaFpiSjNQMmNLRW80a3kwQzhqa1pBa1J5Q1p1ZWc0V1NOYnhWN3ZxM3hIVT1ceGFuZHlhbmR6"},
+			{"no ext", "https://example.com/somewhere/over/the/rainbow", "",
+				"MTIzNDU2XDEzNTY0MjA3MDdcSUdZbUxnSXFMckNlOEN4dktQczRKbFdJQStValdKSm91d2dBUmlWaENBZz1c"},
+		} {
+			v := v
+			It("signs with "+v.name, func() {
+				auth, err := NewURLAuth(v.url, &Credentials{ID: "123456", Key: "2983d45yun89q", Hash: sha256.New}, 300*time.Second)
+				Expect(err).ToNot(HaveOccurred())
+				auth.Ext = v.ext
+				Expect(auth.Bewit()).To(Equal(v.bewit))
+			})
+		}
+
+		It("authenticates the reference bewit", func() {
+			req, _ := http.NewRequest("GET", "http://example.com:8080/resource/4?a=1&b=2&bewit="+
+				"MTIzNDU2XDQ1MTE0ODQ2MjFcMzFjMmNkbUJFd1NJRVZDOVkva1NFb2c3d3YrdEVNWjZ3RXNmOGNHU2FXQT1cc29tZS1hcHAtZGF0YQ", nil)
+			auth, err := NewAuthFromRequest(req, lookup(refKey, sha256.New), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(auth.Credentials.ID).To(Equal("123456"))
+			Expect(auth.Ext).To(Equal("some-app-data"))
+			Expect(auth.RequestURI).To(Equal("/resource/4?a=1&b=2"))
+			Expect(auth.Valid()).To(Succeed())
+		})
+
+	})
+
+	Context("escaped paths", func() {
+
+		// the reference implementation signs the raw escaped path
+		serverRequest := func(method, url, header string) *http.Request {
+			raw := method + " " + url + " HTTP/1.1\r\nHost: example.com:80\r\n"
+			if header != "" {
+				raw += "Authorization: " + header + "\r\n"
+			}
+			req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw + "\r\n")))
+			Expect(err).ToNot(HaveOccurred())
+			return req
+		}
+
+		for _, uri := range []string{"/files/a%20b", "/files/a%2Fb", "/files/caf%C3%A9?q=a%20b"} {
+			uri := uri
+			It("authenticates a header for "+uri, func() {
+				req, _ := http.NewRequest("GET", "http://example.com"+uri, nil)
+				auth := NewRequestAuth(req, &Credentials{ID: "123456", Key: refKey, Hash: sha256.New}, 0)
+				Expect(auth.RequestURI).To(Equal(uri))
+
+				parsed, err := NewAuthFromRequest(serverRequest("GET", uri, auth.RequestHeader()), lookup(refKey, sha256.New), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsed.RequestURI).To(Equal(uri))
+				Expect(parsed.Valid()).To(Succeed())
+			})
+
+			It("authenticates a bewit for "+uri, func() {
+				auth, err := NewURLAuth("http://example.com"+uri, &Credentials{ID: "123456", Key: refKey, Hash: sha256.New}, time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				sep := "?"
+				if strings.Contains(uri, "?") {
+					sep = "&"
+				}
+
+				parsed, err := NewAuthFromRequest(serverRequest("GET", uri+sep+"bewit="+auth.Bewit(), ""), lookup(refKey, sha256.New), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsed.RequestURI).To(Equal(uri))
+				Expect(parsed.Valid()).To(Succeed())
+			})
+		}
+
+	})
+
+	Context("normalized strings", func() {
+
+		newAuth := func() *Auth {
+			return &Auth{
+				Method:     "GET",
+				RequestURI: "/resource/something",
+				Host:       "example.com",
+				Port:       "8080",
+				Nonce:      "k3k4j5",
+				Timestamp:  time.Unix(1357747017, 0),
+			}
+		}
+
+		It("includes the ext", func() {
+			auth := newAuth()
+			auth.Ext = "this is some app data"
+			Expect(auth.NormalizedString(AuthHeader)).To(Equal(
+				"hawk.1.header\n1357747017\nk3k4j5\nGET\n/resource/something\nexample.com\n8080\n\nthis is some app data\n"))
+		})
+
+		It("escapes the backslashes and new lines of the ext", func() {
+			auth := newAuth()
+			auth.Ext = "a\\b\nc"
+			Expect(auth.NormalizedString(AuthHeader)).To(HaveSuffix("\n\na\\\\b\\nc\n"))
+		})
+
+		It("includes the app and dlg", func() {
+			auth := newAuth()
+			auth.Credentials.App = "app-1"
+			auth.Credentials.Delegate = "dlg-1"
+			Expect(auth.NormalizedString(AuthHeader)).To(HaveSuffix("\n\n\napp-1\ndlg-1\n"))
+		})
+
+	})
+
+})
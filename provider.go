@@ -0,0 +1,40 @@
+package hawk
+
+import "context"
+
+// CredentialProvider returns credentials by id like a GetCredentialFunc,
+// with the context of the request. Close releases its resources, for
+// providers backed by connection pools or remote services.
+// GetCredentialFunc is the adapter of the functions.
+type CredentialProvider interface {
+	Get(ctx context.Context, id string) (*Credentials, error)
+	Close() error
+}
+
+// Get calls f, ctx is not used.
+func (f GetCredentialFunc) Get(ctx context.Context, id string) (*Credentials, error) {
+	return f(id)
+}
+
+// Close does nothing.
+func (f GetCredentialFunc) Close() error {
+	return nil
+}
+
+// Close closes the CredentialProvider, or the provider set by
+// SetCredentialProvider.
+func (hm *Middleware) Close() error {
+	p := hm.credentialProvider()
+	if p == nil {
+		return nil
+	}
+	return p.Close()
+}
+
+// context returns the context of the request, never nil.
+func (hr *Request) context() context.Context {
+	if hr.ctx == nil {
+		return context.Background()
+	}
+	return hr.ctx
+}
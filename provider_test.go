@@ -0,0 +1,73 @@
+package hawk_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type ctxKey struct{}
+
+// provider is a CredentialProvider recording the contexts.
+type provider struct {
+	store  *hawktest.Store
+	values []interface{}
+	closed bool
+}
+
+func (p *provider) Get(ctx context.Context, id string) (*Credentials, error) {
+	p.values = append(p.values, ctx.Value(ctxKey{}))
+	return p.store.GetCredentials(id)
+}
+
+func (p *provider) Close() error {
+	p.closed = true
+	return nil
+}
+
+var _ = Describe("CredentialProvider", func() {
+
+	It("gets the credentials with the request context", func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		p := &provider{store: store}
+		hm := NewMiddleware(nil, store.SetNonce)
+		hm.CredentialProvider = p
+
+		router := gin.New()
+		router.GET("/", func(c *gin.Context) {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxKey{}, "value"))
+		}, hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		_, err := hawktest.SignRequest(req, "my-id", "my-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(p.values).To(Equal([]interface{}{"value"}))
+
+		Expect(hm.Close()).To(Succeed())
+		Expect(p.closed).To(BeTrue())
+	})
+
+	It("adapts GetCredentialFunc", func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		var p CredentialProvider = GetCredentialFunc(store.GetCredentials)
+		creds, err := p.Get(context.Background(), "my-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.Key).To(Equal("my-key"))
+		Expect(p.Close()).To(Succeed())
+		Expect(NewMiddleware(nil, nil).Close()).To(Succeed())
+	})
+
+})
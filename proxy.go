@@ -0,0 +1,135 @@
+package hawk
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// firstHeader returns the first value of a comma separated header
+// appended by proxies.
+func firstHeader(r *http.Request, name string) string {
+	v := r.Header.Get(name)
+	if i := strings.IndexByte(v, ','); i != -1 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// forwardedOverride returns the host and port the client used according
+// to the "X-Forwarded-Host", "X-Forwarded-Port" and "X-Forwarded-Proto"
+// headers. The port defaults to the one of the forwarded scheme.
+func forwardedOverride(r *http.Request) HostOverride {
+	res := HostOverride{Port: firstHeader(r, "X-Forwarded-Port")}
+	if host := firstHeader(r, "X-Forwarded-Host"); host != "" {
+		if h, p, err := net.SplitHostPort(host); err == nil {
+			res.Host = h
+			if res.Port == "" {
+				res.Port = p
+			}
+		} else {
+			res.Host = host
+		}
+	}
+	if res.Port == "" {
+		switch strings.ToLower(firstHeader(r, "X-Forwarded-Proto")) {
+		case "https":
+			res.Port = "443"
+		case "http":
+			res.Port = "80"
+		}
+	}
+	return res
+}
+
+// merge returns o with its empty values replaced by the ones of other.
+func (o HostOverride) merge(other HostOverride) HostOverride {
+	if o.Host == "" {
+		o.Host = other.Host
+	}
+	if o.Port == "" {
+		o.Port = other.Port
+	}
+	return o
+}
+
+// hostOverride returns the host and port used to verify r: a listener
+// override takes precedence over the Middleware HostOverride and
+// PortOverride, which take precedence over the forwarded headers of the
+// trusted proxies.
+func (hm *Middleware) hostOverride(r *http.Request) (HostOverride, bool) {
+	res, _ := hm.listenerOverride(r)
+	res = res.merge(HostOverride{Host: hm.HostOverride, Port: hm.PortOverride})
+	if hm.fromTrustedProxy(r) {
+		res = res.merge(forwardedOverride(r))
+	}
+	return res, res != HostOverride{}
+}
+
+// clientIP returns the IP of the client of c, the one of the gin engine
+// unless TrustedProxies is set.
+func (hm *Middleware) clientIP(c *gin.Context) string {
+	if len(hm.TrustedProxies) == 0 {
+		return c.ClientIP()
+	}
+	return hm.forwardedFor(c.Request)
+}
+
+// fromTrustedProxy returns true if the "X-Forwarded-Host", "-Port" and
+// "-Proto" headers of r can be used: TrustProxyHeaders is set and r
+// comes from one of the TrustedProxies, any client can send them.
+func (hm *Middleware) fromTrustedProxy(r *http.Request) bool {
+	return hm.TrustProxyHeaders && hm.trustedProxy(remoteIP(r))
+}
+
+// remoteIP returns the IP of the peer of r.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		return strings.TrimSpace(r.RemoteAddr)
+	}
+	return ip
+}
+
+// forwardedFor returns the remote address of r or, if it's a trusted
+// proxy, the rightmost "X-Forwarded-For" address not of a trusted proxy.
+func (hm *Middleware) forwardedFor(r *http.Request) string {
+	ip := remoteIP(r)
+	if !hm.trustedProxy(ip) {
+		return ip
+	}
+	addrs := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(addrs[i])
+		if _, err := netip.ParseAddr(addr); err != nil {
+			break
+		}
+		ip = addr
+		if !hm.trustedProxy(addr) {
+			break
+		}
+	}
+	return ip
+}
+
+// trustedProxy returns true if ip is in the TrustedProxies.
+func (hm *Middleware) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range hm.TrustedProxies {
+		if prefix, err := netip.ParsePrefix(p); err == nil {
+			if prefix.Contains(addr) {
+				return true
+			}
+		} else if a, err := netip.ParseAddr(p); err == nil && a.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
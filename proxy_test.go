@@ -0,0 +1,108 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Proxy headers", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+
+	credentials := &hawk.Credentials{
+		ID:   "valid-id",
+		Key:  "test-cred-key",
+		Hash: sha256.New,
+	}
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(signedURL string, headers map[string]string) int {
+		signed, err := http.NewRequest("GET", signedURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(signed, credentials, 0)
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", auth.RequestHeader())
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode
+	}
+
+	forwarded := map[string]string{
+		"X-Forwarded-Host":  "api.example.com",
+		"X-Forwarded-Proto": "https, http",
+	}
+
+	It("ignores the forwarded headers by default", func() {
+		Expect(request("https://api.example.com/private", forwarded)).To(Equal(401))
+	})
+
+	It("uses the forwarded scheme, host and port when trusted", func() {
+		hm.TrustProxyHeaders = true
+		hm.TrustedProxies = []string{"127.0.0.1"}
+		Expect(request("https://api.example.com/private", forwarded)).To(Equal(200))
+		Expect(request("http://api.example.com/private", map[string]string{
+			"X-Forwarded-Host":  "api.example.com",
+			"X-Forwarded-Proto": "http",
+		})).To(Equal(200))
+		Expect(request("https://api.example.com:8443/private", map[string]string{
+			"X-Forwarded-Host": "api.example.com:8443",
+		})).To(Equal(200))
+		Expect(request("https://api.example.com:9443/private", map[string]string{
+			"X-Forwarded-Host": "api.example.com",
+			"X-Forwarded-Port": "9443",
+		})).To(Equal(200))
+		Expect(request("https://api.example.com/private", nil)).To(Equal(401))
+	})
+
+	It("ignores the forwarded headers of untrusted peers", func() {
+		hm.TrustProxyHeaders = true
+		Expect(request("https://api.example.com/private", forwarded)).To(Equal(401))
+		hm.TrustedProxies = []string{"10.0.0.0/8"}
+		Expect(request("https://api.example.com/private", forwarded)).To(Equal(401))
+	})
+
+	It("uses the explicit host and port overrides first", func() {
+		hm.TrustProxyHeaders = true
+		hm.HostOverride = "api.example.com"
+		hm.PortOverride = "443"
+		Expect(request("https://api.example.com/private", nil)).To(Equal(200))
+		Expect(request("https://api.example.com/private", map[string]string{
+			"X-Forwarded-Host": "evil.example.com:80",
+		})).To(Equal(200))
+	})
+
+})
@@ -0,0 +1,113 @@
+package hawk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// QueueMessage is a signed pseudo-request sent through a message queue,
+// so consumers can authenticate the producer with the same credentials
+// as the http API. The payload hash is always signed and verified.
+// URL is an absolute URL identifying the destination, for example
+// "https://orders.example.com/created".
+type QueueMessage struct {
+	Method        string `json:"method"`
+	URL           string `json:"url"`
+	ContentType   string `json:"content_type,omitempty"`
+	Authorization string `json:"authorization"`
+	Payload       []byte `json:"payload,omitempty"`
+}
+
+// NewQueueMessage signs a pseudo-request with creds.
+func NewQueueMessage(creds *hawk.Credentials, method, url, contentType string, payload []byte) (*QueueMessage, error) {
+	res := &QueueMessage{
+		Method:      method,
+		URL:         url,
+		ContentType: contentType,
+		Payload:     payload,
+	}
+	r, err := res.Request()
+	if err != nil {
+		return nil, err
+	}
+	auth := hawk.NewRequestAuth(r, creds, 0)
+	auth.Hash = PayloadHash(creds.Hash, NormalizeContentType(contentType), payload)
+	res.Authorization = auth.RequestHeader()
+	return res, nil
+}
+
+// UnmarshalQueueMessage decodes a message encoded with Marshal.
+func UnmarshalQueueMessage(data []byte) (*QueueMessage, error) {
+	res := &QueueMessage{}
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Marshal encodes the message in JSON.
+func (m *QueueMessage) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// Request returns the pseudo-request of the message.
+func (m *QueueMessage) Request() (*http.Request, error) {
+	r, err := http.NewRequest(m.Method, m.URL, bytes.NewReader(m.Payload))
+	if err != nil {
+		return nil, err
+	}
+	if m.ContentType != "" {
+		r.Header.Set("Content-Type", m.ContentType)
+	}
+	if m.Authorization != "" {
+		r.Header.Set("Authorization", m.Authorization)
+	}
+	return r, nil
+}
+
+// VerifyQueueMessage verifies a message with the Middleware credentials
+// and nonces. Messages signed more than maxAge ago are rejected with
+// the protocol ErrTimestampSkew, the SetNonceFunc must remember nonces at
+// least that long. The returned Request holds the credentials id, user
+// and scopes.
+func (hm *Middleware) VerifyQueueMessage(ctx context.Context, m *QueueMessage, maxAge time.Duration) (*Request, error) {
+	r, err := m.Request()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Request{Hawk: hm, ctx: ctx}
+	auth, err := hawk.NewAuthFromRequest(r, res.CredentialsLookup, res.NonceCheck)
+	if res.Error != nil {
+		return nil, res.Error
+	} else if err != nil {
+		return nil, err
+	} else if auth.IsBewit {
+		return nil, hawk.ErrNoAuth
+	}
+
+	// the message may have waited in the queue longer than the skew
+	hm.stamp(auth)
+	age := auth.ActualTimestamp.Sub(auth.Timestamp)
+	if age > maxAge {
+		return nil, hawk.ErrTimestampSkew
+	} else if age > 0 {
+		auth.ActualTimestamp = auth.Timestamp
+	}
+
+	if err := res.Validate(r, auth); err != nil {
+		return nil, err
+	} else if len(auth.Hash) == 0 {
+		return nil, ErrMissingPayloadHash
+	} else if !hm.ValidatePayload {
+		if err := res.checkPayload(r, auth); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
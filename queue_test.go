@@ -0,0 +1,131 @@
+package hawk_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QueueMessage", func() {
+
+	var hm *Middleware
+	var nonces map[string]bool
+	storeErr := errors.New("store error")
+
+	credentials := &hawk.Credentials{
+		ID:   "producer",
+		Key:  "test-cred-key",
+		Hash: sha256.New,
+	}
+
+	getCredentials := func(id string) (*Credentials, error) {
+		if id == "error-id" {
+			return nil, storeErr
+		}
+		return &Credentials{
+			Key:    "test-cred-key",
+			User:   "orders service",
+			Scopes: []string{"orders.publish"},
+		}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		if nonces[nonce] {
+			return false, nil
+		}
+		nonces[nonce] = true
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		nonces = map[string]bool{}
+		hm = NewMiddleware(getCredentials, setNonce)
+	})
+
+	message := func() []byte {
+		m, err := NewQueueMessage(credentials, "POST", "https://orders.example.com/created", "application/json", []byte(`{"id":1}`))
+		Expect(err).ToNot(HaveOccurred())
+		data, err := m.Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		return data
+	}
+
+	verify := func(data []byte, maxAge time.Duration) (*Request, error) {
+		m, err := UnmarshalQueueMessage(data)
+		Expect(err).ToNot(HaveOccurred())
+		return hm.VerifyQueueMessage(context.Background(), m, maxAge)
+	}
+
+	It("verifies a message once", func() {
+		data := message()
+		res, err := verify(data, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.ID).To(Equal("producer"))
+		Expect(res.User).To(Equal("orders service"))
+		Expect(res.Scopes).To(Equal([]string{"orders.publish"}))
+
+		_, err = verify(data, time.Hour)
+		Expect(err).To(Equal(hawk.ErrReplay))
+	})
+
+	It("rejects tampered payloads", func() {
+		m, err := UnmarshalQueueMessage(message())
+		Expect(err).ToNot(HaveOccurred())
+		m.Payload = []byte(`{"id":2}`)
+		_, err = hm.VerifyQueueMessage(context.Background(), m, time.Hour)
+		Expect(err).To(Equal(ErrInvalidPayloadHash))
+	})
+
+	It("rejects tampered destinations", func() {
+		m, err := UnmarshalQueueMessage(message())
+		Expect(err).ToNot(HaveOccurred())
+		m.URL = "https://orders.example.com/deleted"
+		_, err = hm.VerifyQueueMessage(context.Background(), m, time.Hour)
+		Expect(err).To(Equal(hawk.ErrInvalidMAC))
+	})
+
+	It("accepts messages older than the skew up to maxAge", func() {
+		m, err := UnmarshalQueueMessage(message())
+		Expect(err).ToNot(HaveOccurred())
+		r, err := m.Request()
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(r, credentials, -10*time.Minute)
+		auth.Hash = PayloadHash(sha256.New, m.ContentType, m.Payload)
+		m.Authorization = auth.RequestHeader()
+
+		_, err = hm.VerifyQueueMessage(context.Background(), m, time.Minute)
+		Expect(err).To(Equal(hawk.ErrTimestampSkew))
+		nonces = map[string]bool{}
+		_, err = hm.VerifyQueueMessage(context.Background(), m, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("requires a payload hash", func() {
+		m, err := UnmarshalQueueMessage(message())
+		Expect(err).ToNot(HaveOccurred())
+		r, err := m.Request()
+		Expect(err).ToNot(HaveOccurred())
+		m.Authorization = hawk.NewRequestAuth(r, credentials, 0).RequestHeader()
+		_, err = hm.VerifyQueueMessage(context.Background(), m, time.Hour)
+		Expect(err).To(Equal(ErrMissingPayloadHash))
+	})
+
+	It("returns internal errors", func() {
+		m, err := NewQueueMessage(&hawk.Credentials{
+			ID:   "error-id",
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, "POST", "https://orders.example.com/created", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = hm.VerifyQueueMessage(context.Background(), m, time.Hour)
+		Expect(err).To(Equal(storeErr))
+	})
+
+})
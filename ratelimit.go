@@ -0,0 +1,133 @@
+package hawk
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is set in context.Err when the RateLimiter rejects
+// a request. The response status is 429.
+var ErrRateLimited = errors.New("Rate limit exceeded")
+
+// RateLimiter limits the number of requests per credentials id.
+// Allow returns false if the request should be rejected. An error
+// is an external problem (like a Redis connection) and it will be set
+// as the context error.
+type RateLimiter interface {
+	Allow(id string) (bool, error)
+}
+
+// rateLimit consults the RateLimiter if set.
+func (hm *Middleware) rateLimit(id string) error {
+	if hm.RateLimiter == nil {
+		return nil
+	}
+	ok, err := hm.RateLimiter.Allow(id)
+	if err != nil {
+		return err
+	} else if !ok {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// DefaultTokenBucketSize is the default TokenBucket MaxKeys.
+const DefaultTokenBucketSize = 100000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last call, up to burst.
+func (b *bucket) refill(now time.Time, rate float64, burst int) {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.last = now
+}
+
+// TokenBucket is an in-memory RateLimiter with a token bucket per
+// credentials id. Each bucket holds up to Burst tokens and is refilled
+// at Rate tokens per second.
+// MaxKeys if set bounds the buckets, the new ids share a single bucket
+// once reached. The refilled buckets are swept every minute.
+type TokenBucket struct {
+	Rate    float64
+	Burst   int
+	MaxKeys int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	overflow  *bucket
+	nextSweep time.Time
+}
+
+// NewTokenBucket creates a new TokenBucket.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		Rate:    rate,
+		Burst:   burst,
+		MaxKeys: DefaultTokenBucketSize,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the id bucket if available.
+func (tb *TokenBucket) Allow(id string) (bool, error) {
+	now := time.Now()
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	b, exists := tb.buckets[id]
+	if !exists {
+		b = tb.newBucket(id, now)
+	}
+	b.refill(now, tb.Rate, tb.Burst)
+
+	if b.tokens < 1 {
+		return false, nil
+	}
+	b.tokens--
+	return true, nil
+}
+
+// newBucket returns a full bucket for id, or the shared overflow bucket
+// if MaxKeys is reached. tb.mu must be held.
+func (tb *TokenBucket) newBucket(id string, now time.Time) *bucket {
+	full := tb.MaxKeys > 0 && len(tb.buckets) >= tb.MaxKeys
+	if full || now.After(tb.nextSweep) {
+		tb.sweep(now)
+	}
+	if tb.MaxKeys > 0 && len(tb.buckets) >= tb.MaxKeys {
+		if tb.overflow == nil {
+			tb.overflow = &bucket{tokens: float64(tb.Burst), last: now}
+		}
+		return tb.overflow
+	}
+	b := &bucket{tokens: float64(tb.Burst), last: now}
+	tb.buckets[id] = b
+	return b
+}
+
+// sweep removes the refilled buckets, the same as new ones. tb.mu must
+// be held.
+func (tb *TokenBucket) sweep(now time.Time) {
+	for id, b := range tb.buckets {
+		b.refill(now, tb.Rate, tb.Burst)
+		if b.tokens >= float64(tb.Burst) {
+			delete(tb.buckets, id)
+		}
+	}
+	tb.nextSweep = now.Add(time.Minute)
+}
+
+// Len returns the number of buckets.
+func (tb *TokenBucket) Len() int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return len(tb.buckets)
+}
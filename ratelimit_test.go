@@ -0,0 +1,108 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimiter", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		hm.RateLimiter = NewTokenBucket(0.001, 2)
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(id, key string) int {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   id,
+			Key:  key,
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode
+	}
+
+	It("limits per credentials id", func() {
+		Expect(request("a", "test-cred-key")).To(Equal(200))
+		Expect(request("a", "test-cred-key")).To(Equal(200))
+		Expect(request("a", "test-cred-key")).To(Equal(429))
+		Expect(request("b", "test-cred-key")).To(Equal(200))
+	})
+
+	It("does not count failures by default", func() {
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "test-cred-key")).To(Equal(200))
+	})
+
+	It("optionally counts failures", func() {
+		hm.RateLimitFailures = true
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "invalid")).To(Equal(401))
+		Expect(request("a", "invalid")).To(Equal(429))
+		Expect(request("a", "test-cred-key")).To(Equal(429))
+	})
+
+	It("refills tokens", func() {
+		tb := NewTokenBucket(1000, 1)
+		Expect(tb.Allow("a")).To(BeTrue())
+		time.Sleep(5 * time.Millisecond)
+		Expect(tb.Allow("a")).To(BeTrue())
+	})
+
+	It("bounds and sweeps the buckets", func() {
+		tb := NewTokenBucket(0.001, 1)
+		tb.MaxKeys = 2
+		Expect(tb.Allow("a")).To(BeTrue())
+		Expect(tb.Allow("b")).To(BeTrue())
+		Expect(tb.Len()).To(Equal(2))
+
+		// the new ids share a bucket
+		Expect(tb.Allow("c")).To(BeTrue())
+		Expect(tb.Allow("d")).To(BeFalse())
+		Expect(tb.Len()).To(Equal(2))
+
+		tb = NewTokenBucket(1000, 1)
+		tb.MaxKeys = 1
+		Expect(tb.Allow("a")).To(BeTrue())
+		time.Sleep(5 * time.Millisecond)
+		Expect(tb.Allow("b")).To(BeTrue())
+		Expect(tb.Allow("b")).To(BeFalse())
+		Expect(tb.Len()).To(Equal(1))
+	})
+
+})
@@ -0,0 +1,262 @@
+// Package replay records anonymized Hawk authentication metadata from live
+// traffic and replays synthetic equivalents against a handler, so that
+// canonicalization regressions are caught before deploying a new version.
+//
+// Records never contain credential keys nor ids: only the parts of the
+// normalized request string that are needed to rebuild an equivalent
+// request signed with synthetic credentials. The letters and digits of
+// the URI and ext are anonymized, escaped or not, their punctuation is
+// kept.
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+)
+
+// ID and Key are the synthetic credentials used to sign replayed requests.
+const (
+	ID  = "replay-id"
+	Key = "replay-synthetic-key"
+)
+
+// Record is the anonymized authentication metadata of a request.
+// Hash is the base64 payload hash signed by the client.
+type Record struct {
+	Method     string `json:"method"`
+	RequestURI string `json:"uri"`
+	Host       string `json:"host"`
+	Port       string `json:"port"`
+	Ext        string `json:"ext,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	Bewit      bool   `json:"bewit,omitempty"`
+	Normalized string `json:"normalized"`
+}
+
+// anonymize replaces the letters of s with "a" or "A" and its digits
+// with "0", the percent escapes are anonymized the same way keeping
+// their "%XX" shape and the punctuation is kept so s is canonicalized
+// the same way.
+func anonymize(s string) string {
+	res := []rune(s)
+	for i := 0; i < len(res); i++ {
+		switch r := res[i]; {
+		case r == '%' && i+2 < len(res):
+			if b, err := strconv.ParseUint(string(res[i+1:i+3]), 16, 8); err == nil {
+				if esc := anonymizeEscaped(byte(b)); esc != "" {
+					res[i+1], res[i+2] = rune(esc[0]), rune(esc[1])
+				}
+			}
+			i += 2
+		case unicode.IsUpper(r):
+			res[i] = 'A'
+		case unicode.IsLetter(r):
+			res[i] = 'a'
+		case unicode.IsDigit(r):
+			res[i] = '0'
+		}
+	}
+	return string(res)
+}
+
+// anonymizeEscaped returns the hex of the escape replacing the escaped
+// byte b, empty to keep the punctuation. The bytes of the non-ASCII
+// characters are replaced with 0x80.
+func anonymizeEscaped(b byte) string {
+	switch {
+	case b >= 'A' && b <= 'Z':
+		return "41"
+	case b >= 'a' && b <= 'z':
+		return "61"
+	case b >= '0' && b <= '9':
+		return "30"
+	case b >= 0x80:
+		return "80"
+	}
+	return ""
+}
+
+// NewRecord creates an anonymized Record from an authenticated request.
+func NewRecord(auth *hawkgo.Auth) Record {
+	t := hawkgo.AuthHeader
+	if auth.IsBewit {
+		t = hawkgo.AuthBewit
+	}
+	cp := *auth
+	cp.RequestURI = anonymize(auth.RequestURI)
+	cp.Ext = anonymize(auth.Ext)
+	rec := Record{
+		Method:     cp.Method,
+		RequestURI: cp.RequestURI,
+		Host:       cp.Host,
+		Port:       cp.Port,
+		Ext:        cp.Ext,
+		Bewit:      cp.IsBewit,
+		Normalized: normalized(&cp, t),
+	}
+	if len(cp.Hash) > 0 {
+		rec.Hash = base64.StdEncoding.EncodeToString(cp.Hash)
+	}
+	return rec
+}
+
+// normalized returns the normalized string with the timestamp and nonce
+// removed, so that records can be compared across replays.
+func normalized(auth *hawkgo.Auth, t hawkgo.AuthType) string {
+	cp := *auth
+	cp.Timestamp = time.Unix(0, 0)
+	cp.Nonce = ""
+	return cp.NormalizedString(t)
+}
+
+// Recorder is a hawk.AuditSink writing a JSON Record per successful
+// authentication to W, the events are passed to Next if set:
+//
+//	hm.AuditSink = replay.NewRecorder(f, hm.AuditSink)
+type Recorder struct {
+	W    io.Writer
+	Next hawk.AuditSink
+	mu   sync.Mutex
+}
+
+// NewRecorder creates a new Recorder writing to w before next.
+func NewRecorder(w io.Writer, next hawk.AuditSink) *Recorder {
+	return &Recorder{W: w, Next: next}
+}
+
+// Audit is a hawk.AuditSink.
+func (r *Recorder) Audit(e hawk.AuditEvent) {
+	if e.Outcome == hawk.AuditSuccess && e.Auth != nil {
+		b, err := json.Marshal(NewRecord(e.Auth))
+		if err == nil {
+			r.mu.Lock()
+			r.W.Write(append(b, '\n'))
+			r.mu.Unlock()
+		}
+	}
+	if r.Next != nil {
+		r.Next.Audit(e)
+	}
+}
+
+// Read decodes the records written by a Recorder.
+func Read(r io.Reader) ([]Record, error) {
+	res := []Record{}
+	dec := json.NewDecoder(r)
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err == io.EOF {
+			return res, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+}
+
+// GetCredentials is a hawk.GetCredentialFunc that returns the synthetic
+// credentials, to be used by the Middleware under test.
+func GetCredentials(id string) (*hawk.Credentials, error) {
+	if id != ID {
+		return nil, nil
+	}
+	return &hawk.Credentials{Key: Key, User: ID}, nil
+}
+
+// SetNonce is a hawk.SetNonceFunc accepting every nonce, to be used by the
+// Middleware under test.
+func SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	return true, nil
+}
+
+// Failure describes a record that did not replay successfully.
+type Failure struct {
+	Record     Record
+	Normalized string
+	StatusCode int
+}
+
+func (f Failure) Error() string {
+	if f.Normalized != f.Record.Normalized {
+		return fmt.Sprintf("replay: %s %s normalized to %q, recorded %q",
+			f.Record.Method, f.Record.RequestURI, f.Normalized, f.Record.Normalized)
+	}
+	return fmt.Sprintf("replay: %s %s returned status %d",
+		f.Record.Method, f.Record.RequestURI, f.StatusCode)
+}
+
+// Request builds the synthetic request equivalent to the record, signed
+// with the synthetic credentials.
+func (rec Record) Request() (*http.Request, *hawkgo.Auth, error) {
+	req, err := http.NewRequest(rec.Method, "http://"+rec.Host+":"+rec.Port+rec.RequestURI, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	creds := &hawkgo.Credentials{
+		ID:   ID,
+		Key:  Key,
+		Hash: sha256.New,
+	}
+	if rec.Bewit {
+		auth := hawkgo.NewRequestAuth(req, creds, time.Minute)
+		auth.Ext = rec.Ext
+		sep := "?"
+		if req.URL.RawQuery != "" {
+			sep = "&"
+		}
+		req, err = http.NewRequest(rec.Method, req.URL.String()+sep+"bewit="+auth.Bewit(), nil)
+		return req, auth, err
+	}
+	auth := hawkgo.NewRequestAuth(req, creds, 0)
+	auth.Ext = rec.Ext
+	if rec.Hash != "" {
+		if auth.Hash, err = base64.StdEncoding.DecodeString(rec.Hash); err != nil {
+			return nil, nil, err
+		}
+	}
+	req.Header.Set("Authorization", auth.RequestHeader())
+	return req, auth, nil
+}
+
+// Replay sends the synthetic equivalent of each record to h and returns
+// the records that normalized differently or were not accepted with a 2xx
+// status. The requests have no body, so h must not validate the payloads
+// (their hash is still covered by the MAC), and their anonymized paths
+// don't match the application routes, so h should authenticate all the
+// paths (a gin NoRoute handler for example).
+func Replay(h http.Handler, records []Record) ([]Failure, error) {
+	failures := []Failure{}
+	for _, rec := range records {
+		req, auth, err := rec.Request()
+		if err != nil {
+			return nil, err
+		}
+		t := hawkgo.AuthHeader
+		if rec.Bewit {
+			t = hawkgo.AuthBewit
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		n := normalized(auth, t)
+		if n != rec.Normalized || w.Code < 200 || w.Code > 299 {
+			failures = append(failures, Failure{
+				Record:     rec,
+				Normalized: n,
+				StatusCode: w.Code,
+			})
+		}
+	}
+	return failures, nil
+}
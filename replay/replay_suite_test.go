@@ -0,0 +1,18 @@
+package replay_test
+
+import (
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Replay Suite")
+}
+
+var _ = BeforeSuite(func() {
+	gin.SetMode(gin.ReleaseMode)
+})
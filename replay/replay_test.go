@@ -0,0 +1,114 @@
+package replay_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyperboloide/hawk"
+	hawkgo "github.com/hyperboloide/hawk/protocol"
+	. "github.com/hyperboloide/hawk/replay"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Replay", func() {
+
+	var buf *bytes.Buffer
+	var router *gin.Engine
+
+	getCredentials := func(id string) (*hawk.Credentials, error) {
+		if id != "prod-id" {
+			return nil, nil
+		}
+		return &hawk.Credentials{Key: "prod-secret-key"}, nil
+	}
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		hm := hawk.NewMiddleware(getCredentials, SetNonce)
+		hm.AuditSink = NewRecorder(buf, nil)
+		router = gin.New()
+		router.Any("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+	})
+
+	sign := func(bewit bool) *http.Request {
+		creds := &hawkgo.Credentials{
+			ID:   "prod-id",
+			Key:  "prod-secret-key",
+			Hash: sha256.New,
+		}
+		req, _ := http.NewRequest("GET", "http://example.com/private?email=fred%40example.com&name=%4A%6f%C3%A9%31", nil)
+		if bewit {
+			auth := hawkgo.NewRequestAuth(req, creds, time.Hour)
+			req, _ = http.NewRequest("GET", req.URL.String()+"&bewit="+auth.Bewit(), nil)
+			return req
+		}
+		req.Method = "POST"
+		req.Header.Set("Content-Type", "text/plain")
+		auth := hawkgo.NewRequestAuth(req, creds, 0)
+		auth.Ext = "tenant=Acme"
+		auth.Hash = hawk.PayloadHash(sha256.New, "text/plain", []byte("body"))
+		req.Body = io.NopCloser(strings.NewReader("body"))
+		req.Header.Set("Authorization", auth.RequestHeader())
+		return req
+	}
+
+	It("records anonymized metadata and replays it", func() {
+		for _, bewit := range []bool{false, true} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, sign(bewit))
+			Expect(w.Code).To(Equal(200))
+		}
+		for _, s := range []string{"prod-secret-key", "prod-id", "fred", "%40example", "%4A", "%6f", "%C3", "%31", "Acme", "tenant"} {
+			Expect(buf.String()).ToNot(ContainSubstring(s))
+		}
+
+		records, err := Read(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].Ext).To(Equal("aaaaaa=Aaaa"))
+		Expect(records[0].Hash).ToNot(BeEmpty())
+		Expect(records[1].Bewit).To(BeTrue())
+		Expect(records[1].RequestURI).To(Equal("/aaaaaaa?aaaaa=aaaa%40aaaaaaa.aaa&aaaa=%41%61%80%80%30"))
+
+		hm := hawk.NewMiddleware(GetCredentials, SetNonce)
+		hm.RequirePayloadHash = true
+		target := gin.New()
+		target.NoRoute(hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		failures, err := Replay(target, records)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(failures).To(BeEmpty())
+	})
+
+	It("reports canonicalization differences", func() {
+		hm := hawk.NewMiddleware(GetCredentials, SetNonce)
+		target := gin.New()
+		target.Any("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		rec := Record{
+			Method:     "GET",
+			RequestURI: "/private",
+			Host:       "example.com",
+			Port:       "80",
+			Normalized: "hawk.1.header\nmismatch\n",
+		}
+		failures, err := Replay(target, []Record{rec})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(failures).To(HaveLen(1))
+		Expect(failures[0].StatusCode).To(Equal(200))
+		Expect(strings.Contains(failures[0].Error(), "normalized")).To(BeTrue())
+	})
+
+})
@@ -0,0 +1,33 @@
+package hawk
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RequestURLFunc returns the host, port and request URI ("/path?query")
+// the client used to sign r. Empty values are left unchanged.
+type RequestURLFunc func(r *http.Request) (host string, port string, uri string)
+
+// requestURL returns a shallow copy of r with the host, port and URI
+// returned by the Middleware RequestURLFunc.
+func (hm *Middleware) requestURL(r *http.Request) *http.Request {
+	host, port, uri := hm.RequestURLFunc(r)
+	res := overrideHost(r, HostOverride{Host: host, Port: port})
+	if uri != "" {
+		u := *res.URL
+		path := uri
+		u.RawQuery = ""
+		if i := strings.IndexByte(uri, '?'); i != -1 {
+			path, u.RawQuery = uri[:i], uri[i+1:]
+		}
+		// the URI is escaped as signed by the client
+		u.RawPath = path
+		if u.Path, _ = url.PathUnescape(path); u.Path == "" {
+			u.Path = path
+		}
+		res.URL = &u
+	}
+	return res
+}
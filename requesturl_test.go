@@ -0,0 +1,85 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RequestURLFunc", func() {
+
+	var ts *httptest.Server
+	var hm *Middleware
+
+	credentials := &hawk.Credentials{
+		ID:   "valid-id",
+		Key:  "test-cred-key",
+		Hash: sha256.New,
+	}
+
+	getCredentials := func(id string) (*Credentials, error) {
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	// the ingress strips the "/api" prefix and sends the original
+	// host in a custom header.
+	ingress := func(r *http.Request) (string, string, string) {
+		return r.Header.Get("X-Original-Host"), "443", "/api" + r.URL.RequestURI()
+	}
+
+	BeforeEach(func() {
+		hm = NewMiddleware(getCredentials, setNonce)
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, c.Query("q"))
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(signedURL, path string) int {
+		signed, err := http.NewRequest("GET", signedURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(signed, credentials, 0)
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", auth.RequestHeader())
+		req.Header.Set("X-Original-Host", "public.example.com")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode
+	}
+
+	It("verifies the URL returned by the function", func() {
+		Expect(request("https://public.example.com/api/private?q=1", "/private?q=1")).To(Equal(401))
+		hm.RequestURLFunc = ingress
+		Expect(request("https://public.example.com/api/private?q=1", "/private?q=1")).To(Equal(200))
+		Expect(request("https://public.example.com/api/private?q=2", "/private?q=1")).To(Equal(401))
+		Expect(request("https://public.example.com/private?q=1", "/private?q=1")).To(Equal(401))
+	})
+
+	It("keeps the request values when empty", func() {
+		hm.RequestURLFunc = func(r *http.Request) (string, string, string) {
+			return "", "", ""
+		}
+		port := strings.Split(ts.Listener.Addr().String(), ":")[1]
+		Expect(request("http://127.0.0.1:"+port+"/private", "/private")).To(Equal(200))
+	})
+
+})
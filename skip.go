@@ -0,0 +1,46 @@
+package hawk
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SkipFunc is a function that returns true if the request should not be
+// authenticated by the Filter.
+type SkipFunc func(*gin.Context) bool
+
+// SkipPaths returns a SkipFunc that skips requests with a matching URL path.
+func SkipPaths(paths ...string) SkipFunc {
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[p] = true
+	}
+	return func(c *gin.Context) bool {
+		return set[c.Request.URL.Path]
+	}
+}
+
+// SkipMethods returns a SkipFunc that skips requests with a matching
+// HTTP method (case insensitive), "OPTIONS" for CORS preflights for example.
+func SkipMethods(methods ...string) SkipFunc {
+	set := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		set[strings.ToUpper(m)] = true
+	}
+	return func(c *gin.Context) bool {
+		return set[c.Request.Method]
+	}
+}
+
+// SkipAny returns a SkipFunc that skips the request if any of fns does.
+func SkipAny(fns ...SkipFunc) SkipFunc {
+	return func(c *gin.Context) bool {
+		for _, fn := range fns {
+			if fn(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,69 @@
+package hawk
+
+import (
+	"errors"
+	"net/http"
+)
+
+// StoreError is a GetCredentialFunc or SetNonceFunc error, the store
+// is considered unavailable.
+type StoreError struct {
+	Err error
+}
+
+func (e *StoreError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the store error.
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+// IsStoreError returns true if err is a StoreError.
+func IsStoreError(err error) bool {
+	var res *StoreError
+	return errors.As(err, &res)
+}
+
+// StatusMapper returns the http status of the errors rendered without an
+// AbortHandler.
+type StatusMapper func(err error) int
+
+// DefaultStatusMapper returns 401 for the authentication errors, 403 for
+// the banned requests, 429 for the other limited requests, 503 for the store errors and maintenances and
+// 500 for the other errors.
+func DefaultStatusMapper(err error) int {
+	switch {
+	case errors.Is(err, ErrSlowBody):
+		return http.StatusRequestTimeout
+	case errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrBanned):
+		return http.StatusForbidden
+	case ISHawkError(err):
+		return http.StatusUnauthorized
+	case Classify(err).Kind == KindLimited:
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrMaintenance), IsStoreError(err):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// err returns the Error, as a StoreError if returned by the stores.
+func (hr *Request) err() error {
+	if hr.storeFailed {
+		return &StoreError{Err: hr.Error}
+	}
+	return hr.Error
+}
+
+// ErrorStatus returns the http status of err with the StatusMapper,
+// for the adapters rendering their own errors.
+func (hm *Middleware) ErrorStatus(err error) int {
+	if hm.StatusMapper != nil {
+		return hm.StatusMapper(err)
+	}
+	return DefaultStatusMapper(err)
+}
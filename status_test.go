@@ -0,0 +1,70 @@
+package hawk_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StatusMapper", func() {
+
+	var hm *Middleware
+	var router *gin.Engine
+	var ctxErr error
+	redisErr := errors.New("redis is down")
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = NewMiddleware(store.GetCredentials, func(id, nonce string, t time.Time) (bool, error) {
+			return false, redisErr
+		})
+		router = gin.New()
+		router.GET("/", func(c *gin.Context) {
+			c.Next()
+			ctxErr = c.Errors.Last()
+		}, hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+	})
+
+	serve := func(id, key string) int {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		_, err := hawktest.SignRequest(req, id, key)
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	It("maps store errors to 503 by default", func() {
+		Expect(serve("my-id", "my-key")).To(Equal(http.StatusServiceUnavailable))
+		Expect(IsStoreError(ctxErr)).To(BeTrue())
+		Expect(errors.Is(ctxErr, redisErr)).To(BeTrue())
+
+		Expect(DefaultStatusMapper(hawk.ErrInvalidMAC)).To(Equal(http.StatusUnauthorized))
+		Expect(DefaultStatusMapper(&StoreError{Err: redisErr})).To(Equal(http.StatusServiceUnavailable))
+		Expect(DefaultStatusMapper(redisErr)).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("uses the StatusMapper", func() {
+		hm.StatusMapper = func(err error) int {
+			if IsStoreError(err) {
+				return http.StatusBadGateway
+			}
+			return DefaultStatusMapper(err)
+		}
+		Expect(serve("my-id", "my-key")).To(Equal(http.StatusBadGateway))
+		Expect(serve("unknown-id", "my-key")).To(Equal(http.StatusUnauthorized))
+	})
+
+})
@@ -0,0 +1,69 @@
+package hawk_test
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Streaming", func() {
+
+	var ts *httptest.Server
+	var release chan struct{}
+
+	BeforeEach(func() {
+		release = make(chan struct{})
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{Key: "test-cred-key"}, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		router := gin.New()
+		router.GET("/events", hm.Filter, func(c *gin.Context) {
+			c.SSEvent("message", "first")
+			c.Writer.Flush()
+			<-release
+			c.SSEvent("message", "last")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+		ts.Close()
+	})
+
+	It("sends Server-Authorization with the first flushed event", func() {
+		req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "my-id",
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		// the handler is still streaming
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(auth.ValidResponse(resp.Header.Get("Server-Authorization"))).To(Succeed())
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		Expect(err).ToNot(HaveOccurred())
+		Expect(line).To(Equal("event:message\n"))
+	})
+
+})
@@ -0,0 +1,70 @@
+package hawk
+
+import (
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// StrictMinKeyLength is the minimum length of the credentials keys in
+// StrictMode, the length of the keys generated by NewCredential.
+const StrictMinKeyLength = 24
+
+// minKeyLength returns the minimum length of the credentials keys.
+func (hm *Middleware) minKeyLength() int {
+	if hm.StrictMode && hm.MinKeyLength < StrictMinKeyLength {
+		return StrictMinKeyLength
+	}
+	return hm.MinKeyLength
+}
+
+// contextAuth returns the snapshot of auth exposed to the handlers,
+// without the credentials key in StrictMode.
+func (hm *Middleware) contextAuth(auth *hawk.Auth) *hawk.Auth {
+	res := snapshotAuth(auth)
+	if hm.StrictMode {
+		res.Credentials.Key = ""
+	}
+	return res
+}
+
+// keyLookupKey is the context key of the keyLookup of the requests
+// verified in StrictMode.
+const keyLookupKey = "hawk_key_lookup"
+
+// keyLookup looks up the credentials key again when an ext helper needs
+// it in StrictMode, the key is not kept in the context.
+type keyLookup struct {
+	hm     *Middleware
+	tenant string
+}
+
+// keyFromContext returns the credentials key of auth, looked up again
+// if it was removed in StrictMode. It's the rotated key verifying the
+// MAC of auth, the error is ErrInvalidEncryptedExt if none does.
+func keyFromContext(c *gin.Context, auth *hawk.Auth) (string, error) {
+	if auth.Credentials.Key != "" {
+		return auth.Credentials.Key, nil
+	}
+	v, _ := c.Get(keyLookupKey)
+	lookup, ok := v.(keyLookup)
+	if !ok {
+		return "", ErrInvalidEncryptedExt
+	}
+	hr := &Request{Hawk: lookup.hm, Tenant: lookup.tenant, ctx: c.Request.Context()}
+	creds, err := hr.getCredentials(auth.Credentials.ID)
+	if err != nil {
+		return "", err
+	} else if creds == nil {
+		return "", ErrInvalidEncryptedExt
+	}
+	// only the MAC is checked, the request was verified by the Filter
+	check := *auth
+	check.ActualTimestamp = check.Timestamp
+	for _, key := range append([]string{creds.Key}, creds.Keys...) {
+		check.Credentials.Key = key
+		if check.Valid() == nil {
+			return key, nil
+		}
+	}
+	return "", ErrInvalidEncryptedExt
+}
@@ -0,0 +1,93 @@
+package hawk_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StrictMode", func() {
+
+	const key = "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn"
+
+	var store *hawktest.Store
+	var hm *Middleware
+	var router *gin.Engine
+	var lastErr error
+	var authKey, contextKeys string
+
+	BeforeEach(func() {
+		lastErr, authKey, contextKeys = nil, "unset", ""
+		store = hawktest.NewStore()
+		hm = store.Middleware()
+		hm.StrictMode = true
+		hm.OnAuthFailure = func(c *gin.Context, id string, err error) {
+			lastErr = err
+		}
+		router = gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			authKey = GetAuth(c).Credentials.Key
+			contextKeys = fmt.Sprintf("%+v", c.Keys)
+			c.String(200, "ok")
+		})
+	})
+
+	request := func(id, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://example.com/private", nil)
+		auth, err := hawktest.SignRequest(req, id, key)
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			Expect(auth.ValidResponse(w.Header().Get("Server-Authorization"))).To(Succeed())
+		}
+		return w
+	}
+
+	It("enforces the minimum key length", func() {
+		store.Add("short-id", "0123456789abcdef")
+		Expect(request("short-id", "0123456789abcdef").Code).To(Equal(http.StatusInternalServerError))
+		Expect(lastErr).To(Equal(ErrInvalidKey))
+
+		hm.StrictMode = false
+		Expect(request("short-id", "0123456789abcdef").Code).To(Equal(http.StatusOK))
+	})
+
+	It("removes the key from the context auth", func() {
+		store.Add("my-id", key)
+		Expect(request("my-id", key).Code).To(Equal(http.StatusOK))
+		Expect(authKey).To(BeEmpty())
+		Expect(contextKeys).ToNot(ContainSubstring(key))
+
+		hm.StrictMode = false
+		Expect(request("my-id", key).Code).To(Equal(http.StatusOK))
+		Expect(authKey).To(Equal(key))
+	})
+
+	It("checks all the rotated keys", func() {
+		store.SetCredentials("my-id", &Credentials{
+			Key:  "new-" + key,
+			Keys: []string{"older-" + key, key},
+		})
+		Expect(request("my-id", key).Code).To(Equal(http.StatusOK))
+		Expect(request("my-id", "older-"+key).Code).To(Equal(http.StatusOK))
+		Expect(request("my-id", "new-"+key).Code).To(Equal(http.StatusOK))
+		Expect(request("my-id", "unknown-"+key).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("is enabled by PresetStrict", func() {
+		hm.StrictMode = false
+		hm.Apply(PresetStrict())
+		Expect(hm.StrictMode).To(BeTrue())
+		hm.Apply(PresetCompat())
+		Expect(hm.StrictMode).To(BeFalse())
+	})
+
+})
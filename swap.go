@@ -0,0 +1,76 @@
+package hawk
+
+import "sync/atomic"
+
+// swapped are the providers set at runtime, shared by the copies of
+// the Middleware (see With).
+type swapped struct {
+	credentials atomic.Pointer[CredentialProvider]
+	nonces      atomic.Pointer[NonceStore]
+}
+
+func (hm *Middleware) swapped() *swapped {
+	if hm.swap == nil {
+		hm.swap = &swapped{}
+	}
+	return hm.swap
+}
+
+// SetCredentialProvider replaces the CredentialProvider atomically, for
+// example to reconnect to a new database without a restart. It returns
+// the previous provider set, to close once the requests in flight are
+// done. A nil p restores the CredentialProvider and GetCredentials fields.
+// The Middleware must be created with NewMiddleware to be safe for
+// concurrent use.
+func (hm *Middleware) SetCredentialProvider(p CredentialProvider) CredentialProvider {
+	var prev *CredentialProvider
+	if p == nil {
+		prev = hm.swapped().credentials.Swap(nil)
+	} else {
+		prev = hm.swapped().credentials.Swap(&p)
+	}
+	if prev == nil {
+		return nil
+	}
+	return *prev
+}
+
+// SetNonceStore replaces the SetNonce function atomically like
+// SetCredentialProvider. It returns the previous store set, a nil s
+// restores SetNonce.
+func (hm *Middleware) SetNonceStore(s NonceStore) NonceStore {
+	var prev *NonceStore
+	if s == nil {
+		prev = hm.swapped().nonces.Swap(nil)
+	} else {
+		prev = hm.swapped().nonces.Swap(&s)
+	}
+	if prev == nil {
+		return nil
+	}
+	return *prev
+}
+
+// credentialProvider returns the provider set by SetCredentialProvider,
+// or the CredentialProvider.
+func (hm *Middleware) credentialProvider() CredentialProvider {
+	if hm.swap != nil {
+		if p := hm.swap.credentials.Load(); p != nil {
+			return *p
+		}
+	}
+	return hm.CredentialProvider
+}
+
+// nonceStore returns the store set by SetNonceStore, or SetNonce.
+func (hm *Middleware) nonceStore() NonceStore {
+	if hm.swap != nil {
+		if s := hm.swap.nonces.Load(); s != nil {
+			return *s
+		}
+	}
+	if hm.SetNonce == nil {
+		return nil
+	}
+	return hm.SetNonce
+}
@@ -0,0 +1,104 @@
+package hawk_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SetCredentialProvider", func() {
+
+	var old, current *hawktest.Store
+	var hm *Middleware
+	var router *gin.Engine
+
+	request := func(id, key string) int {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		_, err := hawktest.SignRequest(req, id, key)
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	BeforeEach(func() {
+		old = hawktest.NewStore()
+		old.Add("old-id", "old-key")
+		current = hawktest.NewStore()
+		current.Add("new-id", "new-key")
+		hm = NewMiddleware(old.GetCredentials, old.SetNonce)
+		router = gin.New()
+		router.GET("/", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+	})
+
+	It("swaps the credential provider", func() {
+		Expect(request("old-id", "old-key")).To(Equal(http.StatusOK))
+
+		p := &provider{store: current}
+		Expect(hm.SetCredentialProvider(p)).To(BeNil())
+		Expect(request("old-id", "old-key")).To(Equal(http.StatusUnauthorized))
+		Expect(request("new-id", "new-key")).To(Equal(http.StatusOK))
+
+		Expect(hm.Close()).To(Succeed())
+		Expect(p.closed).To(BeTrue())
+
+		Expect(hm.SetCredentialProvider(nil)).To(Equal(p))
+		Expect(request("old-id", "old-key")).To(Equal(http.StatusOK))
+	})
+
+	It("swaps the nonce store", func() {
+		var calls int
+		Expect(hm.SetNonceStore(SetNonceFunc(func(id, nonce string, t time.Time) (bool, error) {
+			calls++
+			return true, nil
+		}))).To(BeNil())
+		Expect(request("old-id", "old-key")).To(Equal(http.StatusOK))
+		Expect(calls).To(Equal(1))
+
+		Expect(hm.SetNonceStore(nil)).ToNot(BeNil())
+		Expect(request("old-id", "old-key")).To(Equal(http.StatusOK))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("applies to the copies of the Middleware", func() {
+		hm.SetCredentialProvider(GetCredentialFunc(current.GetCredentials))
+		_, err := hm.With(Skew(time.Minute)).Verify(signed("new-id", "new-key"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("is safe for concurrent use", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				hm.SetCredentialProvider(GetCredentialFunc(current.GetCredentials))
+				hm.SetNonceStore(SetNonceFunc(current.SetNonce))
+			}()
+			go func() {
+				defer wg.Done()
+				hm.Verify(signed("new-id", "new-key").WithContext(context.Background()))
+			}()
+		}
+		wg.Wait()
+	})
+
+})
+
+func signed(id, key string) *http.Request {
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	_, err := hawktest.SignRequest(req, id, key)
+	Expect(err).ToNot(HaveOccurred())
+	return req
+}
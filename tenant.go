@@ -0,0 +1,99 @@
+package hawk
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantKey is the context key of the tenant resolved by the
+// Middleware TenantResolver.
+const TenantKey = "hawk_tenant"
+
+// ErrUnknownTenant is set in context.Err if the TenantResolver returns
+// an empty tenant.
+var ErrUnknownTenant = errors.New("Unknown tenant")
+
+// ErrNoTenantCredentials is set in context.Err if the Middleware has a
+// TenantResolver but no GetTenantCredentials or a CredentialProvider
+// that is not a TenantCredentialProvider, the credentials could be
+// shared by the tenants. It's a configuration error.
+var ErrNoTenantCredentials = errors.New("No tenant credentials lookup configured")
+
+// TenantResolver returns the tenant of a request, from the host or a
+// path parameter for example, or an empty string if it's unknown.
+type TenantResolver func(c *gin.Context) string
+
+// GetTenantCredentialFunc is like GetCredentialFunc for credentials ids
+// only unique within a tenant, so one gateway can serve isolated tenants.
+// The nonces, Lockout, RateLimiter, FailureMetrics and BanPolicy keys
+// are the ids prefixed by the escaped tenant ("tenant/id").
+type GetTenantCredentialFunc func(tenant, id string) (*Credentials, error)
+
+// TenantCredentialProvider is a CredentialProvider of credentials ids
+// only unique within a tenant, GetTenant is called instead of Get with
+// the tenant of the TenantResolver.
+type TenantCredentialProvider interface {
+	CredentialProvider
+	GetTenant(ctx context.Context, tenant, id string) (*Credentials, error)
+}
+
+// TenantFromContext returns the tenant of the request, empty if the
+// Middleware has no TenantResolver.
+func TenantFromContext(c *gin.Context) string {
+	return c.GetString(TenantKey)
+}
+
+// resolveTenant sets the tenant of hr and in the context.
+func (hm *Middleware) resolveTenant(c *gin.Context, hr *Request) error {
+	if hm.TenantResolver == nil {
+		return nil
+	}
+	if hr.Tenant = hm.TenantResolver(c); hr.Tenant == "" {
+		return ErrUnknownTenant
+	}
+	c.Set(TenantKey, hr.Tenant)
+	return nil
+}
+
+// tenantID returns id scoped by the tenant of hr, the key of the nonces,
+// Lockout, RateLimiter, FailureMetrics and BanPolicy so that tenants
+// sharing an id are isolated. It's id without tenant.
+func (hr *Request) tenantID(id string) string {
+	if hr.Tenant == "" {
+		return id
+	}
+	return url.PathEscape(hr.Tenant) + "/" + id
+}
+
+// checkTenant returns ErrNoTenantCredentials if hm has a TenantResolver
+// without a credentials lookup by tenant.
+func (hm *Middleware) checkTenant() error {
+	if hm.TenantResolver == nil {
+		return nil
+	}
+	if p := hm.credentialProvider(); p != nil {
+		if _, ok := p.(TenantCredentialProvider); !ok {
+			return ErrNoTenantCredentials
+		}
+	} else if hm.GetTenantCredentials == nil {
+		return ErrNoTenantCredentials
+	}
+	return nil
+}
+
+// getCredentials returns the credentials of id in the tenant of hr, the
+// credentialProvider takes precedence over the lookup functions.
+func (hr *Request) getCredentials(id string) (*Credentials, error) {
+	p := hr.Hawk.credentialProvider()
+	if tp, ok := p.(TenantCredentialProvider); ok {
+		return tp.GetTenant(hr.context(), hr.Tenant, id)
+	} else if p != nil {
+		return p.Get(hr.context(), id)
+	} else if hr.Hawk.GetTenantCredentials != nil {
+		return hr.Hawk.GetTenantCredentials(hr.Tenant, id)
+	}
+	return hr.Hawk.GetCredentials(id)
+}
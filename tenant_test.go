@@ -0,0 +1,165 @@
+package hawk_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// tenantProvider is a TenantCredentialProvider of a store by tenant.
+type tenantProvider map[string]*hawktest.Store
+
+func (p tenantProvider) Get(ctx context.Context, id string) (*Credentials, error) {
+	return nil, nil
+}
+
+func (p tenantProvider) GetTenant(ctx context.Context, tenant, id string) (*Credentials, error) {
+	return p[tenant].GetCredentials(id)
+}
+
+func (p tenantProvider) Close() error {
+	return nil
+}
+
+var _ = Describe("Tenants", func() {
+
+	var router *gin.Engine
+	var hm *Middleware
+	var stores map[string]*hawktest.Store
+	var tenant string
+	var lastErr error
+	var nonceIDs []string
+
+	BeforeEach(func() {
+		tenant, lastErr, nonceIDs = "", nil, nil
+		stores = map[string]*hawktest.Store{
+			"acme":   hawktest.NewStore(),
+			"globex": hawktest.NewStore(),
+		}
+		stores["acme"].Add("shared-id", "acme-key")
+		stores["globex"].Add("shared-id", "globex-key")
+
+		hm = NewMiddleware(nil, func(id string, nonce string, t time.Time) (bool, error) {
+			nonceIDs = append(nonceIDs, id)
+			return true, nil
+		})
+		hm.TenantResolver = func(c *gin.Context) string {
+			host := strings.Split(c.Request.Host, ".")
+			if _, ok := stores[host[0]]; !ok {
+				return ""
+			}
+			return host[0]
+		}
+		hm.GetTenantCredentials = func(tenant, id string) (*Credentials, error) {
+			return stores[tenant].GetCredentials(id)
+		}
+		hm.OnAuthFailure = func(c *gin.Context, id string, err error) {
+			lastErr = err
+		}
+		router = gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			tenant = TenantFromContext(c)
+			c.String(200, "ok")
+		})
+		router.POST("/share", hm.Filter, hm.BewitHandler(time.Hour))
+	})
+
+	request := func(host, key string) int {
+		req := httptest.NewRequest("GET", "http://"+host+"/private", nil)
+		_, err := hawktest.SignRequest(req, "shared-id", key)
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	It("isolates the credentials of tenants", func() {
+		Expect(request("acme.example.com", "acme-key")).To(Equal(http.StatusOK))
+		Expect(tenant).To(Equal("acme"))
+		Expect(request("globex.example.com", "globex-key")).To(Equal(http.StatusOK))
+		Expect(tenant).To(Equal("globex"))
+
+		Expect(request("globex.example.com", "acme-key")).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("requires a credentials lookup by tenant", func() {
+		hm.GetTenantCredentials = nil
+		hm.GetCredentials = stores["acme"].GetCredentials
+		Expect(request("acme.example.com", "acme-key")).To(Equal(http.StatusInternalServerError))
+		Expect(func() { hm.With() }).To(PanicWith(ErrNoTenantCredentials))
+
+		hm.GetTenantCredentials = func(tenant, id string) (*Credentials, error) {
+			return stores[tenant].GetCredentials(id)
+		}
+		hm.SetCredentialProvider(GetCredentialFunc(stores["acme"].GetCredentials))
+		Expect(request("acme.example.com", "acme-key")).To(Equal(http.StatusInternalServerError))
+		Expect(func() { hm.With() }).To(PanicWith(ErrNoTenantCredentials))
+	})
+
+	It("looks up the credentials of the TenantCredentialProvider", func() {
+		hm.GetTenantCredentials = nil
+		hm.SetCredentialProvider(tenantProvider(stores))
+		Expect(hm.With()).ToNot(BeNil())
+		Expect(request("acme.example.com", "acme-key")).To(Equal(http.StatusOK))
+		Expect(request("globex.example.com", "globex-key")).To(Equal(http.StatusOK))
+		Expect(request("globex.example.com", "acme-key")).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects unknown tenants", func() {
+		Expect(request("initech.example.com", "acme-key")).To(Equal(http.StatusUnauthorized))
+		Expect(lastErr).To(Equal(ErrUnknownTenant))
+		Expect(ErrorCode(ErrUnknownTenant)).To(Equal("unknown_tenant"))
+	})
+
+	It("keys the nonces and the Lockout by tenant", func() {
+		Expect(request("acme.example.com", "acme-key")).To(Equal(http.StatusOK))
+		Expect(request("globex.example.com", "globex-key")).To(Equal(http.StatusOK))
+		Expect(nonceIDs).To(Equal([]string{"acme/shared-id", "globex/shared-id"}))
+
+		hm.Lockout = NewLockout(1, time.Minute)
+		Expect(request("acme.example.com", "wrong-key")).To(Equal(http.StatusUnauthorized))
+		Expect(request("acme.example.com", "acme-key")).To(Equal(http.StatusTooManyRequests))
+		Expect(request("globex.example.com", "globex-key")).To(Equal(http.StatusOK))
+	})
+
+	It("mints bewits with the credentials of the tenant", func() {
+		req := httptest.NewRequest("POST", "http://globex.example.com/share", strings.NewReader(`{"url":"/private"}`))
+		req.Header.Set("Content-Type", "application/json")
+		_, err := hawktest.SignRequest(req, "shared-id", "globex-key")
+		Expect(err).ToNot(HaveOccurred())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var res map[string]string
+		Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", res["url"], nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(tenant).To(Equal("globex"))
+	})
+
+	It("signs and verifies the messages of a tenant", func() {
+		_, err := hm.SignMessage("shared-id", "example.com", "443", []byte("hello"))
+		Expect(err).To(Equal(ErrUnknownTenant))
+
+		m, err := hm.SignTenantMessage("acme", "shared-id", "example.com", "443", []byte("hello"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = hm.VerifyTenantMessage("globex", "example.com", "443", []byte("hello"), m)
+		Expect(err).To(HaveOccurred())
+		hr, err := hm.VerifyTenantMessage("acme", "example.com", "443", []byte("hello"), m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hr.Tenant).To(Equal("acme"))
+	})
+
+})
@@ -0,0 +1,49 @@
+package hawk
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/hyperboloide/hawk"
+
+// tracer returns the Middleware tracer, from the global provider if
+// TracerProvider is not set.
+func (hm *Middleware) tracer() trace.Tracer {
+	if hm.TracerProvider != nil {
+		return hm.TracerProvider.Tracer(tracerName)
+	}
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// startSpan starts a child span of the request context. The attributes
+// are only set on recording spans to save allocations when not tracing.
+func (hr *Request) startSpan(name, id string) (context.Context, trace.Span) {
+	ctx := hr.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := hr.Hawk.tracer().Start(ctx, name)
+	if span.IsRecording() {
+		span.SetAttributes(attribute.String("hawk.credential_id", id))
+	}
+	return ctx, span
+}
+
+// endSpan records err if any and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		class := "internal"
+		if ISHawkError(err) {
+			class = "auth"
+		}
+		span.SetAttributes(attribute.String("hawk.error_class", class))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
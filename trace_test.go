@@ -0,0 +1,87 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+
+	var ts *httptest.Server
+	var recorder *tracetest.SpanRecorder
+
+	getCredentials := func(id string) (*Credentials, error) {
+		if id != "valid-id" {
+			return nil, nil
+		}
+		return &Credentials{Key: "test-cred-key"}, nil
+	}
+
+	setNonce := func(id string, nonce string, t time.Time) (bool, error) {
+		return true, nil
+	}
+
+	BeforeEach(func() {
+		recorder = tracetest.NewSpanRecorder()
+		hm := NewMiddleware(getCredentials, setNonce)
+		hm.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		router := gin.New()
+		router.GET("/private", hm.Filter, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	request := func(id string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+"/private", nil)
+		Expect(err).ToNot(HaveOccurred())
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   id,
+			Key:  "test-cred-key",
+			Hash: sha256.New,
+		}, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	It("traces each phase", func() {
+		Expect(request("valid-id").StatusCode).To(Equal(200))
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(3))
+		names := []string{}
+		for _, s := range spans {
+			names = append(names, s.Name())
+			Expect(s.Attributes()).To(ContainElement(attribute.String("hawk.credential_id", "valid-id")))
+			Expect(s.Status().Code).ToNot(Equal(codes.Error))
+		}
+		Expect(names).To(Equal([]string{"hawk.CredentialsLookup", "hawk.NonceCheck", "hawk.Validate"}))
+	})
+
+	It("records the error class", func() {
+		Expect(request("invalid-id").StatusCode).To(Equal(401))
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		Expect(spans[0].Attributes()).To(ContainElement(attribute.String("hawk.error_class", "auth")))
+	})
+
+})
@@ -0,0 +1,46 @@
+package hawk
+
+import "encoding/json"
+
+// UserDecoder turns the user stored with credentials, encoded in JSON,
+// into the application user type. It's used by the credential stores so
+// the Credentials User arrives typed instead of as a map[string]interface{}.
+type UserDecoder func(data []byte) (interface{}, error)
+
+// JSONUser returns a UserDecoder decoding the user into a *T:
+//
+//	store.DecodeUser = hawk.JSONUser[User]()
+func JSONUser[T any]() UserDecoder {
+	return func(data []byte) (interface{}, error) {
+		res := new(T)
+		if err := json.Unmarshal(data, res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+}
+
+// Decode decodes data with d, or into an interface{} if d is nil.
+func (d UserDecoder) Decode(data []byte) (interface{}, error) {
+	if d != nil {
+		return d(data)
+	}
+	var res interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DecodeValue decodes a user already parsed from JSON or YAML, it's
+// returned as is if d is nil.
+func (d UserDecoder) DecodeValue(v interface{}) (interface{}, error) {
+	if d == nil || v == nil {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return d(data)
+}
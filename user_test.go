@@ -0,0 +1,34 @@
+package hawk_test
+
+import (
+	. "github.com/hyperboloide/hawk"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type account struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+}
+
+var _ = Describe("UserDecoder", func() {
+
+	It("decodes typed users", func() {
+		d := JSONUser[account]()
+		Expect(d.Decode([]byte(`{"name":"fred","admin":true}`))).To(Equal(&account{"fred", true}))
+		Expect(d.DecodeValue(map[string]interface{}{"name": "fred"})).To(Equal(&account{Name: "fred"}))
+		Expect(d.DecodeValue(nil)).To(BeNil())
+		_, err := d.Decode([]byte(`{"name":42}`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("decodes untyped users without a decoder", func() {
+		var d UserDecoder
+		Expect(d.Decode([]byte(`{"name":"fred"}`))).To(Equal(map[string]interface{}{"name": "fred"}))
+		Expect(d.DecodeValue("fred")).To(Equal("fred"))
+		_, err := d.Decode([]byte(`{`))
+		Expect(err).To(HaveOccurred())
+	})
+
+})
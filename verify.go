@@ -0,0 +1,111 @@
+package hawk
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	hawk "github.com/hyperboloide/hawk/protocol"
+)
+
+// CredentialGetter returns credentials by id, like a GetCredentialFunc.
+type CredentialGetter interface {
+	GetCredentials(id string) (*Credentials, error)
+}
+
+// GetCredentials calls f.
+func (f GetCredentialFunc) GetCredentials(id string) (*Credentials, error) {
+	return f(id)
+}
+
+// NonceStore saves the nonces of requests, like a SetNonceFunc.
+type NonceStore interface {
+	SetNonce(id string, nonce string, t time.Time) (bool, error)
+}
+
+// SetNonce calls f.
+func (f SetNonceFunc) SetNonce(id string, nonce string, t time.Time) (bool, error) {
+	return f(id, nonce, t)
+}
+
+// Result is a verified request.
+// ServerAuthorization is the "Server-Authorization" header to send in the response.
+type Result struct {
+	ID                  string
+	User                interface{}
+	Scopes              []string
+	App                 string
+	Delegate            string
+	Auth                *hawk.Auth
+	ServerAuthorization string
+}
+
+// Verify verifies r outside of gin with creds, nonces and the options
+// (see PresetStrict for example), for custom servers or stored requests:
+//
+//	res, err := hawk.Verify(r, store, nonces, hawk.PresetStrict())
+//	if err != nil {
+//		http.Error(w, hawk.PublicErrorCode(err), http.StatusUnauthorized)
+//		return
+//	}
+//	w.Header().Set("Server-Authorization", res.ServerAuthorization)
+//
+// If nonces is nil the requests are rejected with ErrNoNonceStore,
+// unless the nonce check is disabled with NonceValidation(false).
+func Verify(r *http.Request, creds CredentialGetter, nonces NonceStore, opts ...Option) (*Result, error) {
+	hm := &Middleware{GetCredentials: creds.GetCredentials, RequireNonce: true, swap: &swapped{}}
+	if nonces != nil {
+		hm.SetNonce = nonces.SetNonce
+	}
+	return hm.Apply(opts...).Verify(r)
+}
+
+// Verify verifies r like the Filter without gin, the Maintenance,
+// callbacks and diagnostics are not used. Errors are like the context
+// errors of the Filter, see Classify and ErrorCode. With StreamPayload
+// the body of r must be closed to remove its temporary file.
+func (hm *Middleware) Verify(r *http.Request) (res *Result, err error) {
+	serveGin(r, func(c *gin.Context) {
+		c.Abort()
+		res, err = hm.verify(c)
+		// the payload validation restores the body on the copy of r
+		r.Body = c.Request.Body
+	})
+	return res, err
+}
+
+// verify runs the checks of the Filter on c for Verify.
+func (hm *Middleware) verify(c *gin.Context) (*Result, error) {
+	hr := hm.acquireRequest(c)
+	defer func() {
+		// the caller reads the streamed body after Verify
+		hr.spool = nil
+		releaseRequest(hr)
+	}()
+
+	if err := hm.resolveTenant(c, hr); err != nil {
+		return nil, err
+	}
+	auth, err := hm.authenticate(c, hr)
+	if err != nil {
+		hm.recordFailure(hr, err)
+		if hm.RateLimitFailures && hr.ID != "" {
+			if rlErr := hm.rateLimit(hr.tenantID(hr.ID)); rlErr != nil {
+				err = rlErr
+			}
+		}
+		return nil, err
+	} else if err := hm.rateLimit(hr.tenantID(hr.ID)); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		ID:                  hr.ID,
+		User:                hr.User,
+		Scopes:              hr.Scopes,
+		App:                 auth.Credentials.App,
+		Delegate:            auth.Credentials.Delegate,
+		Auth:                hm.contextAuth(auth),
+		ServerAuthorization: hm.responseHeader(auth),
+	}, nil
+}
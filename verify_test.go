@@ -0,0 +1,131 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verify", func() {
+
+	var store *hawktest.Store
+
+	BeforeEach(func() {
+		store = hawktest.NewStore()
+		creds := store.Add("my-id", "test-cred-key")
+		creds.Scopes = []string{"read"}
+	})
+
+	sign := func(r *http.Request, key string) *hawk.Auth {
+		auth := hawk.NewRequestAuth(r, &hawk.Credentials{
+			ID:   "my-id",
+			Key:  key,
+			Hash: sha256.New,
+			App:  "my-app",
+		}, 0)
+		r.Header.Set("Authorization", auth.RequestHeader())
+		return auth
+	}
+
+	It("verifies requests without gin", func() {
+		var res *Result
+		var verr error
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, verr = Verify(r, store, store)
+			if verr != nil {
+				http.Error(w, ErrorCode(verr), 401)
+				return
+			}
+			w.Header().Set("Server-Authorization", res.ServerAuthorization)
+		}))
+		defer ts.Close()
+
+		req, _ := http.NewRequest("GET", ts.URL+"/resource", nil)
+		auth := sign(req, "test-cred-key")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(auth.ValidResponse(resp.Header.Get("Server-Authorization"))).To(Succeed())
+		Expect(res.ID).To(Equal("my-id"))
+		Expect(res.User).To(Equal("my-id"))
+		Expect(res.Scopes).To(Equal([]string{"read"}))
+		Expect(res.App).To(Equal("my-app"))
+		Expect(res.Auth.Credentials.ID).To(Equal("my-id"))
+
+		resp, err = http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(401))
+		Expect(verr).To(Equal(hawk.ErrReplay))
+	})
+
+	It("applies options", func() {
+		req := httptest.NewRequest("POST", "http://example.com/resource", strings.NewReader("payload"))
+		sign(req, "test-cred-key")
+		_, err := Verify(req, store, store, func(hm *Middleware) {
+			hm.RequirePayloadHash = true
+		})
+		Expect(err).To(Equal(ErrMissingPayloadHash))
+
+		req = httptest.NewRequest("GET", "http://example.com/resource", nil)
+		sign(req, "wrong-key")
+		_, err = Verify(req, GetCredentialFunc(store.GetCredentials), SetNonceFunc(func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		}))
+		Expect(err).To(Equal(hawk.ErrInvalidMAC))
+	})
+
+	It("rejects requests without a nonce store", func() {
+		req := httptest.NewRequest("GET", "http://example.com/resource", nil)
+		sign(req, "test-cred-key")
+		_, err := Verify(req, store, nil)
+		Expect(err).To(Equal(ErrNoNonceStore))
+
+		res, err := Verify(req, store, nil, NonceValidation(false))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.ID).To(Equal("my-id"))
+	})
+
+	It("shares the Filter checks", func() {
+		hm := NewMiddleware(store.GetCredentials, store.SetNonce)
+		hm.TenantResolver = func(c *gin.Context) string {
+			return c.GetHeader("X-Tenant")
+		}
+		hm.GetTenantCredentials = func(tenant, id string) (*Credentials, error) {
+			return store.GetCredentials(id)
+		}
+		req := httptest.NewRequest("GET", "http://example.com/resource", nil)
+		sign(req, "test-cred-key")
+		_, err := hm.Verify(req)
+		Expect(err).To(Equal(ErrUnknownTenant))
+
+		req.Header.Set("X-Tenant", "acme")
+		res, err := hm.Verify(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.ID).To(Equal("my-id"))
+
+		hm.ValidatePayload = true
+		hm.StreamPayload = true
+		req = httptest.NewRequest("POST", "http://example.com/resource", strings.NewReader("payload"))
+		req.Header.Set("X-Tenant", "acme")
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{ID: "my-id", Key: "test-cred-key", Hash: sha256.New}, 0)
+		auth.Hash = PayloadHash(sha256.New, "", []byte("payload"))
+		req.Header.Set("Authorization", auth.RequestHeader())
+		_, err = hm.Verify(req)
+		Expect(err).ToNot(HaveOccurred())
+		body, err := io.ReadAll(req.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("payload"))
+	})
+
+})
@@ -0,0 +1,64 @@
+package hawk
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServerAuthorizationKey is the context key of the "Server-Authorization"
+// header of WebSocket handshakes, see WebSocketResponseHeader.
+const ServerAuthorizationKey = "hawk_server_authorization"
+
+type userContextKey struct{}
+
+// isWebSocket returns true if r is a WebSocket handshake.
+func isWebSocket(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// webSocket prepares an authenticated WebSocket handshake: the
+// "Server-Authorization" header is not set on the response, which is
+// written by the upgrader after hijacking the connection, but kept for
+// WebSocketResponseHeader, and the user is set in the request context
+// for handlers only receiving the *http.Request.
+func (hm *Middleware) webSocket(c *gin.Context, header string, user interface{}) {
+	c.Set(ServerAuthorizationKey, header)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), userContextKey{}, user))
+}
+
+// WebSocketResponseHeader returns the headers to pass to the upgrader of
+// an authenticated WebSocket handshake: "Server-Authorization" and the
+// deprecation headers (see DeprecationHeaders). Browsers cannot set headers on handshakes, they should use a
+// bewit:
+//
+//	conn, err := upgrader.Upgrade(c.Writer, c.Request, hawk.WebSocketResponseHeader(c))
+func WebSocketResponseHeader(c *gin.Context) http.Header {
+	res := http.Header{}
+	if v := c.GetString(ServerAuthorizationKey); v != "" {
+		res.Set("Server-Authorization", v)
+	}
+	for _, k := range []string{"Deprecation", "Sunset", "Link"} {
+		if v := c.Writer.Header().Values(k); len(v) > 0 {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// UserFromRequest returns the user of an authenticated WebSocket
+// handshake from the request context and true, or nil and false.
+func UserFromRequest(r *http.Request) (interface{}, bool) {
+	user := r.Context().Value(userContextKey{})
+	return user, user != nil
+}
@@ -0,0 +1,99 @@
+package hawk_test
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebSocket", func() {
+
+	var ts *httptest.Server
+	var written, upgrade http.Header
+	var user interface{}
+
+	creds := &hawk.Credentials{
+		ID:   "my-id",
+		Key:  "test-cred-key",
+		Hash: sha256.New,
+	}
+
+	BeforeEach(func() {
+		written, upgrade, user = nil, nil, nil
+		hm := NewMiddleware(func(id string) (*Credentials, error) {
+			return &Credentials{
+				Key:             "test-cred-key",
+				User:            "ws-user",
+				Sunset:          time.Now().Add(time.Hour),
+				DeprecationLink: "https://example.com/deprecation",
+			}, nil
+		}, func(id string, nonce string, t time.Time) (bool, error) {
+			return true, nil
+		})
+		hm.DeprecationHeaders = true
+		router := gin.New()
+		router.GET("/ws", hm.Filter, func(c *gin.Context) {
+			written = c.Writer.Header().Clone()
+			upgrade = WebSocketResponseHeader(c)
+			user, _ = UserFromRequest(c.Request)
+			c.String(200, "ok")
+		})
+		ts = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	handshake := func(req *http.Request) int {
+		req.Header.Set("Connection", "keep-alive, Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode
+	}
+
+	It("authenticates handshakes with a header", func() {
+		req, _ := http.NewRequest("GET", ts.URL+"/ws", nil)
+		auth := hawk.NewRequestAuth(req, creds, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		Expect(handshake(req)).To(Equal(200))
+
+		Expect(written.Get("Server-Authorization")).To(BeEmpty())
+		Expect(auth.ValidResponse(upgrade.Get("Server-Authorization"))).To(Succeed())
+		Expect(upgrade.Get("Sunset")).ToNot(BeEmpty())
+		Expect(upgrade.Get("Link")).To(ContainSubstring(`rel="deprecation"`))
+		Expect(user).To(Equal("ws-user"))
+	})
+
+	It("authenticates handshakes with a bewit", func() {
+		auth, err := hawk.NewURLAuth(ts.URL+"/ws", creds, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		req, _ := http.NewRequest("GET", ts.URL+"/ws?bewit="+auth.Bewit(), nil)
+		Expect(handshake(req)).To(Equal(200))
+		Expect(user).To(Equal("ws-user"))
+
+		req, _ = http.NewRequest("GET", ts.URL+"/ws?bewit=invalid", nil)
+		Expect(handshake(req)).ToNot(Equal(200))
+	})
+
+	It("sets the header on other requests", func() {
+		req, _ := http.NewRequest("GET", ts.URL+"/ws", nil)
+		auth := hawk.NewRequestAuth(req, creds, 0)
+		req.Header.Set("Authorization", auth.RequestHeader())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(auth.ValidResponse(resp.Header.Get("Server-Authorization"))).To(Succeed())
+		Expect(upgrade.Get("Server-Authorization")).To(BeEmpty())
+		Expect(user).To(BeNil())
+	})
+
+})
@@ -0,0 +1,53 @@
+package hawk
+
+import (
+	"time"
+)
+
+// With returns a copy of hm with opts applied, so route groups can
+// diverge from the shared settings and credentials and nonce providers.
+// The SlowBodyAborts and NonceFailOpens of the copy are counted separately.
+// It panics with ErrNoTenantCredentials if the copy has a TenantResolver
+// without credentials lookup by tenant.
+//
+//	billing := router.Group("/billing", hm.With(hawk.Ext("billing"), hawk.Skew(2*time.Minute)).Filter)
+func (hm *Middleware) With(opts ...Option) *Middleware {
+	res := *hm
+	res.slowBodyAborts = 0
+	res.nonceFailOpens = 0
+	if err := res.Apply(opts...).checkTenant(); err != nil {
+		panic(err)
+	}
+	return &res
+}
+
+// Ext sets the "ext" field of the "Server-Authorization" header.
+func Ext(ext string) Option {
+	return func(hm *Middleware) {
+		hm.Ext = ext
+	}
+}
+
+// Skew sets the maximum timestamp skew (MaxSkew).
+func Skew(d time.Duration) Option {
+	return func(hm *Middleware) {
+		hm.MaxSkew = d
+	}
+}
+
+// NonceValidation sets if a nonce store is required (RequireNonce),
+// disabling it lets the requests through without replay check when
+// there is no nonce store.
+func NonceValidation(enabled bool) Option {
+	return func(hm *Middleware) {
+		hm.RequireNonce = enabled
+	}
+}
+
+// PayloadValidation sets if the body is checked against the payload
+// hash (ValidatePayload).
+func PayloadValidation(enabled bool) Option {
+	return func(hm *Middleware) {
+		hm.ValidatePayload = enabled
+	}
+}
@@ -0,0 +1,73 @@
+package hawk_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/hyperboloide/hawk"
+	"github.com/hyperboloide/hawk/hawktest"
+	hawk "github.com/hyperboloide/hawk/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("With", func() {
+
+	var hm *Middleware
+	var router *gin.Engine
+
+	BeforeEach(func() {
+		store := hawktest.NewStore()
+		store.Add("my-id", "my-key")
+		hm = store.Middleware()
+		router = gin.New()
+		handler := func(c *gin.Context) {
+			c.String(200, "ok")
+		}
+		router.POST("/private", hm.Filter, handler)
+		billing := router.Group("/billing", hm.With(Ext("billing"), Skew(2*time.Minute), PayloadValidation(true)).Filter)
+		billing.POST("/private", handler)
+	})
+
+	request := func(path string, offset time.Duration, hash []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "http://example.com"+path, bytes.NewReader([]byte("body")))
+		req.Header.Set("Content-Type", "text/plain")
+		auth := hawk.NewRequestAuth(req, &hawk.Credentials{
+			ID:   "my-id",
+			Key:  "my-key",
+			Hash: sha256.New,
+		}, offset)
+		auth.Hash = hash
+		req.Header.Set("Authorization", auth.RequestHeader())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			Expect(auth.ValidResponse(w.Header().Get("Server-Authorization"))).To(Succeed())
+		}
+		return w
+	}
+
+	It("overrides the settings of a route group", func() {
+		w := request("/billing/private", 90*time.Second, nil)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Server-Authorization")).To(ContainSubstring(`ext="billing"`))
+		Expect(request("/billing/private", 3*time.Minute, nil).Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("/billing/private", 0, []byte("invalid hash")).Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("/billing/private", 0, PayloadHash(sha256.New, "text/plain", []byte("body"))).Code).To(Equal(http.StatusOK))
+	})
+
+	It("does not change the parent Middleware", func() {
+		w := request("/private", 0, []byte("invalid hash"))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Server-Authorization")).ToNot(ContainSubstring("ext"))
+		Expect(request("/private", 90*time.Second, nil).Code).To(Equal(http.StatusUnauthorized))
+		Expect(hm.Ext).To(BeEmpty())
+		Expect(hm.MaxSkew).To(BeZero())
+	})
+
+})